@@ -0,0 +1,350 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+// AuthorizationEffect is the result of an authorization rule.
+type AuthorizationEffect string
+
+// Effects of authorization rules.
+const (
+	AuthorizationAllow AuthorizationEffect = "allow"
+	AuthorizationDeny  AuthorizationEffect = "deny"
+)
+
+// AuthorizationRule allows or denies calls that match all of its fields.
+// Each field is a list of patterns in the syntax of path.Match, and matches
+// if any pattern matches. An empty list matches any value.
+type AuthorizationRule struct {
+	Effect AuthorizationEffect `json:"effect"`
+
+	// Callers matches the caller name of the call.
+	Callers []string `json:"callers,omitempty"`
+
+	// Identities matches the name of the identity verified by the
+	// channel's Handshaker. Calls from connections without an identity
+	// never match a rule with identities.
+	Identities []string `json:"identities,omitempty"`
+
+	// Services matches the service name of the call.
+	Services []string `json:"services,omitempty"`
+
+	// Methods matches the method (e.g. the Thrift "Service::method") of the call.
+	Methods []string `json:"methods,omitempty"`
+}
+
+// AuthorizationPolicy is an ordered list of rules that authorizes inbound calls.
+// The first rule that matches a call decides whether it is allowed. Calls that
+// match no rule use DefaultEffect, which defaults to allow.
+type AuthorizationPolicy struct {
+	DefaultEffect AuthorizationEffect `json:"defaultEffect,omitempty"`
+	Rules         []AuthorizationRule `json:"rules"`
+}
+
+// AuthorizationRequest describes an inbound call being authorized.
+type AuthorizationRequest struct {
+	CallerName string
+	Identity   *PeerIdentity
+	Service    string
+	Method     string
+}
+
+// LoadAuthorizationPolicy reads a JSON-encoded AuthorizationPolicy from a file.
+func LoadAuthorizationPolicy(file string) (*AuthorizationPolicy, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &AuthorizationPolicy{}
+	if err := json.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("failed to parse authorization policy %v: %v", file, err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Allowed returns whether the policy allows the given call.
+// A nil policy allows all calls.
+func (p *AuthorizationPolicy) Allowed(req AuthorizationRequest) bool {
+	if p == nil {
+		return true
+	}
+
+	for _, r := range p.Rules {
+		if r.matches(req) {
+			return r.Effect == AuthorizationAllow
+		}
+	}
+	return p.DefaultEffect != AuthorizationDeny
+}
+
+// Validate returns an error if the policy has an invalid effect or pattern.
+func (p *AuthorizationPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+
+	if err := validateEffect(p.DefaultEffect, true /* allowEmpty */); err != nil {
+		return err
+	}
+	for i, r := range p.Rules {
+		if err := validateEffect(r.Effect, false /* allowEmpty */); err != nil {
+			return fmt.Errorf("rule %v: %v", i, err)
+		}
+		for _, patterns := range [][]string{r.Callers, r.Identities, r.Services, r.Methods} {
+			for _, pattern := range patterns {
+				if _, err := path.Match(pattern, ""); err != nil {
+					return fmt.Errorf("rule %v: invalid pattern %q: %v", i, pattern, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func validateEffect(effect AuthorizationEffect, allowEmpty bool) error {
+	switch effect {
+	case AuthorizationAllow, AuthorizationDeny:
+		return nil
+	case "":
+		if allowEmpty {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid authorization effect %q", effect)
+}
+
+func (r AuthorizationRule) matches(req AuthorizationRequest) bool {
+	if len(r.Identities) > 0 {
+		if req.Identity == nil || !matchAny(r.Identities, req.Identity.Name) {
+			return false
+		}
+	}
+	return matchAny(r.Callers, req.CallerName) &&
+		matchAny(r.Services, req.Service) &&
+		matchAny(r.Methods, req.Method)
+}
+
+func matchAny(patterns []string, s string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// authorizer holds the current authorization policy, which is shared by the
+// channel and all of its connections. The zero value allows all calls.
+type authorizer struct {
+	policy atomic.Value // authorizationPolicyHolder
+}
+
+// authorizationPolicyHolder allows a nil policy to be stored in an atomic.Value.
+type authorizationPolicyHolder struct {
+	policy *AuthorizationPolicy
+}
+
+func newAuthorizer(policy *AuthorizationPolicy) (*authorizer, error) {
+	a := &authorizer{}
+	if err := a.set(policy); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *authorizer) set(policy *AuthorizationPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	a.policy.Store(authorizationPolicyHolder{policy})
+	return nil
+}
+
+func (a *authorizer) get() *AuthorizationPolicy {
+	holder, _ := a.policy.Load().(authorizationPolicyHolder)
+	return holder.policy
+}
+
+// SetAuthorizationPolicy replaces the policy used to authorize inbound calls,
+// including relayed calls and calls to internal handlers such as introspection,
+// which use the "tchannel" service name. A nil policy allows all calls.
+func (ch *Channel) SetAuthorizationPolicy(policy *AuthorizationPolicy) error {
+	return ch.authz.set(policy)
+}
+
+// AuthorizationPolicy returns the policy used to authorize inbound calls, if any.
+func (ch *Channel) AuthorizationPolicy() *AuthorizationPolicy {
+	return ch.authz.get()
+}
+
+// WatchAuthorizationPolicy loads the authorization policy from a file, and
+// reloads it whenever the file changes, checking every interval. If a changed
+// file cannot be loaded, the error is logged and the previous policy is kept.
+// The watcher runs until the returned stop function is called, or the channel
+// is closed.
+func (ch *Channel) WatchAuthorizationPolicy(file string, interval time.Duration) (stop func(), _ error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := LoadAuthorizationPolicy(file)
+	if err != nil {
+		return nil, err
+	}
+	if err := ch.SetAuthorizationPolicy(policy); err != nil {
+		return nil, err
+	}
+
+	stopCh := make(chan struct{})
+	go ch.watchAuthorizationPolicy(file, info, interval, stopCh)
+
+	stopped := atomic.NewBool(false)
+	return func() {
+		if stopped.CAS(false, true) {
+			close(stopCh)
+		}
+	}, nil
+}
+
+func (ch *Channel) watchAuthorizationPolicy(file string, last os.FileInfo, interval time.Duration, stopCh <-chan struct{}) {
+	ticker := ch.timeTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-stopCh:
+			return
+		case <-ch.closed:
+			return
+		}
+
+		info, err := os.Stat(file)
+		if err != nil {
+			ch.log.WithFields(
+				LogField{"file", file},
+				ErrField(err),
+			).Warn("Failed to stat authorization policy.")
+			continue
+		}
+		if info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+			continue
+		}
+		last = info
+
+		policy, err := LoadAuthorizationPolicy(file)
+		if err == nil {
+			err = ch.SetAuthorizationPolicy(policy)
+		}
+		if err != nil {
+			ch.log.WithFields(
+				LogField{"file", file},
+				ErrField(err),
+			).Warn("Failed to reload authorization policy, keeping the previous policy.")
+			continue
+		}
+		ch.log.WithFields(LogField{"file", file}).Info("Reloaded authorization policy.")
+	}
+}
+
+// SetAuthorizationPolicy sets a policy used to authorize inbound calls to
+// this service. Calls must be allowed by both this policy and the channel's
+// policy. A nil policy allows all calls.
+func (c *SubChannel) SetAuthorizationPolicy(policy *AuthorizationPolicy) error {
+	return c.authz.set(policy)
+}
+
+// AuthorizationPolicy returns the policy used to authorize inbound calls to
+// this service, if any.
+func (c *SubChannel) AuthorizationPolicy() *AuthorizationPolicy {
+	return c.authz.get()
+}
+
+// authorize checks the call against the channel's authorization policy, and
+// the policy of the call's SubChannel, if any.
+func (c *Connection) authorize(call *InboundCall) bool {
+	if !call.Authorize(c.authz.get()) {
+		return false
+	}
+	if sc, ok := c.subChannels.get(call.ServiceName()); ok {
+		return call.Authorize(sc.authz.get())
+	}
+	return true
+}
+
+// Authorize checks the call against the given policy. If the call is not
+// allowed, it is failed with ErrUnauthorized, the denial is reported to the
+// StatsReporter, and false is returned. It allows handlers with their own
+// policy, such as thrift.Server, to authorize calls before handling them.
+func (call *InboundCall) Authorize(policy *AuthorizationPolicy) bool {
+	allowed := policy.Allowed(AuthorizationRequest{
+		CallerName: call.CallerName(),
+		Identity:   call.conn.remotePeerInfo.Identity,
+		Service:    call.ServiceName(),
+		Method:     call.methodString,
+	})
+	if allowed {
+		return true
+	}
+
+	call.statsReporter.IncCounter("inbound.calls.unauthorized", call.commonStatsTags, 1)
+	call.Response().SendSystemError(ErrUnauthorized)
+	return false
+}
+
+// authorize checks a relayed call against the channel's authorization policy,
+// and fails the call with ErrUnauthorized if it is not allowed.
+func (r *Relayer) authorize(f *lazyCallReq) bool {
+	allowed := r.conn.authz.get().Allowed(AuthorizationRequest{
+		CallerName: string(f.Caller()),
+		Identity:   r.conn.remotePeerInfo.Identity,
+		Service:    string(f.Service()),
+		Method:     string(f.Method()),
+	})
+	if allowed {
+		return true
+	}
+
+	r.conn.statsReporter.IncCounter("inbound.calls.unauthorized", map[string]string{
+		"calling-service": string(f.Caller()),
+		"service":         string(f.Service()),
+		"endpoint":        string(f.Method()),
+	}, 1)
+	r.conn.SendSystemError(f.Header.ID, f.Span(), ErrUnauthorized)
+	return false
+}
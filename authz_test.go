@@ -0,0 +1,331 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/temporalio/tchannel-go"
+	"github.com/temporalio/tchannel-go/json"
+	"github.com/temporalio/tchannel-go/raw"
+	"github.com/temporalio/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestAuthorizationPolicyAllowed(t *testing.T) {
+	policy := &tchannel.AuthorizationPolicy{
+		DefaultEffect: tchannel.AuthorizationDeny,
+		Rules: []tchannel.AuthorizationRule{
+			{Effect: tchannel.AuthorizationDeny, Methods: []string{"Admin::*"}, Identities: []string{"untrusted-*"}},
+			{Effect: tchannel.AuthorizationAllow, Callers: []string{"frontend", "batch"}, Services: []string{"users"}},
+			{Effect: tchannel.AuthorizationAllow, Identities: []string{"admin"}},
+		},
+	}
+
+	tests := []struct {
+		msg  string
+		req  tchannel.AuthorizationRequest
+		want bool
+	}{
+		{
+			msg:  "allowed caller",
+			req:  tchannel.AuthorizationRequest{CallerName: "frontend", Service: "users", Method: "Users::get"},
+			want: true,
+		},
+		{
+			msg:  "allowed caller for another service",
+			req:  tchannel.AuthorizationRequest{CallerName: "frontend", Service: "billing", Method: "Billing::charge"},
+			want: false,
+		},
+		{
+			msg:  "unknown caller uses the default effect",
+			req:  tchannel.AuthorizationRequest{CallerName: "unknown", Service: "users", Method: "Users::get"},
+			want: false,
+		},
+		{
+			msg:  "identity rule",
+			req:  tchannel.AuthorizationRequest{Identity: &tchannel.PeerIdentity{Name: "admin"}, Service: "billing"},
+			want: true,
+		},
+		{
+			msg:  "identity rule without an identity",
+			req:  tchannel.AuthorizationRequest{Service: "billing"},
+			want: false,
+		},
+		{
+			msg: "first matching rule applies",
+			req: tchannel.AuthorizationRequest{
+				CallerName: "frontend",
+				Identity:   &tchannel.PeerIdentity{Name: "untrusted-frontend"},
+				Service:    "users",
+				Method:     "Admin::reset",
+			},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, policy.Allowed(tt.req), tt.msg)
+	}
+
+	var nilPolicy *tchannel.AuthorizationPolicy
+	assert.True(t, nilPolicy.Allowed(tchannel.AuthorizationRequest{}), "Nil policy should allow all calls")
+	assert.True(t, (&tchannel.AuthorizationPolicy{}).Allowed(tchannel.AuthorizationRequest{}), "Default effect should be allow")
+}
+
+func TestAuthorizationPolicyInvalid(t *testing.T) {
+	ch := testutils.NewClient(t, nil)
+	defer ch.Close()
+
+	tests := []struct {
+		msg    string
+		policy *tchannel.AuthorizationPolicy
+	}{
+		{"missing effect", &tchannel.AuthorizationPolicy{Rules: []tchannel.AuthorizationRule{{}}}},
+		{"invalid effect", &tchannel.AuthorizationPolicy{DefaultEffect: "maybe"}},
+		{"invalid pattern", &tchannel.AuthorizationPolicy{Rules: []tchannel.AuthorizationRule{
+			{Effect: tchannel.AuthorizationAllow, Callers: []string{"["}},
+		}}},
+	}
+
+	for _, tt := range tests {
+		assert.Error(t, ch.SetAuthorizationPolicy(tt.policy), tt.msg)
+		assert.Nil(t, ch.AuthorizationPolicy(), "%v: policy should not be replaced", tt.msg)
+	}
+}
+
+func TestAuthorizationDenied(t *testing.T) {
+	stats := newRecordingStatsReporter()
+	opts := testutils.NewOpts().SetServiceName("svc").SetStatsReporter(stats)
+	opts.AuthorizationPolicy = &tchannel.AuthorizationPolicy{
+		Rules: []tchannel.AuthorizationRule{
+			{Effect: tchannel.AuthorizationDeny, Callers: []string{"blocked"}, Methods: []string{"secret"}},
+		},
+	}
+	server := testutils.NewServer(t, opts)
+	defer server.Close()
+
+	var handled int
+	handler := func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+		handled++
+		return &raw.Res{}, nil
+	}
+	testutils.RegisterFunc(server, "secret", handler)
+	testutils.RegisterFunc(server, "public", handler)
+
+	call := func(callerName, method string) error {
+		client := testutils.NewClient(t, testutils.NewOpts().SetServiceName(callerName))
+		defer client.Close()
+
+		ctx, cancel := tchannel.NewContext(time.Second)
+		defer cancel()
+
+		_, _, _, err := raw.Call(ctx, client, server.PeerInfo().HostPort, "svc", method, nil, nil)
+		return err
+	}
+
+	require.NoError(t, call("allowed", "secret"), "Allowed caller should succeed")
+	require.NoError(t, call("blocked", "public"), "Allowed method should succeed")
+
+	err := call("blocked", "secret")
+	require.Error(t, err, "Denied call should fail")
+	assert.Equal(t, tchannel.ErrUnauthorized, err, "Unexpected error")
+	assert.Equal(t, 2, handled, "Handler should not run for denied calls")
+
+	stats.Lock()
+	defer stats.Unlock()
+	unauthorized := stats.Values["inbound.calls.unauthorized"]
+	require.Len(t, unauthorized, 1, "Expected denial to be reported")
+	for tags, v := range unauthorized {
+		assert.Contains(t, tags, "blocked", "Stat should be tagged with the caller")
+		assert.Contains(t, tags, "secret", "Stat should be tagged with the endpoint")
+		assert.EqualValues(t, 1, v.count, "Unexpected denial count")
+	}
+}
+
+func TestAuthorizationByIdentity(t *testing.T) {
+	serverOpts := testutils.NewOpts().SetServiceName("svc")
+	serverOpts.Handshaker = tokenHandshaker{token: "server-token", expected: "client-token"}
+	serverOpts.AuthorizationPolicy = &tchannel.AuthorizationPolicy{
+		DefaultEffect: tchannel.AuthorizationDeny,
+		Rules: []tchannel.AuthorizationRule{
+			{Effect: tchannel.AuthorizationAllow, Identities: []string{"trusted-*"}},
+		},
+	}
+	server := testutils.NewServer(t, serverOpts)
+	defer server.Close()
+	testutils.RegisterEcho(server, nil)
+
+	newClient := func(processName string) *tchannel.Channel {
+		opts := testutils.NewOpts().SetProcessName(processName)
+		opts.Handshaker = tokenHandshaker{token: "client-token", expected: "server-token"}
+		return testutils.NewClient(t, opts)
+	}
+	call := func(client *tchannel.Channel) error {
+		ctx, cancel := tchannel.NewContext(time.Second)
+		defer cancel()
+
+		_, _, _, err := raw.Call(ctx, client, server.PeerInfo().HostPort, "svc", "echo", nil, nil)
+		return err
+	}
+
+	trusted := newClient("trusted-client")
+	defer trusted.Close()
+	assert.NoError(t, call(trusted), "Trusted identity should be allowed")
+
+	other := newClient("other-client")
+	defer other.Close()
+	assert.Equal(t, tchannel.ErrUnauthorized, call(other), "Other identities should be denied")
+
+	// Internal handlers are also authorized.
+	ctx, cancel := json.NewContext(time.Second)
+	defer cancel()
+
+	var resp map[string]interface{}
+	peer := other.Peers().GetOrAdd(server.PeerInfo().HostPort)
+	err := json.CallPeer(ctx, peer, "tchannel", "_gometa_runtime", nil, &resp)
+	require.Error(t, err, "Internal handlers should be authorized")
+	assert.Contains(t, err.Error(), tchannel.ErrUnauthorized.Error(), "Unexpected error")
+
+	peer = trusted.Peers().GetOrAdd(server.PeerInfo().HostPort)
+	assert.NoError(t, json.CallPeer(ctx, peer, "tchannel", "_gometa_runtime", nil, &resp),
+		"Trusted identity should be allowed to call internal handlers")
+}
+
+func TestSubChannelAuthorizationPolicy(t *testing.T) {
+	server := testutils.NewServer(t, testutils.NewOpts().SetServiceName("svc"))
+	defer server.Close()
+	testutils.RegisterEcho(server, nil)
+	testutils.RegisterEcho(server.GetSubChannel("other"), nil)
+
+	sc := server.GetSubChannel("svc")
+	assert.Error(t, sc.SetAuthorizationPolicy(&tchannel.AuthorizationPolicy{DefaultEffect: "maybe"}),
+		"Invalid policies should be rejected")
+	require.NoError(t, sc.SetAuthorizationPolicy(&tchannel.AuthorizationPolicy{
+		DefaultEffect: tchannel.AuthorizationDeny,
+		Rules: []tchannel.AuthorizationRule{
+			{Effect: tchannel.AuthorizationAllow, Callers: []string{"allowed"}},
+		},
+	}), "SetAuthorizationPolicy failed")
+	assert.NotNil(t, sc.AuthorizationPolicy(), "Policy should be set")
+
+	call := func(callerName, service string) error {
+		client := testutils.NewClient(t, testutils.NewOpts().SetServiceName(callerName))
+		defer client.Close()
+
+		ctx, cancel := tchannel.NewContext(time.Second)
+		defer cancel()
+
+		_, _, _, err := raw.Call(ctx, client, server.PeerInfo().HostPort, service, "echo", nil, nil)
+		return err
+	}
+
+	assert.NoError(t, call("allowed", "svc"), "Allowed caller should succeed")
+	assert.Equal(t, tchannel.ErrUnauthorized, call("blocked", "svc"), "SubChannel policy should deny caller")
+	assert.NoError(t, call("blocked", "other"), "SubChannel policy should not apply to other services")
+
+	// The channel's policy applies as well as the SubChannel's policy.
+	require.NoError(t, server.SetAuthorizationPolicy(&tchannel.AuthorizationPolicy{
+		DefaultEffect: tchannel.AuthorizationDeny,
+	}), "SetAuthorizationPolicy failed")
+	assert.Equal(t, tchannel.ErrUnauthorized, call("allowed", "svc"), "Channel policy should deny caller")
+}
+
+func TestRelayAuthorizationPolicy(t *testing.T) {
+	opts := testutils.NewOpts().SetRelayOnly()
+	testutils.WithTestServer(t, opts, func(t testing.TB, ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+		require.NoError(t, ts.Relay().SetAuthorizationPolicy(&tchannel.AuthorizationPolicy{
+			Rules: []tchannel.AuthorizationRule{
+				{Effect: tchannel.AuthorizationDeny, Callers: []string{"blocked"}},
+			},
+		}), "SetAuthorizationPolicy failed")
+
+		call := func(callerName string) error {
+			client := ts.NewClient(testutils.NewOpts().SetServiceName(callerName))
+			defer client.Close()
+
+			ctx, cancel := tchannel.NewContext(time.Second)
+			defer cancel()
+
+			_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, nil)
+			return err
+		}
+
+		assert.NoError(t, call("allowed"), "Allowed caller should be relayed")
+		assert.Equal(t, tchannel.ErrUnauthorized, call("blocked"), "Relay should deny caller")
+	})
+}
+
+func TestWatchAuthorizationPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "authz")
+	require.NoError(t, err, "TempDir failed")
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "policy.json")
+	writePolicy := func(contents string) {
+		require.NoError(t, ioutil.WriteFile(file, []byte(contents), 0644), "Failed to write policy")
+	}
+	writePolicy(`{"rules": [{"effect": "allow"}]}`)
+
+	opts := testutils.NewOpts().SetServiceName("svc").
+		AddLogFilter("Failed to reload authorization policy, keeping the previous policy.", 1)
+	server := testutils.NewServer(t, opts)
+	defer server.Close()
+	testutils.RegisterEcho(server, nil)
+
+	stop, err := server.WatchAuthorizationPolicy(file, 10*time.Millisecond)
+	require.NoError(t, err, "WatchAuthorizationPolicy failed")
+	defer stop()
+
+	client := testutils.NewClient(t, nil)
+	defer client.Close()
+
+	call := func() error {
+		ctx, cancel := tchannel.NewContext(time.Second)
+		defer cancel()
+
+		_, _, _, err := raw.Call(ctx, client, server.PeerInfo().HostPort, "svc", "echo", nil, nil)
+		return err
+	}
+	require.NoError(t, call(), "Call should be allowed by the initial policy")
+
+	writePolicy(`{"defaultEffect": "deny", "rules": [{"effect": "allow", "methods": ["other"]}]}`)
+	require.True(t, testutils.WaitFor(time.Second, func() bool {
+		return call() == tchannel.ErrUnauthorized
+	}), "Policy was not reloaded")
+
+	// Invalid policies are ignored, and the previous policy is kept.
+	writePolicy(`{"rules": [{"effect": "invalid"}]}`)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, tchannel.ErrUnauthorized, call(), "Previous policy should be kept")
+	assert.Equal(t, tchannel.AuthorizationDeny, server.AuthorizationPolicy().DefaultEffect, "Unexpected policy")
+
+	_, err = server.WatchAuthorizationPolicy(filepath.Join(dir, "missing.json"), time.Second)
+	assert.Error(t, err, "Watching a missing file should fail")
+}
@@ -60,12 +60,27 @@ type CallOptions struct {
 	// Optionally override this field to support transparent proxying when inbound
 	// caller names vary across calls.
 	CallerName string
+
+	// Priority is the priority class of the call's frames relative to other
+	// calls on the same connection. It's sent in the "pr" header so that
+	// relays and the server use the same priority. Defaults to PriorityNormal.
+	Priority PriorityClass
+
+	// DisableCompression disables payload compression for this call, even if
+	// compression was negotiated for the connection.
+	DisableCompression bool
+
+	// streaming is set for calls started using BeginStreamingCall.
+	streaming bool
 }
 
 var defaultCallOptions = &CallOptions{}
 
 func (c *CallOptions) setHeaders(headers transportHeaders) {
 	headers[ArgScheme] = Raw.String()
+	if c.streaming {
+		headers[Streaming] = "1"
+	}
 	c.overrideHeaders(headers)
 }
 
@@ -86,6 +101,9 @@ func (c *CallOptions) overrideHeaders(headers transportHeaders) {
 	if c.CallerName != "" {
 		headers[CallerName] = c.CallerName
 	}
+	if p := c.Priority.header(); p != "" {
+		headers[Priority] = p
+	}
 }
 
 // setResponseHeaders copies some headers from the incoming call request to the response.
@@ -0,0 +1,85 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/temporalio/tchannel-go"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/temporalio/tchannel-go/raw"
+	"github.com/temporalio/tchannel-go/testutils"
+	"golang.org/x/net/context"
+)
+
+func TestCancelPropagatesToHandler(t *testing.T) {
+	testutils.WithTestServer(t, nil, func(t testing.TB, ts *testutils.TestServer) {
+		handlerStarted := make(chan struct{})
+		handlerErr := make(chan error, 1)
+		ts.Register(HandlerFunc(func(ctx context.Context, call *InboundCall) {
+			close(handlerStarted)
+			select {
+			case <-ctx.Done():
+				handlerErr <- ctx.Err()
+			case <-time.After(testutils.Timeout(time.Second)):
+				handlerErr <- nil
+			}
+		}), "block")
+
+		client := ts.NewClient(nil)
+
+		// The call has a long TTL, so the handler is only unblocked by a cancel.
+		ctx, cancel := NewContext(time.Minute)
+		go func() {
+			<-handlerStarted
+			cancel()
+		}()
+
+		_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "block", nil, nil)
+		require.Error(t, err, "Call should fail when the context is cancelled")
+		assert.Equal(t, ErrCodeCancelled, GetSystemErrorCode(err), "Unexpected error code")
+
+		select {
+		case err := <-handlerErr:
+			assert.Equal(t, context.Canceled, err, "Handler context should be cancelled")
+		case <-time.After(testutils.Timeout(2 * time.Second)):
+			t.Fatal("Handler did not return")
+		}
+	})
+}
+
+func TestCancelAfterResponseIgnored(t *testing.T) {
+	testutils.WithTestServer(t, nil, func(t testing.TB, ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+		client := ts.NewClient(nil)
+
+		ctx, cancel := NewContext(testutils.Timeout(time.Second))
+		_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, nil)
+		cancel()
+		require.NoError(t, err, "Echo failed")
+
+		// A completed call must still work with a fresh context.
+		testutils.AssertEcho(t, client, ts.HostPort(), ts.ServiceName())
+	})
+}
@@ -21,6 +21,7 @@
 package tchannel
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net"
@@ -66,6 +67,11 @@ type ChannelOptions struct {
 	// The logger to use for this channel
 	Logger Logger
 
+	// LogLevels overrides the log level for parts of the channel, such as the
+	// relay. Overrides can be changed at runtime using SetLogLevel, or the
+	// _gometa_log_levels endpoint.
+	LogLevels map[LogSubsystem]LogLevel
+
 	// The host:port selection implementation to use for relaying. This is an
 	// unstable API - breaking changes are likely.
 	RelayHost RelayHost
@@ -133,13 +139,70 @@ type ChannelOptions struct {
 	SkipHandlerMethods []string
 
 	// Dialer is optional factory method which can be used for overriding
-	// outbound connections for things like TLS handshake
+	// outbound connections for things like TLS handshake.
+	// For peers addressed as "unix://<path>", network is "unix" and the
+	// address is the socket path.
 	Dialer func(ctx context.Context, network, hostPort string) (net.Conn, error)
 
 	// ConnContext runs when a connection is established, which updates
 	// the per-connection base context. This context is used as the parent context
 	// for incoming calls.
 	ConnContext func(ctx context.Context, conn net.Conn) context.Context
+
+	// TLSConfig enables TLS for both inbound and outbound connections.
+	// Outbound connections verify the server using the host of the peer's
+	// host:port if ServerName is not set, so ServerName must be set to use TLS
+	// with peers addressed as "unix://<path>". To require mutual TLS, set
+	// ClientAuth to tls.RequireAndVerifyClientCert and provide ClientCAs.
+	// The verified peer identity is available via PeerInfo.TLS.
+	// Certificates can be rotated using GetCertificate and
+	// GetClientCertificate, or by calling Channel.SetTLSConfig.
+	TLSConfig *tls.Config
+
+	// ConcurrencyLimit limits the number of inbound calls that are handled
+	// concurrently across all services. Limits can also be set per service
+	// and per method using SubChannel.SetConcurrencyLimit and
+	// SubChannel.SetMethodConcurrencyLimit.
+	ConcurrencyLimit ConcurrencyLimit
+
+	// CircuitBreaker enables per-peer circuit breakers. Peers that fail
+	// repeatedly with unexpected or network errors are ejected from peer
+	// selection, and probed with limited traffic after a timeout.
+	// OnPeerStatusChanged is called when a peer's breaker changes state.
+	CircuitBreaker *CircuitBreakerOptions
+
+	// RetryBudget limits retries of outbound calls made by this channel
+	// across all services. Budgets can also be set per service using
+	// SubChannel.SetRetryBudget.
+	RetryBudget *RetryBudget
+
+	// Deadlines configures how the deadlines of inbound calls are propagated
+	// to outbound calls made using the inbound call's context.
+	Deadlines DeadlineOptions
+
+	// Handshaker adds and verifies extra headers in the init handshake of
+	// all connections, and can be used to authenticate peers. The verified
+	// identity is available via PeerInfo.Identity.
+	Handshaker Handshaker
+
+	// AuthorizationPolicy is the initial policy used to authorize inbound
+	// calls before their handlers run. It can be replaced using
+	// Channel.SetAuthorizationPolicy or Channel.WatchAuthorizationPolicy.
+	AuthorizationPolicy *AuthorizationPolicy
+
+	// EnableConfigEndpoint registers the _gometa_config endpoint, which lets
+	// callers change the channel's RuntimeOptions. Any peer that can call the
+	// channel can use it, so the AuthorizationPolicy should restrict calls to
+	// the "tchannel" service.
+	EnableConfigEndpoint bool
+
+	// InboundInterceptors intercept all inbound calls, in order, before
+	// they are handled.
+	InboundInterceptors []InboundInterceptor
+
+	// OutboundInterceptors intercept all outbound calls, in order, before
+	// they are sent.
+	OutboundInterceptors []OutboundInterceptor
 }
 
 // ChannelState is the state of a channel.
@@ -180,7 +243,7 @@ type Channel struct {
 	connectionOptions   ConnectionOptions
 	peers               *PeerList
 	relayHost           RelayHost
-	relayMaxTimeout     time.Duration
+	relayMaxTimeout     *atomic.Duration
 	relayMaxConnTimeout time.Duration
 	relayMaxTombs       uint64
 	relayTimerVerify    bool
@@ -189,6 +252,8 @@ type Channel struct {
 	onPeerStatusChanged func(*Peer)
 	dialer              func(ctx context.Context, hostPort string) (net.Conn, error)
 	connContext         func(ctx context.Context, conn net.Conn) context.Context
+	tlsConfig           atomic.Value // tlsConfigHolder
+	handshaker          Handshaker
 	closed              chan struct{}
 
 	// mutable contains all the members of Channel which are mutable.
@@ -199,6 +264,7 @@ type Channel struct {
 		l            net.Listener  // May be nil if this is a client only channel
 		idleSweep    *idleSweep
 		conns        map[uint32]*Connection
+		drainHooks   []func(ctx context.Context) error
 	}
 }
 
@@ -206,12 +272,21 @@ type Channel struct {
 // and can be copied directly from the channel to the connection.
 type channelConnectionCommon struct {
 	log           Logger
+	logLevels     *logLevels
 	relayLocal    map[string]struct{}
 	statsReporter StatsReporter
 	tracer        opentracing.Tracer
 	subChannels   *subChannelMap
 	timeNow       func() time.Time
 	timeTicker    func(time.Duration) *time.Ticker
+	draining      *atomic.Bool
+	limiter       *concurrencyLimiter
+	retryBudget   *retryBudget
+	deadlineOpts  DeadlineOptions
+	authz         *authorizer
+
+	inboundInterceptors  []InboundInterceptor
+	outboundInterceptors []OutboundInterceptor
 }
 
 // _nextChID is used to allocate unique IDs to every channel for debugging purposes.
@@ -275,11 +350,42 @@ func NewChannel(serviceName string, opts *ChannelOptions) (*Channel, error) {
 		return nil, err
 	}
 
+	if err := validateCompression(opts.DefaultConnectionOptions.Compression); err != nil {
+		return nil, err
+	}
+
+	if err := validateFlowControlWindow(opts.DefaultConnectionOptions.FlowControlWindow); err != nil {
+		return nil, err
+	}
+
+	limiter, err := newConcurrencyLimiter(opts.ConcurrencyLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	authz, err := newAuthorizer(opts.AuthorizationPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	logLevels, err := newLogLevels(opts.LogLevels)
+	if err != nil {
+		return nil, err
+	}
+
+	var retryBudget *retryBudget
+	if opts.RetryBudget != nil {
+		if retryBudget, err = newRetryBudget(*opts.RetryBudget); err != nil {
+			return nil, err
+		}
+	}
+
 	// Default to dialContext if dialer is not passed in as an option
 	dialCtx := dialContext
 	if opts.Dialer != nil {
 		dialCtx = func(ctx context.Context, hostPort string) (net.Conn, error) {
-			return opts.Dialer(ctx, "tcp", hostPort)
+			network, address := splitNetworkAddress(hostPort)
+			return opts.Dialer(ctx, network, address)
 		}
 	}
 
@@ -292,25 +398,41 @@ func NewChannel(serviceName string, opts *ChannelOptions) (*Channel, error) {
 	ch := &Channel{
 		channelConnectionCommon: channelConnectionCommon{
 			log:           logger,
+			logLevels:     logLevels,
 			relayLocal:    toStringSet(opts.RelayLocalHandlers),
 			statsReporter: statsReporter,
 			subChannels:   &subChannelMap{},
 			timeNow:       timeNow,
 			timeTicker:    timeTicker,
 			tracer:        opts.Tracer,
+			draining:      atomic.NewBool(false),
+			limiter:       limiter,
+			retryBudget:   retryBudget,
+			deadlineOpts:  opts.Deadlines,
+			authz:         authz,
+
+			inboundInterceptors:  opts.InboundInterceptors,
+			outboundInterceptors: opts.OutboundInterceptors,
 		},
 		chID:                chID,
 		connectionOptions:   opts.DefaultConnectionOptions.withDefaults(),
 		relayHost:           opts.RelayHost,
-		relayMaxTimeout:     validateRelayMaxTimeout(opts.RelayMaxTimeout, logger),
+		relayMaxTimeout:     atomic.NewDuration(validateRelayMaxTimeout(opts.RelayMaxTimeout, logger)),
 		relayMaxConnTimeout: opts.RelayMaxConnectionTimeout,
 		relayMaxTombs:       opts.RelayMaxTombs,
 		relayTimerVerify:    opts.RelayTimerVerification,
 		dialer:              dialCtx,
 		connContext:         opts.ConnContext,
+		handshaker:          opts.Handshaker,
 		closed:              make(chan struct{}),
 	}
-	ch.peers = newRootPeerList(ch, opts.OnPeerStatusChanged).newChild()
+	var newBreaker func() *circuitBreaker
+	if opts.CircuitBreaker != nil {
+		breakerOpts := *opts.CircuitBreaker
+		newBreaker = func() *circuitBreaker { return newCircuitBreaker(breakerOpts, timeNow) }
+	}
+	ch.peers = newRootPeerList(ch, opts.OnPeerStatusChanged, newBreaker, ch.connectionOptions.ConnectionsPerPeer).newChild()
+	ch.SetTLSConfig(opts.TLSConfig)
 
 	switch {
 	case len(opts.SkipHandlerMethods) > 0 && opts.Handler != nil:
@@ -345,7 +467,7 @@ func NewChannel(serviceName string, opts *ChannelOptions) (*Channel, error) {
 	ch.mutable.state = ChannelClient
 	ch.mutable.conns = make(map[uint32]*Connection)
 	ch.createCommonStats()
-	ch.internalHandlers = ch.createInternalHandlers()
+	ch.internalHandlers = ch.createInternalHandlers(opts.EnableConfigEndpoint)
 
 	registerNewChannel(ch)
 
@@ -354,12 +476,14 @@ func NewChannel(serviceName string, opts *ChannelOptions) (*Channel, error) {
 	}
 
 	// Start the idle connection timer.
-	ch.mutable.idleSweep = startIdleSweep(ch, opts)
+	ch.mutable.idleSweep = startIdleSweep(ch, opts.MaxIdleTime, opts.IdleCheckInterval)
 
 	return ch, nil
 }
 
 // ConnectionOptions returns the channel's connection options.
+// HealthChecks and SendBufferSize may be changed by UpdateOptions, use
+// RuntimeOptions to read them while the channel is in use.
 func (ch *Channel) ConnectionOptions() *ConnectionOptions {
 	return &ch.connectionOptions
 }
@@ -382,7 +506,7 @@ func (ch *Channel) Serve(l net.Listener) error {
 	}
 	mutable.state = ChannelListening
 
-	mutable.peerInfo.HostPort = l.Addr().String()
+	mutable.peerInfo.HostPort = addrHostPort(l.Addr())
 	mutable.peerInfo.IsEphemeral = false
 	ch.log = ch.log.WithFields(LogField{"hostPort", mutable.peerInfo.HostPort})
 	ch.log.Info("Channel is listening.")
@@ -391,7 +515,9 @@ func (ch *Channel) Serve(l net.Listener) error {
 }
 
 // ListenAndServe listens on the given address and serves incoming requests.
-// The port may be 0, in which case the channel will use an OS assigned port
+// The port may be 0, in which case the channel will use an OS assigned port.
+// Addresses of the form "unix://<path>" listen on a Unix domain socket, and
+// paths starting with "@" use the Linux abstract socket namespace.
 // This method does not block as the handling of connections is done in a goroutine.
 func (ch *Channel) ListenAndServe(hostPort string) error {
 	mutable := &ch.mutable
@@ -402,7 +528,7 @@ func (ch *Channel) ListenAndServe(hostPort string) error {
 		return errAlreadyListening
 	}
 
-	l, err := net.Listen("tcp", hostPort)
+	l, err := net.Listen(splitNetworkAddress(hostPort))
 	if err != nil {
 		mutable.RUnlock()
 		return err
@@ -553,6 +679,7 @@ func (ch *Channel) serve() {
 				OnCloseStateChange: ch.connectionCloseStateChange,
 				OnExchangeUpdated:  ch.exchangeUpdated,
 			}
+			netConn := ch.tlsServer(netConn)
 			if _, err := ch.inboundHandshake(context.Background(), netConn, events); err != nil {
 				netConn.Close()
 			}
@@ -647,7 +774,7 @@ func (ch *Channel) Connect(ctx context.Context, hostPort string) (*Connection, e
 		return nil, err
 	}
 
-	conn, err := ch.outboundHandshake(ctx, tcpConn, hostPort, events)
+	conn, err := ch.outboundHandshake(ctx, ch.tlsClient(tcpConn, hostPort), hostPort, events)
 	if conn != nil {
 		// It's possible that the connection we just created responds with a host:port
 		// that is not what we tried to connect to. E.g., we may have connected to
@@ -672,12 +799,16 @@ func (ch *Channel) exchangeUpdated(c *Connection) {
 		return
 	}
 
-	p, ok := ch.RootPeers().Get(c.remotePeerInfo.HostPort)
-	if !ok {
-		return
+	if p, ok := ch.RootPeers().Get(c.remotePeerInfo.HostPort); ok {
+		ch.updatePeer(p)
+	}
+	if c.outboundHP != "" && c.outboundHP != c.remotePeerInfo.HostPort {
+		// Outbound connections may be in multiple peers, and calls on them
+		// update the stats of the peer they were made to.
+		if p, ok := ch.RootPeers().Get(c.outboundHP); ok {
+			ch.updatePeer(p)
+		}
 	}
-
-	ch.updatePeer(p)
 }
 
 // updatePeer updates the score of the peer and update it's position in heap as well.
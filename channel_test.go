@@ -125,7 +125,7 @@ func TestRelayMaxTTL(t *testing.T) {
 			RelayMaxTimeout: tt.max,
 		})
 		assert.NoError(t, err, "Unexpected error when creating channel.")
-		assert.Equal(t, ch.relayMaxTimeout, tt.expected, "Unexpected max timeout on channel.")
+		assert.Equal(t, ch.relayMaxTimeout.Load(), tt.expected, "Unexpected max timeout on channel.")
 	}
 }
 
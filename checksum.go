@@ -21,6 +21,8 @@
 package tchannel
 
 import (
+	"encoding/binary"
+	"fmt"
 	"hash"
 	"hash/crc32"
 	"sync"
@@ -60,9 +62,23 @@ func init() {
 		return newHashChecksum(ChecksumTypeCrc32C, crc32.New(crc32CastagnoliTable))
 	}
 
-	// TODO: Implement farm hash.
 	ChecksumTypeFarmhash.pool().New = func() interface{} {
-		return nullChecksum{}
+		return newFarmhashChecksum()
+	}
+}
+
+func (t ChecksumType) String() string {
+	switch t {
+	case ChecksumTypeNone:
+		return "none"
+	case ChecksumTypeCrc32:
+		return "crc32"
+	case ChecksumTypeFarmhash:
+		return "farmhash"
+	case ChecksumTypeCrc32C:
+		return "crc32c"
+	default:
+		return fmt.Sprintf("ChecksumType(%d)", byte(t))
 	}
 }
 
@@ -174,6 +190,76 @@ func (h *hashChecksum) Release() { h.TypeCode().Release(h) }
 // Reset resets the checksum state to the default 0 value.
 func (h *hashChecksum) Reset() { h.hash.Reset() }
 
+// Farmhash Checksum
+//
+// FarmHash is not a streaming hash, so the checksum of each fragment is the
+// FarmHash Fingerprint32 of the args in that fragment. The checksum of each
+// continuation fragment is seeded with the checksum of the previous fragment,
+// so that it covers all args sent so far. Sum is called once at the end of
+// each fragment, so the first non-empty Add after Sum starts a new fragment.
+type farmhashChecksum struct {
+	pending  []byte
+	prior    uint32
+	hasPrior bool
+	sum      uint32
+	summed   bool
+	sumCache []byte
+}
+
+func newFarmhashChecksum() *farmhashChecksum {
+	return &farmhashChecksum{sumCache: make([]byte, 4)}
+}
+
+// TypeCode returns the type of the checksum
+func (f *farmhashChecksum) TypeCode() ChecksumType { return ChecksumTypeFarmhash }
+
+// Size returns the size of the checksum data
+func (f *farmhashChecksum) Size() int { return 4 }
+
+// Add adds a byte slice to the checksum calculation. The checksum is only
+// calculated by Sum, so Add does not return the intermediate checksum.
+func (f *farmhashChecksum) Add(b []byte) []byte {
+	if len(b) == 0 {
+		return nil
+	}
+
+	if f.summed {
+		f.prior = f.sum
+		f.hasPrior = true
+		f.summed = false
+		f.pending = f.pending[:0]
+	}
+	f.pending = append(f.pending, b...)
+	return nil
+}
+
+// Sum returns the current value of the checksum calculation
+func (f *farmhashChecksum) Sum() []byte {
+	if !f.summed {
+		if f.hasPrior {
+			f.sum = farmhash32WithSeed(f.pending, f.prior)
+		} else {
+			f.sum = farmhash32(f.pending)
+		}
+		f.summed = true
+	}
+
+	binary.BigEndian.PutUint32(f.sumCache, f.sum)
+	return f.sumCache
+}
+
+// Release puts a Checksum back in the pool.
+func (f *farmhashChecksum) Release() { f.TypeCode().Release(f) }
+
+// Reset resets the checksum state to the default 0 value.
+func (f *farmhashChecksum) Reset() {
+	f.pending = f.pending[:0]
+	f.prior = 0
+	f.hasPrior = false
+	f.sum = 0
+	f.summed = false
+}
+
 // noReleaseChecksum overrides .Release() with a NOOP so that the checksum won't
 // be released by the fragmentingWriter when it is managed externally, e.g. by the
 // relayer
@@ -0,0 +1,176 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/temporalio/tchannel-go"
+	"github.com/temporalio/tchannel-go/raw"
+	"github.com/temporalio/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+var (
+	corruptMarker    = []byte("corrupt-me")
+	corruptedPayload = []byte("CORRUPT-me")
+)
+
+// corruptingConn corrupts any writes that contain corruptMarker, simulating
+// corruption on the network path that is caught by checksums.
+type corruptingConn struct {
+	*net.TCPConn
+}
+
+func (c corruptingConn) Write(b []byte) (int, error) {
+	if i := bytes.Index(b, corruptMarker); i >= 0 {
+		corrupted := append([]byte(nil), b...)
+		copy(corrupted[i:], corruptedPayload)
+		b = corrupted
+	}
+	return c.TCPConn.Write(b)
+}
+
+func corruptingDialer(ctx context.Context, network, hostPort string) (net.Conn, error) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, network, hostPort)
+	if err != nil {
+		return nil, err
+	}
+	return corruptingConn{conn.(*net.TCPConn)}, nil
+}
+
+func TestChecksumFailures(t *testing.T) {
+	stats := newRecordingStatsReporter()
+	serverOpts := testutils.NewOpts().
+		SetStatsReporter(stats).
+		AddLogFilter("Received fragment with mismatched checksum.", 2)
+	serverOpts.DefaultConnectionOptions.MaxChecksumFailures = 2
+	server := testutils.NewServer(t, serverOpts)
+	defer server.Close()
+
+	server.Register(tchannel.HandlerFunc(func(ctx context.Context, call *tchannel.InboundCall) {
+		args, err := raw.ReadArgs(call)
+		if err != nil {
+			call.Response().SendSystemError(err)
+			return
+		}
+		raw.WriteResponse(call.Response(), &raw.Res{Arg2: args.Arg2, Arg3: args.Arg3})
+	}), "echo")
+
+	client := testutils.NewClient(t, testutils.NewOpts().SetDialer(corruptingDialer))
+	defer client.Close()
+
+	call := func(arg3 []byte) error {
+		ctx, cancel := tchannel.NewContext(time.Second)
+		defer cancel()
+
+		_, _, _, err := raw.Call(ctx, client, server.PeerInfo().HostPort, server.ServiceName(), "echo", nil, arg3)
+		return err
+	}
+
+	inboundConns := func() []tchannel.ConnectionRuntimeState {
+		var conns []tchannel.ConnectionRuntimeState
+		for _, peer := range server.IntrospectState(&tchannel.IntrospectionOptions{}).RootPeers {
+			conns = append(conns, peer.InboundConnections...)
+		}
+		return conns
+	}
+
+	err := call(corruptMarker)
+	require.Error(t, err, "Call with corrupted payload should fail")
+	assert.Equal(t, tchannel.ErrCodeBadRequest, tchannel.GetSystemErrorCode(err), "Unexpected error code: %v", err)
+	assert.Contains(t, err.Error(), "different checksums between peer and local for "+server.ServiceName())
+
+	require.NoError(t, call([]byte("not corrupted")), "Call without corruption failed")
+
+	conns := inboundConns()
+	require.Len(t, conns, 1, "Expected a single inbound connection")
+	assert.EqualValues(t, 1, conns[0].ChecksumFailures, "Unexpected checksum failures")
+
+	stats.Lock()
+	failures := stats.Values["inbound.calls.checksum-failures"]
+	stats.Unlock()
+	require.Len(t, failures, 1, "Expected checksum failures to be reported")
+	for _, v := range failures {
+		assert.EqualValues(t, 1, v.count, "Unexpected checksum failures count")
+	}
+
+	// The connection is closed once it reaches the maximum checksum failures.
+	err = call(corruptMarker)
+	assert.Equal(t, tchannel.ErrCodeBadRequest, tchannel.GetSystemErrorCode(err), "Unexpected error code: %v", err)
+	assert.True(t, testutils.WaitFor(time.Second, func() bool {
+		return len(inboundConns()) == 0
+	}), "Connection should be closed after too many checksum failures")
+}
+
+// corruptingListener corrupts writes on accepted connections.
+type corruptingListener struct {
+	net.Listener
+}
+
+func (l corruptingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return corruptingConn{conn.(*net.TCPConn)}, nil
+}
+
+func TestChecksumFailuresInResponse(t *testing.T) {
+	server := testutils.NewClient(t, testutils.NewOpts().SetServiceName("server"))
+	defer server.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "Listen failed")
+	require.NoError(t, server.Serve(corruptingListener{ln}), "Serve failed")
+	testutils.RegisterFunc(server, "corrupt", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+		return &raw.Res{Arg3: corruptMarker}, nil
+	})
+
+	stats := newRecordingStatsReporter()
+	client := testutils.NewClient(t, testutils.NewOpts().
+		SetStatsReporter(stats).
+		AddLogFilter("Received fragment with mismatched checksum.", 1))
+	defer client.Close()
+
+	ctx, cancel := tchannel.NewContext(time.Second)
+	defer cancel()
+
+	_, _, _, err = raw.Call(ctx, client, ln.Addr().String(), "server", "corrupt", nil, nil)
+	require.IsType(t, &tchannel.ChecksumError{}, err, "Unexpected error: %v", err)
+
+	checksumErr := err.(*tchannel.ChecksumError)
+	assert.Equal(t, tchannel.ChecksumTypeCrc32, checksumErr.ChecksumType, "Unexpected checksum type")
+	assert.Equal(t, "server", checksumErr.Service, "Unexpected service")
+	assert.Equal(t, "corrupt", checksumErr.Method, "Unexpected method")
+	assert.Equal(t, ln.Addr().String(), checksumErr.RemotePeer.HostPort, "Unexpected remote peer")
+	assert.Equal(t, tchannel.ErrCodeBadRequest, tchannel.GetSystemErrorCode(err), "Unexpected error code")
+
+	stats.Lock()
+	defer stats.Unlock()
+	assert.Len(t, stats.Values["outbound.calls.checksum-failures"], 1, "Expected checksum failures to be reported")
+}
@@ -0,0 +1,234 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	_defaultBreakerFailures       = 5
+	_defaultBreakerOpenDuration   = 10 * time.Second
+	_defaultBreakerHalfOpenProbes = 1
+)
+
+// CircuitState is the state of a peer's circuit breaker.
+type CircuitState int
+
+const (
+	// CircuitClosed is the normal state, where the peer can be selected.
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen means the peer has failed repeatedly, and is ejected
+	// from peer selection.
+	CircuitOpen
+
+	// CircuitHalfOpen means the peer's ejection has expired, and a limited
+	// number of probe calls are sent to it to check whether it has recovered.
+	CircuitHalfOpen
+)
+
+//go:generate stringer -type=CircuitState
+
+// CircuitBreakerOptions configures the per-peer circuit breakers used when
+// selecting peers from a PeerList.
+type CircuitBreakerOptions struct {
+	// ConsecutiveFailures is the number of consecutive failed calls after
+	// which the peer is ejected. Defaults to 5.
+	ConsecutiveFailures int
+
+	// OpenDuration is how long a peer is ejected before it is probed.
+	// Defaults to 10 seconds.
+	OpenDuration time.Duration
+
+	// HalfOpenProbes is the number of probe calls sent to a peer after its
+	// ejection expires. If all probes succeed, the peer is restored, and if
+	// any probe fails, the peer is ejected again. Defaults to 1.
+	HalfOpenProbes int
+}
+
+func (o CircuitBreakerOptions) withDefaults() CircuitBreakerOptions {
+	if o.ConsecutiveFailures <= 0 {
+		o.ConsecutiveFailures = _defaultBreakerFailures
+	}
+	if o.OpenDuration <= 0 {
+		o.OpenDuration = _defaultBreakerOpenDuration
+	}
+	if o.HalfOpenProbes <= 0 {
+		o.HalfOpenProbes = _defaultBreakerHalfOpenProbes
+	}
+	return o
+}
+
+// circuitBreaker tracks call outcomes for a single peer.
+// A nil circuitBreaker always allows calls.
+type circuitBreaker struct {
+	sync.Mutex
+
+	opts    CircuitBreakerOptions
+	timeNow func() time.Time
+
+	state    CircuitState
+	failures int
+	// changedAt is when the breaker was opened, or when probing started.
+	changedAt time.Time
+	probes    int
+	successes int
+}
+
+func newCircuitBreaker(opts CircuitBreakerOptions, timeNow func() time.Time) *circuitBreaker {
+	return &circuitBreaker{
+		opts:    opts.withDefaults(),
+		timeNow: timeNow,
+	}
+}
+
+// State returns the current state of the breaker.
+func (b *circuitBreaker) State() CircuitState {
+	if b == nil {
+		return CircuitClosed
+	}
+
+	b.Lock()
+	defer b.Unlock()
+	b.expireOpenLocked()
+	return b.state
+}
+
+// expireOpenLocked moves an open breaker to half-open once it has been open
+// for OpenDuration. The breaker must be locked.
+func (b *circuitBreaker) expireOpenLocked() {
+	if b.state != CircuitOpen || b.timeNow().Sub(b.changedAt) < b.opts.OpenDuration {
+		return
+	}
+	b.state = CircuitHalfOpen
+	b.changedAt = b.timeNow()
+	b.probes = 0
+	b.successes = 0
+}
+
+// rejecting returns whether allow would currently reject the peer, either
+// because the breaker is open or because all half-open probes are in use.
+// Unlike allow, it does not use up a probe.
+func (b *circuitBreaker) rejecting() bool {
+	if b == nil {
+		return false
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	b.expireOpenLocked()
+	switch b.state {
+	case CircuitClosed:
+		return false
+	case CircuitHalfOpen:
+		return b.probes >= b.opts.HalfOpenProbes && b.timeNow().Sub(b.changedAt) < b.opts.OpenDuration
+	default:
+		return true
+	}
+}
+
+// allow returns whether the peer can be selected. In the half-open state,
+// each selection uses up one of the limited number of probes.
+func (b *circuitBreaker) allow() bool {
+	if b == nil {
+		return true
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	b.expireOpenLocked()
+	switch b.state {
+	case CircuitClosed:
+		return true
+	case CircuitHalfOpen:
+		// If probes were selected but their results never arrived, allow new
+		// probes after another OpenDuration.
+		if b.probes >= b.opts.HalfOpenProbes && b.timeNow().Sub(b.changedAt) >= b.opts.OpenDuration {
+			b.changedAt = b.timeNow()
+			b.probes = 0
+		}
+		if b.probes < b.opts.HalfOpenProbes {
+			b.probes++
+			return true
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// record updates the breaker with the outcome of a call, and returns whether
+// the state of the breaker changed.
+func (b *circuitBreaker) record(err error) (changed bool) {
+	if b == nil {
+		return false
+	}
+
+	failed := isPeerFailure(err)
+	if err != nil && !failed {
+		// Errors that don't indicate peer health are ignored.
+		return false
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	b.expireOpenLocked()
+	prevState := b.state
+	switch b.state {
+	case CircuitClosed:
+		if !failed {
+			b.failures = 0
+			break
+		}
+		b.failures++
+		if b.failures >= b.opts.ConsecutiveFailures {
+			b.openLocked()
+		}
+	case CircuitHalfOpen:
+		if failed {
+			b.openLocked()
+			break
+		}
+		b.successes++
+		if b.successes >= b.opts.HalfOpenProbes {
+			b.state = CircuitClosed
+			b.failures = 0
+		}
+	}
+	return b.state != prevState
+}
+
+func (b *circuitBreaker) openLocked() {
+	b.state = CircuitOpen
+	b.changedAt = b.timeNow()
+	b.failures = 0
+}
+
+// CircuitState returns the state of the peer's circuit breaker. If circuit
+// breakers are not enabled, it always returns CircuitClosed.
+func (p *Peer) CircuitState() CircuitState {
+	return p.breaker.State()
+}
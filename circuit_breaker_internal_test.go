@@ -0,0 +1,92 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type breakerClock struct{ now time.Time }
+
+func (c *breakerClock) Now() time.Time         { return c.now }
+func (c *breakerClock) Elapse(d time.Duration) { c.now = c.now.Add(d) }
+func newBreakerClock() *breakerClock           { return &breakerClock{time.Now()} }
+
+func TestCircuitBreakerStates(t *testing.T) {
+	clock := newBreakerClock()
+	b := newCircuitBreaker(CircuitBreakerOptions{
+		ConsecutiveFailures: 3,
+		OpenDuration:        time.Second,
+		HalfOpenProbes:      2,
+	}, clock.Now)
+
+	failure := NewSystemError(ErrCodeNetwork, "network")
+	assert.False(t, b.record(failure), "First failure should not open the breaker")
+	assert.False(t, b.record(nil), "Success should reset failures")
+	assert.False(t, b.record(failure), "Failure should not open the breaker")
+	assert.False(t, b.record(failure), "Failure should not open the breaker")
+	assert.False(t, b.record(ErrTimeout), "Timeouts should be ignored")
+	assert.True(t, b.record(failure), "Third consecutive failure should open the breaker")
+	assert.Equal(t, CircuitOpen, b.State(), "Unexpected state")
+	assert.False(t, b.allow(), "Open breaker should not allow calls")
+
+	clock.Elapse(time.Second)
+	assert.Equal(t, CircuitHalfOpen, b.State(), "Breaker should be half-open after OpenDuration")
+	assert.True(t, b.allow(), "First probe should be allowed")
+	assert.True(t, b.allow(), "Second probe should be allowed")
+	assert.False(t, b.allow(), "Only two probes should be allowed")
+
+	assert.False(t, b.record(nil), "Single successful probe should not close the breaker")
+	assert.True(t, b.record(nil), "All successful probes should close the breaker")
+	assert.Equal(t, CircuitClosed, b.State(), "Unexpected state")
+
+	for i := 0; i < 3; i++ {
+		b.record(failure)
+	}
+	clock.Elapse(time.Second)
+	assert.True(t, b.allow(), "Probe should be allowed")
+	assert.True(t, b.record(failure), "Failed probe should reopen the breaker")
+	assert.Equal(t, CircuitOpen, b.State(), "Unexpected state")
+}
+
+func TestCircuitBreakerStaleProbes(t *testing.T) {
+	clock := newBreakerClock()
+	b := newCircuitBreaker(CircuitBreakerOptions{ConsecutiveFailures: 1, OpenDuration: time.Second}, clock.Now)
+
+	b.record(NewSystemError(ErrCodeUnexpected, "unexpected"))
+	clock.Elapse(time.Second)
+	assert.True(t, b.allow(), "Probe should be allowed")
+	assert.False(t, b.allow(), "Only one probe should be allowed")
+
+	// The probe result never arrives, so a new probe is allowed later.
+	clock.Elapse(time.Second)
+	assert.True(t, b.allow(), "New probe should be allowed after OpenDuration")
+}
+
+func TestCircuitBreakerNil(t *testing.T) {
+	var b *circuitBreaker
+	assert.True(t, b.allow(), "Nil breaker should allow calls")
+	assert.False(t, b.record(ErrTimeout), "Nil breaker should not change")
+	assert.Equal(t, CircuitClosed, b.State(), "Nil breaker should be closed")
+}
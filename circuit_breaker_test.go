@@ -0,0 +1,154 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/temporalio/tchannel-go"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/temporalio/tchannel-go/raw"
+	"github.com/temporalio/tchannel-go/testutils"
+	"go.uber.org/atomic"
+	"golang.org/x/net/context"
+)
+
+func TestCircuitBreakerEjectsFailingPeer(t *testing.T) {
+	bad := testutils.NewServer(t, nil)
+	defer bad.Close()
+	bad.Register(HandlerFunc(func(ctx context.Context, call *InboundCall) {
+		call.Response().SendSystemError(NewSystemError(ErrCodeUnexpected, "broken"))
+	}), "echo")
+
+	good := testutils.NewServer(t, nil)
+	defer good.Close()
+	testutils.RegisterEcho(good, nil)
+
+	var statusChanges atomic.Int32
+	badHostPort := bad.PeerInfo().HostPort
+	opts := testutils.NewOpts().
+		SetCircuitBreaker(CircuitBreakerOptions{ConsecutiveFailures: 2, OpenDuration: time.Minute}).
+		SetOnPeerStatusChanged(func(p *Peer) {
+			if p.HostPort() == badHostPort && p.CircuitState() == CircuitOpen {
+				statusChanges.Inc()
+			}
+		})
+	client := testutils.NewClient(t, opts)
+	defer client.Close()
+
+	sc := client.GetSubChannel(bad.ServiceName(), Isolated)
+	sc.Peers().Add(badHostPort)
+
+	ctx, cancel := NewContext(testutils.Timeout(time.Second))
+	defer cancel()
+
+	for i := 0; i < 2; i++ {
+		_, _, _, err := raw.CallSC(ctx, sc, "echo", nil, nil)
+		assert.Equal(t, ErrCodeUnexpected, GetSystemErrorCode(err), "Unexpected error code")
+	}
+
+	badPeer, ok := client.RootPeers().Get(badHostPort)
+	require.True(t, ok, "Peer not found")
+	assert.Equal(t, CircuitOpen, badPeer.CircuitState(), "Peer should be ejected")
+	assert.Equal(t, int32(1), statusChanges.Load(), "OnPeerStatusChanged should be called when the breaker opens")
+	assert.Equal(t, "CircuitOpen", client.IntrospectState(nil).RootPeers[badHostPort].CircuitState,
+		"Breaker state should be introspectable")
+
+	_, _, _, err := raw.CallSC(ctx, sc, "echo", nil, nil)
+	assert.Equal(t, ErrAllPeersEjected, err, "All peers should be ejected")
+
+	sc.Peers().Add(good.PeerInfo().HostPort)
+	for i := 0; i < 5; i++ {
+		_, _, _, err := raw.CallSC(ctx, sc, "echo", nil, nil)
+		assert.NoError(t, err, "Calls should go to the healthy peer")
+	}
+}
+
+func TestCircuitBreakerHalfOpenProbesExhausted(t *testing.T) {
+	bad := testutils.NewServer(t, nil)
+	defer bad.Close()
+	bad.Register(HandlerFunc(func(ctx context.Context, call *InboundCall) {
+		call.Response().SendSystemError(NewSystemError(ErrCodeUnexpected, "broken"))
+	}), "echo")
+
+	clock := testutils.NewStubClock(time.Now())
+	client := testutils.NewClient(t, testutils.NewOpts().
+		SetTimeNow(clock.Now).
+		SetCircuitBreaker(CircuitBreakerOptions{ConsecutiveFailures: 1, OpenDuration: time.Minute}))
+	defer client.Close()
+
+	badHostPort := bad.PeerInfo().HostPort
+	sc := client.GetSubChannel(bad.ServiceName(), Isolated)
+	sc.Peers().Add(badHostPort)
+
+	ctx, cancel := NewContext(testutils.Timeout(time.Second))
+	defer cancel()
+
+	_, _, _, err := raw.CallSC(ctx, sc, "echo", nil, nil)
+	assert.Equal(t, ErrCodeUnexpected, GetSystemErrorCode(err), "Unexpected error code")
+
+	_, err = sc.Peers().Get(nil)
+	assert.Equal(t, ErrAllPeersEjected, err, "Open breakers should reject the peer")
+
+	// Once the breaker is half-open, the only probe is used by the first Get.
+	clock.Elapse(time.Minute)
+	peer, err := sc.Peers().Get(nil)
+	require.NoError(t, err, "Half-open peer should be allowed as a probe")
+	assert.Equal(t, badHostPort, peer.HostPort(), "Unexpected peer")
+
+	_, err = sc.Peers().Get(nil)
+	assert.Equal(t, ErrAllPeersEjected, err, "Peers with no probes left should be ejected")
+}
+
+func TestCircuitBreakerIgnoresLocalErrors(t *testing.T) {
+	server := testutils.NewServer(t, nil)
+	defer server.Close()
+	testutils.RegisterEcho(server, nil)
+
+	// Nothing is listening on the closed server's host:port, so dials fail.
+	closed := testutils.NewServer(t, nil)
+	closedHostPort := closed.PeerInfo().HostPort
+	closed.Close()
+
+	client := testutils.NewClient(t, testutils.NewOpts().
+		SetCircuitBreaker(CircuitBreakerOptions{ConsecutiveFailures: 1, OpenDuration: time.Minute}).
+		AddLogFilter("Outbound net.Dial failed.", 1))
+	defer client.Close()
+
+	ctx, cancel := NewContext(testutils.Timeout(time.Second))
+	defer cancel()
+
+	unreachable := client.RootPeers().GetOrAdd(closedHostPort)
+	_, err := unreachable.BeginCall(ctx, server.ServiceName(), "echo", nil)
+	require.Error(t, err, "Call to a peer that is not listening should fail")
+	assert.Equal(t, CircuitOpen, unreachable.CircuitState(), "Dial errors should eject the peer")
+
+	// Calls fail when the client is closing, but the peer is healthy.
+	peer := client.RootPeers().GetOrAdd(server.PeerInfo().HostPort)
+	client.Close()
+	_, err = peer.BeginCall(ctx, server.ServiceName(), "echo", nil)
+	require.Error(t, err, "Call on a closed channel should fail")
+	assert.Equal(t, CircuitClosed, peer.CircuitState(), "Local errors should not eject the peer")
+	assert.Zero(t, peer.ErrorRateEWMA(), "Local errors should not count as peer failures")
+}
@@ -0,0 +1,285 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// CompressionType is an algorithm used to compress the arg2 and arg3 payloads
+// of calls. Compression is negotiated when a connection is established, and is
+// only used if both peers support the same algorithm.
+type CompressionType string
+
+const (
+	// CompressionNone indicates that payloads are not compressed.
+	CompressionNone CompressionType = ""
+
+	// CompressionDeflate compresses payloads using DEFLATE (RFC 1951) at the
+	// fastest compression level.
+	CompressionDeflate CompressionType = "deflate"
+)
+
+var errArgCompressionClosed = errors.New("compressed argument already closed")
+
+// supported returns whether payloads compressed using c can be read and written.
+func (c CompressionType) supported() bool {
+	return c == CompressionDeflate
+}
+
+func validateCompression(types []CompressionType) error {
+	for _, c := range types {
+		if !c.supported() {
+			return fmt.Errorf("unsupported compression type %q", c)
+		}
+	}
+	return nil
+}
+
+// compressionInitParam returns the init param value advertising the given types.
+func compressionInitParam(types []CompressionType) string {
+	names := make([]string, len(types))
+	for i, c := range types {
+		names[i] = string(c)
+	}
+	return strings.Join(names, ",")
+}
+
+// selectCompression returns the first compression type requested by the peer
+// in the init param that is in the list of local types.
+func selectCompression(local []CompressionType, requested string) CompressionType {
+	if requested == "" {
+		return CompressionNone
+	}
+	for _, name := range strings.Split(requested, ",") {
+		for _, c := range local {
+			if c == CompressionType(name) {
+				return c
+			}
+		}
+	}
+	return CompressionNone
+}
+
+// argCompression configures compression for arg2 and arg3 of a call, and
+// reports the uncompressed and compressed sizes of each written argument.
+type argCompression struct {
+	compression CompressionType
+	onWritten   func(uncompressed, compressed int)
+}
+
+var deflateWriterPool = sync.Pool{
+	New: func() interface{} {
+		w, _ := flate.NewWriter(nil, flate.BestSpeed)
+		return w
+	},
+}
+
+var deflateReaderPool = sync.Pool{
+	New: func() interface{} {
+		return flate.NewReader(nil)
+	},
+}
+
+// countingWriter counts the bytes written to an underlying writer.
+type countingWriter struct {
+	w       io.Writer
+	written int
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	w.written += n
+	return n, err
+}
+
+// compressingArgWriter compresses an argument written to an underlying ArgWriter.
+type compressingArgWriter struct {
+	w            ArgWriter
+	counter      countingWriter
+	fw           *flate.Writer
+	uncompressed int
+	onWritten    func(uncompressed, compressed int)
+}
+
+func newCompressingArgWriter(w ArgWriter, c argCompression) ArgWriter {
+	cw := &compressingArgWriter{
+		w:         w,
+		counter:   countingWriter{w: w},
+		fw:        deflateWriterPool.Get().(*flate.Writer),
+		onWritten: c.onWritten,
+	}
+	cw.fw.Reset(&cw.counter)
+	return cw
+}
+
+func (w *compressingArgWriter) Write(b []byte) (int, error) {
+	if w.fw == nil {
+		return 0, errArgCompressionClosed
+	}
+	n, err := w.fw.Write(b)
+	w.uncompressed += n
+	return n, err
+}
+
+func (w *compressingArgWriter) Flush() error {
+	if w.fw == nil {
+		return errArgCompressionClosed
+	}
+	if err := w.fw.Flush(); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+func (w *compressingArgWriter) Close() error {
+	if w.fw == nil {
+		return errArgCompressionClosed
+	}
+	err := w.fw.Close()
+	deflateWriterPool.Put(w.fw)
+	w.fw = nil
+	if err != nil {
+		return err
+	}
+
+	if w.onWritten != nil {
+		w.onWritten(w.uncompressed, w.counter.written)
+	}
+	return w.w.Close()
+}
+
+// decompressingArgReader decompresses an argument read from an underlying ArgReader.
+type decompressingArgReader struct {
+	r  ArgReader
+	fr io.ReadCloser
+
+	// err is the error that the decompressor failed with, which is returned
+	// by later calls since the decompressor has been released.
+	err error
+}
+
+func newDecompressingArgReader(r ArgReader, c CompressionType) (ArgReader, error) {
+	if !c.supported() {
+		return nil, NewSystemError(ErrCodeBadRequest, "unsupported compression type %q", c)
+	}
+
+	fr := deflateReaderPool.Get().(io.ReadCloser)
+	if err := fr.(flate.Resetter).Reset(r, nil); err != nil {
+		deflateReaderPool.Put(fr)
+		return nil, err
+	}
+	return &decompressingArgReader{r: r, fr: fr}, nil
+}
+
+func (r *decompressingArgReader) Read(b []byte) (int, error) {
+	if r.fr == nil {
+		return 0, r.closedErr()
+	}
+	n, err := r.fr.Read(b)
+	if err != nil && err != io.EOF {
+		r.err = err
+		r.release()
+	}
+	return n, err
+}
+
+func (r *decompressingArgReader) Close() error {
+	if r.fr == nil {
+		return r.closedErr()
+	}
+
+	// Read the end of the compressed stream, so the underlying argument is
+	// fully consumed. Any remaining decompressed data was not read by the caller.
+	n, err := io.Copy(ioutil.Discard, r.fr)
+	r.release()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return errMoreDataInArgument
+	}
+	return r.r.Close()
+}
+
+func (r *decompressingArgReader) closedErr() error {
+	if r.err != nil {
+		return r.err
+	}
+	return errArgCompressionClosed
+}
+
+// release returns the decompressor to the pool. The reader can't be used after
+// it is released.
+func (r *decompressingArgReader) release() {
+	r.fr.Close()
+	deflateReaderPool.Put(r.fr)
+	r.fr = nil
+}
+
+// outboundCompression returns the compression to use for an outbound call's
+// payloads, and sets the Compression transport header if it is used.
+func (c *Connection) outboundCompression(ctx context.Context, callOptions *CallOptions, headers transportHeaders) CompressionType {
+	compression := c.remotePeerInfo.Compression
+	// Streaming calls flush each message as it is sent, which the compressed
+	// argument readers cannot consume incrementally.
+	if compression == CompressionNone || callOptions.DisableCompression || callOptions.streaming {
+		return CompressionNone
+	}
+	if opts := currentCallOptions(ctx); opts != nil && opts.DisableCompression {
+		return CompressionNone
+	}
+
+	headers[Compression] = string(compression)
+	return compression
+}
+
+// responseCompression returns the compression to use for an inbound call's
+// response payloads. Responses are only compressed if the request was
+// compressed using an algorithm that this channel is configured to use.
+func (c *Connection) responseCompression(reqHeaders transportHeaders) CompressionType {
+	compression := CompressionType(reqHeaders[Compression])
+	if compression == CompressionNone {
+		return CompressionNone
+	}
+	for _, supported := range c.opts.Compression {
+		if supported == compression {
+			return compression
+		}
+	}
+	return CompressionNone
+}
+
+// compressionStats returns a function that reports the sizes of compressed arguments.
+func compressionStats(statsReporter StatsReporter, prefix string, tags map[string]string) func(uncompressed, compressed int) {
+	return func(uncompressed, compressed int) {
+		statsReporter.IncCounter(prefix+".compression.uncompressed-bytes", tags, int64(uncompressed))
+		statsReporter.IncCounter(prefix+".compression.compressed-bytes", tags, int64(compressed))
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/temporalio/tchannel-go"
+	"github.com/temporalio/tchannel-go/raw"
+	"github.com/temporalio/tchannel-go/relay"
+	"github.com/temporalio/tchannel-go/relay/relaytest"
+	"github.com/temporalio/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestCompressionNegotiation(t *testing.T) {
+	deflate := []tchannel.CompressionType{tchannel.CompressionDeflate}
+
+	tests := []struct {
+		msg    string
+		client []tchannel.CompressionType
+		server []tchannel.CompressionType
+		want   tchannel.CompressionType
+	}{
+		{
+			msg:    "both peers support deflate",
+			client: deflate,
+			server: deflate,
+			want:   tchannel.CompressionDeflate,
+		},
+		{
+			msg:    "only client supports compression",
+			client: deflate,
+		},
+		{
+			msg:    "only server supports compression",
+			server: deflate,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			server := testutils.NewServer(t, testutils.NewOpts().SetCompression(tt.server...))
+			defer server.Close()
+
+			var inbound tchannel.CompressionType
+			testutils.RegisterFunc(server, "compression", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+				inbound = tchannel.CurrentCall(ctx).RemotePeer().Compression
+				return &raw.Res{}, nil
+			})
+
+			client := testutils.NewClient(t, testutils.NewOpts().SetCompression(tt.client...))
+			defer client.Close()
+
+			ctx, cancel := tchannel.NewContext(time.Second)
+			defer cancel()
+
+			_, _, _, err := raw.Call(ctx, client, server.PeerInfo().HostPort, server.ServiceName(), "compression", nil, nil)
+			require.NoError(t, err, "Call failed")
+			assert.Equal(t, tt.want, inbound, "Unexpected compression for inbound connection")
+
+			peer, ok := client.RootPeers().Get(server.PeerInfo().HostPort)
+			require.True(t, ok, "Missing peer for server")
+			state := peer.IntrospectState(&tchannel.IntrospectionOptions{})
+			require.Len(t, state.OutboundConnections, 1, "Expected a single outbound connection")
+			assert.Equal(t, tt.want, state.OutboundConnections[0].RemotePeer.Compression, "Unexpected compression for outbound connection")
+		})
+	}
+}
+
+func TestCompressionUnsupportedType(t *testing.T) {
+	_, err := tchannel.NewChannel("svc", &tchannel.ChannelOptions{
+		DefaultConnectionOptions: tchannel.ConnectionOptions{
+			Compression: []tchannel.CompressionType{"lz4"},
+		},
+	})
+	assert.Error(t, err, "NewChannel should fail with an unsupported compression type")
+}
+
+func TestCompressedCalls(t *testing.T) {
+	serverStats := newRecordingStatsReporter()
+	server := testutils.NewServer(t, testutils.NewOpts().
+		SetCompression(tchannel.CompressionDeflate).
+		SetStatsReporter(serverStats))
+	defer server.Close()
+
+	var compressionHeader string
+	testutils.RegisterFunc(server, "echo", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+		compressionHeader = tchannel.CurrentCall(ctx).(*tchannel.InboundCall).TransportHeaders()[tchannel.Compression]
+		return &raw.Res{Arg2: args.Arg2, Arg3: args.Arg3}, nil
+	})
+
+	clientStats := newRecordingStatsReporter()
+	client := testutils.NewClient(t, testutils.NewOpts().
+		SetCompression(tchannel.CompressionDeflate).
+		SetStatsReporter(clientStats))
+	defer client.Close()
+
+	// Payloads span multiple frames when uncompressed.
+	arg2 := bytes.Repeat([]byte("header "), 10000)
+	arg3 := bytes.Repeat([]byte("compressible body "), 10000)
+
+	ctx, cancel := tchannel.NewContext(time.Second)
+	defer cancel()
+
+	resArg2, resArg3, _, err := raw.Call(ctx, client, server.PeerInfo().HostPort, server.ServiceName(), "echo", arg2, arg3)
+	require.NoError(t, err, "Call failed")
+	assert.Equal(t, arg2, resArg2, "Unexpected arg2")
+	assert.Equal(t, arg3, resArg3, "Unexpected arg3")
+	assert.Equal(t, "deflate", compressionHeader, "Unexpected compression header")
+
+	sumCounter := func(stats *recordingStatsReporter, name string) int64 {
+		stats.Lock()
+		defer stats.Unlock()
+
+		var total int64
+		for _, v := range stats.Values[name] {
+			total += v.count
+		}
+		return total
+	}
+
+	uncompressedSize := int64(len(arg2) + len(arg3))
+	for _, s := range []struct {
+		stats  *recordingStatsReporter
+		prefix string
+	}{
+		{clientStats, "outbound.calls"},
+		{serverStats, "inbound.calls"},
+	} {
+		uncompressed := sumCounter(s.stats, s.prefix+".compression.uncompressed-bytes")
+		compressed := sumCounter(s.stats, s.prefix+".compression.compressed-bytes")
+		assert.Equal(t, uncompressedSize, uncompressed, "Unexpected %v uncompressed bytes", s.prefix)
+		assert.True(t, compressed > 0 && compressed < uncompressed/10,
+			"Unexpected %v compressed bytes %v", s.prefix, compressed)
+	}
+
+	// Compression can be disabled per call.
+	ctx, cancel = tchannel.NewContextBuilder(time.Second).DisableCompression().Build()
+	defer cancel()
+
+	resArg2, resArg3, _, err = raw.Call(ctx, client, server.PeerInfo().HostPort, server.ServiceName(), "echo", arg2, arg3)
+	require.NoError(t, err, "Call failed")
+	assert.Equal(t, arg2, resArg2, "Unexpected arg2")
+	assert.Equal(t, arg3, resArg3, "Unexpected arg3")
+	assert.Empty(t, compressionHeader, "Call should not be compressed")
+	assert.Equal(t, uncompressedSize, sumCounter(clientStats, "outbound.calls.compression.uncompressed-bytes"),
+		"Uncompressed calls should not be reported")
+}
+
+func TestRelayCompressedCalls(t *testing.T) {
+	opts := serviceNameOpts("s1").SetRelayOnly().SetCompression(tchannel.CompressionDeflate)
+	testutils.WithTestServer(t, opts, func(t testing.TB, ts *testutils.TestServer) {
+		// s2 does not support compression, so compressed calls are decompressed
+		// by the relay before they are sent to s2.
+		s2 := ts.NewServer(serviceNameOpts("s2"))
+		compressionHeaders := make(map[string]string)
+		for _, ch := range []*tchannel.Channel{ts.Server(), s2} {
+			service := ch.ServiceName()
+			testutils.RegisterFunc(ch, "echo", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+				compressionHeaders[service] = tchannel.CurrentCall(ctx).(*tchannel.InboundCall).TransportHeaders()[tchannel.Compression]
+				return &raw.Res{Arg2: args.Arg2, Arg3: args.Arg3}, nil
+			})
+		}
+
+		client := ts.NewClient(serviceNameOpts("client").SetCompression(tchannel.CompressionDeflate))
+
+		tests := []struct {
+			msg        string
+			arg2, arg3 []byte
+		}{
+			{
+				msg:  "compressed call fits in a frame",
+				arg2: bytes.Repeat([]byte("header "), 10000),
+				arg3: bytes.Repeat([]byte("compressible body "), 10000),
+			},
+			{
+				msg:  "compressed call spans multiple frames",
+				arg2: testutils.RandBytes(100000),
+				arg3: testutils.RandBytes(200000),
+			},
+		}
+
+		for _, tt := range tests {
+			for _, service := range []string{"s1", "s2"} {
+				ctx, cancel := tchannel.NewContext(time.Second)
+				resArg2, resArg3, _, err := raw.Call(ctx, client, ts.HostPort(), service, "echo", tt.arg2, tt.arg3)
+				cancel()
+				require.NoError(t, err, "%v: relayed call to %v failed", tt.msg, service)
+				assert.Equal(t, tt.arg2, resArg2, "%v: unexpected arg2 from %v", tt.msg, service)
+				assert.Equal(t, tt.arg3, resArg3, "%v: unexpected arg3 from %v", tt.msg, service)
+			}
+
+			assert.Equal(t, "deflate", compressionHeaders["s1"], "%v: call should stay compressed across the relay", tt.msg)
+			assert.Empty(t, compressionHeaders["s2"], "%v: call should be decompressed by the relay", tt.msg)
+		}
+
+		// The relay negotiates compression with each peer.
+		for _, tt := range []struct {
+			ch   *tchannel.Channel
+			want tchannel.CompressionType
+		}{
+			{client, tchannel.CompressionDeflate},
+			{ts.Server(), tchannel.CompressionDeflate},
+			{s2, tchannel.CompressionNone},
+		} {
+			var numConns int
+			for _, peer := range tt.ch.IntrospectState(nil).RootPeers {
+				for _, conn := range append(peer.InboundConnections, peer.OutboundConnections...) {
+					numConns++
+					assert.Equal(t, tt.want, conn.RemotePeer.Compression,
+						"Unexpected compression negotiated by %v with the relay", tt.ch.ServiceName())
+				}
+			}
+			assert.NotZero(t, numConns, "%v has no connections", tt.ch.ServiceName())
+		}
+	})
+}
+
+func TestRelayCompressedCallsArg2Append(t *testing.T) {
+	rh := relaytest.NewStubRelayHost()
+	rh.SetFrameFn(func(f relay.CallFrame, conn *relay.Conn) {
+		f.Arg2Append([]byte("key"), []byte("val"))
+	})
+
+	opts := testutils.NewOpts().
+		SetRelayOnly().
+		SetRelayHost(rh).
+		SetCompression(tchannel.CompressionDeflate)
+	testutils.WithTestServer(t, opts, func(t testing.TB, ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+		client := ts.NewClient(testutils.NewOpts().SetCompression(tchannel.CompressionDeflate))
+
+		ctx, cancel := tchannel.NewContext(time.Second)
+		defer cancel()
+
+		_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", []byte("arg2"), []byte("arg3"))
+		require.Error(t, err, "Compressed call with arg2 appends should fail")
+		assert.Equal(t, tchannel.ErrCodeBadRequest, tchannel.GetSystemErrorCode(err), "Unexpected error: %v", err)
+	})
+}
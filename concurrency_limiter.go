@@ -0,0 +1,331 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const _defaultBackoffRatio = 0.9
+
+var (
+	errAdaptiveMaxRequired    = errors.New("adaptive concurrency limit requires Max to be set")
+	errAdaptiveTargetRequired = errors.New("adaptive concurrency limit requires TargetLatency to be set")
+	errAdaptiveInvalidRatio   = errors.New("adaptive concurrency BackoffRatio must be between 0 and 1")
+	errNegativeConcurrency    = errors.New("concurrency limit cannot be negative")
+)
+
+// Scopes of concurrency limits, used in stats tags.
+const (
+	limitScopeChannel = "channel"
+	limitScopeService = "service"
+	limitScopeMethod  = "method"
+)
+
+// ConcurrencyLimit configures the maximum number of inbound calls that can be
+// handled concurrently. Calls over the limit are rejected with ErrServerBusy
+// before their arguments are read.
+type ConcurrencyLimit struct {
+	// Max is the maximum number of concurrent calls. Zero means no limit.
+	Max int `json:"max"`
+
+	// Adaptive, if set, adjusts the limit between Adaptive.Min and Max
+	// based on the observed latency of calls.
+	Adaptive *AdaptiveConcurrency `json:"adaptive,omitempty"`
+}
+
+// AdaptiveConcurrency configures an AIMD (additive increase, multiplicative
+// decrease) concurrency limit. When a call takes longer than TargetLatency,
+// the limit is multiplied by BackoffRatio. Only calls that started after the
+// limit was last reduced can reduce it again, so many calls that are slow at
+// the same time reduce the limit once. When a call completes within
+// TargetLatency while the limit is fully used, the limit is increased by one.
+type AdaptiveConcurrency struct {
+	// Min is the lowest the limit can go. Defaults to 1.
+	Min int `json:"min"`
+
+	// TargetLatency is the latency above which the limit is reduced.
+	TargetLatency time.Duration `json:"targetLatency"`
+
+	// BackoffRatio is the ratio the limit is reduced by. Defaults to 0.9.
+	BackoffRatio float64 `json:"backoffRatio"`
+}
+
+// ConcurrencyLimitState is the runtime state of a concurrency limit.
+type ConcurrencyLimitState struct {
+	Config   ConcurrencyLimit `json:"config"`
+	Limit    int              `json:"limit"`
+	InFlight int              `json:"inFlight"`
+	Rejected uint64           `json:"rejected"`
+}
+
+func (cl ConcurrencyLimit) validate() (ConcurrencyLimit, error) {
+	if cl.Max < 0 {
+		return cl, errNegativeConcurrency
+	}
+	if cl.Adaptive == nil {
+		return cl, nil
+	}
+
+	adaptive := *cl.Adaptive
+	if cl.Max == 0 {
+		return cl, errAdaptiveMaxRequired
+	}
+	if adaptive.TargetLatency <= 0 {
+		return cl, errAdaptiveTargetRequired
+	}
+	if adaptive.BackoffRatio == 0 {
+		adaptive.BackoffRatio = _defaultBackoffRatio
+	}
+	if adaptive.BackoffRatio < 0 || adaptive.BackoffRatio >= 1 {
+		return cl, errAdaptiveInvalidRatio
+	}
+	if adaptive.Min <= 0 {
+		adaptive.Min = 1
+	}
+	if adaptive.Min > cl.Max {
+		adaptive.Min = cl.Max
+	}
+	cl.Adaptive = &adaptive
+	return cl, nil
+}
+
+// concurrencyLimiter tracks the number of in-flight calls against a limit.
+// A nil limiter accepts all calls.
+type concurrencyLimiter struct {
+	sync.Mutex
+
+	config   ConcurrencyLimit
+	limit    int
+	inflight int
+	rejected uint64
+
+	// lastBackoff is when the adaptive limit was last reduced.
+	lastBackoff time.Time
+}
+
+func newConcurrencyLimiter(cl ConcurrencyLimit) (*concurrencyLimiter, error) {
+	l := &concurrencyLimiter{}
+	if err := l.set(cl); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// set updates the limit. Calls that are in-flight are not affected.
+func (l *concurrencyLimiter) set(cl ConcurrencyLimit) error {
+	cl, err := cl.validate()
+	if err != nil {
+		return err
+	}
+
+	l.Lock()
+	l.config = cl
+	l.limit = cl.Max
+	l.lastBackoff = time.Time{}
+	l.Unlock()
+	return nil
+}
+
+// acquire reserves capacity for a call, and returns false if the limit
+// has been reached.
+func (l *concurrencyLimiter) acquire() bool {
+	if l == nil {
+		return true
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	if l.limit > 0 && l.inflight >= l.limit {
+		l.rejected++
+		return false
+	}
+	l.inflight++
+	return true
+}
+
+// cancel frees the capacity reserved by acquire for a call that was not
+// handled, without updating the adaptive limit.
+func (l *concurrencyLimiter) cancel() {
+	if l == nil {
+		return
+	}
+
+	l.Lock()
+	l.inflight--
+	l.Unlock()
+}
+
+// release frees the capacity used by a call that started and ended at the
+// given times. It returns the new limit if the adaptive limit was changed.
+func (l *concurrencyLimiter) release(start, end time.Time) (newLimit int, changed bool) {
+	if l == nil {
+		return 0, false
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	saturated := l.inflight >= l.limit
+	l.inflight--
+
+	adaptive := l.config.Adaptive
+	if adaptive == nil {
+		return 0, false
+	}
+
+	prevLimit := l.limit
+	if end.Sub(start) > adaptive.TargetLatency {
+		// Calls that started before the last backoff ran with the previous
+		// limit, so they should not reduce the limit again.
+		if start.Before(l.lastBackoff) {
+			return l.limit, false
+		}
+		l.lastBackoff = end
+		l.limit = int(float64(l.limit) * adaptive.BackoffRatio)
+		if l.limit < adaptive.Min {
+			l.limit = adaptive.Min
+		}
+	} else if saturated && l.limit < l.config.Max {
+		l.limit++
+	}
+	return l.limit, l.limit != prevLimit
+}
+
+// IntrospectState returns the runtime state of the limiter.
+func (l *concurrencyLimiter) IntrospectState() ConcurrencyLimitState {
+	l.Lock()
+	defer l.Unlock()
+
+	return ConcurrencyLimitState{
+		Config:   l.config,
+		Limit:    l.limit,
+		InFlight: l.inflight,
+		Rejected: l.rejected,
+	}
+}
+
+// SetConcurrencyLimit updates the limit on the number of inbound calls that
+// the channel handles concurrently across all services.
+func (ch *Channel) SetConcurrencyLimit(cl ConcurrencyLimit) error {
+	return ch.limiter.set(cl)
+}
+
+// SetConcurrencyLimit updates the limit on the number of inbound calls that
+// are handled concurrently for this service.
+func (c *SubChannel) SetConcurrencyLimit(cl ConcurrencyLimit) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.limiter == nil {
+		l, err := newConcurrencyLimiter(cl)
+		if err != nil {
+			return err
+		}
+		c.limiter = l
+		return nil
+	}
+	return c.limiter.set(cl)
+}
+
+// SetMethodConcurrencyLimit updates the limit on the number of inbound calls
+// that are handled concurrently for the given method of this service.
+func (c *SubChannel) SetMethodConcurrencyLimit(method string, cl ConcurrencyLimit) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if l, ok := c.methodLimiters[method]; ok {
+		return l.set(cl)
+	}
+
+	l, err := newConcurrencyLimiter(cl)
+	if err != nil {
+		return err
+	}
+	if c.methodLimiters == nil {
+		c.methodLimiters = make(map[string]*concurrencyLimiter)
+	}
+	c.methodLimiters[method] = l
+	return nil
+}
+
+func (c *SubChannel) getLimiters(method string) (service, m *concurrencyLimiter) {
+	c.RLock()
+	defer c.RUnlock()
+	return c.limiter, c.methodLimiters[method]
+}
+
+type scopedLimiter struct {
+	scope   string
+	limiter *concurrencyLimiter
+}
+
+// acquireConcurrency reserves capacity for an inbound call in the channel,
+// service and method limiters. If any limit is reached, the call is rejected
+// with ErrServerBusy and false is returned. Otherwise, the returned function
+// must be called when the call completes.
+func (c *Connection) acquireConcurrency(call *InboundCall) (release func(), ok bool) {
+	limiters := []scopedLimiter{{limitScopeChannel, c.limiter}}
+	if sc, ok := c.subChannels.get(call.ServiceName()); ok {
+		service, method := sc.getLimiters(call.methodString)
+		if service != nil {
+			limiters = append(limiters, scopedLimiter{limitScopeService, service})
+		}
+		if method != nil {
+			limiters = append(limiters, scopedLimiter{limitScopeMethod, method})
+		}
+	}
+
+	for i, l := range limiters {
+		if l.limiter.acquire() {
+			continue
+		}
+
+		for _, acquired := range limiters[:i] {
+			acquired.limiter.cancel()
+		}
+		call.statsReporter.IncCounter("inbound.calls.shed", limitStatsTags(call, l.scope), 1)
+		call.Response().SendSystemError(ErrServerBusy)
+		return nil, false
+	}
+
+	start := c.timeNow()
+	return func() {
+		end := c.timeNow()
+		for _, l := range limiters {
+			if limit, changed := l.limiter.release(start, end); changed {
+				call.statsReporter.UpdateGauge("inbound.calls.concurrency-limit", limitStatsTags(call, l.scope), int64(limit))
+			}
+		}
+	}, true
+}
+
+func limitStatsTags(call *InboundCall, scope string) map[string]string {
+	tags := make(map[string]string, len(call.commonStatsTags)+1)
+	for k, v := range call.commonStatsTags {
+		tags[k] = v
+	}
+	tags["limit"] = scope
+	return tags
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// limiterClock releases calls one after another, so each call starts when the
+// previous call ends.
+type limiterClock struct {
+	now time.Time
+}
+
+func (c *limiterClock) release(l *concurrencyLimiter, latency time.Duration) (int, bool) {
+	start := c.now
+	c.now = c.now.Add(latency)
+	return l.release(start, c.now)
+}
+
+func TestConcurrencyLimiterStatic(t *testing.T) {
+	l, err := newConcurrencyLimiter(ConcurrencyLimit{Max: 2})
+	require.NoError(t, err, "newConcurrencyLimiter failed")
+	clock := &limiterClock{now: time.Now()}
+
+	assert.True(t, l.acquire(), "First call should be accepted")
+	assert.True(t, l.acquire(), "Second call should be accepted")
+	assert.False(t, l.acquire(), "Third call should be rejected")
+
+	clock.release(l, time.Millisecond)
+	assert.True(t, l.acquire(), "Call after release should be accepted")
+
+	state := l.IntrospectState()
+	assert.Equal(t, 2, state.InFlight, "Unexpected in-flight calls")
+	assert.Equal(t, uint64(1), state.Rejected, "Unexpected rejected calls")
+
+	require.NoError(t, l.set(ConcurrencyLimit{}), "set failed")
+	assert.True(t, l.acquire(), "Calls should not be limited after removing limit")
+}
+
+func TestConcurrencyLimiterAdaptive(t *testing.T) {
+	l, err := newConcurrencyLimiter(ConcurrencyLimit{
+		Max: 10,
+		Adaptive: &AdaptiveConcurrency{
+			Min:           2,
+			TargetLatency: 10 * time.Millisecond,
+			BackoffRatio:  0.5,
+		},
+	})
+	require.NoError(t, err, "newConcurrencyLimiter failed")
+	clock := &limiterClock{now: time.Now()}
+
+	// Slow calls reduce the limit multiplicatively, down to the minimum.
+	wantLimits := []int{5, 2, 2}
+	for _, want := range wantLimits {
+		require.True(t, l.acquire(), "acquire failed")
+		limit, _ := clock.release(l, time.Second)
+		assert.Equal(t, want, limit, "Unexpected limit after slow call")
+	}
+
+	// Fast calls only increase the limit when it is fully used.
+	require.True(t, l.acquire(), "acquire failed")
+	limit, changed := clock.release(l, time.Millisecond)
+	assert.False(t, changed, "Limit should not change when it is not fully used")
+	assert.Equal(t, 2, limit, "Unexpected limit")
+
+	require.True(t, l.acquire(), "acquire failed")
+	require.True(t, l.acquire(), "acquire failed")
+	assert.False(t, l.acquire(), "acquire over the limit should fail")
+	limit, changed = clock.release(l, time.Millisecond)
+	assert.True(t, changed, "Limit should increase when it is fully used")
+	assert.Equal(t, 3, limit, "Unexpected limit")
+	clock.release(l, time.Millisecond)
+}
+
+func TestConcurrencyLimiterCancel(t *testing.T) {
+	l, err := newConcurrencyLimiter(ConcurrencyLimit{
+		Max: 10,
+		Adaptive: &AdaptiveConcurrency{
+			Min:           2,
+			TargetLatency: 10 * time.Millisecond,
+			BackoffRatio:  0.5,
+		},
+	})
+	require.NoError(t, err, "newConcurrencyLimiter failed")
+	clock := &limiterClock{now: time.Now()}
+
+	// Reduce the limit to the minimum, and saturate it.
+	for i := 0; i < 3; i++ {
+		require.True(t, l.acquire(), "acquire failed")
+		clock.release(l, time.Second)
+	}
+	require.True(t, l.acquire(), "acquire failed")
+	require.True(t, l.acquire(), "acquire failed")
+
+	// Cancelled calls free capacity without raising the limit.
+	l.cancel()
+	state := l.IntrospectState()
+	assert.Equal(t, 2, state.Limit, "Cancel should not change the limit")
+	assert.Equal(t, 1, state.InFlight, "Unexpected in-flight calls")
+	l.cancel()
+}
+
+func TestConcurrencyLimiterConcurrentSlowCalls(t *testing.T) {
+	l, err := newConcurrencyLimiter(ConcurrencyLimit{
+		Max: 100,
+		Adaptive: &AdaptiveConcurrency{
+			TargetLatency: 10 * time.Millisecond,
+			BackoffRatio:  0.9,
+		},
+	})
+	require.NoError(t, err, "newConcurrencyLimiter failed")
+
+	// All calls are slow at the same time, which should only reduce the
+	// limit once rather than once per call.
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		require.True(t, l.acquire(), "acquire %v failed", i)
+	}
+	for i := 0; i < 100; i++ {
+		l.release(start, start.Add(time.Second+time.Duration(i)*time.Millisecond))
+	}
+	assert.Equal(t, 90, l.IntrospectState().Limit, "Concurrent slow calls should back off once")
+
+	// A slow call that started after the backoff reduces the limit again.
+	clock := &limiterClock{now: start.Add(2 * time.Second)}
+	require.True(t, l.acquire(), "acquire failed")
+	limit, changed := clock.release(l, time.Second)
+	assert.True(t, changed, "Limit should change")
+	assert.Equal(t, 81, limit, "Unexpected limit after a later slow call")
+}
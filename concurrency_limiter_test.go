@@ -0,0 +1,150 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/temporalio/tchannel-go"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/temporalio/tchannel-go/raw"
+	"github.com/temporalio/tchannel-go/testutils"
+	"golang.org/x/net/context"
+)
+
+func TestConcurrencyLimitValidation(t *testing.T) {
+	tests := []struct {
+		msg     string
+		limit   ConcurrencyLimit
+		wantErr bool
+	}{
+		{msg: "no limit"},
+		{msg: "static limit", limit: ConcurrencyLimit{Max: 10}},
+		{msg: "negative limit", limit: ConcurrencyLimit{Max: -1}, wantErr: true},
+		{
+			msg:   "adaptive",
+			limit: ConcurrencyLimit{Max: 10, Adaptive: &AdaptiveConcurrency{TargetLatency: time.Second}},
+		},
+		{
+			msg:     "adaptive without max",
+			limit:   ConcurrencyLimit{Adaptive: &AdaptiveConcurrency{TargetLatency: time.Second}},
+			wantErr: true,
+		},
+		{
+			msg:     "adaptive without target latency",
+			limit:   ConcurrencyLimit{Max: 10, Adaptive: &AdaptiveConcurrency{}},
+			wantErr: true,
+		},
+		{
+			msg:     "adaptive with invalid ratio",
+			limit:   ConcurrencyLimit{Max: 10, Adaptive: &AdaptiveConcurrency{TargetLatency: time.Second, BackoffRatio: 1.5}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			_, err := NewChannel("svc", &ChannelOptions{ConcurrencyLimit: tt.limit})
+			if tt.wantErr {
+				assert.Error(t, err, "NewChannel should fail")
+			} else {
+				assert.NoError(t, err, "NewChannel failed")
+			}
+		})
+	}
+}
+
+func TestConcurrencyLimitSheds(t *testing.T) {
+	tests := []struct {
+		msg      string
+		setLimit func(ts *testutils.TestServer) error
+	}{
+		{
+			msg: "channel limit",
+			setLimit: func(ts *testutils.TestServer) error {
+				return ts.Server().SetConcurrencyLimit(ConcurrencyLimit{Max: 1})
+			},
+		},
+		{
+			msg: "service limit",
+			setLimit: func(ts *testutils.TestServer) error {
+				return ts.Server().GetSubChannel(ts.ServiceName()).SetConcurrencyLimit(ConcurrencyLimit{Max: 1})
+			},
+		},
+		{
+			msg: "method limit",
+			setLimit: func(ts *testutils.TestServer) error {
+				sc := ts.Server().GetSubChannel(ts.ServiceName())
+				return sc.SetMethodConcurrencyLimit("block", ConcurrencyLimit{Max: 1})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			testutils.WithTestServer(t, nil, func(t testing.TB, ts *testutils.TestServer) {
+				started := make(chan struct{})
+				unblock := make(chan struct{})
+				testutils.RegisterFunc(ts.Server(), "block", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+					close(started)
+					<-unblock
+					return &raw.Res{}, nil
+				})
+				require.NoError(t, tt.setLimit(ts), "Failed to set limit")
+
+				client := ts.NewClient(nil)
+				callErr := make(chan error, 1)
+				go func() {
+					ctx, cancel := NewContext(testutils.Timeout(time.Second))
+					defer cancel()
+					_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "block", nil, nil)
+					callErr <- err
+				}()
+				<-started
+
+				ctx, cancel := NewContext(testutils.Timeout(time.Second))
+				defer cancel()
+				_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "block", nil, nil)
+				assert.Equal(t, ErrCodeBusy, GetSystemErrorCode(err), "Call over the limit should be rejected")
+
+				close(unblock)
+				require.NoError(t, <-callErr, "Call under the limit should succeed")
+			})
+		})
+	}
+}
+
+func TestConcurrencyLimitIntrospection(t *testing.T) {
+	testutils.WithTestServer(t, nil, func(t testing.TB, ts *testutils.TestServer) {
+		require.NoError(t, ts.Server().SetConcurrencyLimit(ConcurrencyLimit{Max: 5}), "Failed to set channel limit")
+		sc := ts.Server().GetSubChannel(ts.ServiceName())
+		require.NoError(t, sc.SetMethodConcurrencyLimit("echo", ConcurrencyLimit{Max: 2}), "Failed to set method limit")
+
+		state := ts.Server().IntrospectState(nil)
+		assert.Equal(t, 5, state.ConcurrencyLimit.Limit, "Unexpected channel limit")
+		scState := state.SubChannels[ts.ServiceName()]
+		assert.Nil(t, scState.ConcurrencyLimit, "Service limit was not set")
+		assert.Equal(t, 2, scState.MethodConcurrencyLimits["echo"].Limit, "Unexpected method limit")
+	})
+}
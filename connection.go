@@ -73,6 +73,24 @@ type PeerInfo struct {
 
 	// Version returns the version information for the remote peer.
 	Version PeerVersion `json:"version"`
+
+	// TLS contains the verified TLS state of the remote peer. It is nil if
+	// the connection does not use TLS.
+	TLS *PeerTLSInfo `json:"tls,omitempty"`
+
+	// Identity is the identity of the remote peer verified by the channel's
+	// Handshaker. It is nil if there is no Handshaker, or it did not return
+	// an identity.
+	Identity *PeerIdentity `json:"identity,omitempty"`
+
+	// Compression is the payload compression negotiated with the remote peer
+	// during the handshake. It is empty if payloads are not compressed.
+	Compression CompressionType `json:"compression,omitempty"`
+
+	// FlowControlWindow is the number of fragments the remote peer buffers
+	// for each message exchange, as advertised during the handshake. It is
+	// zero if the remote peer does not support flow control.
+	FlowControlWindow int `json:"flowControlWindow,omitempty"`
 }
 
 func (p PeerInfo) String() string {
@@ -158,6 +176,31 @@ type ConnectionOptions struct {
 	// MaxCloseTime controls how long we allow a connection to complete pending
 	// calls before shutting down. Only used if it is non-zero.
 	MaxCloseTime time.Duration
+
+	// MaxChecksumFailures is the number of checksum failures after which the
+	// connection is closed, as repeated failures typically indicate corruption
+	// on the network path to the peer. Connections are never closed due to
+	// checksum failures if it is zero.
+	MaxChecksumFailures int
+
+	// Compression is the list of payload compression types supported by this
+	// channel, in order of preference. Compression is negotiated with each peer
+	// during the handshake, and is not used unless both peers support it.
+	// Relays forward compressed calls unchanged when the destination
+	// negotiated the same compression, and decompress them otherwise.
+	Compression []CompressionType
+
+	// ConnectionsPerPeer is the number of outbound connections that each peer
+	// keeps open. Calls to a peer are spread across its connections based on
+	// the number of pending calls on each connection, and connections that
+	// fail are replaced in the background. Defaults to 1.
+	ConnectionsPerPeer int
+
+	// FlowControlWindow enables credit-based flow control for message exchanges
+	// if it is non-zero. It is the number of fragments of a single call that
+	// this channel buffers before the sender must wait for more credits, and
+	// must be at most 512. Flow control is only used if both peers enable it.
+	FlowControlWindow int
 }
 
 // connectionEvents are the events that can be triggered by a connection.
@@ -184,6 +227,7 @@ type Connection struct {
 	localPeerInfo    LocalPeerInfo
 	remotePeerInfo   PeerInfo
 	sendCh           chan *Frame
+	sendChs          [numPriorityQueues]chan *Frame
 	stopCh           chan struct{}
 	state            connectionState
 	stateMut         sync.RWMutex
@@ -207,6 +251,8 @@ type Connection struct {
 	closeNetworkCalled atomic.Bool
 	// stoppedExchanges is atomically set when exchanges are stopped due to error.
 	stoppedExchanges atomic.Bool
+	// failed is set when the connection is closed due to a connection error.
+	failed atomic.Bool
 	// remotePeerAddress is used as a cache for remote peer address parsed into individual
 	// components that can be used to set peer tags on OpenTracing Span.
 	remotePeerAddress peerAddressComponents
@@ -221,6 +267,9 @@ type Connection struct {
 	// idle for the recieve and send connections respectively. (unix time, nano)
 	lastActivityRead  atomic.Int64
 	lastActivityWrite atomic.Int64
+
+	// checksumFailures is the number of received fragments with mismatched checksums.
+	checksumFailures atomic.Uint64
 }
 
 type peerAddressComponents struct {
@@ -303,11 +352,11 @@ func (ch *Channel) setConnectionTosPriority(tosPriority tos.ToS, c net.Conn) err
 }
 
 func (ch *Channel) newConnection(baseCtx context.Context, conn net.Conn, initialID uint32, outboundHP string, remotePeer PeerInfo, remotePeerAddress peerAddressComponents, events connectionEvents) *Connection {
-	opts := ch.connectionOptions.withDefaults()
+	opts := ch.getConnectionOptions().withDefaults()
 
 	connID := _nextConnID.Inc()
 	connDirection := inbound
-	log := ch.log.WithFields(LogFields{
+	log := ch.subsystemLogger(LogSubsystemConnection).WithFields(LogFields{
 		{"connID", connID},
 		{"localAddr", conn.LocalAddr().String()},
 		{"remoteAddr", conn.RemoteAddr().String()},
@@ -333,7 +382,6 @@ func (ch *Channel) newConnection(baseCtx context.Context, conn net.Conn, initial
 		connDirection:      connDirection,
 		opts:               opts,
 		state:              connectionActive,
-		sendCh:             make(chan *Frame, opts.getSendBufferSize(remotePeer.ProcessName)),
 		stopCh:             make(chan struct{}),
 		localPeerInfo:      peerInfo,
 		remotePeerInfo:     remotePeer,
@@ -351,6 +399,12 @@ func (ch *Channel) newConnection(baseCtx context.Context, conn net.Conn, initial
 		baseContext:        ch.connContext(baseCtx, conn),
 	}
 
+	// Each priority class has its own send queue, and sendCh is the normal queue.
+	for i := range c.sendChs {
+		c.sendChs[i] = make(chan *Frame, opts.getSendBufferSize(remotePeer.ProcessName))
+	}
+	c.sendCh = c.sendChs[priorityQueueNormal]
+
 	if tosPriority := opts.TosPriority; tosPriority > 0 {
 		if err := ch.setConnectionTosPriority(tosPriority, conn); err != nil {
 			log.WithFields(ErrField(err)).Error("Failed to set ToS priority.")
@@ -363,6 +417,7 @@ func (ch *Channel) newConnection(baseCtx context.Context, conn net.Conn, initial
 	c.outbound.onRemoved = c.checkExchanges
 	c.inbound.onAdded = c.onExchangeAdded
 	c.outbound.onAdded = c.onExchangeAdded
+	c.outbound.onCancelled = c.sendCancel
 
 	if ch.RelayHost() != nil {
 		c.relay = NewRelayer(ch, c)
@@ -422,7 +477,7 @@ func (c *Connection) callOnExchangeChange() {
 // ping sends a ping message and waits for a ping response.
 func (c *Connection) ping(ctx context.Context) error {
 	req := &pingReq{id: c.NextMessageID()}
-	mex, err := c.outbound.newExchange(ctx, c.opts.FramePool, req.messageType(), req.ID(), 1)
+	mex, err := c.outbound.newExchange(ctx, c.opts.FramePool, req.messageType(), req.ID(), 1, nil /* flow */)
 	if err != nil {
 		return c.connectionError("create ping exchange", err)
 	}
@@ -458,8 +513,62 @@ func (c *Connection) handlePingReq(frame *Frame) {
 	}
 }
 
+// sendCancel notifies the peer that the caller of an outbound call is no
+// longer waiting for the response, so the peer can stop processing the call.
+func (c *Connection) sendCancel(mex *messageExchange) {
+	if mex.msgType != messageTypeCallReq || c.readState() == connectionClosed {
+		return
+	}
+
+	var ttl time.Duration
+	if deadline, ok := mex.ctx.Deadline(); ok && deadline.After(c.timeNow()) {
+		ttl = deadline.Sub(c.timeNow())
+	}
+
+	msg := &cancelMessage{
+		id:      mex.msgID,
+		ttl:     ttl,
+		tracing: *CurrentSpan(mex.ctx),
+		why:     mex.ctx.Err().Error(),
+	}
+	if err := c.sendMessageWithPriority(msg, mex.priority); err != nil {
+		c.log.WithFields(
+			LogField{"id", mex.msgID},
+			ErrField(err),
+		).Info("Failed to send cancel.")
+	}
+}
+
+// handleCancel cancels the context of the inbound call that the peer is no
+// longer waiting for.
+func (c *Connection) handleCancel(frame *Frame) {
+	msg := &cancelMessage{id: frame.Header.ID}
+	if err := frame.read(msg); err != nil {
+		c.log.WithFields(
+			LogField{"header", frame.Header},
+			ErrField(err),
+		).Warn("Unable to read cancel frame.")
+		return
+	}
+
+	if !c.inbound.cancelExchange(msg.id) {
+		c.log.Debugf("Received cancel for unknown inbound call %v", msg.id)
+		return
+	}
+
+	if c.log.Enabled(LogLevelDebug) {
+		c.log.Debugf("Cancelled inbound call %v: %v", msg.id, msg.why)
+	}
+}
+
 // sendMessage sends a standalone message (typically a control message)
 func (c *Connection) sendMessage(msg message) error {
+	return c.sendMessageWithPriority(msg, PriorityNormal)
+}
+
+// sendMessageWithPriority sends a standalone message for a call using the send
+// queue for the call's priority, so it's written after the call's earlier frames.
+func (c *Connection) sendMessageWithPriority(msg message, priority PriorityClass) error {
 	frame := c.opts.FramePool.Get()
 	if err := frame.write(msg); err != nil {
 		c.opts.FramePool.Release(frame)
@@ -467,7 +576,7 @@ func (c *Connection) sendMessage(msg message) error {
 	}
 
 	select {
-	case c.sendCh <- frame:
+	case c.sendChFor(priority) <- frame:
 		return nil
 	default:
 		return ErrSendBufferFull
@@ -502,6 +611,12 @@ func (c *Connection) NextMessageID() uint32 {
 
 // SendSystemError sends an error frame for the given system error.
 func (c *Connection) SendSystemError(id uint32, span Span, err error) error {
+	return c.sendSystemError(id, span, err, PriorityNormal)
+}
+
+// sendSystemError sends an error frame for a call using the send queue for the
+// call's priority, so it's written after the call's earlier frames.
+func (c *Connection) sendSystemError(id uint32, span Span, err error, priority PriorityClass) error {
 	frame := c.opts.FramePool.Get()
 
 	if err := frame.write(&errorMessage{
@@ -533,7 +648,7 @@ func (c *Connection) SendSystemError(id uint32, span Span, err error) error {
 		}
 
 		select {
-		case c.sendCh <- frame: // Good to go
+		case c.sendChFor(priority) <- frame: // Good to go
 			return nil
 		default: // If the send buffer is full, log and return an error.
 		}
@@ -580,6 +695,7 @@ func (c *Connection) connectionError(site string, err error) error {
 		}
 	}
 
+	c.failed.Store(true)
 	c.stopHealthCheck()
 	err = c.logConnectionError(site, err)
 	c.close(closeLogFields...)
@@ -595,6 +711,26 @@ func (c *Connection) connectionError(site string, err error) error {
 	return err
 }
 
+// checksumFailed records a checksum failure for a call on this connection,
+// closing the connection if it has exceeded MaxChecksumFailures.
+func (c *Connection) checksumFailed(err *ChecksumError, statsPrefix string, statsTags map[string]string) {
+	failures := c.checksumFailures.Inc()
+	c.statsReporter.IncCounter(statsPrefix+".checksum-failures", statsTags, 1)
+	c.log.WithFields(
+		LogField{"service", err.Service},
+		LogField{"method", err.Method},
+		LogField{"checksumType", err.ChecksumType},
+		LogField{"checksumFailures", failures},
+	).Warn("Received fragment with mismatched checksum.")
+
+	if max := c.opts.MaxChecksumFailures; max > 0 && failures == uint64(max) {
+		c.close(
+			LogField{"reason", "checksum failures"},
+			LogField{"checksumFailures", failures},
+		)
+	}
+}
+
 func (c *Connection) protocolError(id uint32, err error) error {
 	c.log.WithFields(ErrField(err)).Warn("Protocol error.")
 	sysErr := NewWrappedSystemError(ErrCodeProtocol, err)
@@ -685,7 +821,7 @@ func (c *Connection) readFrames(_ uint32) {
 
 func (c *Connection) handleFrameRelay(frame *Frame) bool {
 	switch frame.Header.messageType {
-	case messageTypeCallReq, messageTypeCallReqContinue, messageTypeCallRes, messageTypeCallResContinue, messageTypeError:
+	case messageTypeCallReq, messageTypeCallReqContinue, messageTypeCallRes, messageTypeCallResContinue, messageTypeError, messageTypeCancel, messageTypeCredit:
 		shouldRelease, err := c.relay.Relay(frame)
 		if err != nil {
 			c.log.WithFields(
@@ -713,6 +849,10 @@ func (c *Connection) handleFrameNoRelay(frame *Frame) bool {
 		releaseFrame = c.handleCallRes(frame)
 	case messageTypeCallResContinue:
 		releaseFrame = c.handleCallResContinue(frame)
+	case messageTypeCancel:
+		c.handleCancel(frame)
+	case messageTypeCredit:
+		c.handleCredit(frame)
 	case messageTypePingReq:
 		c.handlePingReq(frame)
 	case messageTypePingRes:
@@ -733,29 +873,26 @@ func (c *Connection) handleFrameNoRelay(frame *Frame) bool {
 // writeFrames is the main loop that pulls frames from the send channel and
 // writes them to the connection.
 func (c *Connection) writeFrames(_ uint32) {
+	scheduler := newFrameScheduler(c)
 	for {
-		select {
-		case f := <-c.sendCh:
-			if c.log.Enabled(LogLevelDebug) {
-				c.log.Debugf("Writing frame %s", f.Header)
-			}
-
-			c.updateLastActivityWrite(f)
-			err := f.WriteOut(c.conn)
-			c.opts.FramePool.Release(f)
-			if err != nil {
-				c.connectionError("write frames", err)
-				return
-			}
-		case <-c.stopCh:
-			// If there are frames in sendCh, we want to drain them.
-			if len(c.sendCh) > 0 {
-				continue
-			}
+		f, ok := scheduler.next()
+		if !ok {
 			// Close the network once we're no longer writing frames.
 			c.closeNetwork()
 			return
 		}
+
+		if c.log.Enabled(LogLevelDebug) {
+			c.log.Debugf("Writing frame %s", f.Header)
+		}
+
+		c.updateLastActivityWrite(f)
+		err := f.WriteOut(c.conn)
+		c.opts.FramePool.Release(f)
+		if err != nil {
+			c.connectionError("write frames", err)
+			return
+		}
 	}
 }
 
@@ -787,6 +924,15 @@ func (c *Connection) hasPendingCalls() bool {
 	return false
 }
 
+// pendingExchanges returns the number of calls in progress on this connection.
+func (c *Connection) pendingExchanges() int {
+	pending := c.inbound.count() + c.outbound.count()
+	if c.relay != nil {
+		pending += int(c.relay.countPending())
+	}
+	return pending
+}
+
 // checkExchanges is called whenever an exchange is removed, and when Close is called.
 func (c *Connection) checkExchanges() {
 	c.callOnExchangeChange()
@@ -920,6 +1066,11 @@ func (c *Connection) getLastActivityWriteTime() time.Time {
 }
 
 func getSysConn(conn net.Conn, log Logger) syscall.RawConn {
+	// Wrapped connections (e.g. TLS) expose the underlying connection.
+	if wrapped, ok := conn.(interface{ NetConn() net.Conn }); ok {
+		conn = wrapped.NetConn()
+	}
+
 	connSyscall, ok := conn.(syscall.Conn)
 	if !ok {
 		log.WithFields(LogField{"connectionType", fmt.Sprintf("%T", conn)}).
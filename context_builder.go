@@ -136,6 +136,15 @@ func (cb *ContextBuilder) SetRoutingDelegate(rd string) *ContextBuilder {
 	return cb
 }
 
+// DisableCompression disables payload compression for calls made using this context.
+func (cb *ContextBuilder) DisableCompression() *ContextBuilder {
+	if cb.CallOptions == nil {
+		cb.CallOptions = new(CallOptions)
+	}
+	cb.CallOptions.DisableCompression = true
+	return cb
+}
+
 // SetConnectTimeout sets the ConnectionTimeout for this context.
 // The context timeout applies to the whole call, while the connect
 // timeout only applies to creating a new connection.
@@ -0,0 +1,86 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// minTimeToLive is the lowest TTL that can be sent, since TTLs are encoded
+// in milliseconds, and a TTL of 0 is invalid.
+const minTimeToLive = time.Millisecond
+
+// DeadlineOptions configures how the deadlines of inbound calls are
+// propagated to outbound calls made using the inbound call's context.
+type DeadlineOptions struct {
+	// Overhead is subtracted from the remaining time of an inbound call when
+	// making outbound calls using its context, leaving time to process the
+	// response of the outbound call.
+	Overhead time.Duration
+
+	// MinTimeToLive is the minimum time to live for outbound calls. Calls with
+	// less time remaining fail with ErrTimeout without being sent.
+	// Defaults to, and cannot be less than, 1 millisecond.
+	MinTimeToLive time.Duration
+}
+
+func (o DeadlineOptions) minTimeToLive() time.Duration {
+	if o.MinTimeToLive < minTimeToLive {
+		return minTimeToLive
+	}
+	return o.MinTimeToLive
+}
+
+// TimeToLive returns the TTL that the caller set for this call.
+func (call *InboundCall) TimeToLive() time.Duration {
+	return call.timeToLive
+}
+
+// ReceivedAt returns the time at which the call was received.
+func (call *InboundCall) ReceivedAt() time.Time {
+	return call.receivedAt
+}
+
+// RemainingTimeToLive returns the time remaining for outbound calls made while
+// handling this call. It is the original TTL, less the time spent since the
+// call was received, and the channel's DeadlineOptions.Overhead.
+func (call *InboundCall) RemainingTimeToLive() time.Duration {
+	elapsed := call.conn.timeNow().Sub(call.receivedAt)
+	return call.timeToLive - elapsed - call.conn.deadlineOpts.Overhead
+}
+
+// outboundTimeToLive returns the TTL for an outbound call with the given
+// context. If the context is for an inbound call, the overhead is subtracted
+// so the downstream call times out before the inbound call.
+func (c *Connection) outboundTimeToLive(ctx context.Context, now time.Time) (time.Duration, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+
+	timeToLive := deadline.Sub(now)
+	if _, ok := CurrentCall(ctx).(*InboundCall); ok {
+		timeToLive -= c.deadlineOpts.Overhead
+	}
+	return timeToLive, true
+}
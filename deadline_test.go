@@ -0,0 +1,120 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/temporalio/tchannel-go"
+	"github.com/temporalio/tchannel-go/raw"
+	"github.com/temporalio/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestInboundCallTimeToLive(t *testing.T) {
+	server := testutils.NewServer(t, nil)
+	defer server.Close()
+
+	var (
+		ttl        time.Duration
+		receivedAt time.Time
+	)
+	testutils.RegisterFunc(server, "ttl", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+		call := tchannel.CurrentCall(ctx).(*tchannel.InboundCall)
+		ttl = call.TimeToLive()
+		receivedAt = call.ReceivedAt()
+		return &raw.Res{}, nil
+	})
+
+	client := testutils.NewClient(t, nil)
+	defer client.Close()
+
+	ctx, cancel := tchannel.NewContext(500 * time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, _, _, err := raw.Call(ctx, client, server.PeerInfo().HostPort, server.ServiceName(), "ttl", nil, nil)
+	require.NoError(t, err, "Call failed")
+
+	assert.True(t, ttl > 400*time.Millisecond && ttl <= 500*time.Millisecond, "Unexpected TTL %v", ttl)
+	assert.False(t, receivedAt.Before(start) || receivedAt.After(time.Now()), "Unexpected received time %v", receivedAt)
+}
+
+func TestDeadlinePropagation(t *testing.T) {
+	const overhead = 100 * time.Millisecond
+
+	downstream := testutils.NewServer(t, testutils.NewOpts().SetServiceName("downstream"))
+	defer downstream.Close()
+
+	var downstreamTTL time.Duration
+	testutils.RegisterFunc(downstream, "ttl", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+		downstreamTTL = tchannel.CurrentCall(ctx).(*tchannel.InboundCall).TimeToLive()
+		return &raw.Res{}, nil
+	})
+
+	stats := newRecordingStatsReporter()
+	opts := testutils.NewOpts().SetServiceName("frontend").SetStatsReporter(stats)
+	opts.Deadlines = tchannel.DeadlineOptions{
+		Overhead:      overhead,
+		MinTimeToLive: 50 * time.Millisecond,
+	}
+	frontend := testutils.NewServer(t, opts)
+	defer frontend.Close()
+
+	var remaining time.Duration
+	testutils.RegisterFunc(frontend, "forward", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+		remaining = tchannel.CurrentCall(ctx).(*tchannel.InboundCall).RemainingTimeToLive()
+		_, _, _, err := raw.Call(ctx, frontend, downstream.PeerInfo().HostPort, "downstream", "ttl", nil, nil)
+		return &raw.Res{}, err
+	})
+
+	client := testutils.NewClient(t, nil)
+	defer client.Close()
+
+	callForward := func(timeout time.Duration) error {
+		ctx, cancel := tchannel.NewContext(timeout)
+		defer cancel()
+
+		_, _, _, err := raw.Call(ctx, client, frontend.PeerInfo().HostPort, "frontend", "forward", nil, nil)
+		return err
+	}
+
+	require.NoError(t, callForward(500*time.Millisecond), "Call failed")
+	assert.True(t, remaining <= 400*time.Millisecond, "Remaining TTL %v should exclude the overhead", remaining)
+	assert.True(t, downstreamTTL > 300*time.Millisecond && downstreamTTL <= 400*time.Millisecond,
+		"Downstream TTL %v should exclude the overhead", downstreamTTL)
+
+	// With less than the overhead and minimum TTL remaining, the downstream call fails fast.
+	downstreamTTL = 0
+	err := callForward(120 * time.Millisecond)
+	assert.Equal(t, tchannel.ErrCodeTimeout, tchannel.GetSystemErrorCode(err), "Expected timeout, got %v", err)
+	assert.Zero(t, downstreamTTL, "Downstream should not be called")
+
+	var insufficient int64
+	for _, v := range stats.Values["outbound.calls.insufficient-deadline"] {
+		insufficient += v.count
+	}
+	assert.EqualValues(t, 1, insufficient, "Expected insufficient deadline to be reported")
+}
@@ -31,5 +31,6 @@ import (
 
 func dialContext(ctx context.Context, hostPort string) (net.Conn, error) {
 	timeout := getTimeout(ctx)
-	return net.DialTimeout("tcp", hostPort, timeout)
+	network, address := splitNetworkAddress(hostPort)
+	return net.DialTimeout(network, address, timeout)
 }
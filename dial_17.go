@@ -30,5 +30,6 @@ import (
 
 func dialContext(ctx context.Context, hostPort string) (net.Conn, error) {
 	d := net.Dialer{}
-	return d.DialContext(ctx, "tcp", hostPort)
+	network, address := splitNetworkAddress(hostPort)
+	return d.DialContext(ctx, network, address)
 }
@@ -0,0 +1,260 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"errors"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	// drainPollInterval is how often Drain checks for pending calls.
+	drainPollInterval = 10 * time.Millisecond
+
+	// drainLogInterval is how often Drain logs its progress.
+	drainLogInterval = time.Second
+)
+
+var errDrainDeadline = errors.New("drain deadline exceeded with pending calls")
+
+// Directions reported for pending calls in a DrainReport.
+const (
+	PendingInbound  = "inbound"
+	PendingOutbound = "outbound"
+	PendingRelay    = "relay"
+)
+
+// PendingCall describes a call that was still in-flight when Drain gave up.
+type PendingCall struct {
+	ConnectionID   uint32 `json:"connectionID"`
+	RemoteHostPort string `json:"remoteHostPort"`
+	Direction      string `json:"direction"`
+	ID             uint32 `json:"id"`
+}
+
+// DrainReport is the result of draining a channel.
+type DrainReport struct {
+	// Drained is whether all in-flight calls completed before the deadline.
+	Drained bool `json:"drained"`
+
+	// Pending is the list of calls that were still in-flight at the deadline.
+	// These calls were failed when the connections were closed.
+	Pending []PendingCall `json:"pending,omitempty"`
+
+	// Duration is how long the drain took.
+	Duration time.Duration `json:"duration"`
+}
+
+// OnDrain registers a function that is called when Drain starts, before
+// waiting for in-flight calls. It is used to stop advertising the channel
+// (e.g. with Hyperbahn) so that no new calls are routed to it.
+func (ch *Channel) OnDrain(f func(ctx context.Context) error) {
+	ch.mutable.Lock()
+	ch.mutable.drainHooks = append(ch.mutable.drainHooks, f)
+	ch.mutable.Unlock()
+}
+
+// Draining returns whether Drain has been called on the channel.
+func (ch *Channel) Draining() bool {
+	return ch.draining.Load()
+}
+
+// Drain gracefully shuts down the channel:
+//  1. New calls are declined with ErrChannelDraining, except for health checks
+//     and calls to the internal "tchannel" service.
+//  2. Functions registered with OnDrain are run, e.g. to unadvertise.
+//  3. Drain waits until all in-flight calls complete, or ctx is done.
+//  4. The channel is closed. If there were still pending calls, their
+//     connections are closed immediately and the calls are failed.
+//
+// If ctx has no deadline, the connection's MaxCloseTime is used as the limit,
+// if it is set. The returned report lists any calls that were still pending.
+func (ch *Channel) Drain(ctx context.Context) (*DrainReport, error) {
+	switch state := ch.State(); state {
+	case ChannelClient, ChannelListening:
+		break
+	default:
+		ch.log.Debugf("Drain called on channel in state %v", state)
+		return nil, errInvalidStateForOp
+	}
+
+	if !ch.draining.CAS(false, true) {
+		return nil, errInvalidStateForOp
+	}
+
+	if _, ok := ctx.Deadline(); !ok && ch.connectionOptions.MaxCloseTime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ch.connectionOptions.MaxCloseTime)
+		defer cancel()
+	}
+
+	start := ch.timeNow()
+	ch.log.Info("Channel.Drain called.")
+
+	ch.mutable.RLock()
+	hooks := ch.mutable.drainHooks
+	ch.mutable.RUnlock()
+	for _, hook := range hooks {
+		if err := hook(ctx); err != nil {
+			ch.log.WithFields(ErrField(err)).Info("Drain hook failed.")
+		}
+	}
+
+	drained := ch.waitForPending(ctx)
+	report := &DrainReport{Drained: drained}
+	if !drained {
+		report.Pending = ch.pendingCalls()
+		ch.log.WithFields(
+			LogField{"pending", len(report.Pending)},
+		).Info("Drain deadline exceeded, closing connections.")
+		for _, c := range ch.connections() {
+			c.connectionError("drain", errDrainDeadline)
+		}
+	}
+
+	ch.Close()
+	report.Duration = ch.timeNow().Sub(start)
+	return report, nil
+}
+
+// waitForPending waits for all in-flight calls to complete, and returns
+// false if ctx is done before they do.
+func (ch *Channel) waitForPending(ctx context.Context) bool {
+	ticker := ch.timeTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	lastLog := ch.timeNow()
+	for {
+		pending := ch.countPending()
+		if pending == 0 {
+			return true
+		}
+
+		if now := ch.timeNow(); now.Sub(lastLog) >= drainLogInterval {
+			lastLog = now
+			ch.log.WithFields(LogField{"pending", pending}).Info("Waiting for pending calls to drain.")
+		}
+
+		select {
+		case <-ctx.Done():
+			return ch.countPending() == 0
+		case <-ticker.C:
+		}
+	}
+}
+
+func (ch *Channel) connections() []*Connection {
+	ch.mutable.RLock()
+	conns := make([]*Connection, 0, len(ch.mutable.conns))
+	for _, c := range ch.mutable.conns {
+		conns = append(conns, c)
+	}
+	ch.mutable.RUnlock()
+	return conns
+}
+
+// countPending returns the number of in-flight calls across all connections.
+// Other exchanges, such as pings, are not counted.
+func (ch *Channel) countPending() int {
+	var pending int
+	for _, c := range ch.connections() {
+		pending += c.inbound.countCalls() + c.outbound.countCalls()
+		if c.relay != nil {
+			pending += int(c.relay.countPending())
+		}
+	}
+	return pending
+}
+
+// pendingCalls returns all calls that are in-flight across all connections.
+func (ch *Channel) pendingCalls() []PendingCall {
+	var calls []PendingCall
+	for _, c := range ch.connections() {
+		add := func(direction string, ids []uint32) {
+			for _, id := range ids {
+				calls = append(calls, PendingCall{
+					ConnectionID:   c.connID,
+					RemoteHostPort: c.remotePeerInfo.HostPort,
+					Direction:      direction,
+					ID:             id,
+				})
+			}
+		}
+		add(PendingInbound, c.inbound.pendingIDs())
+		add(PendingOutbound, c.outbound.pendingIDs())
+		if c.relay != nil {
+			add(PendingRelay, c.relay.outbound.originatorIDs())
+		}
+	}
+	return calls
+}
+
+// countCalls returns the number of call exchanges in the set.
+func (mexset *messageExchangeSet) countCalls() int {
+	mexset.RLock()
+	defer mexset.RUnlock()
+
+	var count int
+	for _, mex := range mexset.exchanges {
+		if mex.msgType == messageTypeCallReq {
+			count++
+		}
+	}
+	return count
+}
+
+// pendingIDs returns the message IDs of all call exchanges in the set.
+func (mexset *messageExchangeSet) pendingIDs() []uint32 {
+	mexset.RLock()
+	defer mexset.RUnlock()
+
+	var ids []uint32
+	for id, mex := range mexset.exchanges {
+		if mex.msgType == messageTypeCallReq {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// originatorIDs returns the IDs of relayed calls that were started by the
+// peer of this connection.
+func (r *relayItems) originatorIDs() []uint32 {
+	r.RLock()
+	defer r.RUnlock()
+
+	var ids []uint32
+	for id, item := range r.items {
+		if item.isOriginator && !item.tomb {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// drainExempt returns whether a call should be accepted while draining.
+// Health checks and internal calls must keep working so that load balancers
+// can observe the drain.
+func drainExempt(serviceName, method string) bool {
+	return serviceName == "tchannel" || method == "Meta::health"
+}
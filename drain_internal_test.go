@@ -0,0 +1,46 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestPendingCallsExcludePings(t *testing.T) {
+	mexset := newMessageExchangeSet(NullLogger, messageExchangeSetOutbound)
+	mexset.onAdded = func() {}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := mexset.newExchange(ctx, DefaultFramePool, messageTypePingReq, 1, 1, nil /* flow */)
+	require.NoError(t, err, "Failed to create ping exchange")
+	_, err = mexset.newExchange(ctx, DefaultFramePool, messageTypeCallReq, 2, 1, nil /* flow */)
+	require.NoError(t, err, "Failed to create call exchange")
+
+	assert.Equal(t, 2, mexset.count(), "Expected all exchanges to be counted")
+	assert.Equal(t, 1, mexset.countCalls(), "Pings should not be counted as calls")
+	assert.Equal(t, []uint32{2}, mexset.pendingIDs(), "Pings should not be reported as calls")
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/temporalio/tchannel-go"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/temporalio/tchannel-go/raw"
+	"github.com/temporalio/tchannel-go/testutils"
+	"golang.org/x/net/context"
+)
+
+func TestDrainWaitsForInflightCalls(t *testing.T) {
+	server := testutils.NewServer(t, nil)
+	defer server.Close()
+
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	testutils.RegisterFunc(server, "block", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+		close(started)
+		<-unblock
+		return &raw.Res{Arg3: []byte("done")}, nil
+	})
+	testutils.RegisterEcho(server, nil)
+
+	client := testutils.NewClient(t, nil)
+	defer client.Close()
+	hostPort := server.PeerInfo().HostPort
+
+	callErr := make(chan error, 1)
+	go func() {
+		ctx, cancel := NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+		_, arg3, _, err := raw.Call(ctx, client, hostPort, server.ServiceName(), "block", nil, nil)
+		if err == nil {
+			assert.Equal(t, "done", string(arg3), "Unexpected response")
+		}
+		callErr <- err
+	}()
+	<-started
+
+	drainDone := make(chan *DrainReport, 1)
+	go func() {
+		ctx, cancel := NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+		report, err := server.Drain(ctx)
+		assert.NoError(t, err, "Drain failed")
+		drainDone <- report
+	}()
+
+	testutils.WaitFor(time.Second, server.Draining)
+	err := testutils.CallEcho(client, hostPort, server.ServiceName(), nil)
+	assert.Equal(t, ErrCodeDeclined, GetSystemErrorCode(err), "New calls should be declined while draining")
+
+	close(unblock)
+	require.NoError(t, <-callErr, "In-flight call should complete")
+
+	report := <-drainDone
+	assert.True(t, report.Drained, "Channel should drain")
+	assert.Empty(t, report.Pending, "No calls should be pending")
+	assert.True(t, server.Closed(), "Channel should be closed after drain")
+}
+
+func TestDrainReportsPendingCalls(t *testing.T) {
+	server := testutils.NewServer(t, nil)
+	defer server.Close()
+
+	started := make(chan struct{})
+	handlerDone := make(chan struct{})
+	server.Register(HandlerFunc(func(ctx context.Context, call *InboundCall) {
+		defer close(handlerDone)
+		close(started)
+		<-ctx.Done()
+	}), "block")
+
+	client := testutils.NewClient(t, nil)
+	defer client.Close()
+
+	callErr := make(chan error, 1)
+	go func() {
+		ctx, cancel := NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+		_, _, _, err := raw.Call(ctx, client, server.PeerInfo().HostPort, server.ServiceName(), "block", nil, nil)
+		callErr <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), testutils.Timeout(50*time.Millisecond))
+	defer cancel()
+	report, err := server.Drain(ctx)
+	require.NoError(t, err, "Drain failed")
+	assert.False(t, report.Drained, "Channel should not drain with a blocked call")
+	require.Len(t, report.Pending, 1, "Expected a single pending call")
+	assert.Equal(t, PendingInbound, report.Pending[0].Direction, "Unexpected direction")
+
+	assert.Error(t, <-callErr, "Pending call should fail when the connection is closed")
+	<-handlerDone
+}
+
+func TestDrainInvalidState(t *testing.T) {
+	ch := testutils.NewClient(t, nil)
+	ch.Close()
+
+	_, err := ch.Drain(context.Background())
+	assert.Error(t, err, "Drain on a closed channel should fail")
+}
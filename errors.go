@@ -93,6 +93,14 @@ var (
 	// ErrChannelClosed is a SystemError indicating that the channel has been closed.
 	ErrChannelClosed = NewSystemError(ErrCodeDeclined, "closed channel")
 
+	// ErrChannelDraining is a SystemError indicating that the channel is draining
+	// and is not accepting new calls.
+	ErrChannelDraining = NewSystemError(ErrCodeDeclined, "channel is draining")
+
+	// ErrUnauthorized is a SystemError indicating that the call was rejected
+	// by an authorization policy.
+	ErrUnauthorized = NewSystemError(ErrCodeBadRequest, "unauthorized")
+
 	// ErrMethodTooLarge is a SystemError indicating that the method is too large.
 	ErrMethodTooLarge = NewSystemError(ErrCodeProtocol, "method too large")
 )
@@ -213,6 +221,10 @@ func GetSystemErrorCode(err error) SystemErrCode {
 		return se.Code()
 	}
 
+	if _, ok := err.(*ChecksumError); ok {
+		return ErrCodeBadRequest
+	}
+
 	return ErrCodeUnexpected
 }
 
@@ -226,6 +238,30 @@ func GetSystemErrorMessage(err error) string {
 	return err.Error()
 }
 
+// ChecksumError is returned when the checksum of a fragment received from a
+// peer does not match the checksum calculated over the fragment's contents.
+// It is reported to peers as a BadRequest error.
+type ChecksumError struct {
+	// ChecksumType is the type of checksum used for the call.
+	ChecksumType ChecksumType
+
+	// Expected is the checksum sent by the remote peer, and Actual is the
+	// checksum calculated over the received fragment.
+	Expected, Actual []byte
+
+	// RemotePeer is the peer that sent the corrupted fragment.
+	RemotePeer PeerInfo
+
+	// Service and Method identify the call. Method may be empty if the
+	// checksum failed before the method was read.
+	Service, Method string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("different checksums between peer and local for %v::%v from %v: checksum type %v, peer %x, local %x",
+		e.Service, e.Method, e.RemotePeer.HostPort, e.ChecksumType, e.Expected, e.Actual)
+}
+
 type errConnNotActive struct {
 	info  string
 	state connectionState
@@ -109,14 +109,45 @@ func (s *tchanAdminServer) Handle(ctx thrift.Context, methodName string, protoco
 	}
 }
 
+func (s *tchanAdminServer) NewRequest(methodName string) athrift.TStruct {
+	switch methodName {
+	case "clearAll":
+		return &AdminClearAllArgs{}
+
+	case "HealthCheck":
+		return s.TChanServer.(thrift.TChanStructServer).NewRequest(methodName)
+	default:
+		return nil
+	}
+}
+
+func (s *tchanAdminServer) HandleRequest(ctx thrift.Context, methodName string, req athrift.TStruct) (bool, athrift.TStruct, error) {
+	switch methodName {
+	case "clearAll":
+		if args, ok := req.(*AdminClearAllArgs); ok {
+			return s.handleClearAllRequest(ctx, args)
+		}
+		return false, nil, fmt.Errorf("unexpected request type %T for method %v", req, methodName)
+
+	case "HealthCheck":
+		return s.TChanServer.(thrift.TChanStructServer).HandleRequest(ctx, methodName, req)
+	default:
+		return false, nil, fmt.Errorf("method %v not found in service %v", methodName, s.Service())
+	}
+}
+
 func (s *tchanAdminServer) handleClearAll(ctx thrift.Context, protocol athrift.TProtocol) (bool, athrift.TStruct, error) {
 	var req AdminClearAllArgs
-	var res AdminClearAllResult
-
 	if err := req.Read(ctx, protocol); err != nil {
 		return false, nil, err
 	}
 
+	return s.handleClearAllRequest(ctx, &req)
+}
+
+func (s *tchanAdminServer) handleClearAllRequest(ctx thrift.Context, req *AdminClearAllArgs) (bool, athrift.TStruct, error) {
+	var res AdminClearAllResult
+
 	err :=
 		s.handler.ClearAll(ctx)
 
@@ -237,14 +268,52 @@ func (s *tchanKeyValueServer) Handle(ctx thrift.Context, methodName string, prot
 	}
 }
 
+func (s *tchanKeyValueServer) NewRequest(methodName string) athrift.TStruct {
+	switch methodName {
+	case "Get":
+		return &KeyValueGetArgs{}
+	case "Set":
+		return &KeyValueSetArgs{}
+
+	case "HealthCheck":
+		return s.TChanServer.(thrift.TChanStructServer).NewRequest(methodName)
+	default:
+		return nil
+	}
+}
+
+func (s *tchanKeyValueServer) HandleRequest(ctx thrift.Context, methodName string, req athrift.TStruct) (bool, athrift.TStruct, error) {
+	switch methodName {
+	case "Get":
+		if args, ok := req.(*KeyValueGetArgs); ok {
+			return s.handleGetRequest(ctx, args)
+		}
+		return false, nil, fmt.Errorf("unexpected request type %T for method %v", req, methodName)
+	case "Set":
+		if args, ok := req.(*KeyValueSetArgs); ok {
+			return s.handleSetRequest(ctx, args)
+		}
+		return false, nil, fmt.Errorf("unexpected request type %T for method %v", req, methodName)
+
+	case "HealthCheck":
+		return s.TChanServer.(thrift.TChanStructServer).HandleRequest(ctx, methodName, req)
+	default:
+		return false, nil, fmt.Errorf("method %v not found in service %v", methodName, s.Service())
+	}
+}
+
 func (s *tchanKeyValueServer) handleGet(ctx thrift.Context, protocol athrift.TProtocol) (bool, athrift.TStruct, error) {
 	var req KeyValueGetArgs
-	var res KeyValueGetResult
-
 	if err := req.Read(ctx, protocol); err != nil {
 		return false, nil, err
 	}
 
+	return s.handleGetRequest(ctx, &req)
+}
+
+func (s *tchanKeyValueServer) handleGetRequest(ctx thrift.Context, req *KeyValueGetArgs) (bool, athrift.TStruct, error) {
+	var res KeyValueGetResult
+
 	r, err :=
 		s.handler.Get(ctx, req.Key)
 
@@ -272,12 +341,16 @@ func (s *tchanKeyValueServer) handleGet(ctx thrift.Context, protocol athrift.TPr
 
 func (s *tchanKeyValueServer) handleSet(ctx thrift.Context, protocol athrift.TProtocol) (bool, athrift.TStruct, error) {
 	var req KeyValueSetArgs
-	var res KeyValueSetResult
-
 	if err := req.Read(ctx, protocol); err != nil {
 		return false, nil, err
 	}
 
+	return s.handleSetRequest(ctx, &req)
+}
+
+func (s *tchanKeyValueServer) handleSetRequest(ctx thrift.Context, req *KeyValueSetArgs) (bool, athrift.TStruct, error) {
+	var res KeyValueSetResult
+
 	err :=
 		s.handler.Set(ctx, req.Key, req.Value)
 
@@ -360,14 +433,41 @@ func (s *tchanBaseServiceServer) Handle(ctx thrift.Context, methodName string, p
 	}
 }
 
+func (s *tchanBaseServiceServer) NewRequest(methodName string) athrift.TStruct {
+	switch methodName {
+	case "HealthCheck":
+		return &BaseServiceHealthCheckArgs{}
+
+	default:
+		return nil
+	}
+}
+
+func (s *tchanBaseServiceServer) HandleRequest(ctx thrift.Context, methodName string, req athrift.TStruct) (bool, athrift.TStruct, error) {
+	switch methodName {
+	case "HealthCheck":
+		if args, ok := req.(*BaseServiceHealthCheckArgs); ok {
+			return s.handleHealthCheckRequest(ctx, args)
+		}
+		return false, nil, fmt.Errorf("unexpected request type %T for method %v", req, methodName)
+
+	default:
+		return false, nil, fmt.Errorf("method %v not found in service %v", methodName, s.Service())
+	}
+}
+
 func (s *tchanBaseServiceServer) handleHealthCheck(ctx thrift.Context, protocol athrift.TProtocol) (bool, athrift.TStruct, error) {
 	var req BaseServiceHealthCheckArgs
-	var res BaseServiceHealthCheckResult
-
 	if err := req.Read(ctx, protocol); err != nil {
 		return false, nil, err
 	}
 
+	return s.handleHealthCheckRequest(ctx, &req)
+}
+
+func (s *tchanBaseServiceServer) handleHealthCheckRequest(ctx thrift.Context, req *BaseServiceHealthCheckArgs) (bool, athrift.TStruct, error) {
+	var res BaseServiceHealthCheckResult
+
 	r, err :=
 		s.handler.HealthCheck(ctx)
 
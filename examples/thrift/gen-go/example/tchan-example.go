@@ -96,14 +96,41 @@ func (s *tchanBaseServer) Handle(ctx thrift.Context, methodName string, protocol
 	}
 }
 
+func (s *tchanBaseServer) NewRequest(methodName string) athrift.TStruct {
+	switch methodName {
+	case "BaseCall":
+		return &BaseBaseCallArgs{}
+
+	default:
+		return nil
+	}
+}
+
+func (s *tchanBaseServer) HandleRequest(ctx thrift.Context, methodName string, req athrift.TStruct) (bool, athrift.TStruct, error) {
+	switch methodName {
+	case "BaseCall":
+		if args, ok := req.(*BaseBaseCallArgs); ok {
+			return s.handleBaseCallRequest(ctx, args)
+		}
+		return false, nil, fmt.Errorf("unexpected request type %T for method %v", req, methodName)
+
+	default:
+		return false, nil, fmt.Errorf("method %v not found in service %v", methodName, s.Service())
+	}
+}
+
 func (s *tchanBaseServer) handleBaseCall(ctx thrift.Context, protocol athrift.TProtocol) (bool, athrift.TStruct, error) {
 	var req BaseBaseCallArgs
-	var res BaseBaseCallResult
-
 	if err := req.Read(ctx, protocol); err != nil {
 		return false, nil, err
 	}
 
+	return s.handleBaseCallRequest(ctx, &req)
+}
+
+func (s *tchanBaseServer) handleBaseCallRequest(ctx thrift.Context, req *BaseBaseCallArgs) (bool, athrift.TStruct, error) {
+	var res BaseBaseCallResult
+
 	err :=
 		s.handler.BaseCall(ctx)
 
@@ -224,14 +251,59 @@ func (s *tchanFirstServer) Handle(ctx thrift.Context, methodName string, protoco
 	}
 }
 
+func (s *tchanFirstServer) NewRequest(methodName string) athrift.TStruct {
+	switch methodName {
+	case "AppError":
+		return &FirstAppErrorArgs{}
+	case "Echo":
+		return &FirstEchoArgs{}
+	case "Healthcheck":
+		return &FirstHealthcheckArgs{}
+
+	case "BaseCall":
+		return s.TChanServer.(thrift.TChanStructServer).NewRequest(methodName)
+	default:
+		return nil
+	}
+}
+
+func (s *tchanFirstServer) HandleRequest(ctx thrift.Context, methodName string, req athrift.TStruct) (bool, athrift.TStruct, error) {
+	switch methodName {
+	case "AppError":
+		if args, ok := req.(*FirstAppErrorArgs); ok {
+			return s.handleAppErrorRequest(ctx, args)
+		}
+		return false, nil, fmt.Errorf("unexpected request type %T for method %v", req, methodName)
+	case "Echo":
+		if args, ok := req.(*FirstEchoArgs); ok {
+			return s.handleEchoRequest(ctx, args)
+		}
+		return false, nil, fmt.Errorf("unexpected request type %T for method %v", req, methodName)
+	case "Healthcheck":
+		if args, ok := req.(*FirstHealthcheckArgs); ok {
+			return s.handleHealthcheckRequest(ctx, args)
+		}
+		return false, nil, fmt.Errorf("unexpected request type %T for method %v", req, methodName)
+
+	case "BaseCall":
+		return s.TChanServer.(thrift.TChanStructServer).HandleRequest(ctx, methodName, req)
+	default:
+		return false, nil, fmt.Errorf("method %v not found in service %v", methodName, s.Service())
+	}
+}
+
 func (s *tchanFirstServer) handleAppError(ctx thrift.Context, protocol athrift.TProtocol) (bool, athrift.TStruct, error) {
 	var req FirstAppErrorArgs
-	var res FirstAppErrorResult
-
 	if err := req.Read(ctx, protocol); err != nil {
 		return false, nil, err
 	}
 
+	return s.handleAppErrorRequest(ctx, &req)
+}
+
+func (s *tchanFirstServer) handleAppErrorRequest(ctx thrift.Context, req *FirstAppErrorArgs) (bool, athrift.TStruct, error) {
+	var res FirstAppErrorResult
+
 	err :=
 		s.handler.AppError(ctx)
 
@@ -245,12 +317,16 @@ func (s *tchanFirstServer) handleAppError(ctx thrift.Context, protocol athrift.T
 
 func (s *tchanFirstServer) handleEcho(ctx thrift.Context, protocol athrift.TProtocol) (bool, athrift.TStruct, error) {
 	var req FirstEchoArgs
-	var res FirstEchoResult
-
 	if err := req.Read(ctx, protocol); err != nil {
 		return false, nil, err
 	}
 
+	return s.handleEchoRequest(ctx, &req)
+}
+
+func (s *tchanFirstServer) handleEchoRequest(ctx thrift.Context, req *FirstEchoArgs) (bool, athrift.TStruct, error) {
+	var res FirstEchoResult
+
 	r, err :=
 		s.handler.Echo(ctx, req.Msg)
 
@@ -265,12 +341,16 @@ func (s *tchanFirstServer) handleEcho(ctx thrift.Context, protocol athrift.TProt
 
 func (s *tchanFirstServer) handleHealthcheck(ctx thrift.Context, protocol athrift.TProtocol) (bool, athrift.TStruct, error) {
 	var req FirstHealthcheckArgs
-	var res FirstHealthcheckResult
-
 	if err := req.Read(ctx, protocol); err != nil {
 		return false, nil, err
 	}
 
+	return s.handleHealthcheckRequest(ctx, &req)
+}
+
+func (s *tchanFirstServer) handleHealthcheckRequest(ctx thrift.Context, req *FirstHealthcheckArgs) (bool, athrift.TStruct, error) {
+	var res FirstHealthcheckResult
+
 	r, err :=
 		s.handler.Healthcheck(ctx)
 
@@ -346,14 +426,41 @@ func (s *tchanSecondServer) Handle(ctx thrift.Context, methodName string, protoc
 	}
 }
 
+func (s *tchanSecondServer) NewRequest(methodName string) athrift.TStruct {
+	switch methodName {
+	case "Test":
+		return &SecondTestArgs{}
+
+	default:
+		return nil
+	}
+}
+
+func (s *tchanSecondServer) HandleRequest(ctx thrift.Context, methodName string, req athrift.TStruct) (bool, athrift.TStruct, error) {
+	switch methodName {
+	case "Test":
+		if args, ok := req.(*SecondTestArgs); ok {
+			return s.handleTestRequest(ctx, args)
+		}
+		return false, nil, fmt.Errorf("unexpected request type %T for method %v", req, methodName)
+
+	default:
+		return false, nil, fmt.Errorf("method %v not found in service %v", methodName, s.Service())
+	}
+}
+
 func (s *tchanSecondServer) handleTest(ctx thrift.Context, protocol athrift.TProtocol) (bool, athrift.TStruct, error) {
 	var req SecondTestArgs
-	var res SecondTestResult
-
 	if err := req.Read(ctx, protocol); err != nil {
 		return false, nil, err
 	}
 
+	return s.handleTestRequest(ctx, &req)
+}
+
+func (s *tchanSecondServer) handleTestRequest(ctx thrift.Context, req *SecondTestArgs) (bool, athrift.TStruct, error) {
+	var res SecondTestResult
+
 	err :=
 		s.handler.Test(ctx)
 
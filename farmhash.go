@@ -0,0 +1,183 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// This file implements the 32-bit FarmHash functions used by the Farmhash
+// checksum type, ported from the farmhashmk functions in the reference
+// implementation (https://github.com/google/farmhash).
+
+// Magic numbers for 32-bit hashing, copied from Murmur3.
+const (
+	farmC1 uint32 = 0xcc9e2d51
+	farmC2 uint32 = 0x1b873593
+)
+
+func farmFetch32(s []byte, i int) uint32 {
+	return binary.LittleEndian.Uint32(s[i : i+4])
+}
+
+func farmRotate32(v uint32, shift int) uint32 {
+	return bits.RotateLeft32(v, -shift)
+}
+
+// farmFmix is a 32-bit to 32-bit integer hash copied from Murmur3.
+func farmFmix(h uint32) uint32 {
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
+
+// farmMur combines two 32-bit values, copied from Murmur3.
+func farmMur(a, h uint32) uint32 {
+	a *= farmC1
+	a = farmRotate32(a, 17)
+	a *= farmC2
+	h ^= a
+	h = farmRotate32(h, 19)
+	return h*5 + 0xe6546b64
+}
+
+func farmhash32Len0to4(s []byte, seed uint32) uint32 {
+	b := seed
+	c := uint32(9)
+	for _, v := range s {
+		b = b*farmC1 + uint32(int8(v))
+		c ^= b
+	}
+	return farmFmix(farmMur(b, farmMur(uint32(len(s)), c)))
+}
+
+func farmhash32Len5to12(s []byte, seed uint32) uint32 {
+	n := len(s)
+	a := uint32(n)
+	b := uint32(n) * 5
+	c := uint32(9)
+	d := b + seed
+	a += farmFetch32(s, 0)
+	b += farmFetch32(s, n-4)
+	c += farmFetch32(s, (n>>1)&4)
+	return farmFmix(seed ^ farmMur(c, farmMur(b, farmMur(a, d))))
+}
+
+func farmhash32Len13to24(s []byte, seed uint32) uint32 {
+	n := len(s)
+	a := farmFetch32(s, (n>>1)-4)
+	b := farmFetch32(s, 4)
+	c := farmFetch32(s, n-8)
+	d := farmFetch32(s, n>>1)
+	e := farmFetch32(s, 0)
+	f := farmFetch32(s, n-4)
+	h := d*farmC1 + uint32(n) + seed
+	a = farmRotate32(a, 12) + f
+	h = farmMur(c, h) + a
+	a = farmRotate32(a, 3) + c
+	h = farmMur(e, h) + a
+	a = farmRotate32(a+f, 12) + d
+	h = farmMur(b^seed, h) + a
+	return farmFmix(h)
+}
+
+// farmhash32 returns the 32-bit FarmHash of s, which is the same as the
+// FarmHash Fingerprint32 of s.
+func farmhash32(s []byte) uint32 {
+	n := len(s)
+	switch {
+	case n <= 4:
+		return farmhash32Len0to4(s, 0)
+	case n <= 12:
+		return farmhash32Len5to12(s, 0)
+	case n <= 24:
+		return farmhash32Len13to24(s, 0)
+	}
+
+	h := uint32(n)
+	g := farmC1 * uint32(n)
+	f := g
+	a0 := farmRotate32(farmFetch32(s, n-4)*farmC1, 17) * farmC2
+	a1 := farmRotate32(farmFetch32(s, n-8)*farmC1, 17) * farmC2
+	a2 := farmRotate32(farmFetch32(s, n-16)*farmC1, 17) * farmC2
+	a3 := farmRotate32(farmFetch32(s, n-12)*farmC1, 17) * farmC2
+	a4 := farmRotate32(farmFetch32(s, n-20)*farmC1, 17) * farmC2
+	h ^= a0
+	h = farmRotate32(h, 19)
+	h = h*5 + 0xe6546b64
+	h ^= a2
+	h = farmRotate32(h, 19)
+	h = h*5 + 0xe6546b64
+	g ^= a1
+	g = farmRotate32(g, 19)
+	g = g*5 + 0xe6546b64
+	g ^= a3
+	g = farmRotate32(g, 19)
+	g = g*5 + 0xe6546b64
+	f += a4
+	f = farmRotate32(f, 19) + 113
+	for ; len(s) > 20; s = s[20:] {
+		a := farmFetch32(s, 0)
+		b := farmFetch32(s, 4)
+		c := farmFetch32(s, 8)
+		d := farmFetch32(s, 12)
+		e := farmFetch32(s, 16)
+		h += a
+		g += b
+		f += c
+		h = farmMur(d, h) + e
+		g = farmMur(c, g) + a
+		f = farmMur(b+e*farmC1, f) + d
+		f += g
+		g += f
+	}
+	g = farmRotate32(g, 11) * farmC1
+	g = farmRotate32(g, 17) * farmC1
+	f = farmRotate32(f, 11) * farmC1
+	f = farmRotate32(f, 17) * farmC1
+	h = farmRotate32(h+g, 19)
+	h = h*5 + 0xe6546b64
+	h = farmRotate32(h, 17) * farmC1
+	h = farmRotate32(h+f, 19)
+	h = h*5 + 0xe6546b64
+	h = farmRotate32(h, 17) * farmC1
+	return h
+}
+
+// farmhash32WithSeed returns the 32-bit FarmHash of s using the given seed.
+func farmhash32WithSeed(s []byte, seed uint32) uint32 {
+	n := len(s)
+	switch {
+	case n <= 4:
+		return farmhash32Len0to4(s, seed)
+	case n <= 12:
+		return farmhash32Len5to12(s, seed)
+	case n <= 24:
+		return farmhash32Len13to24(s, seed*farmC1)
+	}
+
+	h := farmhash32Len13to24(s[:24], seed^uint32(n))
+	return farmMur(farmhash32(s[24:])+seed, h)
+}
@@ -0,0 +1,256 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/temporalio/tchannel-go/typed"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// farmhashTestVectors are the 32-bit hashes of test inputs produced by other
+// FarmHash implementations, with Hash32WithSeed using a seed of 32.
+var farmhashTestVectors = []struct {
+	in         string
+	hash       uint32
+	seededHash uint32
+}{
+	{"", 0xdc56d17a, 0x0108292b},
+	{"a", 0x3c973d4d, 0x7e4cfeed},
+	{"abcd", 0x98b51e95, 0xdfb26aae},
+	{"abcde", 0xa3f366ac, 0xd29c0f4d},
+	{"abcdefghi", 0x6f98dc86, 0xa7440120},
+	{"0123456789-0", 0xf8cc7928, 0x00be5c31},
+	{"0123456789~01", 0x0d92cafb, 0xe6588500},
+	{"0123456789&012345678", 0x3884aa05, 0xda41f50b},
+	{"0123456789%0123456789£", 0x1723dd7a, 0xf3485759},
+	{"size:  a.out:  bad magic", 0xc6246b8d, 0x9f99edf0},
+	{"Nepal premier won't resign.", 0x322984d9, 0xb630933e},
+	{"C is as portable as Stonehedge!!", 0x221694e4, 0xb959719f},
+	{"The days of the digital watch are numbered.  -Tom Stoppard", 0x2cc30bb7, 0xa3672fa1},
+	{"Give me a rock, paper and scissors and I will move the world.  CCFestoon", 0x1b8db5d0, 0x0159176d},
+	{"The fugacity of a constituent in a mixture of gases at a given temperature is proportional to its mole fraction.  Lewis-Randall Rule", 0x11c493bb, 0xa36704a5},
+	{"Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor incididunt ut labore et dolore magna aliqua. Ut enim ad minim veniam, quis nostrud exercitation ullamco laboris nisi ut aliquip ex ea commodo consequat. Duis aute irure dolor in reprehenderit in voluptate velit esse cillum dolore eu fugiat nulla pariatur. Excepteur sint occaecat cupidatat non proident, sunt in culpa qui officia deserunt mollit anim id est laborum.", 0xd402abf8, 0x656d5959},
+}
+
+func TestFarmhash32(t *testing.T) {
+	for _, tt := range farmhashTestVectors {
+		assert.Equal(t, tt.hash, farmhash32([]byte(tt.in)), "farmhash32(%q)", tt.in)
+		assert.Equal(t, tt.seededHash, farmhash32WithSeed([]byte(tt.in), 32), "farmhash32WithSeed(%q, 32)", tt.in)
+	}
+}
+
+func TestFarmhashChecksum(t *testing.T) {
+	cs := ChecksumTypeFarmhash.New()
+	defer cs.Release()
+
+	assert.Equal(t, ChecksumTypeFarmhash, cs.TypeCode(), "Unexpected checksum type")
+	assert.Equal(t, ChecksumTypeFarmhash.ChecksumSize(), cs.Size(), "Unexpected checksum size")
+
+	sum := func() uint32 {
+		return binary.BigEndian.Uint32(cs.Sum())
+	}
+
+	// The first fragment's checksum is the fingerprint of its args.
+	cs.Add([]byte("0123456789"))
+	cs.Add(nil)
+	cs.Add([]byte("-0"))
+	first := sum()
+	assert.Equal(t, uint32(0xf8cc7928), first, "Unexpected checksum for first fragment")
+	assert.Equal(t, first, sum(), "Sum should not change the checksum")
+
+	// A fragment without any args has the same checksum as the previous fragment.
+	cs.Add(nil)
+	assert.Equal(t, first, sum(), "Empty fragment should not change the checksum")
+
+	// Subsequent fragments are seeded by the previous fragment's checksum.
+	cs.Add([]byte("abcd"))
+	second := sum()
+	assert.Equal(t, farmhash32WithSeed([]byte("abcd"), first), second, "Unexpected checksum for second fragment")
+
+	cs.Add([]byte("Nepal premier won't resign."))
+	assert.Equal(t, farmhash32WithSeed([]byte("Nepal premier won't resign."), second), sum(), "Unexpected checksum for third fragment")
+
+	cs.Reset()
+	assert.Equal(t, uint32(0xdc56d17a), sum(), "Reset checksum should match the fingerprint of no data")
+}
+
+// The frame vectors are a Thrift call to "svc::method" with an empty arg2, where
+// arg3 is split across a callReq and a callReqContinue frame. The checksums were
+// computed using the FarmHash implementation in github.com/dgryski/go-farm
+// (Fingerprint32 for the callReq, and Hash32WithSeed seeded with the previous
+// checksum for the callReqContinue), rather than the functions in this package.
+var (
+	// ttl:4 tracing:25 service~1 nh:1 (hk~1 hv~1){nh}
+	farmhashCallReqPreamble = bytes.Join([][]byte{
+		{0x00, 0x00, 0x03, 0xe8},
+		make([]byte, 25),
+		{0x03}, []byte("svc"),
+		{0x01},
+		{0x02}, []byte("as"), {0x06}, []byte("thrift"),
+	}, nil)
+
+	// flags:1 preamble csumtype:1 csum:4 arg1~2 arg2~2 arg3~2
+	farmhashCallReqVector = bytes.Join([][]byte{
+		{0x01},
+		farmhashCallReqPreamble,
+		{0x02}, {0xe1, 0x59, 0x49, 0x21},
+		{0x00, 0x06}, []byte("method"),
+		{0x00, 0x02}, {0x00, 0x00},
+		{0x00, 0x05}, []byte("arg3-"),
+	}, nil)
+
+	// flags:1 csumtype:1 csum:4 arg3~2
+	farmhashCallReqContinueVector = bytes.Join([][]byte{
+		{0x00},
+		{0x02}, {0xf3, 0x82, 0xab, 0xde},
+		{0x00, 0x07}, []byte("payload"),
+	}, nil)
+)
+
+// farmhashVectorSender writes fragments that start with the callReq preamble
+// used by the frame vectors.
+type farmhashVectorSender struct {
+	fragments [][]byte
+}
+
+func (s *farmhashVectorSender) newFragment(initial bool, checksum Checksum) (*writableFragment, error) {
+	wbuf := typed.NewWriteBuffer(make([]byte, MaxFramePayloadSize))
+	fragment := &writableFragment{
+		flagsRef: wbuf.DeferByte(),
+		checksum: checksum,
+		contents: wbuf,
+	}
+	if initial {
+		wbuf.WriteBytes(farmhashCallReqPreamble)
+	}
+	wbuf.WriteSingleByte(byte(checksum.TypeCode()))
+	fragment.checksumRef = wbuf.DeferBytes(checksum.Size())
+	return fragment, wbuf.Err()
+}
+
+func (s *farmhashVectorSender) flushFragment(fragment *writableFragment) error {
+	var buf bytes.Buffer
+	fragment.contents.FlushTo(&buf)
+	s.fragments = append(s.fragments, buf.Bytes())
+	return nil
+}
+
+func (s *farmhashVectorSender) doneSending() {}
+
+func TestFarmhashFrameVectors(t *testing.T) {
+	t.Run("write", func(t *testing.T) {
+		sender := &farmhashVectorSender{}
+		w := newFragmentingWriter(NullLogger, sender, ChecksumTypeFarmhash.New())
+		require.NoError(t, NewArgWriter(w.ArgWriter(false /* last */)).Write([]byte("method")), "arg1 write failed")
+		require.NoError(t, NewArgWriter(w.ArgWriter(false /* last */)).Write([]byte{0x00, 0x00}), "arg2 write failed")
+
+		arg3, err := w.ArgWriter(true /* last */)
+		require.NoError(t, err, "arg3 writer failed")
+		_, err = arg3.Write([]byte("arg3-"))
+		require.NoError(t, err, "arg3 write failed")
+		require.NoError(t, arg3.Flush(), "arg3 flush failed")
+		_, err = arg3.Write([]byte("payload"))
+		require.NoError(t, err, "arg3 write failed")
+		require.NoError(t, arg3.Close(), "arg3 close failed")
+
+		assert.Equal(t, [][]byte{farmhashCallReqVector, farmhashCallReqContinueVector}, sender.fragments,
+			"Unexpected frames")
+	})
+
+	t.Run("read", func(t *testing.T) {
+		recvCh := make(fragmentChannel, 2)
+		// The fragment reader only reads the flags and the fields from csumtype onward.
+		recvCh <- append([]byte{farmhashCallReqVector[0]}, farmhashCallReqVector[1+len(farmhashCallReqPreamble):]...)
+		recvCh <- farmhashCallReqContinueVector
+
+		r := newFragmentingReader(NullLogger, recvCh)
+		var arg1, arg2, arg3 []byte
+		require.NoError(t, NewArgReader(r.ArgReader(false /* last */)).Read(&arg1), "arg1 read failed")
+		require.NoError(t, NewArgReader(r.ArgReader(false /* last */)).Read(&arg2), "arg2 read failed")
+		require.NoError(t, NewArgReader(r.ArgReader(true /* last */)).Read(&arg3), "arg3 read failed")
+		assert.Equal(t, "method", string(arg1), "Unexpected arg1")
+		assert.Equal(t, []byte{0x00, 0x00}, arg2, "Unexpected arg2")
+		assert.Equal(t, "arg3-payload", string(arg3), "Unexpected arg3")
+	})
+}
+
+// capturingFrameReceiver records the payload of each frame that it receives.
+type capturingFrameReceiver struct {
+	payloads [][]byte
+}
+
+func (r *capturingFrameReceiver) Receive(f *Frame, fType frameType) (sent bool, failureReason string) {
+	r.payloads = append(r.payloads, append([]byte(nil), f.SizedPayload()...))
+	return true, ""
+}
+
+func TestFarmhashRelayMutatedChecksumVectors(t *testing.T) {
+	newFrame := func(messageType messageType, payload []byte) *Frame {
+		f := NewFrame(MaxFramePayloadSize)
+		f.Header.messageType = messageType
+		f.Header.SetPayloadSize(uint16(copy(f.Payload, payload)))
+		return f
+	}
+
+	cr, err := newLazyCallReq(newFrame(messageTypeCallReq, farmhashCallReqVector))
+	require.NoError(t, err, "newLazyCallReq failed")
+	cr.Arg2Append([]byte("key"), []byte("val"))
+
+	// Write the mutated callReq the same way as Relayer.fragmentingSend.
+	receiver := &capturingFrameReceiver{}
+	checksum := cr.checksumType.New()
+	w := newFragmentingWriter(NullLogger, &relayFragmentSender{
+		callReq:       cr,
+		framePool:     DefaultFramePool,
+		frameReceiver: receiver,
+		sentReporter:  &noopSentReporter{},
+	}, checksum)
+	arg2Writer, err := w.ArgWriter(false /* last */)
+	require.NoError(t, err, "arg2 writer failed")
+	require.NoError(t, writeArg2WithAppends(arg2Writer, cr.arg2(), cr.arg2Appends), "arg2 write failed")
+	require.NoError(t, arg2Writer.Close(), "arg2 close failed")
+	require.NoError(t, NewArgWriter(w.ArgWriter(true /* last */)).Write(cr.arg3()), "arg3 write failed")
+
+	continueFrame := newFrame(messageTypeCallReqContinue, farmhashCallReqContinueVector)
+	(&Relayer{}).updateMutatedCallReqContinueChecksum(continueFrame, checksum)
+
+	wantCallReq := bytes.Join([][]byte{
+		{0x01},
+		farmhashCallReqPreamble,
+		{0x02}, {0x75, 0xd3, 0x1d, 0x00},
+		{0x00, 0x06}, []byte("method"),
+		{0x00, 0x0c}, {0x00, 0x01}, {0x00, 0x03}, []byte("key"), {0x00, 0x03}, []byte("val"),
+		{0x00, 0x05}, []byte("arg3-"),
+	}, nil)
+	wantCallReqContinue := bytes.Join([][]byte{
+		{0x00},
+		{0x02}, {0x5d, 0x3b, 0x5b, 0x26},
+		{0x00, 0x07}, []byte("payload"),
+	}, nil)
+	assert.Equal(t, [][]byte{wantCallReq}, receiver.payloads, "Unexpected mutated callReq")
+	assert.Equal(t, wantCallReqContinue, continueFrame.SizedPayload(), "Unexpected mutated callReqContinue")
+}
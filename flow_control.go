@@ -0,0 +1,220 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// maxFlowControlWindow is the largest flow control window a channel can use.
+// Each message exchange buffers up to a window of fragments.
+const maxFlowControlWindow = 512
+
+// flowControl is the credit-based flow control state for a message exchange.
+// Each side of the exchange starts with credits for the window advertised by
+// the remote peer, uses a credit for every fragment it sends, and grants the
+// remote peer credits as the fragments it received are consumed.
+type flowControl struct {
+	conn *Connection
+
+	// sendCredits holds a token for each fragment that can be sent.
+	sendCredits chan struct{}
+
+	// recvWindow is the number of fragments the remote peer can send before
+	// waiting for credits, and so the number of fragments that are buffered.
+	recvWindow int
+
+	// grantThreshold is the number of consumed fragments that are batched
+	// into a single credit message.
+	grantThreshold int
+
+	// pendingGrants is the number of consumed fragments that have not been
+	// granted yet. It's only accessed by the reader of the exchange.
+	pendingGrants int
+
+	// grantsForResponse is whether the exchange receives response fragments,
+	// and so grants credits for callRes fragments.
+	grantsForResponse bool
+}
+
+func validateFlowControlWindow(window int) error {
+	if window < 0 || window > maxFlowControlWindow {
+		return fmt.Errorf("invalid flow control window %v, must be between 0 and %v", window, maxFlowControlWindow)
+	}
+	return nil
+}
+
+// parseFlowControlWindow parses the flow control init param sent by a peer.
+// Invalid or missing values disable flow control.
+func parseFlowControlWindow(v string) int {
+	window, err := strconv.Atoi(v)
+	if err != nil || window <= 0 {
+		return 0
+	}
+	if window > maxFlowControlWindow {
+		return maxFlowControlWindow
+	}
+	return window
+}
+
+// sendWindow returns the number of fragments that can be sent to the remote
+// peer for a message exchange before waiting for credits. It returns 0 if
+// flow control was not negotiated on the connection.
+func (c *Connection) sendWindow() int {
+	if c.opts.FlowControlWindow <= 0 {
+		return 0
+	}
+	return c.remotePeerInfo.FlowControlWindow
+}
+
+// newFlowControl returns the flow control state for a new call exchange, or
+// nil if flow control was not negotiated on the connection.
+func (c *Connection) newFlowControl(recvResponses bool) *flowControl {
+	window := c.sendWindow()
+	if window == 0 {
+		return nil
+	}
+
+	credits := make(chan struct{}, window)
+	for i := 0; i < window; i++ {
+		credits <- struct{}{}
+	}
+
+	// When calls are relayed, the sender starts with credits for the relay's
+	// window rather than ours, so buffer and grant for either window.
+	recvWindow, threshold := c.opts.FlowControlWindow, window
+	if window > recvWindow {
+		recvWindow, threshold = window, recvWindow
+	}
+	threshold /= 2
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	return &flowControl{
+		conn:              c,
+		sendCredits:       credits,
+		recvWindow:        recvWindow,
+		grantThreshold:    threshold,
+		grantsForResponse: recvResponses,
+	}
+}
+
+// handleCredit adds the credits granted by the remote peer to the exchange.
+func (c *Connection) handleCredit(frame *Frame) {
+	msg := &creditMessage{id: frame.Header.ID}
+	if err := frame.read(msg); err != nil {
+		c.log.WithFields(
+			LogField{"header", frame.Header},
+			ErrField(err),
+		).Warn("Unable to read credit frame.")
+		return
+	}
+
+	// Credits for response fragments are granted by the caller, so they are
+	// for an inbound call.
+	mexset := c.outbound
+	if msg.response {
+		mexset = c.inbound
+	}
+
+	if !mexset.addSendCredits(msg.id, msg.credits) {
+		c.log.Debugf("Received credits for unknown %s exchange %v", mexset.name, msg.id)
+	}
+}
+
+// addSendCredits adds credits to the exchange with the given ID, and returns
+// whether the exchange was found.
+func (mexset *messageExchangeSet) addSendCredits(msgID uint32, credits uint32) bool {
+	mexset.RLock()
+	mex := mexset.exchanges[msgID]
+	mexset.RUnlock()
+
+	if mex == nil || mex.flow == nil {
+		return false
+	}
+
+	for i := uint32(0); i < credits; i++ {
+		select {
+		case mex.flow.sendCredits <- struct{}{}:
+		default:
+			// Credits beyond the window are dropped, the sender can never
+			// have more than a window of fragments in flight.
+			return true
+		}
+	}
+	return true
+}
+
+// acquireSendCredit blocks until there's a credit to send a fragment, or the
+// exchange fails.
+func (mex *messageExchange) acquireSendCredit() error {
+	if mex.flow == nil {
+		return nil
+	}
+
+	select {
+	case <-mex.flow.sendCredits:
+		return nil
+	case <-mex.ctx.Done():
+		return GetContextError(mex.ctx.Err())
+	case <-mex.errCh.c:
+		return mex.errCh.err
+	}
+}
+
+// fragmentConsumed is called by the reader of the exchange for every fragment
+// it consumes, and grants credits to the remote peer once enough fragments
+// have been consumed.
+func (mex *messageExchange) fragmentConsumed(moreFragments bool) {
+	f := mex.flow
+	if f == nil || !moreFragments {
+		// The remote peer does not need credits after the last fragment.
+		return
+	}
+
+	f.pendingGrants++
+	if f.pendingGrants < f.grantThreshold {
+		return
+	}
+
+	msg := &creditMessage{
+		id:       mex.msgID,
+		credits:  uint32(f.pendingGrants),
+		response: f.grantsForResponse,
+	}
+	f.pendingGrants = 0
+
+	frame := mex.framePool.Get()
+	if err := frame.write(msg); err != nil {
+		mex.framePool.Release(frame)
+		return
+	}
+
+	select {
+	case f.conn.sendChFor(mex.priority) <- frame:
+	case <-mex.ctx.Done():
+		mex.framePool.Release(frame)
+	case <-mex.errCh.c:
+		mex.framePool.Release(frame)
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/temporalio/tchannel-go"
+	"github.com/temporalio/tchannel-go/raw"
+	"github.com/temporalio/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+	"golang.org/x/net/context"
+)
+
+func TestFlowControlNegotiation(t *testing.T) {
+	tests := []struct {
+		msg          string
+		client       int
+		server       int
+		wantInbound  int
+		wantOutbound int
+	}{
+		{
+			msg:          "both peers enable flow control",
+			client:       16,
+			server:       32,
+			wantInbound:  16,
+			wantOutbound: 32,
+		},
+		{
+			msg:         "only client enables flow control",
+			client:      16,
+			wantInbound: 16,
+		},
+		{
+			msg:          "only server enables flow control",
+			server:       32,
+			wantOutbound: 32,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			server := testutils.NewServer(t, testutils.NewOpts().SetFlowControlWindow(tt.server))
+			defer server.Close()
+
+			var inbound int
+			testutils.RegisterFunc(server, "window", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+				inbound = tchannel.CurrentCall(ctx).RemotePeer().FlowControlWindow
+				return &raw.Res{}, nil
+			})
+
+			client := testutils.NewClient(t, testutils.NewOpts().SetFlowControlWindow(tt.client))
+			defer client.Close()
+
+			ctx, cancel := tchannel.NewContext(time.Second)
+			defer cancel()
+
+			_, _, _, err := raw.Call(ctx, client, server.PeerInfo().HostPort, server.ServiceName(), "window", nil, nil)
+			require.NoError(t, err, "Call failed")
+			assert.Equal(t, tt.wantInbound, inbound, "Unexpected window for inbound connection")
+
+			peer, ok := client.RootPeers().Get(server.PeerInfo().HostPort)
+			require.True(t, ok, "Missing peer for server")
+			state := peer.IntrospectState(&tchannel.IntrospectionOptions{})
+			require.Len(t, state.OutboundConnections, 1, "Expected a single outbound connection")
+			assert.Equal(t, tt.wantOutbound, state.OutboundConnections[0].RemotePeer.FlowControlWindow, "Unexpected window for outbound connection")
+		})
+	}
+}
+
+func TestFlowControlInvalidWindow(t *testing.T) {
+	for _, window := range []int{-1, 513} {
+		_, err := tchannel.NewChannel("svc", &tchannel.ChannelOptions{
+			DefaultConnectionOptions: tchannel.ConnectionOptions{FlowControlWindow: window},
+		})
+		assert.Error(t, err, "Expected window %v to be rejected", window)
+	}
+}
+
+func TestFlowControlLargeCall(t *testing.T) {
+	opts := testutils.NewOpts().SetFlowControlWindow(4)
+	testutils.WithTestServer(t, opts, func(t testing.TB, ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+
+		client := ts.NewClient(opts)
+		ctx, cancel := tchannel.NewContext(testutils.Timeout(5 * time.Second))
+		defer cancel()
+
+		arg3 := testutils.RandBytes(8 * 1024 * 1024)
+		_, got, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, arg3)
+		require.NoError(t, err, "Call failed")
+		assert.True(t, bytes.Equal(arg3, got), "Unexpected arg3")
+	})
+}
+
+// registerBlockedStream registers a streaming handler that waits for unblock
+// to be closed before counting the messages it receives.
+func registerBlockedStream(t testing.TB, ch *tchannel.Channel, unblock <-chan struct{}, received *atomic.Int32) {
+	ch.Register(tchannel.HandlerFunc(func(ctx context.Context, call *tchannel.InboundCall) {
+		stream, err := call.AcceptStream(nil)
+		if !assert.NoError(t, err, "AcceptStream failed") {
+			return
+		}
+
+		select {
+		case <-unblock:
+		case <-ctx.Done():
+			return
+		}
+
+		for {
+			_, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if !assert.NoError(t, err, "Recv failed") {
+				return
+			}
+			received.Inc()
+		}
+		assert.NoError(t, stream.CloseSend(), "CloseSend failed")
+	}), "blocked")
+}
+
+func TestFlowControlSlowReader(t *testing.T) {
+	const numMessages = 100
+
+	opts := testutils.NewOpts().SetFlowControlWindow(8)
+	testutils.WithTestServer(t, opts, func(t testing.TB, ts *testutils.TestServer) {
+		unblock := make(chan struct{})
+		var received atomic.Int32
+		registerBlockedStream(t, ts.Server(), unblock, &received)
+		testutils.RegisterEcho(ts.Server(), nil)
+
+		client := ts.NewClient(opts)
+		ctx, cancel := tchannel.NewContext(testutils.Timeout(5 * time.Second))
+		defer cancel()
+
+		stream, err := client.BeginStreamingCall(ctx, ts.HostPort(), ts.ServiceName(), "blocked", nil, nil)
+		require.NoError(t, err, "BeginStreamingCall failed")
+
+		var sent atomic.Int32
+		sendDone := make(chan struct{})
+		go func() {
+			defer close(sendDone)
+			for i := 0; i < numMessages; i++ {
+				if !assert.NoError(t, stream.Send([]byte("msg")), "Send %v failed", i) {
+					return
+				}
+				sent.Inc()
+			}
+			assert.NoError(t, stream.CloseSend(), "CloseSend failed")
+		}()
+
+		// The sender runs out of credits while the handler is blocked, but
+		// other calls on the same connection are not affected.
+		for i := 0; i < 5; i++ {
+			testutils.AssertEcho(t, client, ts.HostPort(), ts.ServiceName())
+		}
+		assert.Less(t, sent.Load(), int32(numMessages), "Sends should block on the slow reader")
+
+		close(unblock)
+		<-sendDone
+
+		_, err = stream.Recv()
+		assert.Equal(t, io.EOF, err, "Expected EOF after the server closed")
+		assert.Equal(t, int32(numMessages), received.Load(), "Unexpected number of messages received")
+	})
+}
+
+func TestFlowControlSendTimeout(t *testing.T) {
+	opts := testutils.NewOpts().SetFlowControlWindow(2)
+	testutils.WithTestServer(t, opts, func(t testing.TB, ts *testutils.TestServer) {
+		// The handler is never unblocked, so it returns when the call times out.
+		unblock := make(chan struct{})
+		var received atomic.Int32
+		registerBlockedStream(t, ts.Server(), unblock, &received)
+
+		client := ts.NewClient(opts)
+		ctx, cancel := tchannel.NewContext(testutils.Timeout(100 * time.Millisecond))
+		defer cancel()
+
+		stream, err := client.BeginStreamingCall(ctx, ts.HostPort(), ts.ServiceName(), "blocked", nil, nil)
+		require.NoError(t, err, "BeginStreamingCall failed")
+
+		for {
+			if err = stream.Send([]byte("msg")); err != nil {
+				break
+			}
+		}
+		assert.Equal(t, tchannel.ErrTimeout, err, "Send should block until the call times out")
+	})
+}
+
+func TestFlowControlRelayMixedPeers(t *testing.T) {
+	tests := []struct {
+		msg    string
+		client int
+		relay  int
+		server int
+	}{
+		{
+			msg:    "server does not use flow control",
+			client: 4,
+			relay:  4,
+		},
+		{
+			msg:    "client does not use flow control",
+			relay:  4,
+			server: 4,
+		},
+		{
+			msg:    "different windows",
+			client: 2,
+			relay:  16,
+			server: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			relayOpts := testutils.NewOpts().
+				SetFlowControlWindow(tt.relay).
+				SetDisableServer().
+				SetRelayOnly()
+			testutils.WithTestServer(t, relayOpts, func(t testing.TB, ts *testutils.TestServer) {
+				server := ts.NewServer(testutils.NewOpts().SetFlowControlWindow(tt.server))
+				testutils.RegisterEcho(server, nil)
+
+				client := ts.NewClient(testutils.NewOpts().SetFlowControlWindow(tt.client))
+				ctx, cancel := tchannel.NewContext(testutils.Timeout(5 * time.Second))
+				defer cancel()
+
+				arg3 := testutils.RandBytes(4 * 1024 * 1024)
+				_, got, _, err := raw.Call(ctx, client, ts.HostPort(), server.ServiceName(), "echo", nil, arg3)
+				require.NoError(t, err, "Call failed")
+				assert.True(t, bytes.Equal(arg3, got), "Unexpected arg3")
+			})
+		})
+	}
+}
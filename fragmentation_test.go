@@ -22,8 +22,10 @@ package tchannel
 
 import (
 	"bytes"
+	"encoding/binary"
 	"io"
 	"io/ioutil"
+	"strings"
 	"sync"
 	"testing"
 
@@ -296,12 +298,138 @@ func TestFragmentationChecksumMismatch(t *testing.T) {
 	second[2], second[3], second[4], second[5] = 0x01, 0x02, 0x03, 0x04
 	recvCh <- second
 
+	var reported *ChecksumError
+	r.onChecksumError = func(err *ChecksumError) { reported = err }
+
 	// Attempt to read, should fail due to mismatch between local checksum and peer supplied checksum
 	reader, err := r.ArgReader(true /* last */)
 	assert.NoError(t, err)
 
 	_, err = io.Copy(ioutil.Discard, reader)
-	assert.Equal(t, errMismatchedChecksums, err)
+	require.IsType(t, &ChecksumError{}, err)
+	assert.Equal(t, reported, err, "Checksum error should be reported")
+	assert.Equal(t, ErrCodeBadRequest, GetSystemErrorCode(err), "Unexpected error code")
+
+	checksumErr := err.(*ChecksumError)
+	assert.Equal(t, ChecksumTypeCrc32, checksumErr.ChecksumType, "Unexpected checksum type")
+	assert.Equal(t, []byte{0x01, 0x02, 0x03, 0x04}, checksumErr.Expected, "Expected should be the peer's checksum")
+	assert.NotEqual(t, checksumErr.Expected, checksumErr.Actual, "Actual should be the local checksum")
+}
+
+func TestFragmentationCompressedArgs(t *testing.T) {
+	sendCh := make(fragmentChannel, 10)
+	recvCh := make(fragmentChannel, 10)
+
+	var uncompressed, compressed []int
+	w := newFragmentingWriter(NullLogger, sendCh, ChecksumTypeCrc32.New())
+	w.compression = argCompression{
+		compression: CompressionDeflate,
+		onWritten: func(u, c int) {
+			uncompressed = append(uncompressed, u)
+			compressed = append(compressed, c)
+		},
+	}
+	r := newFragmentingReader(NullLogger, recvCh)
+	r.compression = CompressionDeflate
+
+	args := []string{"method", strings.Repeat("compressible ", 100), ""}
+
+	var fragments [][]byte
+	var actualArgs []string
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for fragment := range sendCh {
+			fragments = append(fragments, fragment)
+			recvCh <- fragment
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := range args {
+			var arg []byte
+			require.NoError(t, NewArgReader(r.ArgReader(i == len(args)-1)).Read(&arg))
+			actualArgs = append(actualArgs, string(arg))
+		}
+	}()
+
+	for i, arg := range args {
+		require.NoError(t, NewArgWriter(w.ArgWriter(i == len(args)-1)).Write([]byte(arg)))
+	}
+	close(sendCh)
+	wg.Wait()
+
+	assert.Equal(t, args, actualArgs)
+	assert.Equal(t, []byte{0x00, 0x06, 'm', 'e', 't', 'h', 'o', 'd'}, fragments[0][testFragmentHeaderSize:], "arg1 should not be compressed")
+	assert.Equal(t, []int{len(args[1]), 0}, uncompressed, "unexpected uncompressed sizes")
+	require.Len(t, compressed, 2, "missing compressed sizes")
+	assert.True(t, compressed[0] < len(args[1])/10, "arg2 compressed to %v bytes", compressed[0])
+}
+
+func TestDecompressingArgReaderCorruptData(t *testing.T) {
+	tests := []struct {
+		msg  string
+		read bool
+	}{
+		{msg: "read then close", read: true},
+		{msg: "close without read", read: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			// 0xff starts a DEFLATE block with the reserved block type.
+			arg := ioutil.NopCloser(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff}))
+			reader, err := newDecompressingArgReader(arg, CompressionDeflate)
+			require.NoError(t, err, "newDecompressingArgReader failed")
+			r := reader.(*decompressingArgReader)
+
+			var readErr error
+			if tt.read {
+				_, readErr = r.Read(make([]byte, 10))
+				require.Error(t, readErr, "Read of corrupt data should fail")
+				assert.Nil(t, r.fr, "decompressor should be released after a failed Read")
+			}
+
+			closeErr := r.Close()
+			require.Error(t, closeErr, "Close of corrupt data should fail")
+			assert.Nil(t, r.fr, "decompressor should be released after a failed Close")
+			if tt.read {
+				assert.Equal(t, readErr, closeErr, "Close should return the Read error")
+			}
+		})
+	}
+}
+
+func TestFragmentationFarmhashChecksum(t *testing.T) {
+	sendCh := make(fragmentChannel, 10)
+	recvCh := make(fragmentChannel, 10)
+	w := newFragmentingWriter(NullLogger, sendCh, ChecksumTypeFarmhash.New())
+	r := newFragmentingReader(NullLogger, recvCh)
+
+	// Write two fragments out
+	writer, err := w.ArgWriter(true /* last */)
+	assert.NoError(t, err)
+	assert.NoError(t, NewArgWriter(writer, nil).Write([]byte("hello world this is two")))
+
+	first := <-sendCh
+	second := <-sendCh
+	firstChecksum := farmhash32([]byte("hello wo"))
+	assert.Equal(t, firstChecksum, binary.BigEndian.Uint32(first[2:6]), "Unexpected checksum for first fragment")
+	assert.Equal(t, farmhash32WithSeed([]byte("rld this"), firstChecksum), binary.BigEndian.Uint32(second[2:6]),
+		"Unexpected checksum for second fragment")
+
+	// Corrupt the args in the second fragment, so the checksums don't match.
+	second[len(second)-1] = 'S'
+	recvCh <- first
+	recvCh <- second
+
+	reader, err := r.ArgReader(true /* last */)
+	assert.NoError(t, err)
+
+	_, err = io.Copy(ioutil.Discard, reader)
+	require.IsType(t, &ChecksumError{}, err)
+	assert.Equal(t, ChecksumTypeFarmhash, err.(*ChecksumError).ChecksumType, "Unexpected checksum type")
 }
 
 func runFragmentationErrorTest(f func(w *fragmentingWriter, r *fragmentingReader)) {
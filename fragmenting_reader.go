@@ -30,7 +30,6 @@ import (
 
 var (
 	errMismatchedChecksumTypes  = errors.New("peer returned different checksum types between fragments")
-	errMismatchedChecksums      = errors.New("different checksums between peer and local")
 	errChunkExceedsFragmentSize = errors.New("peer chunk size exceeds remaining data in fragment")
 	errAlreadyReadingArgument   = errors.New("already reading argument")
 	errNotReadingArgument       = errors.New("not reading argument")
@@ -90,6 +89,14 @@ type fragmentingReader struct {
 	curFragment      *readableFragment
 	checksum         Checksum
 	err              error
+
+	// compression is used to decompress all arguments after arg1.
+	compression CompressionType
+	args        int
+
+	// onChecksumError is called to add call details to checksum failures and
+	// record them, if it is set.
+	onChecksumError func(err *ChecksumError)
 }
 
 func newFragmentingReader(logger Logger, receiver fragmentReceiver) *fragmentingReader {
@@ -106,6 +113,11 @@ func (r *fragmentingReader) ArgReader(last bool) (ArgReader, error) {
 	if err := r.BeginArgument(last); err != nil {
 		return nil, err
 	}
+
+	r.args++
+	if r.args > 1 && r.compression != CompressionNone {
+		return newDecompressingArgReader(r, r.compression)
+	}
 	return r, nil
 }
 
@@ -299,7 +311,7 @@ func (r *fragmentingReader) recvAndParseNextFragment(initial bool) error {
 	// Validate checksums
 	localChecksum := r.checksum.Sum()
 	if bytes.Compare(r.curFragment.checksum, localChecksum) != 0 {
-		r.err = errMismatchedChecksums
+		r.err = r.checksumError(localChecksum)
 		return r.err
 	}
 
@@ -308,6 +320,18 @@ func (r *fragmentingReader) recvAndParseNextFragment(initial bool) error {
 	return nil
 }
 
+func (r *fragmentingReader) checksumError(localChecksum []byte) error {
+	err := &ChecksumError{
+		ChecksumType: r.checksum.TypeCode(),
+		Expected:     append([]byte(nil), r.curFragment.checksum...),
+		Actual:       append([]byte(nil), localChecksum...),
+	}
+	if r.onChecksumError != nil {
+		r.onChecksumError(err)
+	}
+	return err
+}
+
 func (r *fragmentingReader) doneReading(err error) {
 	if r.checksum != nil {
 		r.checksum.Release()
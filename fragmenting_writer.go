@@ -138,6 +138,10 @@ type fragmentingWriter struct {
 	curChunk    *writableChunk
 	state       fragmentingWriterState
 	err         error
+
+	// compression is applied to all arguments after arg1.
+	compression argCompression
+	args        int
 }
 
 // newFragmentingWriter creates a new fragmenting writer
@@ -156,6 +160,11 @@ func (w *fragmentingWriter) ArgWriter(last bool) (ArgWriter, error) {
 	if err := w.BeginArgument(last); err != nil {
 		return nil, err
 	}
+
+	w.args++
+	if w.args > 1 && w.compression.compression != CompressionNone {
+		return newCompressingArgWriter(w, w.compression), nil
+	}
 	return w, nil
 }
 
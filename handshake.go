@@ -0,0 +1,112 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"fmt"
+	"net"
+
+	"golang.org/x/net/context"
+)
+
+// Handshaker adds and verifies extra headers in the init handshake, which can
+// be used to authenticate connections, e.g. using signed tokens.
+//
+// For outbound connections, InitHeaders is called before the init request is
+// sent, and VerifyInitHeaders is called with the init response. For inbound
+// connections, VerifyInitHeaders is called with the init request, and
+// InitHeaders is called before the init response is sent.
+type Handshaker interface {
+	// InitHeaders returns headers to add to the init message sent to the peer.
+	// Headers cannot override the standard init headers, such as host_port.
+	InitHeaders(ctx context.Context, info HandshakeInfo) (map[string]string, error)
+
+	// VerifyInitHeaders verifies the init headers received from the peer, and
+	// returns the peer's authenticated identity, which may be nil.
+	// If an error is returned, the connection is rejected with a protocol error.
+	VerifyInitHeaders(ctx context.Context, info HandshakeInfo, headers map[string]string) (*PeerIdentity, error)
+}
+
+// HandshakeInfo describes a connection during the init handshake.
+type HandshakeInfo struct {
+	// Outbound is whether the connection was initiated by this channel.
+	Outbound bool
+
+	// RemoteAddr is the remote address of the underlying connection.
+	RemoteAddr net.Addr
+
+	// TLS contains the verified TLS state of the remote peer. It is nil if
+	// the connection does not use TLS.
+	TLS *PeerTLSInfo
+
+	// RemotePeer is the remote peer's information. It is only set once the
+	// peer's init message has been received.
+	RemotePeer *PeerInfo
+}
+
+// PeerIdentity is the identity of a remote peer, as verified by a Handshaker.
+type PeerIdentity struct {
+	// Name identifies the peer, e.g. a service name.
+	Name string `json:"name"`
+
+	// Attributes contains any other verified properties of the peer.
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+func (i *PeerIdentity) String() string {
+	if i == nil {
+		return ""
+	}
+	return i.Name
+}
+
+// addHandshakeHeaders adds the Handshaker's headers to an init message.
+func (ch *Channel) addHandshakeHeaders(ctx context.Context, info HandshakeInfo, msg *initMessage) error {
+	if ch.handshaker == nil {
+		return nil
+	}
+
+	headers, err := ch.handshaker.InitHeaders(ctx, info)
+	if err != nil {
+		return err
+	}
+	for k, v := range headers {
+		if _, ok := msg.initParams[k]; ok {
+			return fmt.Errorf("handshake header %v cannot override init header", k)
+		}
+		msg.initParams[k] = v
+	}
+	return nil
+}
+
+// verifyHandshakeHeaders verifies the init headers received from the peer
+// using the Handshaker, and returns the peer's identity.
+func (ch *Channel) verifyHandshakeHeaders(ctx context.Context, info HandshakeInfo, params initParams) (*PeerIdentity, error) {
+	if ch.handshaker == nil {
+		return nil, nil
+	}
+
+	identity, err := ch.handshaker.VerifyInitHeaders(ctx, info, params)
+	if err != nil {
+		return nil, NewWrappedSystemError(ErrCodeProtocol, err)
+	}
+	return identity, nil
+}
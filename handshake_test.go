@@ -0,0 +1,141 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/temporalio/tchannel-go"
+	"github.com/temporalio/tchannel-go/raw"
+	"github.com/temporalio/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+const authTokenHeader = "auth_token"
+
+// tokenHandshaker sends a fixed token, and accepts peers that send the expected token.
+type tokenHandshaker struct {
+	token    string
+	expected string
+	headers  map[string]string
+}
+
+func (h tokenHandshaker) InitHeaders(ctx context.Context, info tchannel.HandshakeInfo) (map[string]string, error) {
+	if h.headers != nil {
+		return h.headers, nil
+	}
+	return map[string]string{authTokenHeader: h.token}, nil
+}
+
+func (h tokenHandshaker) VerifyInitHeaders(ctx context.Context, info tchannel.HandshakeInfo, headers map[string]string) (*tchannel.PeerIdentity, error) {
+	if headers[authTokenHeader] != h.expected {
+		return nil, errors.New("invalid token")
+	}
+	return &tchannel.PeerIdentity{
+		Name:       info.RemotePeer.ProcessName,
+		Attributes: map[string]string{"outbound": boolString(info.Outbound)},
+	}, nil
+}
+
+func boolString(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
+
+func TestHandshakerIdentity(t *testing.T) {
+	serverOpts := testutils.NewOpts().SetProcessName("server")
+	serverOpts.Handshaker = tokenHandshaker{token: "server-token", expected: "client-token"}
+	server := testutils.NewServer(t, serverOpts)
+	defer server.Close()
+
+	var identity *tchannel.PeerIdentity
+	testutils.RegisterFunc(server, "identity", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+		identity = tchannel.CurrentCall(ctx).RemotePeer().Identity
+		return &raw.Res{}, nil
+	})
+
+	clientOpts := testutils.NewOpts().SetProcessName("client")
+	clientOpts.Handshaker = tokenHandshaker{token: "client-token", expected: "server-token"}
+	client := testutils.NewClient(t, clientOpts)
+	defer client.Close()
+
+	ctx, cancel := tchannel.NewContext(time.Second)
+	defer cancel()
+
+	_, _, _, err := raw.Call(ctx, client, server.PeerInfo().HostPort, server.ServiceName(), "identity", nil, nil)
+	require.NoError(t, err, "Call failed")
+	assert.Equal(t, &tchannel.PeerIdentity{
+		Name:       "client",
+		Attributes: map[string]string{"outbound": "false"},
+	}, identity, "Unexpected identity for the inbound call")
+
+	peer, ok := client.RootPeers().Get(server.PeerInfo().HostPort)
+	require.True(t, ok, "Missing peer for server")
+	state := peer.IntrospectState(&tchannel.IntrospectionOptions{})
+	require.Len(t, state.OutboundConnections, 1, "Expected a single outbound connection")
+	assert.Equal(t, &tchannel.PeerIdentity{
+		Name:       "server",
+		Attributes: map[string]string{"outbound": "true"},
+	}, state.OutboundConnections[0].RemotePeer.Identity, "Unexpected identity in introspection")
+}
+
+func TestHandshakerCannotOverrideInitHeaders(t *testing.T) {
+	// The server fails to read the init request as the client closes the connection.
+	server := testutils.NewServer(t, testutils.NewOpts().AddLogFilter("Failed during connection handshake.", 1))
+	defer server.Close()
+
+	clientOpts := testutils.NewOpts().AddLogFilter("Failed during connection handshake.", 1)
+	clientOpts.Handshaker = tokenHandshaker{headers: map[string]string{tchannel.InitParamHostPort: "1.1.1.1:1"}}
+	client := testutils.NewClient(t, clientOpts)
+	defer client.Close()
+
+	ctx, cancel := tchannel.NewContext(time.Second)
+	defer cancel()
+
+	err := client.Ping(ctx, server.PeerInfo().HostPort)
+	require.Error(t, err, "Ping should fail")
+	assert.Contains(t, err.Error(), "cannot override", "Unexpected error")
+}
+
+func TestHandshakerRejectsWithProtocolError(t *testing.T) {
+	serverOpts := testutils.NewOpts().AddLogFilter("Failed during connection handshake.", 1)
+	serverOpts.Handshaker = tokenHandshaker{token: "server-token", expected: "client-token"}
+	server := testutils.NewServer(t, serverOpts)
+	defer server.Close()
+
+	client := testutils.NewClient(t, testutils.NewOpts().AddLogFilter("Failed during connection handshake.", 1))
+	defer client.Close()
+
+	ctx, cancel := tchannel.NewContext(time.Second)
+	defer cancel()
+
+	err := client.Ping(ctx, server.PeerInfo().HostPort)
+	require.Error(t, err, "Ping should fail without a token")
+	assert.Equal(t, tchannel.ErrCodeProtocol, tchannel.GetSystemErrorCode(err), "Expected protocol error, got %v", err)
+	assert.Contains(t, err.Error(), "invalid token", "Error should include the rejection reason")
+}
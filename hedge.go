@@ -0,0 +1,288 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+	"golang.org/x/net/context"
+)
+
+const (
+	_defaultMaxHedges = 1
+
+	// hedgeLatencyWindow is the number of recent latencies used to calculate
+	// the hedge delay percentile.
+	hedgeLatencyWindow = 100
+
+	// hedgeMinLatencies is the number of latencies required before the
+	// percentile is used instead of the fixed delay.
+	hedgeMinLatencies = 10
+)
+
+// HedgeOptions configures hedged requests in RunWithRetry. When an attempt has not
+// completed after the hedge delay, another attempt is started concurrently,
+// preferring a peer that has not been selected by earlier attempts. The first
+// successful attempt is used, and the remaining attempts are cancelled.
+//
+// Hedged attempts count towards RetryOptions.MaxAttempts. Since attempts may
+// run concurrently, hedging should only be used for idempotent calls.
+type HedgeOptions struct {
+	// Delay is how long to wait for an attempt before starting a hedged attempt.
+	// If Percentile is set, Delay is used until enough latencies are recorded.
+	// If there is no positive delay, no hedged attempts are started, and
+	// attempts are only retried after they fail.
+	Delay time.Duration
+
+	// Percentile, if between 0 and 1, uses the given percentile of the latencies
+	// of recent successful attempts as the hedge delay. Latencies are tracked in
+	// the HedgeOptions, so the same HedgeOptions should be shared by calls
+	// to the same endpoint.
+	Percentile float64
+
+	// MaxHedges is the maximum number of hedged attempts started for a call.
+	// Defaults to 1.
+	MaxHedges int
+
+	latencies latencyWindow
+}
+
+func (o *HedgeOptions) maxHedges() int {
+	if o.MaxHedges <= 0 {
+		return _defaultMaxHedges
+	}
+	return o.MaxHedges
+}
+
+// delay returns the hedge delay, or false if there is no positive delay,
+// so that a zero delay doesn't start all hedged attempts immediately.
+func (o *HedgeOptions) delay() (time.Duration, bool) {
+	d := o.Delay
+	if o.Percentile > 0 && o.Percentile < 1 {
+		if pd, ok := o.latencies.percentile(o.Percentile); ok && pd > 0 {
+			d = pd
+		}
+	}
+	return d, d > 0
+}
+
+func (o *HedgeOptions) recordLatency(d time.Duration) {
+	if o.Percentile <= 0 || o.Percentile >= 1 {
+		return
+	}
+	o.latencies.add(d)
+}
+
+// latencyWindow is a fixed size ring buffer of recent latencies.
+type latencyWindow struct {
+	sync.Mutex
+
+	samples []time.Duration
+	next    int
+}
+
+func (w *latencyWindow) add(d time.Duration) {
+	w.Lock()
+	defer w.Unlock()
+
+	if len(w.samples) < hedgeLatencyWindow {
+		w.samples = append(w.samples, d)
+		return
+	}
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % hedgeLatencyWindow
+}
+
+func (w *latencyWindow) percentile(p float64) (time.Duration, bool) {
+	w.Lock()
+	if len(w.samples) < hedgeMinLatencies {
+		w.Unlock()
+		return 0, false
+	}
+	sorted := make([]time.Duration, len(w.samples))
+	copy(sorted, w.samples)
+	w.Unlock()
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[int(p*float64(len(sorted)-1))], true
+}
+
+// hedgeState is shared by the concurrent attempts of a hedged request.
+type hedgeState struct {
+	sync.Mutex
+
+	selectedPeers map[string]struct{}
+
+	// done is set once an attempt succeeds, and the remaining attempts
+	// are being cancelled.
+	done atomic.Bool
+}
+
+func (h *hedgeState) addSelectedPeer(hostPort, host string) {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.selectedPeers == nil {
+		h.selectedPeers = make(map[string]struct{})
+	}
+	h.selectedPeers[hostPort] = struct{}{}
+	h.selectedPeers[host] = struct{}{}
+}
+
+// prevSelectedPeers returns a copy of the peers selected by all attempts, since
+// attempts running concurrently may select new peers.
+func (h *hedgeState) prevSelectedPeers() map[string]struct{} {
+	h.Lock()
+	defer h.Unlock()
+
+	selected := make(map[string]struct{}, len(h.selectedPeers))
+	for k := range h.selectedPeers {
+		selected[k] = struct{}{}
+	}
+	return selected
+}
+
+type attemptResult struct {
+	err     error
+	latency time.Duration
+}
+
+// runWithHedging runs attempts of f, starting a new attempt whenever the hedge
+// delay passes without a result, or when all running attempts have failed with
+// a retriable error. It returns once all attempts have returned.
+func (ch *Channel) runWithHedging(runCtx context.Context, opts *RetryOptions, f RetriableFunc) error {
+	hedge := opts.Hedge
+	state := &hedgeState{}
+	start := ch.timeNow()
+
+	ctx, cancel := context.WithCancel(runCtx)
+	defer cancel()
+
+	results := make(chan attemptResult, opts.MaxAttempts)
+	var attempts, running, hedges int
+	startAttempt := func() {
+		attempts++
+		running++
+		rs := &RequestState{
+			Start:     start,
+			Attempt:   attempts,
+			retryOpts: opts,
+			hedge:     state,
+		}
+		go func() {
+			attemptStart := ch.timeNow()
+			err := runAttempt(ctx, opts, rs, f)
+			results <- attemptResult{err, ch.timeNow().Sub(attemptStart)}
+		}()
+	}
+
+	// waitRunning cancels and waits for any attempts that are still running.
+	waitRunning := func() {
+		state.done.Store(true)
+		cancel()
+		for ; running > 0; running-- {
+			<-results
+		}
+	}
+
+	// The timer is only started if there is a hedge delay.
+	hedgeTimer := time.NewTimer(math.MaxInt64)
+	defer hedgeTimer.Stop()
+
+	// resetHedgeTimer restarts the hedge delay after an attempt is started.
+	resetHedgeTimer := func() {
+		if hedges >= hedge.maxHedges() {
+			return
+		}
+		if !hedgeTimer.Stop() {
+			select {
+			case <-hedgeTimer.C:
+			default:
+			}
+		}
+		if d, ok := hedge.delay(); ok {
+			hedgeTimer.Reset(d)
+		}
+	}
+
+	startAttempt()
+	resetHedgeTimer()
+
+	var err error
+	for running > 0 {
+		select {
+		case <-hedgeTimer.C:
+			if attempts >= opts.MaxAttempts || hedges >= hedge.maxHedges() {
+				continue
+			}
+			hedges++
+			ch.log.WithFields(
+				LogField{"attempt", attempts + 1},
+				LogField{"maxAttempts", opts.MaxAttempts},
+			).Debug("Starting hedged attempt.")
+			startAttempt()
+			resetHedgeTimer()
+
+		case res := <-results:
+			running--
+			if res.err == ErrRetryBudgetExhausted {
+				if running == 0 {
+					ch.log.WithFields(ErrField(err)).Info("Not retrying request as retry budget is exhausted.")
+					return err
+				}
+				continue
+			}
+			if res.err == nil {
+				hedge.recordLatency(res.latency)
+				waitRunning()
+				return nil
+			}
+
+			err = res.err
+			if !opts.RetryOn.CanRetry(err) {
+				if ch.log.Enabled(LogLevelInfo) {
+					ch.log.WithFields(ErrField(err)).Info("Failed after non-retriable error.")
+				}
+				waitRunning()
+				return err
+			}
+			if running == 0 && attempts < opts.MaxAttempts {
+				ch.log.WithFields(
+					ErrField(err),
+					LogField{"attempt", attempts},
+					LogField{"maxAttempts", opts.MaxAttempts},
+				).Info("Retrying request after retryable error.")
+				if !waitBackoff(runCtx, opts, attempts) {
+					return err
+				}
+				startAttempt()
+				resetHedgeTimer()
+			}
+		}
+	}
+
+	// Too many retries, return the last error
+	return err
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/temporalio/tchannel-go"
+	"github.com/temporalio/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+	"golang.org/x/net/context"
+)
+
+func hedgedContext(hedge *tchannel.HedgeOptions) (context.Context, context.CancelFunc) {
+	return tchannel.NewContextBuilder(time.Second).
+		SetRetryOptions(&tchannel.RetryOptions{Hedge: hedge}).
+		Build()
+}
+
+func TestHedgedRequestFirstSuccess(t *testing.T) {
+	ch := testutils.NewClient(t, nil)
+	defer ch.Close()
+
+	ctx, cancel := hedgedContext(&tchannel.HedgeOptions{Delay: 10 * time.Millisecond})
+	defer cancel()
+
+	var (
+		mu            sync.Mutex
+		attempts      []int
+		firstCanceled atomic.Bool
+	)
+	started := time.Now()
+	err := ch.RunWithRetry(ctx, func(ctx context.Context, rs *tchannel.RequestState) error {
+		assert.True(t, rs.Hedged(), "Attempts should be hedged")
+		mu.Lock()
+		attempts = append(attempts, rs.Attempt)
+		mu.Unlock()
+
+		if rs.Attempt == 1 {
+			rs.AddSelectedPeer("1.1.1.1:1")
+			<-ctx.Done()
+			firstCanceled.Store(true)
+			return ctx.Err()
+		}
+
+		assert.True(t, time.Since(started) >= 10*time.Millisecond, "Hedged attempt started before delay")
+		assert.Contains(t, rs.PrevSelectedPeers(), "1.1.1.1:1", "Hedged attempt should see peers selected by other attempts")
+		return nil
+	})
+	require.NoError(t, err, "RunWithRetry failed")
+	assert.True(t, firstCanceled.Load(), "First attempt should be cancelled before RunWithRetry returns")
+	assert.Equal(t, []int{1, 2}, attempts, "Unexpected attempts")
+}
+
+func TestHedgedRequestMaxHedges(t *testing.T) {
+	ch := testutils.NewClient(t, nil)
+	defer ch.Close()
+
+	ctx, cancel := hedgedContext(&tchannel.HedgeOptions{
+		Delay:     time.Millisecond,
+		MaxHedges: 2,
+	})
+	defer cancel()
+
+	var attempts atomic.Int32
+	err := ch.RunWithRetry(ctx, func(ctx context.Context, rs *tchannel.RequestState) error {
+		attempts.Inc()
+		if rs.Attempt < 3 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+
+		// Wait to ensure no further hedged attempts are started.
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	require.NoError(t, err, "RunWithRetry failed")
+	assert.EqualValues(t, 3, attempts.Load(), "Expected the original attempt and 2 hedged attempts")
+}
+
+func TestHedgedRequestRetries(t *testing.T) {
+	ch := testutils.NewClient(t, nil)
+	defer ch.Close()
+
+	ctx, cancel := hedgedContext(&tchannel.HedgeOptions{Delay: time.Second})
+	defer cancel()
+
+	e := getTestErrors()
+	f, counter := createFuncToRetry(t, e.Busy, e.Busy, nil)
+	require.NoError(t, ch.RunWithRetry(ctx, f), "RunWithRetry failed")
+	assert.Equal(t, 3, *counter, "Failed attempts should be retried")
+
+	f, counter = createFuncToRetry(t, e.Busy, e.BadRequest)
+	assert.Equal(t, e.BadRequest, ch.RunWithRetry(ctx, f), "Non-retriable errors should be returned")
+	assert.Equal(t, 2, *counter, "Non-retriable errors should not be retried")
+}
+
+func TestHedgedRequestPercentileDelay(t *testing.T) {
+	ch := testutils.NewClient(t, nil)
+	defer ch.Close()
+
+	hedge := &tchannel.HedgeOptions{
+		Delay:      time.Second,
+		Percentile: 0.5,
+	}
+
+	// Record latencies so the median is much lower than the fixed delay.
+	for i := 0; i < 10; i++ {
+		ctx, cancel := hedgedContext(hedge)
+		require.NoError(t, ch.RunWithRetry(ctx, func(context.Context, *tchannel.RequestState) error {
+			time.Sleep(time.Millisecond)
+			return nil
+		}), "RunWithRetry failed")
+		cancel()
+	}
+
+	ctx, cancel := hedgedContext(hedge)
+	defer cancel()
+
+	started := time.Now()
+	err := ch.RunWithRetry(ctx, func(ctx context.Context, rs *tchannel.RequestState) error {
+		if rs.Attempt == 1 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	})
+	require.NoError(t, err, "RunWithRetry failed")
+	assert.True(t, time.Since(started) < 500*time.Millisecond, "Hedge delay should use the latency percentile")
+}
+
+func TestHedgedRequestNoDelay(t *testing.T) {
+	ch := testutils.NewClient(t, nil)
+	defer ch.Close()
+
+	tests := []struct {
+		msg   string
+		hedge *tchannel.HedgeOptions
+	}{
+		{
+			msg:   "no delay",
+			hedge: &tchannel.HedgeOptions{MaxHedges: 3},
+		},
+		{
+			msg:   "percentile without enough latencies",
+			hedge: &tchannel.HedgeOptions{Percentile: 0.5, MaxHedges: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		ctx, cancel := hedgedContext(tt.hedge)
+
+		var attempts atomic.Int32
+		err := ch.RunWithRetry(ctx, func(ctx context.Context, rs *tchannel.RequestState) error {
+			attempts.Inc()
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		})
+		cancel()
+		require.NoError(t, err, "%v: RunWithRetry failed", tt.msg)
+		assert.EqualValues(t, 1, attempts.Load(), "%v: no hedged attempts should be started", tt.msg)
+	}
+}
@@ -376,3 +376,67 @@ func withSetup(t *testing.T, f func(ch *tchannel.Channel, hostPort string)) {
 	f(serverCh, listener.Addr().String())
 	serverCh.Close()
 }
+
+func TestUnadvertiseOnDrain(t *testing.T) {
+	withSetup(t, func(hypCh *tchannel.Channel, hyperbahnHostPort string) {
+		unadCh := make(chan *AdRequest, 1)
+		json.Register(hypCh, json.Handlers{
+			"ad": func(ctx json.Context, req *AdRequest) (*AdResponse, error) {
+				return &AdResponse{1}, nil
+			},
+			"unad": func(ctx json.Context, req *AdRequest) (*AdResponse, error) {
+				unadCh <- req
+				return &AdResponse{}, nil
+			},
+		}, nil)
+
+		ch := testutils.NewServer(t, nil)
+		client, err := NewClient(ch, configFor(hyperbahnHostPort), stubbedSleep())
+		require.NoError(t, err, "hyperbahn NewClient failed")
+		defer client.Close()
+		require.NoError(t, client.Advertise(), "Advertise failed")
+
+		ctx, cancel := tchannel.NewContext(time.Second)
+		defer cancel()
+		report, err := ch.Drain(ctx)
+		require.NoError(t, err, "Drain failed")
+		assert.True(t, report.Drained, "Expected channel to drain")
+		assert.True(t, client.IsClosed(), "Client should be closed after unadvertise")
+
+		select {
+		case req := <-unadCh:
+			assert.Equal(t, []service{{Name: ch.ServiceName()}}, req.Services, "Unexpected unad services")
+		default:
+			t.Fatal("Drain did not unadvertise")
+		}
+	})
+}
+
+func TestAdvertiseTwiceUnadvertisesOnce(t *testing.T) {
+	withSetup(t, func(hypCh *tchannel.Channel, hyperbahnHostPort string) {
+		unadCh := make(chan *AdRequest, 2)
+		json.Register(hypCh, json.Handlers{
+			"ad": func(ctx json.Context, req *AdRequest) (*AdResponse, error) {
+				return &AdResponse{1}, nil
+			},
+			"unad": func(ctx json.Context, req *AdRequest) (*AdResponse, error) {
+				unadCh <- req
+				return &AdResponse{}, nil
+			},
+		}, nil)
+
+		ch := testutils.NewServer(t, nil)
+		client, err := NewClient(ch, configFor(hyperbahnHostPort), stubbedSleep())
+		require.NoError(t, err, "hyperbahn NewClient failed")
+		defer client.Close()
+		require.NoError(t, client.Advertise(), "Advertise failed")
+		require.NoError(t, client.Advertise(), "Advertise failed")
+
+		ctx, cancel := tchannel.NewContext(time.Second)
+		defer cancel()
+		report, err := ch.Drain(ctx)
+		require.NoError(t, err, "Drain failed")
+		assert.True(t, report.Drained, "Expected channel to drain")
+		assert.Len(t, unadCh, 1, "Expected a single unadvertise")
+	})
+}
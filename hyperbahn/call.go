@@ -79,3 +79,13 @@ func (c *Client) sendAdvertise() error {
 	c.opts.Handler.On(SendAdvertise)
 	return c.jsonClient.Call(ctx, "ad", c.createRequest(), &resp)
 }
+
+func (c *Client) sendUnadvertise() error {
+	ctx, cancel := tchannel.NewContextBuilder(c.opts.Timeout).
+		DisableTracing().
+		Build()
+	defer cancel()
+
+	var resp AdResponse
+	return c.jsonClient.Call(ctx, "unad", c.createRequest(), &resp)
+}
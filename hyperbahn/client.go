@@ -25,12 +25,14 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/temporalio/tchannel-go"
 	htypes "github.com/temporalio/tchannel-go/hyperbahn/gen-go/hyperbahn"
 	tjson "github.com/temporalio/tchannel-go/json"
 	tthrift "github.com/temporalio/tchannel-go/thrift"
+	"golang.org/x/net/context"
 )
 
 // Client manages Hyperbahn connections and registrations.
@@ -40,6 +42,9 @@ type Client struct {
 	opts     ClientOptions
 	quit     chan struct{}
 
+	// onDrain ensures the unadvertise hook is only registered once.
+	onDrain sync.Once
+
 	jsonClient      *tjson.Client
 	hyperbahnClient htypes.TChanHyperbahn
 }
@@ -188,6 +193,27 @@ func (c *Client) Advertise(otherServices ...tchannel.Registrar) error {
 
 	c.opts.Handler.On(Advertised)
 	go c.advertiseLoop()
+	c.onDrain.Do(func() {
+		c.tchan.OnDrain(func(context.Context) error {
+			return c.Unadvertise()
+		})
+	})
+	return nil
+}
+
+// Unadvertise stops any background re-advertisements, and removes the
+// advertised services from Hyperbahn so that no new calls are routed to
+// this channel. It is called automatically when the channel is drained.
+func (c *Client) Unadvertise() error {
+	if c.IsClosed() {
+		return nil
+	}
+	c.Close()
+
+	if err := c.sendUnadvertise(); err != nil {
+		return err
+	}
+	c.opts.Handler.On(Unadvertised)
 	return nil
 }
 
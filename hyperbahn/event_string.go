@@ -4,9 +4,9 @@ package hyperbahn
 
 import "fmt"
 
-const _Event_name = "UnknownEventRegistrationAttemptRegisteredRegistrationRefreshed"
+const _Event_name = "UnknownEventRegistrationAttemptRegisteredRegistrationRefreshedUnadvertised"
 
-var _Event_index = [...]uint8{0, 12, 31, 41, 62}
+var _Event_index = [...]uint8{0, 12, 31, 41, 62, 74}
 
 func (i Event) String() string {
 	if i < 0 || i+1 >= Event(len(_Event_index)) {
@@ -32,6 +32,8 @@ const (
 	Advertised
 	// Readvertised is triggered on periodic advertisements.
 	Readvertised
+	// Unadvertised is triggered when the service is unadvertised, e.g. when the channel drains.
+	Unadvertised
 )
 
 //go:generate stringer -type=Event
@@ -88,14 +88,41 @@ func (s *tchanHyperbahnServer) Handle(ctx thrift.Context, methodName string, pro
 	}
 }
 
+func (s *tchanHyperbahnServer) NewRequest(methodName string) athrift.TStruct {
+	switch methodName {
+	case "discover":
+		return &HyperbahnDiscoverArgs{}
+
+	default:
+		return nil
+	}
+}
+
+func (s *tchanHyperbahnServer) HandleRequest(ctx thrift.Context, methodName string, req athrift.TStruct) (bool, athrift.TStruct, error) {
+	switch methodName {
+	case "discover":
+		if args, ok := req.(*HyperbahnDiscoverArgs); ok {
+			return s.handleDiscoverRequest(ctx, args)
+		}
+		return false, nil, fmt.Errorf("unexpected request type %T for method %v", req, methodName)
+
+	default:
+		return false, nil, fmt.Errorf("method %v not found in service %v", methodName, s.Service())
+	}
+}
+
 func (s *tchanHyperbahnServer) handleDiscover(ctx thrift.Context, protocol athrift.TProtocol) (bool, athrift.TStruct, error) {
 	var req HyperbahnDiscoverArgs
-	var res HyperbahnDiscoverResult
-
 	if err := req.Read(ctx, protocol); err != nil {
 		return false, nil, err
 	}
 
+	return s.handleDiscoverRequest(ctx, &req)
+}
+
+func (s *tchanHyperbahnServer) handleDiscoverRequest(ctx thrift.Context, req *HyperbahnDiscoverArgs) (bool, athrift.TStruct, error) {
+	var res HyperbahnDiscoverResult
+
 	r, err :=
 		s.handler.Discover(ctx, req.Query)
 
@@ -36,11 +36,11 @@ type idleSweep struct {
 
 // startIdleSweep starts a poller that checks for idle connections at given
 // intervals.
-func startIdleSweep(ch *Channel, opts *ChannelOptions) *idleSweep {
+func startIdleSweep(ch *Channel, maxIdleTime, idleCheckInterval time.Duration) *idleSweep {
 	is := &idleSweep{
 		ch:                ch,
-		maxIdleTime:       opts.MaxIdleTime,
-		idleCheckInterval: opts.IdleCheckInterval,
+		maxIdleTime:       maxIdleTime,
+		idleCheckInterval: idleCheckInterval,
 	}
 
 	is.start()
@@ -53,7 +53,7 @@ func (is *idleSweep) start() {
 		return
 	}
 
-	is.ch.log.WithFields(
+	is.ch.subsystemLogger(LogSubsystemIdleSweep).WithFields(
 		LogField{"idleCheckInterval", is.idleCheckInterval},
 		LogField{"maxIdleTime", is.maxIdleTime},
 	).Info("Starting idle connections poller.")
@@ -70,7 +70,7 @@ func (is *idleSweep) Stop() {
 	}
 
 	is.started = false
-	is.ch.log.Info("Stopping idle connections poller.")
+	is.ch.subsystemLogger(LogSubsystemIdleSweep).Info("Stopping idle connections poller.")
 	close(is.stopCh)
 }
 
@@ -115,7 +115,7 @@ func (is *idleSweep) checkIdleConnections() {
 		// We shouldn't get to a state where we have pending calls, but the connection
 		// is idle. This either means the max-idle time is too low, or there's a stuck call.
 		if conn.hasPendingCalls() {
-			conn.log.Error("Skip closing idle Connection as it has pending calls.")
+			withLogSubsystem(conn.log, conn.logLevels, LogSubsystemIdleSweep).Error("Skip closing idle Connection as it has pending calls.")
 			continue
 		}
 
@@ -63,7 +63,7 @@ func (c *Connection) handleCallReq(frame *Frame) bool {
 	call.conn = c
 	ctx, cancel := newIncomingContext(c.baseContext, call, callReq.TimeToLive)
 
-	mex, err := c.inbound.newExchange(ctx, c.opts.FramePool, callReq.messageType(), frame.Header.ID, mexChannelBufferSize)
+	mex, err := c.inbound.newExchange(ctx, c.opts.FramePool, callReq.messageType(), frame.Header.ID, mexChannelBufferSize, c.newFlowControl(false /* recvResponses */))
 	if err != nil {
 		if err == errDuplicateMex {
 			err = errInboundRequestAlreadyActive
@@ -73,6 +73,9 @@ func (c *Connection) handleCallReq(frame *Frame) bool {
 		return true
 	}
 
+	mex.cancel = cancel
+	mex.priority = PriorityClass(callReq.Headers[Priority])
+
 	// Close may have been called between the time we checked the state and us creating the exchange.
 	if c.readState() != connectionActive {
 		mex.shutdown()
@@ -111,17 +114,37 @@ func (c *Connection) handleCallReq(frame *Frame) bool {
 	call.initialFragment = initialFragment
 	call.serviceName = string(callReq.Service)
 	call.headers = callReq.Headers
+	call.tracing = callReq.Tracing
+	call.timeToLive = callReq.TimeToLive
+	call.receivedAt = now
 	call.response = response
 	call.log = c.log.WithFields(LogField{"In-Call", callReq.ID()})
 	call.messageForFragment = func(initial bool) message { return new(callReqContinue) }
 	call.contents = newFragmentingReader(call.log, call)
+	call.contents.compression = CompressionType(call.headers[Compression])
 	call.statsReporter = c.statsReporter
 	call.createStatsTags(c.commonStatsTags)
+	call.contents.onChecksumError = func(err *ChecksumError) {
+		err.RemotePeer, err.Service, err.Method = c.remotePeerInfo, call.ServiceName(), call.methodString
+		if call.method == nil {
+			// No handler will respond to a call without a method, so return the
+			// error to the caller before the connection may be closed.
+			c.SendSystemError(frame.Header.ID, callReqSpan(frame), err)
+		}
+		c.checksumFailed(err, "inbound.calls", call.commonStatsTags)
+	}
 
 	response.statsReporter = c.statsReporter
 	response.commonStatsTags = call.commonStatsTags
 
 	setResponseHeaders(call.headers, response.headers)
+	if compression := c.responseCompression(call.headers); compression != CompressionNone {
+		response.headers[Compression] = string(compression)
+		response.contents.compression = argCompression{
+			compression: compression,
+			onWritten:   compressionStats(c.statsReporter, "inbound.calls", call.commonStatsTags),
+		}
+	}
 	go c.dispatchInbound(c.connID, callReq.ID(), call, frame)
 	return false
 }
@@ -154,10 +177,13 @@ func (c *Connection) dispatchInbound(_ uint32, _ uint32, call *InboundCall, fram
 	}
 
 	if err := call.readMethod(); err != nil {
-		call.log.WithFields(
-			LogField{"remotePeer", c.remotePeerInfo},
-			ErrField(err),
-		).Error("Couldn't read method.")
+		// Checksum failures are logged and returned to the caller when they occur.
+		if _, ok := err.(*ChecksumError); !ok {
+			call.log.WithFields(
+				LogField{"remotePeer", c.remotePeerInfo},
+				ErrField(err),
+			).Error("Couldn't read method.")
+		}
 		c.opts.FramePool.Release(frame)
 		return
 	}
@@ -166,6 +192,12 @@ func (c *Connection) dispatchInbound(_ uint32, _ uint32, call *InboundCall, fram
 	call.statsReporter.IncCounter("inbound.calls.recvd", call.commonStatsTags, 1)
 	if span := call.response.span; span != nil {
 		span.SetOperationName(call.methodString)
+		span.SetTag(rpcMethodTag, call.methodString)
+	}
+
+	if compression := call.contents.compression; compression != CompressionNone && !compression.supported() {
+		call.Response().SendSystemError(NewSystemError(ErrCodeBadRequest, "unsupported compression type %q", compression))
+		return
 	}
 
 	// TODO(prashant): This is an expensive way to check for cancellation. Use a heap for timeouts.
@@ -192,14 +224,37 @@ func (c *Connection) dispatchInbound(_ uint32, _ uint32, call *InboundCall, fram
 	}()
 
 	// Internal handlers (e.g., introspection) trump all other user-registered handlers on
-	// the "tchannel" name.
+	// the "tchannel" name. They skip interceptors, draining and concurrency limits,
+	// but are still authorized, since some of them change the channel's state.
 	if call.ServiceName() == "tchannel" {
 		if h := c.internalHandlers.find(call.Method()); h != nil {
+			if !c.authorize(call) {
+				return
+			}
 			h.Handle(call.mex.ctx, call)
 			return
 		}
 	}
 
+	if !c.interceptInbound(call) {
+		return
+	}
+
+	if c.draining.Load() && !drainExempt(call.ServiceName(), call.methodString) {
+		call.Response().SendSystemError(ErrChannelDraining)
+		return
+	}
+
+	if !c.authorize(call) {
+		return
+	}
+
+	release, ok := c.acquireConcurrency(call)
+	if !ok {
+		return
+	}
+	defer release()
+
 	c.handler.Handle(call.mex.ctx, call)
 }
 
@@ -213,6 +268,10 @@ type InboundCall struct {
 	method          []byte
 	methodString    string
 	headers         transportHeaders
+	tracing         Span
+	timeToLive      time.Duration
+	receivedAt      time.Time
+	interceptors    []InboundInterceptor
 	statsReporter   StatsReporter
 	commonStatsTags map[string]string
 }
@@ -257,6 +316,11 @@ func (call *InboundCall) RoutingDelegate() string {
 	return call.headers[RoutingDelegate]
 }
 
+// Priority returns the priority class from the Priority transport header.
+func (call *InboundCall) Priority() PriorityClass {
+	return priorityClasses[PriorityClass(call.headers[Priority]).queue()]
+}
+
 // LocalPeer returns the local peer information for this call.
 func (call *InboundCall) LocalPeer() LocalPeerInfo {
 	return call.conn.localPeerInfo
@@ -275,6 +339,7 @@ func (call *InboundCall) CallOptions() *CallOptions {
 		ShardKey:        call.ShardKey(),
 		RoutingDelegate: call.RoutingDelegate(),
 		RoutingKey:      call.RoutingKey(),
+		Priority:        PriorityClass(call.headers[Priority]),
 	}
 }
 
@@ -328,6 +393,7 @@ type InboundCallResponse struct {
 	timeNow          func() time.Time
 	applicationError bool
 	systemError      bool
+	systemErr        error
 	headers          transportHeaders
 	span             opentracing.Span
 	statsReporter    StatsReporter
@@ -343,12 +409,13 @@ func (response *InboundCallResponse) SendSystemError(err error) error {
 	// Fail all future attempts to read fragments
 	response.state = reqResWriterComplete
 	response.systemError = true
+	response.systemErr = err
 	response.doneSending()
 	response.call.releasePreviousFragment()
 
 	span := CurrentSpan(response.mex.ctx)
 
-	return response.conn.SendSystemError(response.mex.msgID, *span, err)
+	return response.conn.sendSystemError(response.mex.msgID, *span, err, response.mex.priority)
 }
 
 // SetApplicationError marks the response as being an application error.  This method can
@@ -411,6 +478,11 @@ func (response *InboundCallResponse) doneSending() {
 		response.statsReporter.IncCounter("inbound.calls.success", response.commonStatsTags, 1)
 	}
 
+	response.call.afterInbound(CallOutcome{
+		Err:              response.systemErr,
+		ApplicationError: response.applicationError,
+	})
+
 	// Cancel the context since the response is complete.
 	response.cancel()
 
@@ -152,6 +152,7 @@ func TestCallOptionsPropogated(t *testing.T) {
 		ShardKey:        "test-shard-key",
 		RoutingKey:      "test-routing-key",
 		RoutingDelegate: "test-routing-delegate",
+		Priority:        tchannel.PriorityBulk,
 	}
 
 	var gotCallOpts *tchannel.CallOptions
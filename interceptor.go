@@ -0,0 +1,142 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import "golang.org/x/net/context"
+
+// CallOutcome is the result of a call, as seen by interceptors.
+type CallOutcome struct {
+	// Err is the system error that the call failed with, if any.
+	Err error
+
+	// ApplicationError is whether the call completed with an application error.
+	ApplicationError bool
+}
+
+// InboundInterceptor intercepts all inbound calls handled by a channel, other
+// than calls to the internal "tchannel" service.
+//
+// Interceptors are run in the order they are configured. If an interceptor
+// returns an error from BeforeInbound, the call is failed with that error as a
+// system error, and neither later interceptors nor the handler are run.
+// AfterInbound is called in reverse order for each interceptor whose
+// BeforeInbound succeeded, once the response has been sent.
+type InboundInterceptor interface {
+	// BeforeInbound is called before the call is handled.
+	BeforeInbound(ctx context.Context, call *InboundCall) error
+
+	// AfterInbound is called with the outcome of the call.
+	AfterInbound(ctx context.Context, call *InboundCall, outcome CallOutcome)
+}
+
+// OutboundInterceptor intercepts all outbound calls made by a channel,
+// including each attempt of a retried call.
+//
+// Interceptors are run in the order they are configured. If an interceptor
+// returns an error from BeforeOutbound, the call fails with that error, and
+// nothing is sent to the peer. Errors that are not SystemErrors are wrapped as
+// ErrCodeUnexpected. AfterOutbound is called in reverse order for each
+// interceptor whose BeforeOutbound succeeded, once the response has been read.
+type OutboundInterceptor interface {
+	// BeforeOutbound is called before the call is sent.
+	BeforeOutbound(ctx context.Context, call *OutboundCall) error
+
+	// AfterOutbound is called with the outcome of the call.
+	AfterOutbound(ctx context.Context, call *OutboundCall, outcome CallOutcome)
+}
+
+// TransportHeaders returns a copy of the transport headers of the call.
+func (call *InboundCall) TransportHeaders() map[TransportHeaderName]string {
+	return call.headers.clone()
+}
+
+// ServiceName returns the name of the service being called.
+func (call *OutboundCall) ServiceName() string {
+	return call.callReq.Service
+}
+
+// MethodString returns the method being called.
+func (call *OutboundCall) MethodString() string {
+	return call.methodString
+}
+
+// CallerName returns the caller name from the CallerName transport header.
+func (call *OutboundCall) CallerName() string {
+	return call.callReq.Headers[CallerName]
+}
+
+// TransportHeaders returns a copy of the transport headers of the call.
+func (call *OutboundCall) TransportHeaders() map[TransportHeaderName]string {
+	return call.callReq.Headers.clone()
+}
+
+func (ch transportHeaders) clone() map[TransportHeaderName]string {
+	headers := make(map[TransportHeaderName]string, len(ch))
+	for k, v := range ch {
+		headers[k] = v
+	}
+	return headers
+}
+
+// interceptInbound runs the inbound interceptors for the call, and fails the
+// call if any of them return an error.
+func (c *Connection) interceptInbound(call *InboundCall) bool {
+	for i, interceptor := range c.inboundInterceptors {
+		if err := interceptor.BeforeInbound(call.mex.ctx, call); err != nil {
+			call.interceptors = c.inboundInterceptors[:i]
+			call.Response().SendSystemError(err)
+			return false
+		}
+	}
+	call.interceptors = c.inboundInterceptors
+	return true
+}
+
+// afterInbound runs AfterInbound for interceptors that intercepted the call.
+func (call *InboundCall) afterInbound(outcome CallOutcome) {
+	for i := len(call.interceptors) - 1; i >= 0; i-- {
+		call.interceptors[i].AfterInbound(call.mex.ctx, call, outcome)
+	}
+}
+
+// interceptOutbound runs the outbound interceptors for the call. If any of
+// them return an error, the call is completed with that error.
+func (c *Connection) interceptOutbound(ctx context.Context, call *OutboundCall) error {
+	for i, interceptor := range c.outboundInterceptors {
+		if err := interceptor.BeforeOutbound(ctx, call); err != nil {
+			if _, ok := err.(SystemError); !ok {
+				err = NewWrappedSystemError(ErrCodeUnexpected, err)
+			}
+			call.response.interceptors = c.outboundInterceptors[:i]
+			call.response.doneReading(err)
+			return err
+		}
+	}
+	call.response.interceptors = c.outboundInterceptors
+	return nil
+}
+
+// afterOutbound runs AfterOutbound for interceptors that intercepted the call.
+func (response *OutboundCallResponse) afterOutbound(outcome CallOutcome) {
+	for i := len(response.interceptors) - 1; i >= 0; i-- {
+		response.interceptors[i].AfterOutbound(response.mex.ctx, response.call, outcome)
+	}
+}
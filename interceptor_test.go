@@ -0,0 +1,228 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/temporalio/tchannel-go"
+	"github.com/temporalio/tchannel-go/raw"
+	"github.com/temporalio/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// interceptorLog records the calls seen by a set of interceptors.
+type interceptorLog struct {
+	sync.Mutex
+	entries []string
+}
+
+func (l *interceptorLog) newInterceptor(name string, err error) *recordingInterceptor {
+	return &recordingInterceptor{name: name, log: l, err: err}
+}
+
+func (l *interceptorLog) add(entry string) {
+	l.Lock()
+	l.entries = append(l.entries, entry)
+	l.Unlock()
+}
+
+// waitFor waits for n entries, and returns and clears the log.
+func (l *interceptorLog) waitFor(t *testing.T, n int) []string {
+	require.True(t, testutils.WaitFor(time.Second, func() bool {
+		l.Lock()
+		defer l.Unlock()
+		return len(l.entries) >= n
+	}), "Timed out waiting for interceptors")
+
+	l.Lock()
+	defer l.Unlock()
+	entries := l.entries
+	l.entries = nil
+	return entries
+}
+
+// recordingInterceptor records the calls it intercepts to a log, and
+// rejects calls to the "reject" method with err.
+type recordingInterceptor struct {
+	name string
+	log  *interceptorLog
+	err  error
+}
+
+func (i *recordingInterceptor) before(method string) error {
+	i.log.add(i.name + " before " + method)
+	if method == "reject" {
+		return i.err
+	}
+	return nil
+}
+
+func (i *recordingInterceptor) after(method string, outcome tchannel.CallOutcome) {
+	i.log.add(fmt.Sprintf("%v after %v err=%v appErr=%v", i.name, method, outcome.Err, outcome.ApplicationError))
+}
+
+func (i *recordingInterceptor) BeforeInbound(ctx context.Context, call *tchannel.InboundCall) error {
+	return i.before(call.MethodString())
+}
+
+func (i *recordingInterceptor) AfterInbound(ctx context.Context, call *tchannel.InboundCall, outcome tchannel.CallOutcome) {
+	i.after(call.MethodString(), outcome)
+}
+
+func (i *recordingInterceptor) BeforeOutbound(ctx context.Context, call *tchannel.OutboundCall) error {
+	return i.before(call.MethodString())
+}
+
+func (i *recordingInterceptor) AfterOutbound(ctx context.Context, call *tchannel.OutboundCall, outcome tchannel.CallOutcome) {
+	i.after(call.MethodString(), outcome)
+}
+
+// outboundFunc is an OutboundInterceptor that calls f before each call.
+type outboundFunc func(call *tchannel.OutboundCall)
+
+func (f outboundFunc) BeforeOutbound(ctx context.Context, call *tchannel.OutboundCall) error {
+	f(call)
+	return nil
+}
+
+func (f outboundFunc) AfterOutbound(ctx context.Context, call *tchannel.OutboundCall, outcome tchannel.CallOutcome) {
+}
+
+func TestInterceptorsInbound(t *testing.T) {
+	var log interceptorLog
+	opts := testutils.NewOpts().SetServiceName("svc")
+	opts.InboundInterceptors = []tchannel.InboundInterceptor{
+		log.newInterceptor("first", nil),
+		log.newInterceptor("second", tchannel.ErrServerBusy),
+	}
+	server := testutils.NewServer(t, opts)
+	defer server.Close()
+
+	var handled []string
+	handler := func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+		call := tchannel.CurrentCall(ctx).(*tchannel.InboundCall)
+		handled = append(handled, args.Method)
+		assert.Equal(t, "caller", call.CallerName(), "Unexpected caller name")
+		assert.Equal(t, "caller", call.TransportHeaders()[tchannel.CallerName], "Unexpected transport headers")
+		assert.Equal(t, "key", call.TransportHeaders()[tchannel.ShardKey], "Unexpected transport headers")
+		return &raw.Res{IsErr: args.Method == "appError"}, nil
+	}
+	testutils.RegisterFunc(server, "ok", handler)
+	testutils.RegisterFunc(server, "appError", handler)
+	testutils.RegisterFunc(server, "reject", handler)
+
+	client := testutils.NewClient(t, testutils.NewOpts().SetServiceName("caller"))
+	defer client.Close()
+
+	call := func(method string) error {
+		ctx, cancel := tchannel.NewContextBuilder(time.Second).SetShardKey("key").Build()
+		defer cancel()
+
+		_, _, _, err := raw.Call(ctx, client, server.PeerInfo().HostPort, "svc", method, nil, nil)
+		return err
+	}
+
+	require.NoError(t, call("ok"), "Call failed")
+	assert.Equal(t, []string{
+		"first before ok",
+		"second before ok",
+		"second after ok err=<nil> appErr=false",
+		"first after ok err=<nil> appErr=false",
+	}, log.waitFor(t, 4), "Unexpected interceptor calls")
+
+	require.NoError(t, call("appError"), "Call failed")
+	assert.Equal(t, []string{
+		"first before appError",
+		"second before appError",
+		"second after appError err=<nil> appErr=true",
+		"first after appError err=<nil> appErr=true",
+	}, log.waitFor(t, 4), "Unexpected interceptor calls")
+
+	assert.Equal(t, tchannel.ErrServerBusy, call("reject"), "Rejected call should fail with the interceptor's error")
+	assert.Equal(t, []string{
+		"first before reject",
+		"second before reject",
+		"first after reject err=" + tchannel.ErrServerBusy.Error() + " appErr=false",
+	}, log.waitFor(t, 3), "Unexpected interceptor calls")
+	assert.Equal(t, []string{"ok", "appError"}, handled, "Handler should not run for rejected calls")
+}
+
+func TestInterceptorsOutbound(t *testing.T) {
+	server := testutils.NewServer(t, testutils.NewOpts().SetServiceName("svc"))
+	defer server.Close()
+
+	var handled []string
+	handler := func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+		handled = append(handled, args.Method)
+		return &raw.Res{}, nil
+	}
+	testutils.RegisterFunc(server, "ok", handler)
+	testutils.RegisterFunc(server, "reject", handler)
+
+	var log interceptorLog
+	var seen []string
+	opts := testutils.NewOpts().SetServiceName("caller")
+	opts.OutboundInterceptors = []tchannel.OutboundInterceptor{
+		log.newInterceptor("first", nil),
+		log.newInterceptor("second", errors.New("fault injected")),
+		outboundFunc(func(call *tchannel.OutboundCall) {
+			seen = append(seen, call.ServiceName(), call.CallerName(), call.TransportHeaders()[tchannel.CallerName])
+		}),
+	}
+	client := testutils.NewClient(t, opts)
+	defer client.Close()
+
+	call := func(method string) error {
+		ctx, cancel := tchannel.NewContext(time.Second)
+		defer cancel()
+
+		_, _, _, err := raw.Call(ctx, client, server.PeerInfo().HostPort, "svc", method, nil, nil)
+		return err
+	}
+
+	require.NoError(t, call("ok"), "Call failed")
+	assert.Equal(t, []string{
+		"first before ok",
+		"second before ok",
+		"second after ok err=<nil> appErr=false",
+		"first after ok err=<nil> appErr=false",
+	}, log.waitFor(t, 4), "Unexpected interceptor calls")
+	assert.Equal(t, []string{"svc", "caller", "caller"}, seen, "Unexpected call properties")
+
+	err := call("reject")
+	require.Error(t, err, "Rejected call should fail")
+	assert.Equal(t, tchannel.ErrCodeUnexpected, tchannel.GetSystemErrorCode(err), "Unexpected error code")
+	assert.Contains(t, err.Error(), "fault injected", "Unexpected error")
+	assert.Equal(t, []string{
+		"first before reject",
+		"second before reject",
+		"first after reject err=" + err.Error() + " appErr=false",
+	}, log.waitFor(t, 3), "Unexpected interceptor calls")
+	assert.Equal(t, []string{"ok"}, handled, "Rejected calls should not be sent")
+}
@@ -59,6 +59,12 @@ type RuntimeState struct {
 	ID           uint32 `json:"id"`
 	ChannelState string `json:"channelState"`
 
+	// Draining is whether the channel is draining.
+	Draining bool `json:"draining"`
+
+	// ConcurrencyLimit is the state of the channel's inbound concurrency limit.
+	ConcurrencyLimit ConcurrencyLimitState `json:"concurrencyLimit"`
+
 	// CreatedStack is the stack for how this channel was created.
 	CreatedStack string `json:"createdStack"`
 
@@ -120,6 +126,10 @@ type SubChannelRuntimeState struct {
 	// IsolatedPeers is the list of all isolated peers for this channel.
 	IsolatedPeers []SubPeerScore      `json:"isolatedPeers,omitempty"`
 	Handler       HandlerRuntimeState `json:"handler"`
+	// ConcurrencyLimit is the state of the service's inbound concurrency limit, if any.
+	ConcurrencyLimit *ConcurrencyLimitState `json:"concurrencyLimit,omitempty"`
+	// MethodConcurrencyLimits is the state of per-method concurrency limits.
+	MethodConcurrencyLimits map[string]ConcurrencyLimitState `json:"methodConcurrencyLimits,omitempty"`
 }
 
 // HandlerRuntimeState TODO
@@ -159,8 +169,10 @@ type ConnectionRuntimeState struct {
 	LastActivityWrite int64                   `json:"lastActivityWrite"`
 	SendChQueued      int                     `json:"sendChQueued"`
 	SendChCapacity    int                     `json:"sendChCapacity"`
+	SendQueues        map[PriorityClass]int   `json:"sendQueues"`
 	SendBufferUsage   int                     `json:"sendBufferUsage"`
 	SendBufferSize    int                     `json:"sendBufferSize"`
+	ChecksumFailures  uint64                  `json:"checksumFailures"`
 }
 
 // RelayerRuntimeState is the runtime state for a single relayer.
@@ -207,6 +219,9 @@ type PeerRuntimeState struct {
 	InboundConnections  []ConnectionRuntimeState `json:"inboundConnections"`
 	ChosenCount         uint64                   `json:"chosenCount"`
 	SCCount             uint32                   `json:"scCount"`
+	CircuitState        string                   `json:"circuitState"`
+	LatencyEWMA         time.Duration            `json:"latencyEWMA"`
+	ErrorRateEWMA       float64                  `json:"errorRateEWMA"`
 }
 
 // IntrospectState returns the RuntimeState for this channel.
@@ -234,6 +249,8 @@ func (ch *Channel) IntrospectState(opts *IntrospectionOptions) *RuntimeState {
 	return &RuntimeState{
 		ID:                  ch.chID,
 		ChannelState:        state.String(),
+		Draining:            ch.Draining(),
+		ConcurrencyLimit:    ch.limiter.IntrospectState(),
 		CreatedStack:        ch.createdStack,
 		LocalPeer:           ch.PeerInfo(),
 		SubChannels:         ch.subChannels.IntrospectState(opts),
@@ -247,6 +264,22 @@ func (ch *Channel) IntrospectState(opts *IntrospectionOptions) *RuntimeState {
 	}
 }
 
+func (c *SubChannel) introspectLimits(state *SubChannelRuntimeState) {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.limiter != nil {
+		limitState := c.limiter.IntrospectState()
+		state.ConcurrencyLimit = &limitState
+	}
+	if len(c.methodLimiters) > 0 {
+		state.MethodConcurrencyLimits = make(map[string]ConcurrencyLimitState, len(c.methodLimiters))
+		for method, l := range c.methodLimiters {
+			state.MethodConcurrencyLimits[method] = l.IntrospectState()
+		}
+	}
+}
+
 // IntrospectOthers returns the ChannelInfo for all other channels in this process.
 func (ch *Channel) IntrospectOthers(opts *IntrospectionOptions) map[string][]ChannelInfo {
 	if !opts.IncludeOtherChannels {
@@ -323,6 +356,7 @@ func (subChMap *subChannelMap) IntrospectState(opts *IntrospectionOptions) map[s
 		} else {
 			state.Handler.Type = overrideHandler
 		}
+		sc.introspectLimits(&state)
 		m[k] = state
 	}
 	subChMap.RUnlock()
@@ -341,6 +375,8 @@ func getConnectionRuntimeState(conns []*Connection, opts *IntrospectionOptions)
 
 // IntrospectState returns the runtime state for this peer.
 func (p *Peer) IntrospectState(opts *IntrospectionOptions) PeerRuntimeState {
+	latency, errorRate := p.stats.get(p.timeNow())
+
 	p.RLock()
 	defer p.RUnlock()
 
@@ -350,6 +386,9 @@ func (p *Peer) IntrospectState(opts *IntrospectionOptions) PeerRuntimeState {
 		OutboundConnections: getConnectionRuntimeState(p.outboundConnections, opts),
 		ChosenCount:         p.chosenCount.Load(),
 		SCCount:             p.scCount,
+		CircuitState:        p.breaker.State().String(),
+		LatencyEWMA:         latency,
+		ErrorRateEWMA:       errorRate,
 	}
 }
 
@@ -376,8 +415,10 @@ func (c *Connection) IntrospectState(opts *IntrospectionOptions) ConnectionRunti
 		LastActivityWrite: c.lastActivityWrite.Load(),
 		SendChQueued:      len(c.sendCh),
 		SendChCapacity:    cap(c.sendCh),
+		SendQueues:        c.introspectSendQueues(),
 		SendBufferUsage:   sendBufUsage,
 		SendBufferSize:    sendBufSize,
+		ChecksumFailures:  c.checksumFailures.Load(),
 	}
 	if c.relay != nil {
 		state.Relayer = c.relay.IntrospectState(opts)
@@ -392,7 +433,7 @@ func (r *Relayer) IntrospectState(opts *IntrospectionOptions) RelayerRuntimeStat
 		Count:                count,
 		InboundItems:         r.inbound.IntrospectState(opts, "inbound"),
 		OutboundItems:        r.outbound.IntrospectState(opts, "outbound"),
-		MaxTimeout:           r.maxTimeout,
+		MaxTimeout:           r.maxTimeout.Load(),
 		MaxConnectionTimeout: r.maxConnTimeout,
 	}
 }
@@ -534,15 +575,23 @@ func introspectRuntimeVersion() RuntimeVersion {
 // registerInternal registers the following internal handlers which return runtime state:
 //  _gometa_introspect: TChannel internal state.
 //  _gometa_runtime: Golang runtime stats.
-func (ch *Channel) createInternalHandlers() *handlerMap {
+//  _gometa_log_levels: Subsystem log level overrides, see LogLevelsOptions.
+//  _gometa_config: Options that can be changed at runtime, see RuntimeOptions.
+//    Only registered if ChannelOptions.EnableConfigEndpoint is set.
+func (ch *Channel) createInternalHandlers(enableConfig bool) *handlerMap {
 	internalHandlers := &handlerMap{}
 
-	endpoints := []struct {
+	type endpoint struct {
 		name    string
 		handler func([]byte) interface{}
-	}{
+	}
+	endpoints := []endpoint{
 		{"_gometa_introspect", ch.handleIntrospection},
 		{"_gometa_runtime", handleInternalRuntime},
+		{"_gometa_log_levels", ch.handleLogLevels},
+	}
+	if enableConfig {
+		endpoints = append(endpoints, endpoint{"_gometa_config", ch.handleConfig})
 	}
 
 	for _, ep := range endpoints {
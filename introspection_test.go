@@ -171,3 +171,48 @@ func TestIntrospectionNotBlocked(t *testing.T) {
 		require.NoError(t, err, "Call _gometa_runtime failed")
 	})
 }
+
+func TestIntrospectLogLevels(t *testing.T) {
+	opts := testutils.NewOpts().NoRelay()
+	opts.LogLevels = map[tchannel.LogSubsystem]tchannel.LogLevel{
+		tchannel.LogSubsystemPeer: tchannel.LogLevelDebug,
+	}
+	testutils.WithTestServer(t, opts, func(t testing.TB, ts *testutils.TestServer) {
+		client := testutils.NewClient(t, nil)
+		defer client.Close()
+
+		ctx, cancel := json.NewContext(time.Second)
+		defer cancel()
+
+		assert.Equal(t, map[tchannel.LogSubsystem]tchannel.LogLevel{
+			tchannel.LogSubsystemPeer: tchannel.LogLevelDebug,
+		}, ts.Server().LogLevels(), "unexpected overrides from ChannelOptions")
+
+		var resp tchannel.LogLevelsState
+		peer := client.Peers().GetOrAdd(ts.HostPort())
+		err := json.CallPeer(ctx, peer, "tchannel", "_gometa_log_levels", map[string]interface{}{
+			"set":   map[string]string{"relay": "debug", "connection": "warn"},
+			"clear": []string{"peer"},
+		}, &resp)
+		require.NoError(t, err, "Call _gometa_log_levels failed")
+		assert.Len(t, resp.Subsystems, 4, "unexpected subsystems")
+		assert.Equal(t, map[tchannel.LogSubsystem]tchannel.LogLevel{
+			tchannel.LogSubsystemRelay:      tchannel.LogLevelDebug,
+			tchannel.LogSubsystemConnection: tchannel.LogLevelWarn,
+		}, resp.Overrides, "unexpected overrides in response")
+		assert.Equal(t, resp.Overrides, ts.Server().LogLevels(), "overrides not applied to channel")
+
+		var errResp map[string]interface{}
+		err = json.CallPeer(ctx, peer, "tchannel", "_gometa_log_levels", map[string]interface{}{
+			"set": map[string]string{"unknown": "debug"},
+		}, &errResp)
+		require.NoError(t, err, "Call _gometa_log_levels failed")
+		assert.Equal(t, `unknown log subsystem "unknown"`, errResp["error"])
+
+		err = json.CallPeer(ctx, peer, "tchannel", "_gometa_log_levels", map[string]interface{}{
+			"set": map[string]string{"relay": "verbose"},
+		}, &errResp)
+		require.NoError(t, err, "Call _gometa_log_levels failed")
+		assert.Contains(t, errResp["error"], `unknown log level "verbose"`)
+	})
+}
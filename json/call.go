@@ -22,6 +22,8 @@ package json
 
 import (
 	"fmt"
+	"reflect"
+	"sync"
 
 	"github.com/temporalio/tchannel-go"
 
@@ -103,26 +105,50 @@ func (c *Client) Call(ctx Context, method string, arg, resp interface{}) error {
 	var (
 		headers = ctx.Headers()
 
-		respHeaders map[string]string
-		respErr     ErrApplication
-		errAt       string
-		isOK        bool
+		respErr ErrApplication
+		errAt   string
+		isOK    bool
+
+		// Hedged attempts run concurrently, so only the first successful
+		// attempt sets the results.
+		resultsMu  sync.Mutex
+		resultsSet bool
 	)
 
 	err := c.ch.RunWithRetry(ctx, func(ctx context.Context, rs *tchannel.RequestState) error {
-		respHeaders, respErr, isOK = nil, nil, false
-		errAt = "connect"
+		var (
+			attemptResp    = resp
+			attemptHeaders map[string]string
+			attemptErr     ErrApplication
+			attemptOK      bool
+			attemptErrAt   = "connect"
+		)
+		if rs.Hedged() {
+			attemptResp = newResponse(resp)
+		}
 
 		call, err := c.startCall(ctx, method, &tchannel.CallOptions{
 			Format:       tchannel.JSON,
 			RequestState: rs,
 		})
+		if err == nil {
+			attemptOK, attemptErrAt, err = makeCall(call, headers, arg, &attemptHeaders, attemptResp, &attemptErr)
+		}
+
+		resultsMu.Lock()
+		defer resultsMu.Unlock()
 		if err != nil {
+			errAt = attemptErrAt
 			return err
 		}
-
-		isOK, errAt, err = makeCall(call, headers, arg, &respHeaders, resp, &respErr)
-		return err
+		if !resultsSet {
+			resultsSet = true
+			respErr, isOK = attemptErr, attemptOK
+			if rs.Hedged() {
+				copyResponse(resp, attemptResp)
+			}
+		}
+		return nil
 	})
 	if err != nil {
 		// TODO: Don't lose the error type here.
@@ -135,6 +161,23 @@ func (c *Client) Call(ctx Context, method string, arg, resp interface{}) error {
 	return nil
 }
 
+// newResponse returns a new response of the same type as resp, so that
+// concurrent attempts do not read into the same response.
+func newResponse(resp interface{}) interface{} {
+	t := reflect.TypeOf(resp)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return resp
+	}
+	return reflect.New(t.Elem()).Interface()
+}
+
+// copyResponse copies a response created by newResponse into dst.
+func copyResponse(dst, src interface{}) {
+	if v := reflect.ValueOf(dst); v.Kind() == reflect.Ptr && !v.IsNil() {
+		v.Elem().Set(reflect.ValueOf(src).Elem())
+	}
+}
+
 // TODO(prashantv): Clean up json.Call* interfaces.
 func wrapCall(ctx Context, call *tchannel.OutboundCall, method string, arg, resp interface{}) error {
 	var respHeaders map[string]string
@@ -30,6 +30,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+	"golang.org/x/net/context"
 )
 
 func TestRetryJSONCall(t *testing.T) {
@@ -70,3 +72,34 @@ func TestRetryJSONNoConnect(t *testing.T) {
 	require.Error(t, err, "Call should fail")
 	assert.True(t, strings.HasPrefix(err.Error(), "connect: "), "Error does not contain expected prefix: %v", err.Error())
 }
+
+func TestHedgedJSONCall(t *testing.T) {
+	ch := testutils.NewServer(t, nil)
+	ch.Peers().Add(ch.PeerInfo().HostPort)
+
+	var count atomic.Int32
+	handler := func(ctx Context, req map[string]string) (map[string]string, error) {
+		if count.Inc() == 1 {
+			// The first attempt is slow, and is cancelled once the hedged attempt succeeds.
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return req, nil
+	}
+	// The cancelled attempt fails to send its response.
+	Register(ch, Handlers{"test": handler}, func(context.Context, error) {})
+
+	ctx, cancel := tchannel.NewContextBuilder(time.Second).
+		SetRetryOptions(&tchannel.RetryOptions{
+			Hedge: &tchannel.HedgeOptions{Delay: 10 * time.Millisecond},
+		}).Build()
+	defer cancel()
+
+	client := NewClient(ch, ch.ServiceName(), nil)
+
+	var res map[string]string
+	err := client.Call(Wrap(ctx), "test", map[string]string{"k": "v"}, &res)
+	require.NoError(t, err, "Call should succeed")
+	assert.Equal(t, map[string]string{"k": "v"}, res, "Unexpected response")
+	assert.EqualValues(t, 2, count.Load(), "Handler should have been invoked twice")
+}
@@ -0,0 +1,321 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"go.uber.org/atomic"
+)
+
+// LogSubsystem identifies a part of TChannel whose log level can be
+// overridden independently of the channel's Logger.
+type LogSubsystem string
+
+// The subsystems that support log level overrides.
+const (
+	LogSubsystemRelay      LogSubsystem = "relay"
+	LogSubsystemConnection LogSubsystem = "connection"
+	LogSubsystemPeer       LogSubsystem = "peer"
+	LogSubsystemIdleSweep  LogSubsystem = "idle-sweep"
+)
+
+var logSubsystems = []LogSubsystem{
+	LogSubsystemRelay,
+	LogSubsystemConnection,
+	LogSubsystemPeer,
+	LogSubsystemIdleSweep,
+}
+
+// noLogLevelOverride is stored for subsystems that use the Logger's level.
+const noLogLevelOverride = -1
+
+var logLevelNames = map[LogLevel]string{
+	LogLevelAll:   "all",
+	LogLevelDebug: "debug",
+	LogLevelInfo:  "info",
+	LogLevelWarn:  "warn",
+	LogLevelError: "error",
+	LogLevelFatal: "fatal",
+}
+
+func (l LogLevel) String() string {
+	if name, ok := logLevelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("LogLevel(%d)", int(l))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (l LogLevel) MarshalText() ([]byte, error) {
+	if _, ok := logLevelNames[l]; !ok {
+		return nil, fmt.Errorf("invalid log level %d", int(l))
+	}
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (l *LogLevel) UnmarshalText(text []byte) error {
+	for level, name := range logLevelNames {
+		if name == string(text) {
+			*l = level
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown log level %q", text)
+}
+
+// unfilteredLogger is implemented by loggers that filter messages by level,
+// so that a subsystem override can log messages below the logger's level.
+type unfilteredLogger interface {
+	unfiltered() Logger
+}
+
+// logLevels holds the log level overrides for each subsystem.
+type logLevels struct {
+	levels map[LogSubsystem]*atomic.Int32
+}
+
+func newLogLevels(overrides map[LogSubsystem]LogLevel) (*logLevels, error) {
+	ll := &logLevels{levels: make(map[LogSubsystem]*atomic.Int32, len(logSubsystems))}
+	for _, s := range logSubsystems {
+		ll.levels[s] = atomic.NewInt32(noLogLevelOverride)
+	}
+	for s, level := range overrides {
+		if err := ll.set(s, level); err != nil {
+			return nil, err
+		}
+	}
+	return ll, nil
+}
+
+func (ll *logLevels) set(s LogSubsystem, level LogLevel) error {
+	l, ok := ll.levels[s]
+	if !ok {
+		return fmt.Errorf("unknown log subsystem %q", s)
+	}
+	if _, ok := logLevelNames[level]; !ok {
+		return fmt.Errorf("invalid log level %d for subsystem %q", int(level), s)
+	}
+	l.Store(int32(level))
+	return nil
+}
+
+func (ll *logLevels) clear(s LogSubsystem) error {
+	l, ok := ll.levels[s]
+	if !ok {
+		return fmt.Errorf("unknown log subsystem %q", s)
+	}
+	l.Store(noLogLevelOverride)
+	return nil
+}
+
+func (ll *logLevels) overrides() map[LogSubsystem]LogLevel {
+	overrides := make(map[LogSubsystem]LogLevel)
+	for s, l := range ll.levels {
+		if level := l.Load(); level != noLogLevelOverride {
+			overrides[s] = LogLevel(level)
+		}
+	}
+	return overrides
+}
+
+// subsystemLogger applies a subsystem's log level override, if any, to
+// messages logged to the underlying Logger. It is used as a pointer so each
+// connection's Logger has a unique identity.
+type subsystemLogger struct {
+	Logger
+
+	level *atomic.Int32
+}
+
+// withLogSubsystem returns a Logger that uses the log level override for
+// subsystem s. If logger is already scoped to a subsystem, it is replaced.
+func withLogSubsystem(logger Logger, ll *logLevels, s LogSubsystem) Logger {
+	if ll == nil {
+		return logger
+	}
+	if sl, ok := logger.(*subsystemLogger); ok {
+		logger = sl.Logger
+	}
+	return &subsystemLogger{logger, ll.levels[s]}
+}
+
+// logger returns the Logger to use for a message at level, or false if the
+// message should be dropped.
+func (l *subsystemLogger) logger(level LogLevel) (Logger, bool) {
+	override := l.level.Load()
+	if override == noLogLevelOverride {
+		return l.Logger, true
+	}
+	if level < LogLevel(override) {
+		return nil, false
+	}
+	if ul, ok := l.Logger.(unfilteredLogger); ok {
+		return ul.unfiltered(), true
+	}
+	return l.Logger, true
+}
+
+func (l *subsystemLogger) Enabled(level LogLevel) bool {
+	if override := l.level.Load(); override != noLogLevelOverride {
+		return level >= LogLevel(override)
+	}
+	return l.Logger.Enabled(level)
+}
+
+func (l *subsystemLogger) Fatal(msg string) {
+	// Fatal messages exit the process, so they are never dropped.
+	logger, ok := l.logger(LogLevelFatal)
+	if !ok {
+		logger = l.Logger
+	}
+	logger.Fatal(msg)
+}
+
+func (l *subsystemLogger) Error(msg string) {
+	if logger, ok := l.logger(LogLevelError); ok {
+		logger.Error(msg)
+	}
+}
+
+func (l *subsystemLogger) Warn(msg string) {
+	if logger, ok := l.logger(LogLevelWarn); ok {
+		logger.Warn(msg)
+	}
+}
+
+func (l *subsystemLogger) Infof(msg string, args ...interface{}) {
+	if logger, ok := l.logger(LogLevelInfo); ok {
+		logger.Infof(msg, args...)
+	}
+}
+
+func (l *subsystemLogger) Info(msg string) {
+	if logger, ok := l.logger(LogLevelInfo); ok {
+		logger.Info(msg)
+	}
+}
+
+func (l *subsystemLogger) Debugf(msg string, args ...interface{}) {
+	if logger, ok := l.logger(LogLevelDebug); ok {
+		logger.Debugf(msg, args...)
+	}
+}
+
+func (l *subsystemLogger) Debug(msg string) {
+	if logger, ok := l.logger(LogLevelDebug); ok {
+		logger.Debug(msg)
+	}
+}
+
+func (l *subsystemLogger) WithFields(fields ...LogField) Logger {
+	return &subsystemLogger{l.Logger.WithFields(fields...), l.level}
+}
+
+// subsystemLogger returns the channel's Logger for the given subsystem.
+func (ch *Channel) subsystemLogger(s LogSubsystem) Logger {
+	return withLogSubsystem(ch.log, ch.logLevels, s)
+}
+
+// loggerFor returns the Logger for subsystem s of the given channel.
+// Log level overrides are only supported for a *Channel.
+func loggerFor(ch Connectable, s LogSubsystem) Logger {
+	if c, ok := ch.(*Channel); ok {
+		return c.subsystemLogger(s)
+	}
+	return ch.Logger()
+}
+
+// SetLogLevel overrides the log level for the given subsystem. Messages from
+// the subsystem at or above level are passed to the channel's Logger, even
+// if the Logger was created by NewLevelLogger or NewSlogLogger with a
+// higher level.
+func (ch *Channel) SetLogLevel(s LogSubsystem, level LogLevel) error {
+	return ch.logLevels.set(s, level)
+}
+
+// ClearLogLevel removes the log level override for the given subsystem, so
+// that it uses the channel's Logger's level.
+func (ch *Channel) ClearLogLevel(s LogSubsystem) error {
+	return ch.logLevels.clear(s)
+}
+
+// LogLevels returns the subsystems with log level overrides.
+func (ch *Channel) LogLevels() map[LogSubsystem]LogLevel {
+	return ch.logLevels.overrides()
+}
+
+// LogLevelsOptions are the options for the _gometa_log_levels endpoint.
+type LogLevelsOptions struct {
+	// Set overrides the log level for the given subsystems.
+	Set map[LogSubsystem]LogLevel `json:"set"`
+
+	// Clear removes the log level override for the given subsystems.
+	Clear []LogSubsystem `json:"clear"`
+}
+
+// LogLevelsState is the response for the _gometa_log_levels endpoint.
+type LogLevelsState struct {
+	// Subsystems lists the subsystems that support log level overrides.
+	Subsystems []LogSubsystem `json:"subsystems"`
+
+	// Overrides are the current log level overrides.
+	Overrides map[LogSubsystem]LogLevel `json:"overrides"`
+}
+
+func (ch *Channel) handleLogLevels(arg3 []byte) interface{} {
+	var opts LogLevelsOptions
+	if len(arg3) > 0 {
+		if err := json.Unmarshal(arg3, &opts); err != nil {
+			return map[string]string{"error": err.Error()}
+		}
+	}
+
+	for _, s := range opts.Clear {
+		if err := ch.ClearLogLevel(s); err != nil {
+			return map[string]string{"error": err.Error()}
+		}
+	}
+	// Apply overrides in a consistent order, so any error is deterministic.
+	set := make([]LogSubsystem, 0, len(opts.Set))
+	for s := range opts.Set {
+		set = append(set, s)
+	}
+	sort.Slice(set, func(i, j int) bool { return set[i] < set[j] })
+	for _, s := range set {
+		if err := ch.SetLogLevel(s, opts.Set[s]); err != nil {
+			return map[string]string{"error": err.Error()}
+		}
+	}
+	if len(opts.Clear) > 0 || len(opts.Set) > 0 {
+		ch.log.WithFields(
+			LogField{"overrides", ch.LogLevels()},
+		).Info("Updated subsystem log levels.")
+	}
+
+	return LogLevelsState{
+		Subsystems: logSubsystems,
+		Overrides:  ch.LogLevels(),
+	}
+}
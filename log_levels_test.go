@@ -0,0 +1,102 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogLevelText(t *testing.T) {
+	for level := LogLevelAll; level <= LogLevelFatal; level++ {
+		text, err := level.MarshalText()
+		require.NoError(t, err, "MarshalText(%v) failed", level)
+
+		var got LogLevel
+		require.NoError(t, got.UnmarshalText(text), "UnmarshalText(%s) failed", text)
+		assert.Equal(t, level, got, "LogLevel text round trip")
+	}
+
+	_, err := LogLevel(100).MarshalText()
+	assert.Error(t, err, "MarshalText should fail for unknown levels")
+
+	var level LogLevel
+	assert.Error(t, level.UnmarshalText([]byte("verbose")), "UnmarshalText should fail for unknown names")
+}
+
+func TestSubsystemLogger(t *testing.T) {
+	var buf bytes.Buffer
+	ll, err := newLogLevels(nil)
+	require.NoError(t, err, "newLogLevels failed")
+
+	base := NewLevelLogger(NewLogger(&buf), LogLevelWarn)
+	relayLogger := withLogSubsystem(base, ll, LogSubsystemRelay).WithFields(LogField{"k", "v"})
+	peerLogger := withLogSubsystem(base, ll, LogSubsystemPeer)
+
+	logAll := func(logger Logger) int {
+		buf.Reset()
+		logger.Debug("debug")
+		logger.Debugf("debu%v", "g")
+		logger.Info("info")
+		logger.Infof("inf%v", "o")
+		logger.Warn("warn")
+		logger.Error("error")
+		return bytes.Count(buf.Bytes(), []byte{'\n'})
+	}
+
+	assert.Equal(t, 2, logAll(relayLogger), "relay should use the Logger's level without an override")
+	assert.False(t, relayLogger.Enabled(LogLevelDebug), "relay debug should be disabled")
+
+	require.NoError(t, ll.set(LogSubsystemRelay, LogLevelDebug))
+	assert.Equal(t, 6, logAll(relayLogger), "relay override should log below the Logger's level")
+	assert.True(t, relayLogger.Enabled(LogLevelDebug), "relay debug should be enabled")
+	assert.Contains(t, buf.String(), "{k v}", "fields should be kept")
+	assert.Equal(t, 2, logAll(peerLogger), "override should not affect other subsystems")
+
+	require.NoError(t, ll.set(LogSubsystemRelay, LogLevelError))
+	assert.Equal(t, 1, logAll(relayLogger), "relay override should drop messages above the Logger's level")
+
+	require.NoError(t, ll.clear(LogSubsystemRelay))
+	assert.Equal(t, 2, logAll(relayLogger), "relay should use the Logger's level after clearing")
+	assert.Empty(t, ll.overrides(), "overrides should be empty")
+
+	// Replacing the subsystem should not stack overrides.
+	require.NoError(t, ll.set(LogSubsystemPeer, LogLevelError))
+	assert.Equal(t, 2, logAll(withLogSubsystem(peerLogger, ll, LogSubsystemRelay)))
+
+	assert.Equal(t, base, withLogSubsystem(base, nil, LogSubsystemRelay), "nil logLevels should not wrap")
+}
+
+func TestSetLogLevelErrors(t *testing.T) {
+	_, err := newLogLevels(map[LogSubsystem]LogLevel{"unknown": LogLevelInfo})
+	assert.Error(t, err, "unknown subsystem should fail")
+
+	ll, err := newLogLevels(map[LogSubsystem]LogLevel{LogSubsystemConnection: LogLevelDebug})
+	require.NoError(t, err, "newLogLevels failed")
+	assert.Equal(t, map[LogSubsystem]LogLevel{LogSubsystemConnection: LogLevelDebug}, ll.overrides())
+
+	assert.Error(t, ll.set(LogSubsystemPeer, LogLevel(100)), "invalid level should fail")
+	assert.Error(t, ll.set("unknown", LogLevelInfo), "unknown subsystem should fail")
+	assert.Error(t, ll.clear("unknown"), "unknown subsystem should fail")
+}
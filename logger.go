@@ -221,6 +221,10 @@ func (l levelLogger) Fields() LogFields {
 	return l.logger.Fields()
 }
 
+func (l levelLogger) unfiltered() Logger {
+	return l.logger
+}
+
 func (l levelLogger) WithFields(fields ...LogField) Logger {
 	return levelLogger{
 		logger: l.logger.WithFields(fields...),
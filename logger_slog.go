@@ -0,0 +1,245 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+//go:build go1.21
+// +build go1.21
+
+package tchannel
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"time"
+)
+
+// SlogLevelFatal is the slog level used for messages logged with Fatal.
+const SlogLevelFatal = slog.Level(12)
+
+// slogLogger is a Logger that writes to a slog.Handler.
+type slogLogger struct {
+	handler slog.Handler
+	fields  LogFields
+
+	// ignoreLevel is set when a subsystem log level override applies,
+	// so the handler's level is not checked.
+	ignoreLevel bool
+}
+
+// NewSlogLogger returns a Logger that writes to the given slog.Handler.
+// LogFields are passed to the handler as attributes, and LogLevels are
+// mapped to the equivalent slog levels, with Fatal using SlogLevelFatal.
+func NewSlogLogger(handler slog.Handler, fields ...LogField) Logger {
+	return slogLogger{
+		handler: handler.WithAttrs(fieldsToAttrs(fields)),
+		fields:  fields,
+	}
+}
+
+func (l slogLogger) Enabled(level LogLevel) bool {
+	return l.ignoreLevel || l.handler.Enabled(context.Background(), slogLevel(level))
+}
+
+func (l slogLogger) Fatal(msg string) {
+	l.log(LogLevelFatal, msg)
+	os.Exit(1)
+}
+
+func (l slogLogger) Error(msg string)                       { l.log(LogLevelError, msg) }
+func (l slogLogger) Warn(msg string)                        { l.log(LogLevelWarn, msg) }
+func (l slogLogger) Infof(msg string, args ...interface{})  { l.logf(LogLevelInfo, msg, args...) }
+func (l slogLogger) Info(msg string)                        { l.log(LogLevelInfo, msg) }
+func (l slogLogger) Debugf(msg string, args ...interface{}) { l.logf(LogLevelDebug, msg, args...) }
+func (l slogLogger) Debug(msg string)                       { l.log(LogLevelDebug, msg) }
+
+func (l slogLogger) logf(level LogLevel, msg string, args ...interface{}) {
+	if l.Enabled(level) {
+		l.write(level, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l slogLogger) log(level LogLevel, msg string) {
+	if l.Enabled(level) {
+		l.write(level, msg)
+	}
+}
+
+func (l slogLogger) write(level LogLevel, msg string) {
+	// Skip runtime.Callers, write, log or logf, and the Logger method.
+	var pcs [1]uintptr
+	runtime.Callers(4, pcs[:])
+
+	r := slog.NewRecord(time.Now(), slogLevel(level), msg, pcs[0])
+	l.handler.Handle(context.Background(), r)
+}
+
+func (l slogLogger) Fields() LogFields {
+	return l.fields
+}
+
+func (l slogLogger) WithFields(fields ...LogField) Logger {
+	newFields := make(LogFields, 0, len(l.fields)+len(fields))
+	newFields = append(newFields, l.fields...)
+	newFields = append(newFields, fields...)
+	return slogLogger{
+		handler:     l.handler.WithAttrs(fieldsToAttrs(fields)),
+		fields:      newFields,
+		ignoreLevel: l.ignoreLevel,
+	}
+}
+
+func (l slogLogger) unfiltered() Logger {
+	l.ignoreLevel = true
+	return l
+}
+
+func fieldsToAttrs(fields LogFields) []slog.Attr {
+	attrs := make([]slog.Attr, len(fields))
+	for i, f := range fields {
+		attrs[i] = slog.Any(f.Key, f.Value)
+	}
+	return attrs
+}
+
+// slogLevel returns the slog level for a LogLevel.
+func slogLevel(level LogLevel) slog.Level {
+	switch level {
+	case LogLevelAll:
+		return slog.LevelDebug - 4
+	case LogLevelDebug:
+		return slog.LevelDebug
+	case LogLevelInfo:
+		return slog.LevelInfo
+	case LogLevelWarn:
+		return slog.LevelWarn
+	case LogLevelError:
+		return slog.LevelError
+	default:
+		return SlogLevelFatal
+	}
+}
+
+// logLevelFromSlog returns the LogLevel for a slog level.
+func logLevelFromSlog(level slog.Level) LogLevel {
+	switch {
+	case level >= SlogLevelFatal:
+		return LogLevelFatal
+	case level >= slog.LevelError:
+		return LogLevelError
+	case level >= slog.LevelWarn:
+		return LogLevelWarn
+	case level >= slog.LevelInfo:
+		return LogLevelInfo
+	case level >= slog.LevelDebug:
+		return LogLevelDebug
+	default:
+		return LogLevelAll
+	}
+}
+
+// loggerHandler is a slog.Handler that writes to a Logger.
+type loggerHandler struct {
+	logger Logger
+	prefix string
+}
+
+// NewSlogHandler returns a slog.Handler that writes to the given Logger.
+// Attributes are passed as LogFields, with keys in groups joined by ".".
+// Records at SlogLevelFatal or above are logged as errors, as the handler
+// must not exit the process.
+func NewSlogHandler(logger Logger) slog.Handler {
+	return loggerHandler{logger: logger}
+}
+
+func (h loggerHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.Enabled(logLevelFromSlog(level))
+}
+
+func (h loggerHandler) Handle(_ context.Context, r slog.Record) error {
+	fields := make(LogFields, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendAttrFields(fields, h.prefix, a)
+		return true
+	})
+
+	logger := h.logger
+	if len(fields) > 0 {
+		logger = logger.WithFields(fields...)
+	}
+
+	switch logLevelFromSlog(r.Level) {
+	case LogLevelFatal, LogLevelError:
+		logger.Error(r.Message)
+	case LogLevelWarn:
+		logger.Warn(r.Message)
+	case LogLevelInfo:
+		logger.Info(r.Message)
+	default:
+		logger.Debug(r.Message)
+	}
+	return nil
+}
+
+func (h loggerHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var fields LogFields
+	for _, a := range attrs {
+		fields = appendAttrFields(fields, h.prefix, a)
+	}
+	if len(fields) == 0 {
+		return h
+	}
+	return loggerHandler{
+		logger: h.logger.WithFields(fields...),
+		prefix: h.prefix,
+	}
+}
+
+func (h loggerHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return loggerHandler{
+		logger: h.logger,
+		prefix: h.prefix + name + ".",
+	}
+}
+
+// appendAttrFields appends a as LogFields, flattening groups.
+func appendAttrFields(fields LogFields, prefix string, a slog.Attr) LogFields {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	if a.Value.Kind() != slog.KindGroup {
+		return append(fields, LogField{prefix + a.Key, a.Value.Any()})
+	}
+
+	// Groups without a key are inlined.
+	if a.Key != "" {
+		prefix += a.Key + "."
+	}
+	for _, ga := range a.Value.Group() {
+		fields = appendAttrFields(fields, prefix, ga)
+	}
+	return fields
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+//go:build go1.21
+// +build go1.21
+
+package tchannel_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/temporalio/tchannel-go"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeSlogLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var m map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &m), "failed to decode %q", line)
+		lines = append(lines, m)
+	}
+	return lines
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	handler := slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		AddSource: true,
+		Level:     slog.LevelInfo,
+	})
+	logger := tchannel.NewSlogLogger(handler, field("service", "svc")).WithFields(field("peer", "1.1.1.1:1"))
+
+	assert.Equal(t, tchannel.LogFields{field("service", "svc"), field("peer", "1.1.1.1:1")}, logger.Fields())
+	assert.False(t, logger.Enabled(tchannel.LogLevelDebug), "debug should be disabled")
+	assert.True(t, logger.Enabled(tchannel.LogLevelInfo), "info should be enabled")
+
+	logger.Debug("debug")
+	logger.Debugf("debu%v", "g")
+	logger.Infof("inf%v", "o")
+	logger.Warn("warn")
+	logger.Error("error")
+
+	lines := decodeSlogLines(t, &buf)
+	require.Len(t, lines, 3, "unexpected number of lines")
+	assert.Equal(t, "info", lines[0]["msg"])
+	assert.Equal(t, "INFO", lines[0]["level"])
+	assert.Equal(t, "WARN", lines[1]["level"])
+	assert.Equal(t, "ERROR", lines[2]["level"])
+	for _, line := range lines {
+		assert.Equal(t, "svc", line["service"], "missing service field")
+		assert.Equal(t, "1.1.1.1:1", line["peer"], "missing peer field")
+
+		source, _ := line["source"].(map[string]interface{})
+		assert.Contains(t, source["file"], "logger_slog_test.go", "source should be the caller")
+	}
+}
+
+func TestSlogLoggerLogLevelOverride(t *testing.T) {
+	var buf bytes.Buffer
+	handler := slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn})
+
+	opts := &tchannel.ChannelOptions{
+		Logger: tchannel.NewSlogLogger(handler),
+		LogLevels: map[tchannel.LogSubsystem]tchannel.LogLevel{
+			tchannel.LogSubsystemIdleSweep: tchannel.LogLevelDebug,
+		},
+	}
+	ch, err := tchannel.NewChannel("svc", opts)
+	require.NoError(t, err, "NewChannel failed")
+	defer ch.Close()
+
+	assert.Equal(t, map[tchannel.LogSubsystem]tchannel.LogLevel{
+		tchannel.LogSubsystemIdleSweep: tchannel.LogLevelDebug,
+	}, ch.LogLevels())
+	assert.Error(t, ch.SetLogLevel("unknown", tchannel.LogLevelDebug), "unknown subsystem should fail")
+	assert.Error(t, ch.SetLogLevel(tchannel.LogSubsystemRelay, tchannel.LogLevel(100)), "invalid level should fail")
+
+	require.NoError(t, ch.SetLogLevel(tchannel.LogSubsystemRelay, tchannel.LogLevelInfo))
+	require.NoError(t, ch.ClearLogLevel(tchannel.LogSubsystemIdleSweep))
+	assert.Equal(t, map[tchannel.LogSubsystem]tchannel.LogLevel{
+		tchannel.LogSubsystemRelay: tchannel.LogLevelInfo,
+	}, ch.LogLevels())
+}
+
+func TestSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(tchannel.NewSlogHandler(tchannel.NewLevelLogger(tchannel.NewLogger(&buf), tchannel.LogLevelInfo)))
+
+	assert.False(t, logger.Enabled(context.Background(), slog.LevelDebug), "debug should be disabled")
+	assert.True(t, logger.Enabled(context.Background(), slog.LevelInfo), "info should be enabled")
+
+	logger.Debug("debug message")
+	logger.With("k1", "v1").WithGroup("g").Info("info message", "k2", 2, slog.Group("inner", "k3", true))
+	logger.Warn("warn message", slog.Group("", "inlined", "x"))
+	logger.Log(context.Background(), tchannel.SlogLevelFatal, "fatal message")
+
+	out := buf.String()
+	assert.NotContains(t, out, "debug message", "debug should be filtered")
+	assert.Contains(t, out, "[I] info message tags: [{k1 v1} {g.k2 2} {g.inner.k3 true}]")
+	assert.Contains(t, out, "[W] warn message tags: [{inlined x}]")
+	assert.Contains(t, out, "[E] fatal message", "fatal should be logged as an error")
+}
@@ -36,6 +36,8 @@ const (
 	messageTypeCallRes         messageType = 0x04
 	messageTypeCallReqContinue messageType = 0x13
 	messageTypeCallResContinue messageType = 0x14
+	messageTypeCancel          messageType = 0xc0
+	messageTypeCredit          messageType = 0xc2
 	messageTypePingReq         messageType = 0xd0
 	messageTypePingRes         messageType = 0xd1
 	messageTypeError           messageType = 0xFF
@@ -78,6 +80,12 @@ const (
 	InitParamTChannelLanguageVersion = "tchannel_language_version"
 	// InitParamTChannelVersion contains the library version.
 	InitParamTChannelVersion = "tchannel_version"
+	// InitParamCompression contains the comma-separated list of compression
+	// types the peer supports in an initReq, and the selected type in an initRes.
+	InitParamCompression = "tchannel_compression"
+	// InitParamFlowControl contains the number of fragments the peer is willing
+	// to buffer for each message exchange before it grants more credits.
+	InitParamFlowControl = "tchannel_flow_control"
 )
 
 // initMessage is the base for messages in the initialization handshake
@@ -175,6 +183,26 @@ const (
 	// requested service. A relay may use the routing key over the service if
 	// it knows about traffic groups.
 	RoutingKey TransportHeaderName = "rk"
+
+	// Compression header specifies the compression type used for the arg2 and
+	// arg3 payloads of the call or response.
+	Compression TransportHeaderName = "cmp"
+
+	// Priority header specifies the priority class of the call's frames
+	// relative to other calls on the same connection.
+	Priority TransportHeaderName = "pr"
+
+	// Streaming header marks a call whose arg3 is a sequence of messages
+	// exchanged by both peers. See StreamingCall.
+	Streaming TransportHeaderName = "st"
+
+	// TraceParent header carries the W3C Trace Context traceparent for
+	// formats without application headers, such as raw.
+	TraceParent TransportHeaderName = "traceparent"
+
+	// TraceState header carries the W3C Trace Context tracestate that
+	// accompanies TraceParent.
+	TraceState TransportHeaderName = "tracestate"
 )
 
 // transportHeaders are passed as part of a CallReq/CallRes
@@ -313,6 +341,64 @@ func (m errorMessage) Error() string {
 	return m.AsSystemError().Error()
 }
 
+// cancelMessage is sent by a caller to indicate that it is no longer
+// interested in the response for the call with the same id.
+type cancelMessage struct {
+	id      uint32
+	ttl     time.Duration
+	tracing Span
+	why     string
+}
+
+func (m *cancelMessage) ID() uint32               { return m.id }
+func (m *cancelMessage) messageType() messageType { return messageTypeCancel }
+func (m *cancelMessage) read(r *typed.ReadBuffer) error {
+	m.ttl = time.Duration(r.ReadUint32()) * time.Millisecond
+	m.tracing.read(r)
+	m.why = r.ReadLen16String()
+	return r.Err()
+}
+
+func (m *cancelMessage) write(w *typed.WriteBuffer) error {
+	w.WriteUint32(uint32(m.ttl / time.Millisecond))
+	m.tracing.write(w)
+	w.WriteLen16String(m.why)
+	return w.Err()
+}
+
+// creditMessage is sent by the receiver of call fragments to allow the
+// sender to send more fragments for the call with the same id. It is only
+// sent on connections that negotiated flow control.
+type creditMessage struct {
+	id      uint32
+	credits uint32
+
+	// response is set when the credits are for callRes fragments, and so
+	// are sent by the caller. Otherwise they are for callReq fragments.
+	response bool
+}
+
+// creditResponseFlag is set in the credit flags for response credits.
+const creditResponseFlag = 0x01
+
+func (m *creditMessage) ID() uint32               { return m.id }
+func (m *creditMessage) messageType() messageType { return messageTypeCredit }
+func (m *creditMessage) read(r *typed.ReadBuffer) error {
+	m.credits = r.ReadUint32()
+	m.response = r.ReadSingleByte()&creditResponseFlag != 0
+	return r.Err()
+}
+
+func (m *creditMessage) write(w *typed.WriteBuffer) error {
+	var flags byte
+	if m.response {
+		flags |= creditResponseFlag
+	}
+	w.WriteUint32(m.credits)
+	w.WriteSingleByte(flags)
+	return w.Err()
+}
+
 type pingReq struct {
 	noBodyMsg
 	id uint32
@@ -139,6 +139,40 @@ func TestErrorMessage(t *testing.T) {
 	assertRoundTrip(t, &m, &errorMessage{})
 }
 
+func TestCancelMessage(t *testing.T) {
+	m := cancelMessage{
+		id:  0xDEADBEEF,
+		ttl: 3 * time.Second,
+		tracing: Span{
+			traceID:  294390430934,
+			parentID: 398348934,
+			spanID:   12762782,
+			flags:    0x04,
+		},
+		why: "context canceled",
+	}
+
+	assert.Equal(t, uint32(0xDEADBEEF), m.ID())
+	assert.Equal(t, messageTypeCancel, m.messageType())
+	assert.Equal(t, "messageTypeCancel", m.messageType().String())
+	assertRoundTrip(t, &m, &cancelMessage{id: 0xDEADBEEF})
+}
+
+func TestCreditMessage(t *testing.T) {
+	for _, response := range []bool{false, true} {
+		m := creditMessage{
+			id:       0xDEADBEEF,
+			credits:  16,
+			response: response,
+		}
+
+		assert.Equal(t, uint32(0xDEADBEEF), m.ID())
+		assert.Equal(t, messageTypeCredit, m.messageType())
+		assert.Equal(t, "messageTypeCredit", m.messageType().String())
+		assertRoundTrip(t, &m, &creditMessage{id: 0xDEADBEEF})
+	}
+}
+
 func assertRoundTrip(t *testing.T, expected message, actual message) {
 	w := typed.NewWriteBufferWithSize(1024)
 	require.Nil(t, expected.write(w), fmt.Sprintf("error writing message %v", expected.messageType()))
@@ -97,6 +97,16 @@ type messageExchange struct {
 	mexset    *messageExchangeSet
 	framePool FramePool
 
+	// cancel cancels the handler's context for inbound exchanges.
+	cancel context.CancelFunc
+
+	// priority is the priority class of frames sent for the exchange.
+	priority PriorityClass
+
+	// flow is the flow control state for call exchanges on connections that
+	// negotiated flow control, and nil otherwise.
+	flow *flowControl
+
 	shutdownAtomic atomic.Bool
 	errChNotified  atomic.Bool
 }
@@ -243,6 +253,10 @@ func (mex *messageExchange) shutdown() {
 		mex.errCh.Notify(errMexShutdown)
 	}
 
+	if mex.ctx.Err() == context.Canceled && mex.mexset.onCancelled != nil {
+		mex.mexset.onCancelled(mex)
+	}
+
 	mex.mexset.removeExchange(mex.msgID)
 }
 
@@ -270,6 +284,10 @@ type messageExchangeSet struct {
 	onRemoved func()
 	onAdded   func()
 
+	// onCancelled is called when an exchange is shutdown after its
+	// context was cancelled.
+	onCancelled func(mex *messageExchange)
+
 	// maps are mutable, and are protected by the mutex.
 	exchanges        map[uint32]*messageExchange
 	expiredExchanges map[uint32]struct{}
@@ -302,11 +320,17 @@ func (mexset *messageExchangeSet) addExchange(mex *messageExchange) error {
 
 // newExchange creates and adds a new message exchange to this set
 func (mexset *messageExchangeSet) newExchange(ctx context.Context, framePool FramePool,
-	msgType messageType, msgID uint32, bufferSize int) (*messageExchange, error) {
+	msgType messageType, msgID uint32, bufferSize int, flow *flowControl) (*messageExchange, error) {
 	if mexset.log.Enabled(LogLevelDebug) {
 		mexset.log.Debugf("Creating new %s message exchange for [%v:%d]", mexset.name, msgType, msgID)
 	}
 
+	// The peer can send a window of fragments before waiting for credits,
+	// so buffer them all rather than blocking the connection's read loop.
+	if flow != nil && flow.recvWindow > bufferSize {
+		bufferSize = flow.recvWindow
+	}
+
 	mex := &messageExchange{
 		msgType:   msgType,
 		msgID:     msgID,
@@ -315,6 +339,7 @@ func (mexset *messageExchangeSet) newExchange(ctx context.Context, framePool Fra
 		errCh:     newErrNotifier(),
 		mexset:    mexset,
 		framePool: framePool,
+		flow:      flow,
 	}
 
 	mexset.Lock()
@@ -405,6 +430,21 @@ func (mexset *messageExchangeSet) expireExchange(msgID uint32) {
 	mexset.onRemoved()
 }
 
+// cancelExchange cancels the context of the exchange with the given ID, and
+// returns whether the exchange was found.
+func (mexset *messageExchangeSet) cancelExchange(msgID uint32) bool {
+	mexset.RLock()
+	mex := mexset.exchanges[msgID]
+	mexset.RUnlock()
+
+	if mex == nil || mex.cancel == nil {
+		return false
+	}
+
+	mex.cancel()
+	return true
+}
+
 func (mexset *messageExchangeSet) count() int {
 	mexset.RLock()
 	count := len(mexset.exchanges)
@@ -0,0 +1,47 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+/*
+Package otelbridge connects TChannel to OpenTelemetry.
+
+NewTracer returns an OpenTracing Tracer, to be used as ChannelOptions.Tracer,
+that records spans with an OpenTelemetry TracerProvider. Spans are propagated
+using W3C Trace Context: in application headers for Thrift and JSON calls, and
+in the traceparent and tracestate transport headers for all other calls.
+Relays forward both unchanged. Callers that only support Zipkin-style trace
+IDs, such as those using Jaeger, are interoperable: their sampled traces are
+continued using the IDs in the call frame, and outbound calls set those IDs
+from the lower 64 bits of the OpenTelemetry trace ID.
+
+NewStatsReporter returns a StatsReporter, to be used as
+ChannelOptions.StatsReporter, that records metrics with an OpenTelemetry
+MeterProvider.
+
+	tp := sdktrace.NewTracerProvider(...)
+	mp := sdkmetric.NewMeterProvider(...)
+	ch, err := tchannel.NewChannel("svc", &tchannel.ChannelOptions{
+		Tracer:        otelbridge.NewTracer(tp, otelbridge.TracerOptions{}),
+		StatsReporter: otelbridge.NewStatsReporter(mp),
+	})
+
+This package is a separate module, as OpenTelemetry requires a newer version
+of Go than TChannel.
+*/
+package otelbridge
@@ -0,0 +1,177 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package otelbridge
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// defaultEventName is the name of events for logs without an "event" field.
+const defaultEventName = "log"
+
+// span is an OpenTracing Span that wraps an OpenTelemetry span.
+type span struct {
+	tracer   *Tracer
+	otelSpan trace.Span
+
+	sync.RWMutex
+	baggage map[string]string
+}
+
+var _ opentracing.Span = (*span)(nil)
+
+// OTelSpan returns the OpenTelemetry span for an OpenTracing span created by
+// a Tracer, or nil for spans created by other tracers.
+func OTelSpan(s opentracing.Span) trace.Span {
+	if s, ok := s.(*span); ok {
+		return s.otelSpan
+	}
+	return nil
+}
+
+func (s *span) Finish() {
+	s.FinishWithOptions(opentracing.FinishOptions{})
+}
+
+func (s *span) FinishWithOptions(opts opentracing.FinishOptions) {
+	for _, lr := range opts.LogRecords {
+		s.logFields(lr.Timestamp, lr.Fields)
+	}
+	for _, ld := range opts.BulkLogData {
+		lr := ld.ToLogRecord()
+		s.logFields(lr.Timestamp, lr.Fields)
+	}
+
+	var endOpts []trace.SpanEndOption
+	if !opts.FinishTime.IsZero() {
+		endOpts = append(endOpts, trace.WithTimestamp(opts.FinishTime))
+	}
+	s.otelSpan.End(endOpts...)
+}
+
+func (s *span) Context() opentracing.SpanContext {
+	s.RLock()
+	defer s.RUnlock()
+
+	c := spanContext{otelContext: s.otelSpan.SpanContext()}
+	if len(s.baggage) > 0 {
+		c.baggage = make(map[string]string, len(s.baggage))
+		for k, v := range s.baggage {
+			c.baggage[k] = v
+		}
+	}
+	return c
+}
+
+func (s *span) SetOperationName(operationName string) opentracing.Span {
+	s.otelSpan.SetName(operationName)
+	return s
+}
+
+// SetTag sets an attribute on the span. An "error" tag of true sets the span
+// status to Error.
+func (s *span) SetTag(key string, value interface{}) opentracing.Span {
+	if key == string(ext.Error) {
+		if isErr, ok := value.(bool); ok {
+			if isErr {
+				s.otelSpan.SetStatus(codes.Error, "")
+			}
+			return s
+		}
+	}
+	s.otelSpan.SetAttributes(toAttribute(key, value))
+	return s
+}
+
+func (s *span) LogFields(fields ...log.Field) {
+	s.logFields(time.Time{}, fields)
+}
+
+func (s *span) LogKV(alternatingKeyValues ...interface{}) {
+	fields, err := log.InterleavedKVToFields(alternatingKeyValues...)
+	if err != nil {
+		fields = []log.Field{log.Error(err), log.String("function", "LogKV")}
+	}
+	s.logFields(time.Time{}, fields)
+}
+
+// logFields adds an event to the span, named after the "event" field.
+func (s *span) logFields(timestamp time.Time, fields []log.Field) {
+	name := defaultEventName
+	attrs := make([]attribute.KeyValue, 0, len(fields))
+	for _, f := range fields {
+		if f.Key() == "event" {
+			name = fmt.Sprint(f.Value())
+			continue
+		}
+		attrs = append(attrs, toAttribute(f.Key(), f.Value()))
+	}
+
+	opts := []trace.EventOption{trace.WithAttributes(attrs...)}
+	if !timestamp.IsZero() {
+		opts = append(opts, trace.WithTimestamp(timestamp))
+	}
+	s.otelSpan.AddEvent(name, opts...)
+}
+
+func (s *span) SetBaggageItem(restrictedKey, value string) opentracing.Span {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.baggage == nil {
+		s.baggage = make(map[string]string)
+	}
+	s.baggage[restrictedKey] = value
+	return s
+}
+
+func (s *span) BaggageItem(restrictedKey string) string {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.baggage[restrictedKey]
+}
+
+func (s *span) Tracer() opentracing.Tracer {
+	return s.tracer
+}
+
+func (s *span) LogEvent(event string) {
+	s.LogFields(log.String("event", event))
+}
+
+func (s *span) LogEventWithPayload(event string, payload interface{}) {
+	s.LogFields(log.String("event", event), log.Object("payload", payload))
+}
+
+func (s *span) Log(ld opentracing.LogData) {
+	lr := ld.ToLogRecord()
+	s.logFields(lr.Timestamp, lr.Fields)
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package otelbridge
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/temporalio/tchannel-go"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// StatsReporter is a StatsReporter that records metrics using OpenTelemetry.
+// Counters are recorded as Int64Counters, gauges as Int64Gauges, and timers as
+// Float64Histograms in seconds. TChannel's tags are recorded as attributes.
+type StatsReporter struct {
+	meter metric.Meter
+
+	sync.RWMutex
+	counters map[string]metric.Int64Counter
+	gauges   map[string]metric.Int64Gauge
+	timers   map[string]metric.Float64Histogram
+}
+
+var _ tchannel.StatsReporter = (*StatsReporter)(nil)
+
+// NewStatsReporter returns a StatsReporter that records metrics using the
+// given provider.
+func NewStatsReporter(provider metric.MeterProvider) *StatsReporter {
+	return &StatsReporter{
+		meter:    provider.Meter(instrumentationName, metric.WithInstrumentationVersion(tchannel.VersionInfo)),
+		counters: make(map[string]metric.Int64Counter),
+		gauges:   make(map[string]metric.Int64Gauge),
+		timers:   make(map[string]metric.Float64Histogram),
+	}
+}
+
+// IncCounter adds value to the counter for the given name and tags.
+func (r *StatsReporter) IncCounter(name string, tags map[string]string, value int64) {
+	r.getCounter(name).Add(context.Background(), value, attributes(tags))
+}
+
+// UpdateGauge records value for the gauge for the given name and tags.
+func (r *StatsReporter) UpdateGauge(name string, tags map[string]string, value int64) {
+	r.getGauge(name).Record(context.Background(), value, attributes(tags))
+}
+
+// RecordTimer records d, in seconds, in the histogram for the given name and tags.
+func (r *StatsReporter) RecordTimer(name string, tags map[string]string, d time.Duration) {
+	r.getTimer(name).Record(context.Background(), d.Seconds(), attributes(tags))
+}
+
+// getCounter returns the counter for name, creating it if needed. Errors
+// creating instruments are reported to the global error handler, as the meter
+// still returns a usable instrument.
+func (r *StatsReporter) getCounter(name string) metric.Int64Counter {
+	r.RLock()
+	c, ok := r.counters[name]
+	r.RUnlock()
+	if ok {
+		return c
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	if c, ok := r.counters[name]; ok {
+		return c
+	}
+	c, err := r.meter.Int64Counter(name)
+	if err != nil {
+		otel.Handle(err)
+	}
+	r.counters[name] = c
+	return c
+}
+
+func (r *StatsReporter) getGauge(name string) metric.Int64Gauge {
+	r.RLock()
+	g, ok := r.gauges[name]
+	r.RUnlock()
+	if ok {
+		return g
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	if g, ok := r.gauges[name]; ok {
+		return g
+	}
+	g, err := r.meter.Int64Gauge(name)
+	if err != nil {
+		otel.Handle(err)
+	}
+	r.gauges[name] = g
+	return g
+}
+
+func (r *StatsReporter) getTimer(name string) metric.Float64Histogram {
+	r.RLock()
+	t, ok := r.timers[name]
+	r.RUnlock()
+	if ok {
+		return t
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	if t, ok := r.timers[name]; ok {
+		return t
+	}
+	t, err := r.meter.Float64Histogram(name, metric.WithUnit("s"))
+	if err != nil {
+		otel.Handle(err)
+	}
+	r.timers[name] = t
+	return t
+}
+
+func attributes(tags map[string]string) metric.MeasurementOption {
+	attrs := make([]attribute.KeyValue, 0, len(tags))
+	for k, v := range tags {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+	return metric.WithAttributes(attrs...)
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package otelbridge_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/temporalio/tchannel-go/otelbridge"
+	"github.com/temporalio/tchannel-go/raw"
+	"github.com/temporalio/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func collectMetrics(t testing.TB, reader sdkmetric.Reader) map[string]metricdata.Aggregation {
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm), "Collect failed")
+
+	metrics := make(map[string]metricdata.Aggregation)
+	for _, sm := range rm.ScopeMetrics {
+		assert.Equal(t, "github.com/temporalio/tchannel-go/otelbridge", sm.Scope.Name, "Scope name")
+		for _, m := range sm.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+	return metrics
+}
+
+func TestStatsReporter(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	reporter := otelbridge.NewStatsReporter(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+
+	tags := map[string]string{"service": "svc", "target-endpoint": "echo"}
+	reporter.IncCounter("outbound.calls.send", tags, 1)
+	reporter.IncCounter("outbound.calls.send", tags, 2)
+	reporter.UpdateGauge("connections.active", nil, 5)
+	reporter.UpdateGauge("connections.active", nil, 3)
+	reporter.RecordTimer("outbound.calls.latency", tags, 1500*time.Millisecond)
+
+	wantAttrs := attribute.NewSet(attribute.String("service", "svc"), attribute.String("target-endpoint", "echo"))
+	metrics := collectMetrics(t, reader)
+
+	counter, ok := metrics["outbound.calls.send"].(metricdata.Sum[int64])
+	require.True(t, ok, "Counter should be an int64 sum")
+	require.Len(t, counter.DataPoints, 1, "Counter data points")
+	assert.True(t, counter.IsMonotonic, "Counter should be monotonic")
+	assert.Equal(t, int64(3), counter.DataPoints[0].Value, "Counter value")
+	assert.Equal(t, wantAttrs, counter.DataPoints[0].Attributes, "Counter attributes")
+
+	gauge, ok := metrics["connections.active"].(metricdata.Gauge[int64])
+	require.True(t, ok, "Gauge should be an int64 gauge")
+	require.Len(t, gauge.DataPoints, 1, "Gauge data points")
+	assert.Equal(t, int64(3), gauge.DataPoints[0].Value, "Gauge should have the last value")
+
+	timer, ok := metrics["outbound.calls.latency"].(metricdata.Histogram[float64])
+	require.True(t, ok, "Timer should be a float64 histogram")
+	require.Len(t, timer.DataPoints, 1, "Timer data points")
+	assert.Equal(t, uint64(1), timer.DataPoints[0].Count, "Timer count")
+	assert.Equal(t, 1.5, timer.DataPoints[0].Sum, "Timer should be recorded in seconds")
+	assert.Equal(t, wantAttrs, timer.DataPoints[0].Attributes, "Timer attributes")
+}
+
+func TestStatsReporterChannel(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	reporter := otelbridge.NewStatsReporter(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	opts := testutils.NewOpts().SetStatsReporter(reporter).NoRelay()
+
+	testutils.WithTestServer(t, opts, func(t testing.TB, ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+
+		client := ts.NewClient(opts)
+		ctx, cancel := context.WithTimeout(context.Background(), testutils.Timeout(time.Second))
+		defer cancel()
+		_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, nil)
+		require.NoError(t, err, "Call failed")
+	})
+
+	metrics := collectMetrics(t, reader)
+	for _, name := range []string{"outbound.calls.send", "outbound.calls.success", "inbound.calls.recvd", "outbound.calls.latency"} {
+		assert.Contains(t, metrics, name, "Missing metric %v", name)
+	}
+}
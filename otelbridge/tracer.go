@@ -0,0 +1,324 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package otelbridge
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/temporalio/tchannel-go"
+	"github.com/temporalio/tchannel-go/trand"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is the name of the OpenTelemetry tracer and meter.
+const instrumentationName = "github.com/temporalio/tchannel-go/otelbridge"
+
+// idRng generates IDs for unsampled spans, which are not created by the
+// TracerProvider.
+var idRng = trand.NewSeeded()
+
+// TracerOptions are options for NewTracer.
+type TracerOptions struct {
+	// Propagator serializes span contexts and baggage into TChannel headers.
+	// Defaults to W3C Trace Context and W3C Baggage.
+	Propagator propagation.TextMapPropagator
+}
+
+// Tracer is an OpenTracing Tracer that records spans using OpenTelemetry.
+type Tracer struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+var _ opentracing.Tracer = (*Tracer)(nil)
+
+// NewTracer returns a Tracer that records spans using the given provider.
+func NewTracer(provider trace.TracerProvider, opts TracerOptions) *Tracer {
+	if opts.Propagator == nil {
+		opts.Propagator = propagation.NewCompositeTextMapPropagator(
+			propagation.TraceContext{},
+			propagation.Baggage{},
+		)
+	}
+	return &Tracer{
+		tracer:     provider.Tracer(instrumentationName, trace.WithInstrumentationVersion(tchannel.VersionInfo)),
+		propagator: opts.Propagator,
+	}
+}
+
+// StartSpan starts a new OpenTelemetry span. The first ChildOf or FollowsFrom
+// reference is used as the parent, and any others are added as links.
+// The "span.kind" tag sets the span kind, and a "sampling.priority" of 0
+// starts a span that is not recorded.
+func (t *Tracer) StartSpan(operationName string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	var sso opentracing.StartSpanOptions
+	for _, o := range opts {
+		o.Apply(&sso)
+	}
+
+	ctx := context.Background()
+	var (
+		parent *spanContext
+		links  []trace.Link
+		items  = make(map[string]string)
+	)
+	for _, ref := range sso.References {
+		sc, ok := ref.ReferencedContext.(spanContext)
+		if !ok {
+			continue
+		}
+		for k, v := range sc.baggage {
+			items[k] = v
+		}
+		if parent == nil {
+			parent = &sc
+			ctx = trace.ContextWithSpanContext(ctx, sc.otelContext)
+			continue
+		}
+		links = append(links, trace.Link{SpanContext: sc.otelContext})
+	}
+
+	var (
+		startOpts []trace.SpanStartOption
+		attrs     []attribute.KeyValue
+		sampled   = true
+	)
+	for k, v := range sso.Tags {
+		switch k {
+		case string(ext.SpanKind):
+			startOpts = append(startOpts, trace.WithSpanKind(spanKind(v)))
+		case string(ext.SamplingPriority):
+			sampled = !isZeroPriority(v)
+		default:
+			attrs = append(attrs, toAttribute(k, v))
+		}
+	}
+	if !sampled {
+		return &span{
+			tracer:   t,
+			otelSpan: trace.SpanFromContext(trace.ContextWithSpanContext(ctx, unsampledContext(parent))),
+			baggage:  items,
+		}
+	}
+
+	startOpts = append(startOpts, trace.WithAttributes(attrs...), trace.WithLinks(links...))
+	if !sso.StartTime.IsZero() {
+		startOpts = append(startOpts, trace.WithTimestamp(sso.StartTime))
+	}
+	_, otelSpan := t.tracer.Start(ctx, operationName, startOpts...)
+	return &span{
+		tracer:   t,
+		otelSpan: otelSpan,
+		baggage:  items,
+	}
+}
+
+// Inject serializes the span context using the propagator. Only the TextMap
+// and HTTPHeaders formats are supported, so TChannel falls back to W3C Trace
+// Context rather than Zipkin-style trace IDs.
+func (t *Tracer) Inject(sc opentracing.SpanContext, format interface{}, carrier interface{}) error {
+	c, ok := sc.(spanContext)
+	if !ok {
+		return opentracing.ErrInvalidSpanContext
+	}
+	if format != opentracing.TextMap && format != opentracing.HTTPHeaders {
+		return opentracing.ErrUnsupportedFormat
+	}
+	writer, ok := carrier.(opentracing.TextMapWriter)
+	if !ok {
+		return opentracing.ErrInvalidCarrier
+	}
+
+	ctx := trace.ContextWithSpanContext(context.Background(), c.otelContext)
+	if len(c.baggage) > 0 {
+		var members []baggage.Member
+		for k, v := range c.baggage {
+			// Keys that are not valid W3C Baggage keys are dropped.
+			if m, err := baggage.NewMemberRaw(k, v); err == nil {
+				members = append(members, m)
+			}
+		}
+		if b, err := baggage.New(members...); err == nil {
+			ctx = baggage.ContextWithBaggage(ctx, b)
+		}
+	}
+	t.propagator.Inject(ctx, textMapWriter{writer})
+	return nil
+}
+
+// Extract deserializes a span context using the propagator. Only the TextMap
+// and HTTPHeaders formats are supported.
+func (t *Tracer) Extract(format interface{}, carrier interface{}) (opentracing.SpanContext, error) {
+	if format != opentracing.TextMap && format != opentracing.HTTPHeaders {
+		return nil, opentracing.ErrUnsupportedFormat
+	}
+	reader, ok := carrier.(opentracing.TextMapReader)
+	if !ok {
+		return nil, opentracing.ErrInvalidCarrier
+	}
+
+	// Propagators expect lower case keys, while HTTPHeaders may be canonicalized.
+	headers := make(propagation.MapCarrier)
+	if err := reader.ForeachKey(func(key, val string) error {
+		headers[strings.ToLower(key)] = val
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	ctx := t.propagator.Extract(context.Background(), headers)
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil, opentracing.ErrSpanContextNotFound
+	}
+	c := spanContext{otelContext: sc}
+	if members := baggage.FromContext(ctx).Members(); len(members) > 0 {
+		c.baggage = make(map[string]string, len(members))
+		for _, m := range members {
+			c.baggage[m.Key()] = m.Value()
+		}
+	}
+	return c, nil
+}
+
+// spanContext is the OpenTracing SpanContext for an OpenTelemetry span.
+type spanContext struct {
+	otelContext trace.SpanContext
+	baggage     map[string]string
+}
+
+// ForeachBaggageItem implements opentracing.SpanContext.
+func (c spanContext) ForeachBaggageItem(handler func(k, v string) bool) {
+	for k, v := range c.baggage {
+		if !handler(k, v) {
+			return
+		}
+	}
+}
+
+// unsampledContext returns a span context for an unsampled child of parent,
+// which may be nil for a root span.
+func unsampledContext(parent *spanContext) trace.SpanContext {
+	var cfg trace.SpanContextConfig
+	if parent != nil {
+		cfg.TraceID = parent.otelContext.TraceID()
+		cfg.TraceState = parent.otelContext.TraceState()
+	} else {
+		binary.BigEndian.PutUint64(cfg.TraceID[:8], randomID())
+		binary.BigEndian.PutUint64(cfg.TraceID[8:], randomID())
+	}
+	binary.BigEndian.PutUint64(cfg.SpanID[:], randomID())
+	return trace.NewSpanContext(cfg)
+}
+
+// randomID returns a random non-zero ID.
+func randomID() uint64 {
+	for {
+		// Int63 is safe for concurrent use, unlike Read.
+		if id := uint64(idRng.Int63()); id != 0 {
+			return id
+		}
+	}
+}
+
+// textMapWriter adapts an OpenTracing TextMapWriter for propagators.
+type textMapWriter struct {
+	opentracing.TextMapWriter
+}
+
+func (textMapWriter) Get(string) string { return "" }
+
+func (textMapWriter) Keys() []string { return nil }
+
+func isZeroPriority(v interface{}) bool {
+	switch v := v.(type) {
+	case uint16:
+		return v == 0
+	case int:
+		return v == 0
+	}
+	return false
+}
+
+func spanKind(v interface{}) trace.SpanKind {
+	var kind string
+	switch v := v.(type) {
+	case ext.SpanKindEnum:
+		kind = string(v)
+	case string:
+		kind = v
+	}
+	switch kind {
+	case string(ext.SpanKindRPCClientEnum):
+		return trace.SpanKindClient
+	case string(ext.SpanKindRPCServerEnum):
+		return trace.SpanKindServer
+	case string(ext.SpanKindProducerEnum):
+		return trace.SpanKindProducer
+	case string(ext.SpanKindConsumerEnum):
+		return trace.SpanKindConsumer
+	}
+	return trace.SpanKindInternal
+}
+
+// toAttribute converts an OpenTracing tag or log field to an attribute.
+func toAttribute(key string, v interface{}) attribute.KeyValue {
+	switch v := v.(type) {
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int8:
+		return attribute.Int(key, int(v))
+	case int16:
+		return attribute.Int(key, int(v))
+	case int32:
+		return attribute.Int(key, int(v))
+	case int64:
+		return attribute.Int64(key, v)
+	case uint8:
+		return attribute.Int(key, int(v))
+	case uint16:
+		return attribute.Int(key, int(v))
+	case uint32:
+		return attribute.Int64(key, int64(v))
+	case float32:
+		return attribute.Float64(key, float64(v))
+	case float64:
+		return attribute.Float64(key, v)
+	case error:
+		return attribute.String(key, v.Error())
+	case fmt.Stringer:
+		return attribute.String(key, v.String())
+	}
+	return attribute.String(key, fmt.Sprint(v))
+}
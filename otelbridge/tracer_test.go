@@ -0,0 +1,415 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package otelbridge_test
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/temporalio/tchannel-go"
+	"github.com/temporalio/tchannel-go/json"
+	"github.com/temporalio/tchannel-go/otelbridge"
+	"github.com/temporalio/tchannel-go/raw"
+	"github.com/temporalio/tchannel-go/testutils"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-client-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/net/context"
+)
+
+const testTraceState = "vendor=value"
+
+type echoRequest struct {
+	Value string
+}
+
+func newTestTracer() (*otelbridge.Tracer, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return otelbridge.NewTracer(provider, otelbridge.TracerOptions{}), recorder
+}
+
+// startRemoteChild starts a span that continues a remote trace with
+// testTraceState. Each call uses a new trace ID, as test servers are run
+// multiple times with the same span recorder.
+func startRemoteChild(t testing.TB, tracer *otelbridge.Tracer) opentracing.Span {
+	parent, err := tracer.Extract(opentracing.TextMap, opentracing.TextMapCarrier{
+		"traceparent": fmt.Sprintf("00-%016x%016x-%016x-01", rand.Uint64(), rand.Uint64(), rand.Uint64()),
+		"tracestate":  testTraceState,
+	})
+	require.NoError(t, err, "Extract failed")
+	return tracer.StartSpan("client", opentracing.ChildOf(parent))
+}
+
+// waitForSpans waits for n ended spans in the trace, as server spans may end
+// after the response has been received.
+func waitForSpans(t testing.TB, recorder *tracetest.SpanRecorder, traceID trace.TraceID, n int) []sdktrace.ReadOnlySpan {
+	getSpans := func() []sdktrace.ReadOnlySpan {
+		var spans []sdktrace.ReadOnlySpan
+		for _, s := range recorder.Ended() {
+			if s.SpanContext().TraceID() == traceID {
+				spans = append(spans, s)
+			}
+		}
+		return spans
+	}
+	testutils.WaitFor(time.Second, func() bool {
+		return len(getSpans()) >= n
+	})
+	spans := getSpans()
+	require.Len(t, spans, n, "Wrong span count")
+	return spans
+}
+
+func spansByKind(spans []sdktrace.ReadOnlySpan, kind trace.SpanKind) []sdktrace.ReadOnlySpan {
+	var matched []sdktrace.ReadOnlySpan
+	for _, s := range spans {
+		if s.SpanKind() == kind {
+			matched = append(matched, s)
+		}
+	}
+	return matched
+}
+
+func attributeValue(s sdktrace.ReadOnlySpan, key string) attribute.Value {
+	for _, kv := range s.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+// zipkinID returns the Zipkin-style ID for the lower 64 bits of an ID.
+func zipkinID(id []byte) uint64 {
+	return binary.BigEndian.Uint64(id[len(id)-8:])
+}
+
+func TestTraceContextPropagation(t *testing.T) {
+	tests := []struct {
+		msg  string
+		opts *testutils.ChannelOpts
+	}{
+		{msg: "default", opts: testutils.NewOpts()},
+		{msg: "relay only", opts: testutils.NewOpts().SetRelayOnly()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			tracer, recorder := newTestTracer()
+			opts := tt.opts.SetTracer(tracer)
+
+			testutils.WithTestServer(t, opts, func(t testing.TB, ts *testutils.TestServer) {
+				var (
+					mu          sync.Mutex
+					handlerCtxs []trace.SpanContext
+					baggage     []string
+				)
+				recordSpan := func(ctx context.Context) {
+					mu.Lock()
+					defer mu.Unlock()
+					span := opentracing.SpanFromContext(ctx)
+					require.NotNil(t, span, "Handler should have a span")
+					handlerCtxs = append(handlerCtxs, otelbridge.OTelSpan(span).SpanContext())
+					baggage = append(baggage, span.BaggageItem("user"))
+				}
+				testutils.RegisterFunc(ts.Server(), "echo", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+					recordSpan(ctx)
+					return &raw.Res{Arg2: args.Arg2, Arg3: args.Arg3}, nil
+				})
+				require.NoError(t, json.Register(ts.Server(), json.Handlers{
+					"echo": func(ctx json.Context, req *echoRequest) (*echoRequest, error) {
+						recordSpan(ctx)
+						return req, nil
+					},
+				}, func(ctx context.Context, err error) { t.Errorf("onError: %v", err) }), "Register failed")
+
+				client := ts.NewClient(opts)
+				span := startRemoteChild(t, tracer)
+				span.SetBaggageItem("user", "alice")
+				ctx, cancel := tchannel.NewContextBuilder(testutils.Timeout(time.Second)).
+					SetParentContext(opentracing.ContextWithSpan(context.Background(), span)).
+					Build()
+				defer cancel()
+
+				_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, nil)
+				require.NoError(t, err, "raw call failed")
+
+				var resp echoRequest
+				peer := client.Peers().GetOrAdd(ts.HostPort())
+				require.NoError(t, json.CallPeer(json.Wrap(ctx), peer, ts.ServiceName(), "echo", &echoRequest{Value: "hello"}, &resp), "json call failed")
+				span.Finish()
+
+				rootCtx := otelbridge.OTelSpan(span).SpanContext()
+				// The relay does not create spans, so there is a client and
+				// server span for each call, and the root span.
+				spans := waitForSpans(t, recorder, rootCtx.TraceID(), 5)
+
+				clientSpans := spansByKind(spans, trace.SpanKindClient)
+				serverSpans := spansByKind(spans, trace.SpanKindServer)
+				require.Len(t, clientSpans, 2, "Expected a client span for each call")
+				require.Len(t, serverSpans, 2, "Expected a server span for each call")
+
+				for i, clientSpan := range clientSpans {
+					assert.Equal(t, rootCtx.SpanID(), clientSpan.Parent().SpanID(), "Client span should be a child of the root span")
+
+					serverSpan := serverSpans[i]
+					assert.Equal(t, clientSpan.SpanContext().SpanID(), serverSpan.Parent().SpanID(),
+						"Server span should be a child of the client span")
+					assert.True(t, serverSpan.Parent().IsRemote(), "Server span should have a remote parent")
+					assert.Equal(t, testTraceState, serverSpan.SpanContext().TraceState().String(), "Trace state should be propagated")
+					assert.Equal(t, "echo", serverSpan.Name(), "Server span name")
+				}
+
+				for _, s := range append(clientSpans, serverSpans...) {
+					assert.Equal(t, "tchannel", attributeValue(s, "rpc.system").AsString(), "rpc.system attribute")
+					assert.Equal(t, ts.ServiceName(), attributeValue(s, "rpc.service").AsString(), "rpc.service attribute")
+					assert.Equal(t, "echo", attributeValue(s, "rpc.method").AsString(), "rpc.method attribute")
+					assert.NotEmpty(t, attributeValue(s, "network.peer.address").AsString(), "network.peer.address attribute")
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				require.Len(t, handlerCtxs, 2, "Expected a handler span for each call")
+				for _, sc := range handlerCtxs {
+					assert.Equal(t, rootCtx.TraceID(), sc.TraceID(), "Handler span trace ID mismatch")
+				}
+				// Raw calls only propagate the trace context in transport headers.
+				assert.Equal(t, []string{"", "alice"}, baggage, "Baggage should be propagated in application headers")
+			})
+		})
+	}
+}
+
+func TestTraceContextNotSampled(t *testing.T) {
+	tracer, recorder := newTestTracer()
+	opts := testutils.NewOpts().SetTracer(tracer)
+
+	testutils.WithTestServer(t, opts, func(t testing.TB, ts *testutils.TestServer) {
+		var serverCtx trace.SpanContext
+		testutils.RegisterFunc(ts.Server(), "echo", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			serverCtx = otelbridge.OTelSpan(opentracing.SpanFromContext(ctx)).SpanContext()
+			return &raw.Res{}, nil
+		})
+
+		client := ts.NewClient(opts)
+		span := startRemoteChild(t, tracer)
+		ctx, cancel := tchannel.NewContextBuilder(testutils.Timeout(time.Second)).
+			SetParentContext(opentracing.ContextWithSpan(context.Background(), span)).
+			DisableTracing().
+			Build()
+		defer cancel()
+
+		_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, nil)
+		require.NoError(t, err, "Call failed")
+		span.Finish()
+
+		rootCtx := otelbridge.OTelSpan(span).SpanContext()
+		assert.Equal(t, rootCtx.TraceID(), serverCtx.TraceID(), "Trace ID mismatch")
+		assert.False(t, serverCtx.IsSampled(), "Server span should not be sampled")
+
+		// Only the root span is recorded.
+		waitForSpans(t, recorder, rootCtx.TraceID(), 1)
+	})
+}
+
+func TestZipkinInterop(t *testing.T) {
+	jaegerReporter := jaeger.NewInMemoryReporter()
+	jaegerTracer, jaegerCloser := jaeger.NewTracer(testutils.DefaultServerName,
+		jaeger.NewConstSampler(true),
+		jaegerReporter)
+	defer jaegerCloser.Close()
+
+	otelTracer, recorder := newTestTracer()
+
+	t.Run("zipkin caller", func(t *testing.T) {
+		opts := testutils.NewOpts().SetTracer(otelTracer)
+		testutils.WithTestServer(t, opts, func(t testing.TB, ts *testutils.TestServer) {
+			jaegerReporter.Reset()
+			testutils.RegisterFunc(ts.Server(), "echo", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+				return &raw.Res{}, nil
+			})
+
+			client := ts.NewClient(testutils.NewOpts().SetTracer(jaegerTracer))
+			span := jaegerTracer.StartSpan("client")
+			defer span.Finish()
+			ctx, cancel := tchannel.NewContextBuilder(testutils.Timeout(time.Second)).
+				SetParentContext(opentracing.ContextWithSpan(context.Background(), span)).
+				Build()
+			defer cancel()
+
+			_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, nil)
+			require.NoError(t, err, "Call failed")
+
+			// The server continues the trace using the Zipkin-style IDs in the frame.
+			testutils.WaitFor(time.Second, func() bool { return len(jaegerReporter.GetSpans()) > 0 })
+			require.Len(t, jaegerReporter.GetSpans(), 1, "Expected a client span")
+			clientCtx := jaegerReporter.GetSpans()[0].Context().(jaeger.SpanContext)
+
+			var traceID trace.TraceID
+			binary.BigEndian.PutUint64(traceID[8:], clientCtx.TraceID().Low)
+			spans := waitForSpans(t, recorder, traceID, 1)
+			assert.Equal(t, trace.SpanKindServer, spans[0].SpanKind(), "Expected a server span")
+			parentID := spans[0].Parent().SpanID()
+			assert.Equal(t, uint64(clientCtx.SpanID()), zipkinID(parentID[:]),
+				"Server span should be a child of the client span")
+		})
+	})
+
+	t.Run("zipkin server", func(t *testing.T) {
+		opts := testutils.NewOpts().SetTracer(jaegerTracer)
+		testutils.WithTestServer(t, opts, func(t testing.TB, ts *testutils.TestServer) {
+			jaegerReporter.Reset()
+			testutils.RegisterFunc(ts.Server(), "echo", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+				return &raw.Res{}, nil
+			})
+
+			client := ts.NewClient(testutils.NewOpts().SetTracer(otelTracer))
+			span := otelTracer.StartSpan("client")
+			ctx, cancel := tchannel.NewContextBuilder(testutils.Timeout(time.Second)).
+				SetParentContext(opentracing.ContextWithSpan(context.Background(), span)).
+				Build()
+			defer cancel()
+
+			_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, nil)
+			require.NoError(t, err, "Call failed")
+			span.Finish()
+
+			// The server only understands the lower 64 bits of the trace ID.
+			rootCtx := otelbridge.OTelSpan(span).SpanContext()
+			clientSpans := spansByKind(waitForSpans(t, recorder, rootCtx.TraceID(), 2), trace.SpanKindClient)
+			require.Len(t, clientSpans, 1, "Expected a client span")
+
+			testutils.WaitFor(time.Second, func() bool { return len(jaegerReporter.GetSpans()) > 0 })
+			require.Len(t, jaegerReporter.GetSpans(), 1, "Expected a server span")
+			serverCtx := jaegerReporter.GetSpans()[0].Context().(jaeger.SpanContext)
+			traceID := rootCtx.TraceID()
+			clientID := clientSpans[0].SpanContext().SpanID()
+			assert.Equal(t, zipkinID(traceID[:]), serverCtx.TraceID().Low, "Trace ID mismatch")
+			assert.Equal(t, zipkinID(clientID[:]), uint64(serverCtx.ParentID()),
+				"Server span should be a child of the client span")
+			assert.True(t, serverCtx.IsSampled(), "Server span should be sampled")
+		})
+	})
+}
+
+func TestTracerInjectExtract(t *testing.T) {
+	tracer, _ := newTestTracer()
+	span := startRemoteChild(t, tracer)
+	span.SetBaggageItem("user", "alice")
+	defer span.Finish()
+
+	headers := opentracing.HTTPHeadersCarrier{}
+	require.NoError(t, tracer.Inject(span.Context(), opentracing.HTTPHeaders, headers), "Inject failed")
+	canonical := opentracing.HTTPHeadersCarrier{}
+	for k, v := range headers {
+		canonical[http.CanonicalHeaderKey(k)] = v
+	}
+
+	sc, err := tracer.Extract(opentracing.HTTPHeaders, canonical)
+	require.NoError(t, err, "Extract failed")
+	child := tracer.StartSpan("child", opentracing.ChildOf(sc))
+	defer child.Finish()
+
+	spanCtx := otelbridge.OTelSpan(span).SpanContext()
+	childCtx := otelbridge.OTelSpan(child).SpanContext()
+	assert.Equal(t, spanCtx.TraceID(), childCtx.TraceID(), "Trace ID mismatch")
+	assert.Equal(t, testTraceState, childCtx.TraceState().String(), "Trace state mismatch")
+	assert.Equal(t, "alice", child.BaggageItem("user"), "Baggage mismatch")
+
+	_, err = tracer.Extract(opentracing.TextMap, opentracing.TextMapCarrier{})
+	assert.Equal(t, opentracing.ErrSpanContextNotFound, err, "Extract without a trace context")
+
+	// TChannel relies on unsupported formats to fall back to W3C Trace Context.
+	assert.Equal(t, opentracing.ErrUnsupportedFormat, tracer.Inject(span.Context(), "zipkin-span-format", nil), "Inject format")
+	_, err = tracer.Extract(opentracing.Binary, nil)
+	assert.Equal(t, opentracing.ErrUnsupportedFormat, err, "Extract format")
+}
+
+func TestTracerSpans(t *testing.T) {
+	tracer, recorder := newTestTracer()
+
+	root := tracer.StartSpan("root")
+	other := tracer.StartSpan("other")
+	other.Finish()
+
+	startTime := time.Unix(1500000000, 0)
+	span := tracer.StartSpan("span",
+		opentracing.ChildOf(root.Context()),
+		opentracing.FollowsFrom(other.Context()),
+		opentracing.StartTime(startTime),
+		ext.SpanKindRPCServer,
+		opentracing.Tag{Key: "peer.port", Value: uint16(1234)},
+	)
+	span.SetOperationName("renamed")
+	span.SetTag("as", "raw")
+	ext.Error.Set(span, true)
+	span.LogKV("event", "retry", "attempt", 2)
+	span.FinishWithOptions(opentracing.FinishOptions{FinishTime: startTime.Add(time.Second)})
+	root.Finish()
+
+	rootCtx := otelbridge.OTelSpan(root).SpanContext()
+	spans := waitForSpans(t, recorder, rootCtx.TraceID(), 2)
+	s := spans[0]
+	assert.Equal(t, "renamed", s.Name(), "Span name")
+	assert.Equal(t, trace.SpanKindServer, s.SpanKind(), "Span kind")
+	assert.Equal(t, rootCtx.SpanID(), s.Parent().SpanID(), "Span should be a child of the first reference")
+	require.Len(t, s.Links(), 1, "Other references should be links")
+	assert.Equal(t, otelbridge.OTelSpan(other).SpanContext(), s.Links()[0].SpanContext, "Link mismatch")
+	assert.Equal(t, startTime, s.StartTime(), "Start time")
+	assert.Equal(t, startTime.Add(time.Second), s.EndTime(), "End time")
+	assert.Equal(t, int64(1234), attributeValue(s, "peer.port").AsInt64(), "peer.port attribute")
+	assert.Equal(t, "raw", attributeValue(s, "as").AsString(), "as attribute")
+	assert.Equal(t, codes.Error, s.Status().Code, "Error tag should set the status")
+	require.Len(t, s.Events(), 1, "Logs should be events")
+	assert.Equal(t, "retry", s.Events()[0].Name, "Event name")
+	assert.Equal(t, []attribute.KeyValue{attribute.Int("attempt", 2)}, s.Events()[0].Attributes, "Event attributes")
+}
+
+func TestTracerNotSampled(t *testing.T) {
+	tracer, recorder := newTestTracer()
+
+	root := tracer.StartSpan("root", opentracing.Tag{Key: string(ext.SamplingPriority), Value: uint16(0)})
+	child := tracer.StartSpan("child", opentracing.ChildOf(root.Context()))
+	child.Finish()
+	root.Finish()
+
+	rootCtx := otelbridge.OTelSpan(root).SpanContext()
+	childCtx := otelbridge.OTelSpan(child).SpanContext()
+	assert.True(t, rootCtx.IsValid(), "Unsampled spans should have valid IDs")
+	assert.False(t, rootCtx.IsSampled(), "Root should not be sampled")
+	assert.Equal(t, rootCtx.TraceID(), childCtx.TraceID(), "Trace ID mismatch")
+	assert.False(t, childCtx.IsSampled(), "Child of an unsampled span should not be sampled")
+	assert.Empty(t, recorder.Ended(), "Unsampled spans should not be recorded")
+}
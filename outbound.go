@@ -47,7 +47,7 @@ func (c *Connection) beginCall(ctx context.Context, serviceName, methodName stri
 		return nil, errConnectionUnknownState{"beginCall", state}
 	}
 
-	deadline, ok := ctx.Deadline()
+	timeToLive, ok := c.outboundTimeToLive(ctx, now)
 	if !ok {
 		// This case is handled by validateCall, so we should
 		// never get here.
@@ -55,9 +55,10 @@ func (c *Connection) beginCall(ctx context.Context, serviceName, methodName stri
 	}
 
 	// If the timeToLive is less than a millisecond, it will be encoded as 0 on
-	// the wire, hence we return a timeout immediately.
-	timeToLive := deadline.Sub(now)
-	if timeToLive < time.Millisecond {
+	// the wire, so we return a timeout immediately, as we do for calls with
+	// less time remaining than the configured minimum.
+	if timeToLive < c.deadlineOpts.minTimeToLive() {
+		c.statsReporter.IncCounter("outbound.calls.insufficient-deadline", c.outboundCallTags(serviceName, methodName, callOptions), 1)
 		return nil, ErrTimeout
 	}
 
@@ -65,11 +66,17 @@ func (c *Connection) beginCall(ctx context.Context, serviceName, methodName stri
 		return nil, GetContextError(err)
 	}
 
+	if !c.allowCall(serviceName, callOptions.RequestState) {
+		c.statsReporter.IncCounter("outbound.calls.retries.suppressed", c.outboundCallTags(serviceName, methodName, callOptions), 1)
+		return nil, ErrRetryBudgetExhausted
+	}
+
 	requestID := c.NextMessageID()
-	mex, err := c.outbound.newExchange(ctx, c.opts.FramePool, messageTypeCallReq, requestID, mexChannelBufferSize)
+	mex, err := c.outbound.newExchange(ctx, c.opts.FramePool, messageTypeCallReq, requestID, mexChannelBufferSize, c.newFlowControl(true /* recvResponses */))
 	if err != nil {
 		return nil, err
 	}
+	mex.priority = callOptions.Priority
 
 	// Close may have been called between the time we checked the state and us creating the exchange.
 	if state := c.readState(); state != connectionActive {
@@ -86,6 +93,7 @@ func (c *Connection) beginCall(ctx context.Context, serviceName, methodName stri
 	if opts := currentCallOptions(ctx); opts != nil {
 		opts.overrideHeaders(headers)
 	}
+	compression := c.outboundCompression(ctx, callOptions, headers)
 
 	call := new(OutboundCall)
 	call.mex = mex
@@ -96,6 +104,7 @@ func (c *Connection) beginCall(ctx context.Context, serviceName, methodName stri
 		Service:    serviceName,
 		TimeToLive: timeToLive,
 	}
+	call.methodString = methodName
 	call.statsReporter = c.statsReporter
 	call.createStatsTags(c.commonStatsTags, callOptions, methodName)
 	call.log = c.log.WithFields(LogField{"Out-Call", requestID})
@@ -110,6 +119,12 @@ func (c *Connection) beginCall(ctx context.Context, serviceName, methodName stri
 	}
 
 	call.contents = newFragmentingWriter(call.log, call, c.opts.ChecksumType.New())
+	if compression != CompressionNone {
+		call.contents.compression = argCompression{
+			compression: compression,
+			onWritten:   compressionStats(c.statsReporter, "outbound.calls", call.commonStatsTags),
+		}
+	}
 
 	response := new(OutboundCallResponse)
 	response.startedAt = now
@@ -128,9 +143,18 @@ func (c *Connection) beginCall(ctx context.Context, serviceName, methodName stri
 	response.contents = newFragmentingReader(response.log, response)
 	response.statsReporter = call.statsReporter
 	response.commonStatsTags = call.commonStatsTags
+	response.contents.onChecksumError = func(err *ChecksumError) {
+		err.RemotePeer, err.Service, err.Method = c.remotePeerInfo, serviceName, methodName
+		c.checksumFailed(err, "outbound.calls", response.commonStatsTags)
+	}
 
+	response.call = call
 	call.response = response
 
+	if err := c.interceptOutbound(ctx, call); err != nil {
+		return nil, err
+	}
+
 	if err := call.writeMethod([]byte(methodName)); err != nil {
 		return nil, err
 	}
@@ -163,6 +187,7 @@ type OutboundCall struct {
 	reqResWriter
 
 	callReq         callReq
+	methodString    string
 	response        *OutboundCallResponse
 	statsReporter   StatsReporter
 	commonStatsTags map[string]string
@@ -187,6 +212,19 @@ func (call *OutboundCall) createStatsTags(connectionTags map[string]string, call
 	}
 }
 
+// outboundCallTags returns the stats tags for an outbound call that failed
+// before it was started.
+func (c *Connection) outboundCallTags(serviceName, methodName string, callOptions *CallOptions) map[string]string {
+	tags := map[string]string{"target-service": serviceName}
+	for k, v := range c.commonStatsTags {
+		tags[k] = v
+	}
+	if callOptions.Format != HTTP {
+		tags["target-endpoint"] = methodName
+	}
+	return tags
+}
+
 // writeMethod writes the method (arg1) to the call
 func (call *OutboundCall) writeMethod(method []byte) error {
 	call.statsReporter.IncCounter("outbound.calls.send", call.commonStatsTags, 1)
@@ -230,6 +268,12 @@ type OutboundCallResponse struct {
 	span            opentracing.Span
 	statsReporter   StatsReporter
 	commonStatsTags map[string]string
+
+	// peer is the peer the call was sent to, if it was started using a Peer.
+	peer *Peer
+
+	call         *OutboundCall
+	interceptors []OutboundInterceptor
 }
 
 // ApplicationError returns true if the call resulted in an application level error
@@ -254,6 +298,8 @@ func (response *OutboundCallResponse) Arg2Reader() (ArgReader, error) {
 		return nil, err
 	}
 
+	// The response headers are only available once the first fragment is read.
+	response.contents.compression = CompressionType(response.callRes.Headers[Compression])
 	return response.arg2Reader()
 }
 
@@ -321,7 +367,8 @@ func (response *OutboundCallResponse) doneReading(unexpected error) {
 	now := response.timeNow()
 
 	isSuccess := unexpected == nil && !response.ApplicationError()
-	lastAttempt := isSuccess || !response.requestState.HasRetries(unexpected)
+	// Attempts cancelled since another hedged attempt succeeded are not the last attempt.
+	lastAttempt := isSuccess || (!response.requestState.HasRetries(unexpected) && !response.requestState.superseded())
 
 	// TODO how should this work with retries?
 	if span := response.span; span != nil {
@@ -359,6 +406,15 @@ func (response *OutboundCallResponse) doneReading(unexpected error) {
 		response.statsReporter.IncCounter("outbound.calls.success", response.commonStatsTags, 1)
 	}
 
+	if response.peer != nil {
+		response.peer.recordCallResult(now, latency, unexpected)
+	}
+
+	response.afterOutbound(CallOutcome{
+		Err:              unexpected,
+		ApplicationError: unexpected == nil && response.ApplicationError(),
+	})
+
 	response.mex.shutdown()
 }
 
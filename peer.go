@@ -48,6 +48,10 @@ var (
 	// ErrNoNewPeers indicates that no previously unselected peer is available.
 	ErrNoNewPeers = errors.New("no new peer available")
 
+	// ErrAllPeersEjected indicates that all peers were ejected by their
+	// circuit breakers.
+	ErrAllPeersEjected = errors.New("all peers are ejected by circuit breakers")
+
 	peerRng = trand.NewSeeded()
 )
 
@@ -68,6 +72,7 @@ type PeerList struct {
 	peerHeap        *peerHeap
 	scoreCalculator ScoreCalculator
 	lastSelected    uint64
+	lastRescore     time.Time
 }
 
 func newPeerList(root *RootPeerList) *PeerList {
@@ -127,6 +132,7 @@ func (l *PeerList) GetNew(prevSelected map[string]struct{}) (*Peer, error) {
 	if l.peerHeap.Len() == 0 {
 		return nil, ErrNoPeers
 	}
+	l.rescoreIfStale()
 
 	// Select a peer, avoiding previously selected peers. If all peers have been previously
 	// selected, then it's OK to repick them.
@@ -135,6 +141,9 @@ func (l *PeerList) GetNew(prevSelected map[string]struct{}) (*Peer, error) {
 		peer = l.choosePeer(prevSelected, false /* avoidHost */)
 	}
 	if peer == nil {
+		if l.allEjected() {
+			return nil, ErrAllPeersEjected
+		}
 		return nil, ErrNoNewPeers
 	}
 	return peer, nil
@@ -147,7 +156,11 @@ func (l *PeerList) Get(prevSelected map[string]struct{}) (*Peer, error) {
 	if err == ErrNoNewPeers {
 		l.Lock()
 		peer = l.choosePeer(nil, false /* avoidHost */)
+		ejected := peer == nil && l.allEjected()
 		l.Unlock()
+		if ejected {
+			return nil, ErrAllPeersEjected
+		}
 	} else if err != nil {
 		return nil, err
 	}
@@ -194,7 +207,8 @@ func (l *PeerList) choosePeer(prevSelected map[string]struct{}, avoidHost bool)
 	for i := 0; i < size; i++ {
 		popped := l.peerHeap.popPeer()
 
-		if canChoosePeer(popped.HostPort()) {
+		// The breaker is checked last, as allowing a half-open peer uses a probe.
+		if canChoosePeer(popped.HostPort()) && popped.breaker.allow() {
 			ps = popped
 			break
 		}
@@ -214,6 +228,18 @@ func (l *PeerList) choosePeer(prevSelected map[string]struct{}, avoidHost bool)
 	return ps.Peer
 }
 
+// allEjected returns whether all peers are rejected by their circuit breakers,
+// either because they are open, or because they are half-open with no probes left.
+// Note that at least a Read lock must be held to call this function.
+func (l *PeerList) allEjected() bool {
+	for _, ps := range l.peersByHostPort {
+		if !ps.breaker.rejecting() {
+			return false
+		}
+	}
+	return len(l.peersByHostPort) > 0
+}
+
 // GetOrAdd returns a peer for the given hostPort, creating one if it doesn't yet exist.
 func (l *PeerList) GetOrAdd(hostPort string) *Peer {
 	if ps, ok := l.exists(hostPort); ok {
@@ -281,6 +307,27 @@ func (l *PeerList) onPeerChange(p *Peer) {
 	l.Unlock()
 }
 
+// rescoreIfStale recalculates the scores of all peers for a
+// PeriodicScoreCalculator, if they were last recalculated more than its
+// RescoreInterval ago, so that peers that are not chosen are rescored.
+// Note that a Write lock must be held to call this function.
+func (l *PeerList) rescoreIfStale() {
+	sc, ok := l.scoreCalculator.(PeriodicScoreCalculator)
+	if !ok {
+		return
+	}
+
+	now := timeNowFor(l.parent.channel)()
+	if now.Sub(l.lastRescore) < sc.RescoreInterval() {
+		return
+	}
+
+	l.lastRescore = now
+	for _, ps := range l.peersByHostPort {
+		l.updatePeer(ps, l.scoreCalculator.GetScore(ps.Peer))
+	}
+}
+
 // updatePeer is called to update the score of the peer given the existing score.
 // Note that a Write lock must be held to call this function.
 func (l *PeerList) updatePeer(ps *peerScore, newScore uint64) {
@@ -322,6 +369,7 @@ type Peer struct {
 	hostPort            string
 	onStatusChanged     func(*Peer)
 	onClosedConnRemoved func(*Peer)
+	timeNow             func() time.Time
 
 	// scCount is the number of subchannels that this peer is added to.
 	scCount uint32
@@ -331,6 +379,19 @@ type Peer struct {
 	inboundConnections  []*Connection
 	outboundConnections []*Connection
 	chosenCount         atomic.Uint64
+	stats               peerCallStats
+
+	// breaker is the peer's circuit breaker, or nil if they are disabled.
+	breaker *circuitBreaker
+
+	// connsPerPeer is the number of outbound connections to keep open.
+	connsPerPeer int
+
+	// warming is set while connections are created in the background, and
+	// newWarmContext is the context used to create them, which is protected
+	// by the mutex. It's nil until the peer has warmed connections.
+	warming        atomic.Bool
+	newWarmContext func() (context.Context, context.CancelFunc)
 
 	// onUpdate is a test-only hook.
 	onUpdate func(*Peer)
@@ -348,7 +409,17 @@ func newPeer(channel Connectable, hostPort string, onStatusChanged func(*Peer),
 		hostPort:            hostPort,
 		onStatusChanged:     onStatusChanged,
 		onClosedConnRemoved: onClosedConnRemoved,
+		timeNow:             timeNowFor(channel),
+	}
+}
+
+// timeNowFor returns the time source of the given channel.
+// A custom time source is only supported for a *Channel.
+func timeNowFor(ch Connectable) func() time.Time {
+	if c, ok := ch.(*Channel); ok {
+		return c.timeNow
 	}
+	return time.Now
 }
 
 // HostPort returns the host:port used to connect to this peer.
@@ -379,14 +450,28 @@ func (p *Peer) getActiveConnLocked() (*Connection, bool) {
 	if allConns > 1 {
 		startOffset = peerRng.Intn(allConns)
 	}
+
+	var (
+		best        *Connection
+		bestPending int
+	)
 	for i := 0; i < allConns; i++ {
 		connIndex := (i + startOffset) % allConns
-		if conn := p.getConn(connIndex); conn.IsActive() {
+		conn := p.getConn(connIndex)
+		if !conn.IsActive() {
+			continue
+		}
+		if p.connsPerPeer <= 1 {
 			return conn, true
 		}
+
+		// Spread calls across connections using the number of pending calls.
+		if pending := conn.pendingExchanges(); best == nil || pending < bestPending {
+			best, bestPending = conn, pending
+		}
 	}
 
-	return nil, false
+	return best, best != nil
 }
 
 // getActiveConn will randomly select an active connection.
@@ -403,6 +488,16 @@ func (p *Peer) getActiveConn() (*Connection, bool) {
 // GetConnection returns an active connection to this peer. If no active connections
 // are found, it will create a new outbound connection and return it.
 func (p *Peer) GetConnection(ctx context.Context) (*Connection, error) {
+	conn, err := p.getConnection(ctx)
+	if err == nil {
+		p.warmConnections(func() (context.Context, context.CancelFunc) {
+			return NewContext(DefaultConnectTimeout)
+		})
+	}
+	return conn, err
+}
+
+func (p *Peer) getConnection(ctx context.Context) (*Connection, error) {
 	if activeConn, ok := p.getActiveConn(); ok {
 		return activeConn, nil
 	}
@@ -423,6 +518,20 @@ func (p *Peer) GetConnection(ctx context.Context) (*Connection, error) {
 // getConnectionRelay gets a connection, and uses the given timeout to lazily
 // create a context if a new connection is required.
 func (p *Peer) getConnectionRelay(callTimeout, relayMaxConnTimeout time.Duration) (*Connection, error) {
+	conn, err := p.getConnectionRelayOnce(callTimeout, relayMaxConnTimeout)
+	if err == nil {
+		timeout := DefaultConnectTimeout
+		if relayMaxConnTimeout > 0 {
+			timeout = relayMaxConnTimeout
+		}
+		p.warmConnections(func() (context.Context, context.CancelFunc) {
+			return NewContextBuilder(timeout).HideListeningOnOutbound().Build()
+		})
+	}
+	return conn, err
+}
+
+func (p *Peer) getConnectionRelayOnce(callTimeout, relayMaxConnTimeout time.Duration) (*Connection, error) {
 	if conn, ok := p.getActiveConn(); ok {
 		return conn, nil
 	}
@@ -452,6 +561,54 @@ func (p *Peer) getConnectionRelay(callTimeout, relayMaxConnTimeout time.Duration
 	return p.Connect(ctx)
 }
 
+// warmConnections creates outbound connections in the background until the
+// peer has connsPerPeer active outbound connections. newCtx returns the
+// context used to create each connection.
+func (p *Peer) warmConnections(newCtx func() (context.Context, context.CancelFunc)) {
+	if p.connsPerPeer <= 1 || isEphemeralHostPort(p.hostPort) {
+		return
+	}
+
+	missing := p.connsPerPeer - p.numActiveOutbound()
+	if missing <= 0 || !p.warming.CAS(false, true) {
+		return
+	}
+
+	p.Lock()
+	p.newWarmContext = newCtx
+	p.Unlock()
+
+	go func() {
+		defer p.warming.Store(false)
+
+		for i := 0; i < missing; i++ {
+			ctx, cancel := newCtx()
+			_, err := p.Connect(ctx)
+			cancel()
+			if err != nil {
+				loggerFor(p.channel, LogSubsystemPeer).WithFields(
+					LogField{"remoteHostPort", p.hostPort},
+					ErrField(err),
+				).Info("Failed to create background connection to peer.")
+				return
+			}
+		}
+	}()
+}
+
+// numActiveOutbound returns the number of active outbound connections.
+func (p *Peer) numActiveOutbound() int {
+	var active int
+	p.RLock()
+	for _, c := range p.outboundConnections {
+		if c.IsActive() {
+			active++
+		}
+	}
+	p.RUnlock()
+	return active
+}
+
 // addSC adds a reference to a peer from a subchannel (e.g. peer list).
 func (p *Peer) addSC() {
 	p.Lock()
@@ -537,6 +694,17 @@ func (p *Peer) connectionCloseStateChange(changed *Connection) {
 		// Inform third parties that a peer lost a connection.
 		p.onStatusChanged(p)
 	}
+
+	// Replace outbound connections that failed if the peer keeps warm connections.
+	if found && changed.connDirection == outbound && changed.failed.Load() {
+		p.RLock()
+		newCtx := p.newWarmContext
+		p.RUnlock()
+
+		if newCtx != nil {
+			p.warmConnections(newCtx)
+		}
+	}
 }
 
 // Connect adds a new outbound connection to the peer.
@@ -558,6 +726,7 @@ func (p *Peer) BeginCall(ctx context.Context, serviceName, methodName string, ca
 
 	conn, err := p.GetConnection(ctx)
 	if err != nil {
+		p.recordCallResult(p.timeNow(), 0 /* latency */, err)
 		return nil, err
 	}
 
@@ -566,6 +735,7 @@ func (p *Peer) BeginCall(ctx context.Context, serviceName, methodName string, ca
 		return nil, err
 	}
 
+	call.response.peer = p
 	return call, err
 }
 
@@ -619,5 +789,8 @@ func noopOnStatusChanged(*Peer) {}
 
 // isEphemeralHostPort returns if hostPort is the default ephemeral hostPort.
 func isEphemeralHostPort(hostPort string) bool {
+	if isUnixHostPort(hostPort) {
+		return false
+	}
 	return hostPort == "" || hostPort == ephemeralHostPort || strings.HasSuffix(hostPort, ":0")
 }
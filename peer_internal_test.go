@@ -21,6 +21,7 @@
 package tchannel
 
 import (
+	"net"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -37,6 +38,11 @@ func TestIsEphemeralHostPort(t *testing.T) {
 		{"10.1.1.1:0", true},
 		{"127.0.0.1:1", false},
 		{"10.1.1.1:1", false},
+		{"unix:///tmp/tchannel.sock", false},
+		{"unix://@tchannel:0", false},
+		{addrHostPort(&net.UnixAddr{Net: "unix"}), true},
+		{addrHostPort(&net.UnixAddr{Name: "@", Net: "unix"}), true},
+		{addrHostPort(&net.UnixAddr{Name: "/tmp/tchannel.sock", Net: "unix"}), false},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,139 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"errors"
+	"math"
+	"net"
+	"sync"
+	"time"
+)
+
+// peerStatsDecay is the time constant for the peer's moving averages.
+// Samples older than this have about a third of the weight of new samples.
+const peerStatsDecay = 10 * time.Second
+
+// isPeerFailure returns whether a call error indicates the peer is unhealthy.
+// Application errors and errors such as timeouts or cancellations that may be
+// caused by the caller are not considered failures. Errors that are not system
+// errors are returned by the local channel, e.g. when it is closed, so they are
+// only failures if they come from the network, such as dial errors.
+func isPeerFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if _, ok := err.(SystemError); !ok {
+		var netErr net.Error
+		return errors.As(err, &netErr)
+	}
+
+	switch GetSystemErrorCode(err) {
+	case ErrCodeUnexpected, ErrCodeNetwork:
+		return true
+	default:
+		return false
+	}
+}
+
+// peerCallStats tracks a peak EWMA of call latency and an EWMA of the call
+// error rate for a peer. The peak EWMA immediately jumps up to latency spikes,
+// and decays slowly back down, so slow peers are quickly avoided.
+type peerCallStats struct {
+	sync.Mutex
+
+	latency    float64 // nanoseconds
+	errorRate  float64
+	lastUpdate time.Time
+}
+
+// record adds a completed call to the moving averages. A zero latency means
+// the call did not reach the peer, so only the error rate is updated.
+func (s *peerCallStats) record(now time.Time, latency time.Duration, failed bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	weight := s.weightLocked(now)
+	s.lastUpdate = now
+
+	if latency > 0 {
+		if sample := float64(latency); sample > s.latency {
+			s.latency = sample
+		} else {
+			s.latency = s.latency*weight + sample*(1-weight)
+		}
+	}
+
+	sample := 0.0
+	if failed {
+		sample = 1
+	}
+	s.errorRate = s.errorRate*weight + sample*(1-weight)
+}
+
+// get returns the moving averages at now. They decay by the time since the
+// last recorded call, so a peer that is avoided after a latency spike
+// recovers and is chosen again, rather than keeping its peak forever.
+func (s *peerCallStats) get(now time.Time) (latency time.Duration, errorRate float64) {
+	s.Lock()
+	defer s.Unlock()
+
+	weight := s.weightLocked(now)
+	return time.Duration(s.latency * weight), s.errorRate * weight
+}
+
+// weightLocked returns the weight of the current averages at now.
+// The stats must be locked.
+func (s *peerCallStats) weightLocked(now time.Time) float64 {
+	if s.lastUpdate.IsZero() {
+		return 0
+	}
+	elapsed := now.Sub(s.lastUpdate)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Exp(-float64(elapsed) / float64(peerStatsDecay))
+}
+
+// LatencyEWMA returns the peak exponentially weighted moving average of the
+// latency of calls to this peer, or zero if no calls have completed.
+func (p *Peer) LatencyEWMA() time.Duration {
+	latency, _ := p.stats.get(p.timeNow())
+	return latency
+}
+
+// ErrorRateEWMA returns the exponentially weighted moving average of the
+// rate of unexpected and network errors for calls to this peer, between 0 and 1.
+func (p *Peer) ErrorRateEWMA() float64 {
+	_, errorRate := p.stats.get(p.timeNow())
+	return errorRate
+}
+
+// recordCallResult records the outcome of a call to this peer. It is called
+// before the call's exchange is removed, so the peer's score is recalculated
+// with the updated stats when the connection reports the exchange change.
+func (p *Peer) recordCallResult(now time.Time, latency time.Duration, err error) {
+	p.stats.record(now, latency, isPeerFailure(err))
+	if p.breaker.record(err) {
+		p.onStatusChanged(p)
+	}
+}
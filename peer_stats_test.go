@@ -0,0 +1,107 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeerCallStats(t *testing.T) {
+	var s peerCallStats
+	now := time.Now()
+
+	latency, errorRate := s.get(now)
+	assert.Zero(t, latency, "No latency before any calls")
+	assert.Zero(t, errorRate, "No errors before any calls")
+
+	s.record(now, 10*time.Millisecond, false)
+	latency, _ = s.get(now)
+	assert.Equal(t, 10*time.Millisecond, latency, "First sample should be used as-is")
+
+	s.record(now, 100*time.Millisecond, false)
+	latency, _ = s.get(now)
+	assert.Equal(t, 100*time.Millisecond, latency, "Latency should jump to a higher sample")
+
+	now = now.Add(peerStatsDecay)
+	s.record(now, 10*time.Millisecond, false)
+	latency, _ = s.get(now)
+	assert.True(t, latency > 10*time.Millisecond && latency < 100*time.Millisecond,
+		"Latency should decay towards lower samples, got %v", latency)
+
+	s.record(now, 0 /* latency */, true)
+	latency2, errorRate := s.get(now)
+	assert.Equal(t, latency, latency2, "Calls without a latency should not update latency")
+	assert.Zero(t, errorRate, "Error rate should not change without elapsed time")
+
+	for i := 0; i < 10; i++ {
+		now = now.Add(peerStatsDecay)
+		s.record(now, 0 /* latency */, true)
+	}
+	_, errorRate = s.get(now)
+	assert.InDelta(t, 1, errorRate, 0.01, "Error rate should approach 1 after repeated failures")
+}
+
+func TestPeerCallStatsDecayWithoutCalls(t *testing.T) {
+	var s peerCallStats
+	now := time.Now()
+
+	s.record(now, time.Second, true)
+	latency, errorRate := s.get(now)
+	assert.Equal(t, time.Second, latency, "Unexpected latency")
+
+	// A peer that is not chosen after a spike should recover over time.
+	laterLatency, laterErrorRate := s.get(now.Add(peerStatsDecay))
+	assert.InDelta(t, float64(latency)/math.E, float64(laterLatency), float64(time.Millisecond),
+		"Latency should decay without new calls")
+	assert.InDelta(t, errorRate/math.E, laterErrorRate, 0.01, "Error rate should decay without new calls")
+
+	laterLatency, _ = s.get(now.Add(10 * peerStatsDecay))
+	assert.True(t, laterLatency < time.Millisecond, "Latency should decay to near zero, got %v", laterLatency)
+}
+
+func TestIsPeerFailure(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{ErrTimeout, false},
+		{NewSystemError(ErrCodeBadRequest, "bad request"), false},
+		{NewSystemError(ErrCodeNetwork, "network"), true},
+		{NewSystemError(ErrCodeUnexpected, "unexpected"), true},
+		{ErrChannelClosed, false},
+		{errInvalidStateForOp, false},
+		{ErrSendBufferFull, false},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, true},
+		{fmt.Errorf("custom dialer: %w", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}), true},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, isPeerFailure(tt.err), "isPeerFailure(%v)", tt.err)
+	}
+}
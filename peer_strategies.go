@@ -20,13 +20,25 @@
 
 package tchannel
 
-import "math"
+import (
+	"math"
+	"time"
+)
 
 // ScoreCalculator defines the interface to calculate the score.
 type ScoreCalculator interface {
 	GetScore(p *Peer) uint64
 }
 
+// PeriodicScoreCalculator is a ScoreCalculator whose scores change over time
+// without any change to the peer, such as scores based on decaying averages.
+// When a peer is selected, the scores of all peers are recalculated if they
+// were last calculated more than RescoreInterval ago.
+type PeriodicScoreCalculator interface {
+	ScoreCalculator
+	RescoreInterval() time.Duration
+}
+
 // ScoreCalculatorFunc is an adapter that allows functions to be used as ScoreCalculator
 type ScoreCalculatorFunc func(p *Peer) uint64
 
@@ -22,6 +22,8 @@ package tchannel_test
 
 import (
 	"fmt"
+	"io"
+	"net"
 	"sort"
 	"sync"
 	"testing"
@@ -1199,3 +1201,159 @@ func createConstScoreStrategy(score uint64) (calc tchannel.ScoreCalculator) {
 		return score
 	})
 }
+
+func TestConnectionsPerPeer(t *testing.T) {
+	const connsPerPeer = 3
+
+	opts := testutils.NewOpts().SetConnectionsPerPeer(connsPerPeer)
+	testutils.WithTestServer(t, opts, func(t testing.TB, ts *testutils.TestServer) {
+		unblock := make(chan struct{})
+		testutils.RegisterEcho(ts.Server(), func() { <-unblock })
+
+		client := ts.NewClient(opts)
+		peer := client.RootPeers().GetOrAdd(ts.HostPort())
+
+		ctx, cancel := tchannel.NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+
+		_, err := peer.GetConnection(ctx)
+		require.NoError(t, err, "GetConnection failed")
+		require.True(t, testutils.WaitFor(time.Second, func() bool {
+			_, out := peer.NumConnections()
+			return out == connsPerPeer
+		}), "Peer did not create %v connections", connsPerPeer)
+
+		// Calls are spread across connections by the number of pending calls.
+		var wg sync.WaitGroup
+		for i := 0; i < 2*connsPerPeer; i++ {
+			call, err := peer.BeginCall(ctx, ts.ServiceName(), "echo", nil)
+			require.NoError(t, err, "BeginCall failed")
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _, _, err := raw.WriteArgs(call, nil, nil)
+				assert.NoError(t, err, "Call failed")
+			}()
+		}
+
+		state := peer.IntrospectState(&tchannel.IntrospectionOptions{})
+		require.Len(t, state.OutboundConnections, connsPerPeer, "Unexpected number of connections")
+		for _, conn := range state.OutboundConnections {
+			assert.Equal(t, 2, conn.OutboundExchange.Count, "Calls not spread evenly across connections")
+		}
+
+		close(unblock)
+		wg.Wait()
+	})
+}
+
+func TestConnectionsPerPeerRelay(t *testing.T) {
+	opts := testutils.NewOpts().SetConnectionsPerPeer(2).SetRelayOnly()
+	testutils.WithTestServer(t, opts, func(t testing.TB, ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+
+		client := ts.NewClient(nil)
+		testutils.AssertEcho(t, client, ts.HostPort(), ts.ServiceName())
+
+		peer, ok := ts.Relay().RootPeers().Get(ts.Server().PeerInfo().HostPort)
+		require.True(t, ok, "Relay is missing peer for server")
+		assert.True(t, testutils.WaitFor(time.Second, func() bool {
+			_, out := peer.NumConnections()
+			return out == 2
+		}), "Relay did not create connections to the server")
+	})
+}
+
+// tcpProxy forwards connections to a destination, and can close the
+// connections it has accepted to simulate connection failures.
+type tcpProxy struct {
+	sync.Mutex
+
+	ln       net.Listener
+	accepted []net.Conn
+}
+
+func newTCPProxy(t testing.TB, destination string) *tcpProxy {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "Listen failed")
+
+	p := &tcpProxy{ln: ln}
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			out, err := net.Dial("tcp", destination)
+			if !assert.NoError(t, err, "Dial failed") {
+				c.Close()
+				return
+			}
+
+			p.Lock()
+			p.accepted = append(p.accepted, c)
+			p.Unlock()
+
+			go func() {
+				io.Copy(out, c)
+				out.Close()
+			}()
+			go func() {
+				io.Copy(c, out)
+				c.Close()
+			}()
+		}
+	}()
+	return p
+}
+
+func (p *tcpProxy) numAccepted() int {
+	p.Lock()
+	defer p.Unlock()
+	return len(p.accepted)
+}
+
+func (p *tcpProxy) closeConn(i int) {
+	p.Lock()
+	defer p.Unlock()
+	p.accepted[i].Close()
+}
+
+func (p *tcpProxy) Close() {
+	p.ln.Close()
+	p.Lock()
+	defer p.Unlock()
+	for _, c := range p.accepted {
+		c.Close()
+	}
+}
+
+func TestConnectionsPerPeerReplacesFailed(t *testing.T) {
+	opts := testutils.NewOpts().SetConnectionsPerPeer(2)
+	server := testutils.NewServer(t, nil)
+	defer server.Close()
+	testutils.RegisterEcho(server, nil)
+
+	proxy := newTCPProxy(t, server.PeerInfo().HostPort)
+	defer proxy.Close()
+
+	client := testutils.NewClient(t, opts)
+	defer client.Close()
+
+	numConns := func() int {
+		_, out := client.RootPeers().GetOrAdd(proxy.ln.Addr().String()).NumConnections()
+		return out
+	}
+
+	testutils.AssertEcho(t, client, proxy.ln.Addr().String(), server.ServiceName())
+	require.True(t, testutils.WaitFor(time.Second, func() bool {
+		return numConns() == 2
+	}), "Peer did not create connections")
+
+	proxy.closeConn(0)
+	assert.True(t, testutils.WaitFor(time.Second, func() bool {
+		return proxy.numAccepted() == 3 && numConns() == 2
+	}), "Failed connection was not replaced")
+	testutils.AssertEcho(t, client, proxy.ln.Addr().String(), server.ServiceName())
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package peers
+
+import (
+	"math"
+	"time"
+
+	"github.com/temporalio/tchannel-go"
+)
+
+const (
+	// _defaultErrorPenalty is the latency added for a peer whose calls all fail.
+	_defaultErrorPenalty = time.Second
+
+	// _rescoreInterval is how often peers are rescored, so that the score of
+	// a peer that is not chosen decays with its latency and error rate.
+	_rescoreInterval = time.Second
+)
+
+type latencyScoreCalc struct {
+	errorPenalty time.Duration
+}
+
+// NewLatencyScorer returns a ScoreCalculator that prefers peers with low latency,
+// few pending calls, and few errors, similar to peak EWMA load balancing.
+// A peer's cost is its peak EWMA latency, plus errorPenalty scaled by the
+// peer's error rate, multiplied by the number of pending calls plus one.
+// Peers without any completed calls have no latency, so new peers are tried
+// before they are scored on their latency.
+// Scores are recalculated every second, so peers that are avoided after a
+// latency spike are tried again once their latency has decayed.
+// If errorPenalty is 0, a default of 1 second is used.
+func NewLatencyScorer(errorPenalty time.Duration) tchannel.ScoreCalculator {
+	if errorPenalty <= 0 {
+		errorPenalty = _defaultErrorPenalty
+	}
+	return &latencyScoreCalc{errorPenalty}
+}
+
+func (s *latencyScoreCalc) GetScore(p *tchannel.Peer) uint64 {
+	latency := float64(p.LatencyEWMA()) + p.ErrorRateEWMA()*float64(s.errorPenalty)
+	cost := (latency/float64(time.Microsecond) + 1) * float64(p.NumPendingOutbound()+1)
+	if cost >= math.MaxUint64 {
+		return math.MaxUint64
+	}
+	return uint64(cost)
+}
+
+func (s *latencyScoreCalc) RescoreInterval() time.Duration {
+	return _rescoreInterval
+}
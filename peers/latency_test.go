@@ -0,0 +1,115 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package peers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/temporalio/tchannel-go"
+	"github.com/temporalio/tchannel-go/raw"
+	"github.com/temporalio/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+func TestLatencyScorerNewPeer(t *testing.T) {
+	client := testutils.NewClient(t, nil)
+	peer := client.Peers().GetOrAdd("1.1.1.1")
+
+	assert.EqualValues(t, 1, NewLatencyScorer(0).GetScore(peer), "New peers should have the lowest score")
+}
+
+func TestLatencyScorerIntegration(t *testing.T) {
+	// Client pings to the server may cause errors during Close.
+	sOpts := testutils.NewOpts().SetServiceName("svc").DisableLogVerification()
+
+	fast, fastCount := countingServer(t, sOpts)
+	var slowCount atomic.Int32
+	slow := testutils.NewServer(t, sOpts)
+	testutils.RegisterEcho(slow, func() {
+		slowCount.Inc()
+		time.Sleep(20 * time.Millisecond)
+	})
+
+	client := testutils.NewClient(t, testutils.NewOpts().DisableLogVerification())
+	client.Peers().SetStrategy(NewLatencyScorer(0))
+	client.Peers().Add(fast.PeerInfo().HostPort)
+	client.Peers().Add(slow.PeerInfo().HostPort)
+
+	for i := 0; i < 20; i++ {
+		ctx, cancel := tchannel.NewContext(time.Second)
+		_, err := raw.CallV2(ctx, client.GetSubChannel("svc"), raw.CArgs{Method: "echo"})
+		cancel()
+		require.NoError(t, err, "Call %v failed", i)
+	}
+
+	slowPeer, ok := client.RootPeers().Get(slow.PeerInfo().HostPort)
+	require.True(t, ok, "Missing slow peer")
+	assert.True(t, slowPeer.LatencyEWMA() >= 20*time.Millisecond, "Slow peer latency should be tracked")
+
+	assert.EqualValues(t, 20, fastCount.Load()+slowCount.Load(), "Unexpected number of calls")
+	assert.True(t, slowCount.Load() <= 2, "Slow peer should be avoided, got %v calls", slowCount.Load())
+}
+
+func TestLatencyScorerRecoversAfterSpike(t *testing.T) {
+	// Client pings to the server may cause errors during Close.
+	sOpts := testutils.NewOpts().SetServiceName("svc").DisableLogVerification()
+	// The client uses the clock for call deadlines, so keep it in the past.
+	clock := testutils.NewStubClock(time.Now().Add(-time.Hour))
+
+	fast, fastCount := countingServer(t, sOpts)
+	var spikeCount atomic.Int32
+	spike := testutils.NewServer(t, sOpts)
+	testutils.RegisterEcho(spike, func() {
+		// Only the first call to this peer is slow.
+		if spikeCount.Inc() == 1 {
+			clock.Elapse(time.Second)
+		}
+	})
+
+	client := testutils.NewClient(t, testutils.NewOpts().SetTimeNow(clock.Now).DisableLogVerification())
+	client.Peers().SetStrategy(NewLatencyScorer(0))
+	client.Peers().Add(fast.PeerInfo().HostPort)
+	client.Peers().Add(spike.PeerInfo().HostPort)
+
+	call := func() {
+		ctx, cancel := tchannel.NewContext(time.Second)
+		defer cancel()
+		_, err := raw.CallV2(ctx, client.GetSubChannel("svc"), raw.CArgs{Method: "echo"})
+		require.NoError(t, err, "Call failed")
+	}
+
+	for i := 0; i < 10; i++ {
+		call()
+	}
+	require.EqualValues(t, 1, spikeCount.Load(), "Peer should be avoided after a latency spike")
+
+	// Without any calls to the peer, its latency decays so it's chosen again.
+	clock.Elapse(5 * time.Minute)
+	for i := 0; i < 10; i++ {
+		call()
+	}
+	assert.True(t, spikeCount.Load() > 1, "Peer should recover after its latency decays")
+	assert.EqualValues(t, 20, fastCount.Load()+spikeCount.Load(), "Unexpected number of calls")
+}
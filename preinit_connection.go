@@ -38,7 +38,26 @@ func (ch *Channel) outboundHandshake(ctx context.Context, c net.Conn, outboundHP
 		err = ch.initError(c, outbound, 1, err)
 	}()
 
+	tlsInfo, err := tlsHandshake(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	info := HandshakeInfo{
+		Outbound:   true,
+		RemoteAddr: c.RemoteAddr(),
+		TLS:        tlsInfo,
+	}
 	msg := &initReq{initMessage: ch.getInitMessage(ctx, 1)}
+	if compression := ch.connectionOptions.Compression; len(compression) > 0 {
+		msg.initParams[InitParamCompression] = compressionInitParam(compression)
+	}
+	if window := ch.connectionOptions.FlowControlWindow; window > 0 {
+		msg.initParams[InitParamFlowControl] = strconv.Itoa(window)
+	}
+	if err := ch.addHandshakeHeaders(ctx, info, &msg.initMessage); err != nil {
+		return nil, err
+	}
 	if err := ch.writeMessage(c, msg); err != nil {
 		return nil, err
 	}
@@ -61,6 +80,14 @@ func (ch *Channel) outboundHandshake(ctx context.Context, c net.Conn, outboundHP
 	if err != nil {
 		return nil, NewWrappedSystemError(ErrCodeProtocol, err)
 	}
+	remotePeer.TLS = tlsInfo
+	remotePeer.Compression = selectCompression(ch.connectionOptions.Compression, res.initParams[InitParamCompression])
+	remotePeer.FlowControlWindow = parseFlowControlWindow(res.initParams[InitParamFlowControl])
+
+	info.RemotePeer = &remotePeer
+	if remotePeer.Identity, err = ch.verifyHandshakeHeaders(ctx, info, res.initParams); err != nil {
+		return nil, err
+	}
 
 	baseCtx := context.Background()
 	if p := getTChannelParams(ctx); p != nil && p.connectBaseContext != nil {
@@ -78,6 +105,11 @@ func (ch *Channel) inboundHandshake(ctx context.Context, c net.Conn, events conn
 		err = ch.initError(c, inbound, id, err)
 	}()
 
+	tlsInfo, err := tlsHandshake(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
 	req := &initReq{}
 	id, err = ch.readMessage(c, req)
 	if err != nil {
@@ -92,8 +124,29 @@ func (ch *Channel) inboundHandshake(ctx context.Context, c net.Conn, events conn
 	if err != nil {
 		return nil, NewWrappedSystemError(ErrCodeProtocol, err)
 	}
+	remotePeer.TLS = tlsInfo
+
+	info := HandshakeInfo{
+		RemoteAddr: c.RemoteAddr(),
+		TLS:        tlsInfo,
+		RemotePeer: &remotePeer,
+	}
+	if remotePeer.Identity, err = ch.verifyHandshakeHeaders(ctx, info, req.initParams); err != nil {
+		return nil, err
+	}
 
 	res := &initRes{initMessage: ch.getInitMessage(ctx, id)}
+	remotePeer.Compression = selectCompression(ch.connectionOptions.Compression, req.initParams[InitParamCompression])
+	if remotePeer.Compression != CompressionNone {
+		res.initParams[InitParamCompression] = string(remotePeer.Compression)
+	}
+	if window := ch.connectionOptions.FlowControlWindow; window > 0 {
+		res.initParams[InitParamFlowControl] = strconv.Itoa(window)
+	}
+	remotePeer.FlowControlWindow = parseFlowControlWindow(req.initParams[InitParamFlowControl])
+	if err := ch.addHandshakeHeaders(ctx, info, &res.initMessage); err != nil {
+		return nil, err
+	}
 	if err := ch.writeMessage(c, res); err != nil {
 		return nil, err
 	}
@@ -197,7 +250,7 @@ func parseRemotePeer(p initParams, remoteAddr net.Addr) (PeerInfo, peerAddressCo
 	// If the remote host:port is ephemeral, use the socket address as the
 	// host:port and set IsEphemeral to true.
 	if isEphemeralHostPort(remotePeer.HostPort) {
-		remotePeer.HostPort = remoteAddr.String()
+		remotePeer.HostPort = addrHostPort(remoteAddr)
 		remotePeer.IsEphemeral = true
 	}
 
@@ -205,6 +258,11 @@ func parseRemotePeer(p initParams, remoteAddr net.Addr) (PeerInfo, peerAddressCo
 	remotePeer.Version.LanguageVersion = p[InitParamTChannelLanguageVersion]
 	remotePeer.Version.TChannelVersion = p[InitParamTChannelVersion]
 
+	// Unix domain sockets have no host or port to report.
+	if _, ok := remoteAddr.(*net.UnixAddr); ok || isUnixHostPort(remotePeer.HostPort) {
+		return remotePeer, remotePeerAddress, nil
+	}
+
 	address := remotePeer.HostPort
 	if sHost, sPort, err := net.SplitHostPort(address); err == nil {
 		address = sHost
@@ -0,0 +1,165 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+// PriorityClass is the priority of a call's frames relative to other calls
+// sharing the same connection. Connections write frames using weighted fair
+// queueing between classes, so that the fragments of a bulk transfer do not
+// delay latency-sensitive calls.
+type PriorityClass string
+
+const (
+	// PriorityCritical is for latency-sensitive calls.
+	PriorityCritical PriorityClass = "critical"
+
+	// PriorityNormal is the default priority for calls.
+	PriorityNormal PriorityClass = "normal"
+
+	// PriorityBulk is for large transfers that can be delayed by other calls.
+	PriorityBulk PriorityClass = "bulk"
+)
+
+// Indexes of the send queues for each priority class.
+const (
+	priorityQueueCritical = iota
+	priorityQueueNormal
+	priorityQueueBulk
+	numPriorityQueues
+)
+
+// priorityClasses is the priority class for each send queue.
+var priorityClasses = [numPriorityQueues]PriorityClass{
+	PriorityCritical,
+	PriorityNormal,
+	PriorityBulk,
+}
+
+// priorityWeights is the number of frames written from each send queue before
+// the connection moves on to the next queue, if that queue has frames.
+var priorityWeights = [numPriorityQueues]int{
+	priorityQueueCritical: 8,
+	priorityQueueNormal:   4,
+	priorityQueueBulk:     1,
+}
+
+// queue returns the index of the send queue for the priority class. Unknown
+// classes, including the empty class, use the normal queue.
+func (p PriorityClass) queue() int {
+	switch p {
+	case PriorityCritical:
+		return priorityQueueCritical
+	case PriorityBulk:
+		return priorityQueueBulk
+	default:
+		return priorityQueueNormal
+	}
+}
+
+// header returns the transport header value for the priority class, which
+// is empty for the normal class since it's the default.
+func (p PriorityClass) header() string {
+	if p.queue() == priorityQueueNormal {
+		return ""
+	}
+	return string(p)
+}
+
+// sendChFor returns the send queue for frames with the given priority. Frames
+// that are not part of a call, such as pings and protocol errors, use sendCh
+// which is the normal queue.
+func (c *Connection) sendChFor(p PriorityClass) chan *Frame {
+	return c.sendChs[p.queue()]
+}
+
+// introspectSendQueues returns the number of frames queued for each priority class.
+func (c *Connection) introspectSendQueues() map[PriorityClass]int {
+	queues := make(map[PriorityClass]int, numPriorityQueues)
+	for i, ch := range c.sendChs {
+		queues[priorityClasses[i]] = len(ch)
+	}
+	return queues
+}
+
+// frameScheduler picks the next frame to write from the send queues using
+// weighted round robin. It's only used by the connection's writer.
+type frameScheduler struct {
+	queues [numPriorityQueues]chan *Frame
+	stopCh chan struct{}
+
+	// queue is the queue being served, and served is the number of frames
+	// written from it in the current round.
+	queue  int
+	served int
+}
+
+func newFrameScheduler(c *Connection) *frameScheduler {
+	return &frameScheduler{
+		queues: c.sendChs,
+		stopCh: c.stopCh,
+	}
+}
+
+// next returns the next frame to write. It blocks until there's a frame, and
+// returns false once the connection is stopped and all queues are empty.
+func (s *frameScheduler) next() (*Frame, bool) {
+	for {
+		// Serve each queue up to its weight before moving to the next queue.
+		for i := 0; i < numPriorityQueues; i++ {
+			if s.served < priorityWeights[s.queue] {
+				select {
+				case f := <-s.queues[s.queue]:
+					s.served++
+					return f, true
+				default:
+				}
+			}
+			s.queue = (s.queue + 1) % numPriorityQueues
+			s.served = 0
+		}
+
+		// All queues are empty, so wait for a frame in any queue.
+		select {
+		case f := <-s.queues[priorityQueueCritical]:
+			s.queue, s.served = priorityQueueCritical, 1
+			return f, true
+		case f := <-s.queues[priorityQueueNormal]:
+			s.queue, s.served = priorityQueueNormal, 1
+			return f, true
+		case f := <-s.queues[priorityQueueBulk]:
+			s.queue, s.served = priorityQueueBulk, 1
+			return f, true
+		case <-s.stopCh:
+			// If there are queued frames, we want to drain them.
+			if s.queued() > 0 {
+				continue
+			}
+			return nil, false
+		}
+	}
+}
+
+func (s *frameScheduler) queued() int {
+	var queued int
+	for _, q := range s.queues {
+		queued += len(q)
+	}
+	return queued
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestPriorityClassQueue(t *testing.T) {
+	tests := []struct {
+		priority   PriorityClass
+		wantQueue  int
+		wantHeader string
+	}{
+		{"", priorityQueueNormal, ""},
+		{PriorityNormal, priorityQueueNormal, ""},
+		{"unknown", priorityQueueNormal, ""},
+		{PriorityCritical, priorityQueueCritical, "critical"},
+		{PriorityBulk, priorityQueueBulk, "bulk"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.wantQueue, tt.priority.queue(), "Unexpected queue for %q", tt.priority)
+		assert.Equal(t, tt.wantHeader, tt.priority.header(), "Unexpected header for %q", tt.priority)
+	}
+}
+
+func newTestFrameScheduler(queued map[int]int) *frameScheduler {
+	s := &frameScheduler{stopCh: make(chan struct{})}
+	for i := range s.queues {
+		s.queues[i] = make(chan *Frame, 100)
+		for j := 0; j < queued[i]; j++ {
+			f := NewFrame(0)
+			f.Header.ID = uint32(i)
+			s.queues[i] <- f
+		}
+	}
+	return s
+}
+
+func TestFrameSchedulerWeights(t *testing.T) {
+	s := newTestFrameScheduler(map[int]int{
+		priorityQueueCritical: 10,
+		priorityQueueNormal:   10,
+		priorityQueueBulk:     10,
+	})
+
+	var want []int
+	add := func(queue, n int) {
+		for i := 0; i < n; i++ {
+			want = append(want, queue)
+		}
+	}
+	add(priorityQueueCritical, 8)
+	add(priorityQueueNormal, 4)
+	add(priorityQueueBulk, 1)
+	add(priorityQueueCritical, 2)
+	add(priorityQueueNormal, 4)
+	add(priorityQueueBulk, 1)
+	add(priorityQueueNormal, 2)
+	add(priorityQueueBulk, 8)
+
+	var got []int
+	for range want {
+		f, ok := s.next()
+		require.True(t, ok, "Expected a frame")
+		got = append(got, int(f.Header.ID))
+	}
+	assert.Equal(t, want, got, "Unexpected order of frames")
+}
+
+func TestFrameSchedulerWaitsForFrames(t *testing.T) {
+	s := newTestFrameScheduler(nil)
+
+	go func() {
+		s.queues[priorityQueueBulk] <- NewFrame(0)
+	}()
+	_, ok := s.next()
+	assert.True(t, ok, "Expected a frame")
+
+	// Frames that are queued when the connection is stopped are still written.
+	s.queues[priorityQueueNormal] <- NewFrame(0)
+	close(s.stopCh)
+	_, ok = s.next()
+	assert.True(t, ok, "Expected queued frame to be drained")
+
+	_, ok = s.next()
+	assert.False(t, ok, "Expected no frames once stopped")
+}
+
+func TestExchangeFramesUsePriorityQueue(t *testing.T) {
+	c := &Connection{
+		channelConnectionCommon: channelConnectionCommon{
+			log:     NullLogger,
+			timeNow: time.Now,
+		},
+		opts: ConnectionOptions{FramePool: DefaultFramePool},
+	}
+	for i := range c.sendChs {
+		c.sendChs[i] = make(chan *Frame, 10)
+	}
+	c.sendCh = c.sendChs[priorityQueueNormal]
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	mex := &messageExchange{
+		msgType:  messageTypeCallReq,
+		msgID:    1,
+		ctx:      ctx,
+		priority: PriorityBulk,
+	}
+
+	c.sendCancel(mex)
+	require.NoError(t, c.sendSystemError(mex.msgID, Span{}, ErrTimeout, mex.priority), "sendSystemError failed")
+	require.NoError(t, c.sendMessageWithPriority(&creditMessage{id: mex.msgID, credits: 1}, mex.priority), "sendMessageWithPriority failed")
+
+	// Frames for a call must not overtake the call's frames in its queue.
+	wantTypes := []messageType{messageTypeCancel, messageTypeError, messageTypeCredit}
+	require.Len(t, c.sendChs[priorityQueueBulk], len(wantTypes), "Expected frames in the bulk queue")
+	for _, want := range wantTypes {
+		f := <-c.sendChs[priorityQueueBulk]
+		assert.Equal(t, want, f.Header.messageType, "Unexpected frame type")
+	}
+	assert.Len(t, c.sendCh, 0, "Expected no frames in the normal queue")
+
+	// Frames that are not part of a call use the normal queue.
+	require.NoError(t, c.SendSystemError(2, Span{}, ErrTimeout), "SendSystemError failed")
+	assert.Len(t, c.sendCh, 1, "Expected protocol error in the normal queue")
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/temporalio/tchannel-go"
+	"github.com/temporalio/tchannel-go/raw"
+	"github.com/temporalio/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestCallPriority(t *testing.T) {
+	tests := []struct {
+		priority tchannel.PriorityClass
+		want     tchannel.PriorityClass
+	}{
+		{"", tchannel.PriorityNormal},
+		{tchannel.PriorityNormal, tchannel.PriorityNormal},
+		{tchannel.PriorityCritical, tchannel.PriorityCritical},
+		{tchannel.PriorityBulk, tchannel.PriorityBulk},
+	}
+
+	testutils.WithTestServer(t, nil, func(t testing.TB, ts *testutils.TestServer) {
+		var got tchannel.PriorityClass
+		ts.Register(tchannel.HandlerFunc(func(ctx context.Context, call *tchannel.InboundCall) {
+			got = call.Priority()
+			assert.NoError(t, raw.WriteResponse(call.Response(), &raw.Res{}), "WriteResponse failed")
+		}), "priority")
+
+		client := ts.NewClient(nil)
+		for _, tt := range tests {
+			ctx, cancel := tchannel.NewContext(testutils.Timeout(time.Second))
+			call, err := client.BeginCall(ctx, ts.HostPort(), ts.ServiceName(), "priority", &tchannel.CallOptions{
+				Priority: tt.priority,
+			})
+			require.NoError(t, err, "BeginCall failed")
+
+			_, _, _, err = raw.WriteArgs(call, nil, testutils.RandBytes(100000))
+			require.NoError(t, err, "Call failed")
+			assert.Equal(t, tt.want, got, "Unexpected priority for %q", tt.priority)
+			cancel()
+		}
+	})
+}
+
+func TestIntrospectSendQueues(t *testing.T) {
+	testutils.WithTestServer(t, nil, func(t testing.TB, ts *testutils.TestServer) {
+		ctx, cancel := tchannel.NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+
+		client := ts.NewClient(nil)
+		require.NoError(t, client.Ping(ctx, ts.HostPort()), "Ping failed")
+
+		peer, ok := client.RootPeers().Get(ts.HostPort())
+		require.True(t, ok, "Missing peer for server")
+		state := peer.IntrospectState(&tchannel.IntrospectionOptions{})
+		require.Len(t, state.OutboundConnections, 1, "Expected a single outbound connection")
+		assert.Equal(t, map[tchannel.PriorityClass]int{
+			tchannel.PriorityCritical: 0,
+			tchannel.PriorityNormal:   0,
+			tchannel.PriorityBulk:     0,
+		}, state.OutboundConnections[0].SendQueues, "Unexpected send queues")
+	})
+}
@@ -51,6 +51,7 @@ const (
 	_relayErrorDestConnSlow   = "relay-dest-conn-slow"
 	_relayErrorSourceConnSlow = "relay-source-conn-slow"
 	_relayArg2ModifyFailed    = "relay-arg2-modify-failed"
+	_relayDecompressFailed    = "relay-decompress-failed"
 
 	// _relayNoRelease indicates that the relayed frame should not be released immediately, since
 	// relayed frames normally end up in a send queue where it is released afterward. However in some
@@ -69,6 +70,7 @@ var (
 	errNoNHInArg2               = errors.New("no nh in arg2")
 	errFragmentedArg2WithAppend = errors.New("fragmented arg2 not supported for appends")
 	errArg2ThriftOnly           = errors.New("cannot inspect or modify arg2 for non-Thrift calls")
+	errArg2Compressed           = errors.New("cannot inspect or modify arg2 for compressed calls")
 )
 
 type relayItem struct {
@@ -80,6 +82,14 @@ type relayItem struct {
 	span            Span
 	timeout         *relayTimer
 	mutatedChecksum Checksum
+
+	// decompressor collects the fragments of a compressed call that is sent
+	// to a destination that did not negotiate the same compression.
+	decompressor *relayDecompressor
+
+	// priority is the priority class of the call, used to pick the send
+	// queue for frames relayed to the destination.
+	priority PriorityClass
 }
 
 type relayItems struct {
@@ -212,7 +222,7 @@ const (
 // A Relayer forwards frames.
 type Relayer struct {
 	relayHost      RelayHost
-	maxTimeout     time.Duration
+	maxTimeout     *atomic.Duration
 	maxConnTimeout time.Duration
 
 	// localHandlers is the set of service names that are handled by the local
@@ -242,13 +252,14 @@ type Relayer struct {
 
 // NewRelayer constructs a Relayer.
 func NewRelayer(ch *Channel, conn *Connection) *Relayer {
+	logger := withLogSubsystem(conn.log, conn.logLevels, LogSubsystemRelay)
 	r := &Relayer{
 		relayHost:      ch.RelayHost(),
 		maxTimeout:     ch.relayMaxTimeout,
 		maxConnTimeout: ch.relayMaxConnTimeout,
 		localHandler:   ch.relayLocal,
-		outbound:       newRelayItems(conn.log.WithFields(LogField{"relayItems", "outbound"}), ch.relayMaxTombs),
-		inbound:        newRelayItems(conn.log.WithFields(LogField{"relayItems", "inbound"}), ch.relayMaxTombs),
+		outbound:       newRelayItems(logger.WithFields(LogField{"relayItems", "outbound"}), ch.relayMaxTombs),
+		inbound:        newRelayItems(logger.WithFields(LogField{"relayItems", "inbound"}), ch.relayMaxTombs),
 		peers:          ch.RootPeers(),
 		conn:           conn,
 		relayConn: &relay.Conn{
@@ -256,8 +267,13 @@ func NewRelayer(ch *Channel, conn *Connection) *Relayer {
 			RemoteProcessName: conn.RemotePeerInfo().ProcessName,
 			IsOutbound:        conn.connDirection == outbound,
 			Context:           conn.baseContext,
+			TLS:               getTLSConnectionState(conn.conn),
 		},
-		logger: conn.log,
+		logger: logger,
+	}
+	if identity := conn.RemotePeerInfo().Identity; identity != nil {
+		r.relayConn.RemoteIdentity = identity.Name
+		r.relayConn.RemoteIdentityAttributes = identity.Attributes
 	}
 	r.timeouts = newRelayTimerPool(r.timeoutRelayItem, ch.relayTimerVerify)
 	return r
@@ -265,6 +281,13 @@ func NewRelayer(ch *Channel, conn *Connection) *Relayer {
 
 // Relay is called for each frame that is read on the connection.
 func (r *Relayer) Relay(f *Frame) (shouldRelease bool, _ error) {
+	switch f.messageType() {
+	case messageTypeCancel:
+		return r.handleCancel(f), nil
+	case messageTypeCredit:
+		return r.handleCredit(f), nil
+	}
+
 	if f.messageType() != messageTypeCallReq {
 		err := r.handleNonCallReq(f)
 		if err == errUnknownID {
@@ -321,8 +344,9 @@ func (r *Relayer) Receive(f *Frame, fType frameType) (sent bool, failureReason s
 			item.call.Failed(failMsg)
 		}
 	}
+	sendCh := r.conn.sendChFor(item.priority)
 	select {
-	case r.conn.sendCh <- f:
+	case sendCh <- f:
 	default:
 		// Buffer is full, so drop this frame and cancel the call.
 
@@ -334,8 +358,9 @@ func (r *Relayer) Receive(f *Frame, fType frameType) (sent bool, failureReason s
 			{"id", id},
 			{"destConnSendBufferCurrent", sendBuf},
 			{"destConnSendBufferLimit", sendBufLimit},
-			{"sendChQueued", len(r.conn.sendCh)},
-			{"sendChCapacity", cap(r.conn.sendCh)},
+			{"priority", item.priority},
+			{"sendChQueued", len(sendCh)},
+			{"sendChCapacity", cap(sendCh)},
 			{"lastActivityRead", r.conn.lastActivityRead.Load()},
 			{"lastActivityWrite", r.conn.lastActivityRead.Load()},
 			{"sinceLastActivityRead", time.Duration(now - r.conn.lastActivityRead.Load()).String()},
@@ -426,6 +451,15 @@ func (r *Relayer) handleCallReq(f *lazyCallReq) (shouldRelease bool, _ error) {
 		return _relayNoRelease, nil
 	}
 
+	if r.conn.draining.Load() && !drainExempt(string(f.Service()), string(f.Method())) {
+		r.conn.SendSystemError(f.Header.ID, f.Span(), ErrChannelDraining)
+		return _relayNoRelease, nil
+	}
+
+	if !r.authorize(f) {
+		return _relayNoRelease, nil
+	}
+
 	call, err := r.relayHost.Start(f, r.relayConn)
 	if err != nil {
 		// If we have a RateLimitDropError we record the statistic, but
@@ -463,9 +497,17 @@ func (r *Relayer) handleCallReq(f *lazyCallReq) (shouldRelease bool, _ error) {
 	}
 
 	// Get a remote connection and check whether it can handle this call.
+	var decompressor *relayDecompressor
 	remoteConn, ok, err := r.getDestination(f, call)
 	if err == nil && ok {
-		if canHandle, state := remoteConn.relay.canHandleNewCall(); !canHandle {
+		// The compression is checked first, since the remote connection's
+		// pending count is incremented if it can handle the call.
+		var compressionErr error
+		if decompressor, compressionErr = newRelayDecompressor(f, remoteConn); compressionErr != nil {
+			ok = false
+			call.Failed("relay-compression-unsupported")
+			r.conn.SendSystemError(f.Header.ID, f.Span(), compressionErr)
+		} else if canHandle, state := remoteConn.relay.canHandleNewCall(); !canHandle {
 			err = NewWrappedSystemError(ErrCodeNetwork, errConnNotActive{"selected remote", state})
 			call.Failed("relay-remote-inactive")
 			r.conn.SendSystemError(f.Header.ID, f.Span(), NewWrappedSystemError(ErrCodeDeclined, err))
@@ -483,9 +525,9 @@ func (r *Relayer) handleCallReq(f *lazyCallReq) (shouldRelease bool, _ error) {
 	origID := f.Header.ID
 	destinationID := remoteConn.NextMessageID()
 	ttl := f.TTL()
-	if ttl > r.maxTimeout {
-		ttl = r.maxTimeout
-		f.SetTTL(r.maxTimeout)
+	if maxTimeout := r.maxTimeout.Load(); ttl > maxTimeout {
+		ttl = maxTimeout
+		f.SetTTL(maxTimeout)
 	}
 	span := f.Span()
 
@@ -495,10 +537,20 @@ func (r *Relayer) handleCallReq(f *lazyCallReq) (shouldRelease bool, _ error) {
 	}
 
 	// The remote side of the relay doesn't need to track stats or call state.
-	remoteConn.relay.addRelayItem(false /* isOriginator */, destinationID, f.Header.ID, r, ttl, span, call, nil /* mutatedChecksum */)
-	relayToDest := r.addRelayItem(true /* isOriginator */, f.Header.ID, destinationID, remoteConn.relay, ttl, span, call, mutatedChecksum)
+	priority := PriorityClass(f.priority)
+	remoteConn.relay.addRelayItem(false /* isOriginator */, destinationID, f.Header.ID, r, ttl, span, call, nil /* mutatedChecksum */, nil /* decompressor */, priority)
+	relayToDest := r.addRelayItem(true /* isOriginator */, f.Header.ID, destinationID, remoteConn.relay, ttl, span, call, mutatedChecksum, decompressor, priority)
 
 	f.Header.ID = destinationID
+	moreFragments := f.HasMoreFragments()
+
+	// The decompressor keeps a copy of the callReq, and sends the call once all
+	// of its fragments are received, so the original frame is released.
+	if decompressor != nil {
+		decompressor.callReq.Header.ID = destinationID
+		r.decompressingSend(relayToDest, origID, f.Frame)
+		return _relayShouldRelease, nil
+	}
 
 	// If we have appends, the size of the frame to be relayed will change, potentially going
 	// over the max frame size. Do a fragmenting send which is slightly more expensive but
@@ -513,6 +565,8 @@ func (r *Relayer) handleCallReq(f *lazyCallReq) (shouldRelease bool, _ error) {
 				LogField{"dest", string(f.Service())},
 				LogField{"method", string(f.Method())},
 			).Warn("Failed to send call with modified arg2.")
+		} else {
+			r.grantForDestination(remoteConn.relay, origID, requestFrame, moreFragments, priority)
 		}
 
 		// fragmentingSend always sends new frames in place of the old frame so we must
@@ -526,6 +580,7 @@ func (r *Relayer) handleCallReq(f *lazyCallReq) (shouldRelease bool, _ error) {
 		r.failRelayItem(r.outbound, origID, failure, errFrameNotSent)
 		return _relayNoRelease, nil
 	}
+	r.grantForDestination(remoteConn.relay, origID, requestFrame, moreFragments, priority)
 	return _relayNoRelease, nil
 }
 
@@ -552,6 +607,12 @@ func (r *Relayer) handleNonCallReq(f *Frame) error {
 		return nil
 	}
 
+	if item.decompressor != nil {
+		r.decompressingSend(item, f.Header.ID, f)
+		r.conn.opts.FramePool.Release(f)
+		return nil
+	}
+
 	switch f.messageType() {
 	case messageTypeCallRes:
 		// Invoke call.CallResponse() if we get a valid call response frame.
@@ -578,12 +639,14 @@ func (r *Relayer) handleNonCallReq(f *Frame) error {
 
 	originalID := f.Header.ID
 	f.Header.ID = item.remapID
+	moreFragments := f.messageType() != messageTypeError && hasMoreFragments(f)
 
 	sent, failure := item.destination.Receive(f, frameType)
 	if !sent {
 		r.failRelayItem(items, originalID, failure, errFrameNotSent)
 		return nil
 	}
+	r.grantForDestination(item.destination, originalID, frameType, moreFragments, item.priority)
 
 	if finished {
 		r.finishRelayItem(items, originalID)
@@ -591,8 +654,113 @@ func (r *Relayer) handleNonCallReq(f *Frame) error {
 	return nil
 }
 
+// handleCancel forwards a cancel frame to the destination of the call, and
+// entombs the relay items on both sides since the caller is no longer waiting
+// for the response.
+func (r *Relayer) handleCancel(f *Frame) (shouldRelease bool) {
+	id := f.Header.ID
+	item, _, ok := r.outbound.Get(id, false /* stopTimeout */)
+	if !ok {
+		// The call may have been handled by the local channel.
+		r.conn.handleCancel(f)
+		return _relayShouldRelease
+	}
+	if item.tomb {
+		return _relayShouldRelease
+	}
+
+	destination, remapID := item.destination, item.remapID
+	f.Header.ID = remapID
+	sent, _ := destination.Receive(f, requestFrame)
+
+	r.cancelRelayItem(r.outbound, id)
+	if sent {
+		destination.cancelRelayItem(destination.inbound, remapID)
+	}
+	return _relayNoRelease
+}
+
+// handleCredit forwards a credit frame to the sender of the call's fragments.
+// Credits are dropped if the sender does not use flow control, since the relay
+// grants credits on its behalf.
+func (r *Relayer) handleCredit(f *Frame) (shouldRelease bool) {
+	msg := &creditMessage{id: f.Header.ID}
+	if err := f.read(msg); err != nil {
+		r.logger.WithFields(
+			LogField{"header", f.Header},
+			ErrField(err),
+		).Warn("Unable to read credit frame.")
+		return _relayShouldRelease
+	}
+
+	// Credits for response fragments are sent by the caller, so they travel
+	// in the same direction as request frames.
+	items, fType := r.inbound, responseFrame
+	if msg.response {
+		items, fType = r.outbound, requestFrame
+	}
+
+	item, _, ok := items.Get(msg.id, false /* stopTimeout */)
+	if !ok {
+		// The call may have been made or handled by the local channel.
+		r.conn.handleCredit(f)
+		return _relayShouldRelease
+	}
+	if item.tomb || item.destination.conn.sendWindow() == 0 {
+		return _relayShouldRelease
+	}
+
+	f.Header.ID = item.remapID
+	if sent, failure := item.destination.Receive(f, fType); !sent {
+		r.failRelayItem(items, msg.id, failure, errFrameNotSent)
+		return _relayShouldRelease
+	}
+	return _relayNoRelease
+}
+
+// grantForDestination grants a credit to the sender of a relayed fragment if
+// the sender uses flow control but the destination does not, since the
+// destination will never grant credits itself.
+func (r *Relayer) grantForDestination(destination *Relayer, id uint32, fType frameType, moreFragments bool, priority PriorityClass) {
+	if !moreFragments || r.conn.sendWindow() == 0 || destination.conn.sendWindow() > 0 {
+		return
+	}
+	r.grantCredit(id, fType, priority)
+}
+
+// grantCredit grants a credit to the sender of the call's fragments.
+func (r *Relayer) grantCredit(id uint32, fType frameType, priority PriorityClass) {
+	msg := &creditMessage{id: id, credits: 1, response: fType == responseFrame}
+	if err := r.conn.sendMessageWithPriority(msg, priority); err != nil {
+		r.logger.WithFields(
+			LogField{"id", id},
+			ErrField(err),
+		).Info("Failed to grant credit for relayed fragment.")
+	}
+}
+
+// cancelRelayItem tombs the relay item for a call that was cancelled by the
+// caller. Unlike failRelayItem, no error frame is sent to the caller.
+func (r *Relayer) cancelRelayItem(items *relayItems, id uint32) {
+	_, stopped, found := items.Get(id, true /* stopTimeout */)
+	if !found || !stopped {
+		return
+	}
+
+	item, ok := items.Entomb(id, _relayTombTTL)
+	if !ok {
+		return
+	}
+	if item.isOriginator {
+		item.call.Failed(ErrCodeCancelled.relayMetricsKey())
+		item.call.End()
+	}
+
+	r.decrementPending()
+}
+
 // addRelayItem adds a relay item to either outbound or inbound.
-func (r *Relayer) addRelayItem(isOriginator bool, id, remapID uint32, destination *Relayer, ttl time.Duration, span Span, call RelayCall, mutatedChecksum Checksum) relayItem {
+func (r *Relayer) addRelayItem(isOriginator bool, id, remapID uint32, destination *Relayer, ttl time.Duration, span Span, call RelayCall, mutatedChecksum Checksum, decompressor *relayDecompressor, priority PriorityClass) relayItem {
 	item := relayItem{
 		isOriginator:    isOriginator,
 		call:            call,
@@ -600,6 +768,8 @@ func (r *Relayer) addRelayItem(isOriginator bool, id, remapID uint32, destinatio
 		destination:     destination,
 		span:            span,
 		mutatedChecksum: mutatedChecksum,
+		decompressor:    decompressor,
+		priority:        priority,
 	}
 
 	items := r.inbound
@@ -618,7 +788,7 @@ func (r *Relayer) timeoutRelayItem(items *relayItems, id uint32, isOriginator bo
 		return
 	}
 	if isOriginator {
-		r.conn.SendSystemError(id, item.span, ErrTimeout)
+		r.conn.sendSystemError(id, item.span, ErrTimeout, item.priority)
 		item.call.Failed("timeout")
 		item.call.End()
 	}
@@ -649,7 +819,7 @@ func (r *Relayer) failRelayItem(items *relayItems, id uint32, reason string, err
 	if item.isOriginator {
 		// If the client is too slow, then there's no point sending an error frame.
 		if reason != _relayErrorSourceConnSlow {
-			r.conn.SendSystemError(id, item.span, fmt.Errorf("%v: %v", reason, err))
+			r.conn.sendSystemError(id, item.span, fmt.Errorf("%v: %v", reason, err), item.priority)
 		}
 		item.call.Failed(reason)
 		item.call.End()
@@ -730,6 +900,9 @@ func (r *Relayer) fragmentingSend(call RelayCall, f *lazyCallReq, relayToDest re
 	if !bytes.Equal(f.as, _tchanThriftValueBytes) {
 		return fmt.Errorf("%v: got %s", errArg2ThriftOnly, f.as)
 	}
+	if len(f.compression) > 0 {
+		return errArg2Compressed
+	}
 
 	cs := relayToDest.mutatedChecksum
 
@@ -813,7 +986,7 @@ func frameTypeFor(f *Frame) frameType {
 	switch t := f.Header.messageType; t {
 	case messageTypeCallRes, messageTypeCallResContinue, messageTypeError, messageTypePingRes:
 		return responseFrame
-	case messageTypeCallReq, messageTypeCallReqContinue, messageTypePingReq:
+	case messageTypeCallReq, messageTypeCallReqContinue, messageTypePingReq, messageTypeCancel:
 		return requestFrame
 	default:
 		panic(fmt.Sprintf("unsupported frame type: %v", t))
@@ -835,9 +1008,14 @@ func determinesCallSuccess(f *Frame) (succeeded bool, failMsg string) {
 	}
 }
 
-func validateRelayMaxTimeout(d time.Duration, logger Logger) time.Duration {
+// isValidRelayMaxTimeout returns whether d can be used as a TTL in frames.
+func isValidRelayMaxTimeout(d time.Duration) bool {
 	maxMillis := d / time.Millisecond
-	if maxMillis > 0 && maxMillis <= math.MaxUint32 {
+	return maxMillis > 0 && maxMillis <= math.MaxUint32
+}
+
+func validateRelayMaxTimeout(d time.Duration, logger Logger) time.Duration {
+	if isValidRelayMaxTimeout(d) {
 		return d
 	}
 	if d == 0 {
@@ -26,6 +26,7 @@ package relay
 
 import (
 	"context"
+	"crypto/tls"
 	"time"
 
 	"github.com/temporalio/tchannel-go/thrift/arg2"
@@ -97,6 +98,18 @@ type Conn struct {
 	// Context contains connection-specific context which can be accessed via
 	// RelayHost.Start()
 	Context context.Context
+
+	// TLS is the TLS state of the connection, including the verified peer
+	// certificates. It is nil if the connection does not use TLS.
+	TLS *tls.ConnectionState
+
+	// RemoteIdentity is the name of the remote peer's identity verified by
+	// the channel's Handshaker. It is empty if there is no verified identity.
+	RemoteIdentity string
+
+	// RemoteIdentityAttributes are the attributes of the remote peer's
+	// verified identity.
+	RemoteIdentityAttributes map[string]string
 }
 
 // RateLimitDropError is the error that should be returned from
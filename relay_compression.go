@@ -0,0 +1,198 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/temporalio/tchannel-go/typed"
+)
+
+var errRelayCompressedArgs = errors.New("compressed call has more than 3 arguments")
+
+// relayDecompressor collects the compressed arg2 and arg3 of a call that is
+// relayed to a destination that did not negotiate the caller's compression.
+// Once all of the call's fragments are received, the arguments are
+// decompressed and sent to the destination without the Compression header.
+type relayDecompressor struct {
+	// callReq is a copy of the call's callReq frame without the Compression
+	// header, and with only the arguments received in that frame.
+	callReq     *lazyCallReq
+	compression CompressionType
+
+	// args holds the compressed arg2 and arg3, and arg is the index of the
+	// argument that the next chunk is added to.
+	args [2][]byte
+	arg  int
+}
+
+// newRelayDecompressor returns a decompressor for the call if it is compressed
+// using a different compression than remoteConn negotiated, or an error if the
+// call cannot be relayed to remoteConn. Compressed payloads that can be relayed
+// unchanged are not decompressed, so arg2 cannot be modified.
+func newRelayDecompressor(f *lazyCallReq, remoteConn *Connection) (*relayDecompressor, error) {
+	compression := CompressionType(f.compression)
+	if compression == CompressionNone {
+		return nil, nil
+	}
+	if len(f.arg2Appends) > 0 {
+		return nil, NewWrappedSystemError(ErrCodeBadRequest, errArg2Compressed)
+	}
+	if compression == remoteConn.remotePeerInfo.Compression {
+		return nil, nil
+	}
+	if !compression.supported() {
+		return nil, NewSystemError(ErrCodeBadRequest, "relay cannot decompress compression type %q", compression)
+	}
+
+	callReq, err := copyCallReqWithoutCompression(f)
+	if err != nil {
+		return nil, NewWrappedSystemError(ErrCodeBadRequest, err)
+	}
+	return &relayDecompressor{callReq: callReq, compression: compression}, nil
+}
+
+// copyCallReqWithoutCompression returns a copy of the callReq frame without the
+// Compression transport header. The copy does not have more fragments, since
+// it is only used to write the decompressed call.
+func copyCallReqWithoutCompression(f *lazyCallReq) (*lazyCallReq, error) {
+	payload := f.SizedPayload()
+	frame := NewFrame(len(payload))
+	frame.Header = f.Header
+
+	rbuf := typed.NewReadBuffer(payload)
+	wbuf := typed.NewWriteBuffer(frame.Payload)
+
+	// flags:1 ttl:4 tracing:25 service~1
+	wbuf.WriteBytes(rbuf.ReadBytes(_serviceLenIndex))
+	wbuf.WriteLen8String(rbuf.ReadLen8String())
+
+	// nh:1 (hk~1 hv~1){nh}
+	numHeaders := int(rbuf.ReadSingleByte())
+	numHeadersRef := wbuf.DeferByte()
+	var written byte
+	for i := 0; i < numHeaders; i++ {
+		key := rbuf.ReadBytes(int(rbuf.ReadSingleByte()))
+		val := rbuf.ReadBytes(int(rbuf.ReadSingleByte()))
+		if bytes.Equal(key, _compressionKeyBytes) {
+			continue
+		}
+		wbuf.WriteSingleByte(byte(len(key)))
+		wbuf.WriteBytes(key)
+		wbuf.WriteSingleByte(byte(len(val)))
+		wbuf.WriteBytes(val)
+		written++
+	}
+	numHeadersRef.Update(written)
+
+	// csumtype:1 (csum:4){0,1} arg1~2 arg2~2 arg3~2
+	wbuf.WriteBytes(rbuf.ReadBytes(rbuf.BytesRemaining()))
+	if err := rbuf.Err(); err != nil {
+		return nil, err
+	}
+	if err := wbuf.Err(); err != nil {
+		return nil, err
+	}
+
+	frame.Header.SetPayloadSize(uint16(wbuf.BytesWritten()))
+	callReq, err := newLazyCallReq(frame)
+	if err != nil {
+		return nil, err
+	}
+
+	// Flags are copied to the fragments written for the decompressed call, which
+	// set the more fragments flag as needed.
+	callReq.Payload[_flagsIndex] &^= hasMoreFragmentsFlag
+	return callReq, nil
+}
+
+// addFragment adds the argument chunks in a callReq or callReqContinue frame.
+func (d *relayDecompressor) addFragment(f *Frame) error {
+	var rbuf *typed.ReadBuffer
+	if f.messageType() == messageTypeCallReq {
+		// arg1 is not compressed, and is written from the copied callReq.
+		rbuf = typed.NewReadBuffer(d.callReq.SizedPayload()[d.callReq.arg2StartOffset-2:])
+	} else {
+		// flags:1 csumtype:1 (csum:4){0,1}
+		rbuf = typed.NewReadBuffer(f.SizedPayload())
+		rbuf.SkipBytes(1)
+		rbuf.SkipBytes(ChecksumType(rbuf.ReadSingleByte()).ChecksumSize())
+	}
+
+	// Every chunk except the last in a fragment completes its argument, while
+	// the last chunk may be continued in the next fragment.
+	for rbuf.BytesRemaining() > 0 {
+		if d.arg == len(d.args) {
+			return errRelayCompressedArgs
+		}
+		chunk := rbuf.ReadBytes(int(rbuf.ReadUint16()))
+		d.args[d.arg] = append(d.args[d.arg], chunk...)
+		if rbuf.BytesRemaining() > 0 {
+			d.arg++
+		}
+	}
+	return rbuf.Err()
+}
+
+// decompressingSend adds the arguments in the fragment f to the call's
+// decompressor, and sends the decompressed call to the destination once the
+// last fragment is received. Since the fragments are not relayed, the relay
+// grants credits to the caller if it uses flow control.
+func (r *Relayer) decompressingSend(item relayItem, id uint32, f *Frame) {
+	d := item.decompressor
+	if err := d.addFragment(f); err != nil {
+		r.failRelayItem(r.outbound, id, _relayDecompressFailed, err)
+		return
+	}
+	if hasMoreFragments(f) {
+		if r.conn.sendWindow() > 0 {
+			r.grantCredit(id, requestFrame, item.priority)
+		}
+		return
+	}
+
+	var arg2, arg3 []byte
+	if err := NewArgReader(newDecompressingArgReader(ioutil.NopCloser(bytes.NewReader(d.args[0])), d.compression)).Read(&arg2); err != nil {
+		r.failRelayItem(r.outbound, id, _relayDecompressFailed, fmt.Errorf("arg2: %v", err))
+		return
+	}
+	if err := NewArgReader(newDecompressingArgReader(ioutil.NopCloser(bytes.NewReader(d.args[1])), d.compression)).Read(&arg3); err != nil {
+		r.failRelayItem(r.outbound, id, _relayDecompressFailed, fmt.Errorf("arg3: %v", err))
+		return
+	}
+
+	// The checksum is only used while writing, since all fragments are sent
+	// before the writer returns.
+	checksum := d.callReq.checksumType.New()
+	defer checksum.Release()
+
+	fragWriter := newFragmentingWriter(r.logger, r.newFragmentSender(item.destination, d.callReq, id, item.call), checksum)
+	if err := NewArgWriter(fragWriter.ArgWriter(false /* last */)).Write(arg2); err != nil {
+		r.failRelayItem(r.outbound, id, _relayDecompressFailed, fmt.Errorf("write arg2: %v", err))
+		return
+	}
+	if err := NewArgWriter(fragWriter.ArgWriter(true /* last */)).Write(arg3); err != nil {
+		r.failRelayItem(r.outbound, id, _relayDecompressFailed, fmt.Errorf("write arg3: %v", err))
+	}
+}
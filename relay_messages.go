@@ -38,6 +38,8 @@ var (
 	_routingDelegateKeyBytes = []byte(RoutingDelegate)
 	_routingKeyKeyBytes      = []byte(RoutingKey)
 	_argSchemeKeyBytes       = []byte(ArgScheme)
+	_compressionKeyBytes     = []byte(Compression)
+	_priorityKeyBytes        = []byte(Priority)
 	_tchanThriftValueBytes   = []byte(Thrift)
 )
 
@@ -164,6 +166,8 @@ type lazyCallReq struct {
 	arg3StartOffset                uint16
 
 	caller, method, delegate, key, as []byte
+	compression                       []byte
+	priority                          []byte
 	arg2Appends                       []relay.KeyVal
 	checksumType                      ChecksumType
 	isArg2Fragmented                  bool
@@ -206,6 +210,10 @@ func newLazyCallReq(f *Frame) (*lazyCallReq, error) {
 			cr.delegate = val
 		} else if bytes.Equal(key, _routingKeyKeyBytes) {
 			cr.key = val
+		} else if bytes.Equal(key, _compressionKeyBytes) {
+			cr.compression = val
+		} else if bytes.Equal(key, _priorityKeyBytes) {
+			cr.priority = val
 		}
 	}
 
@@ -315,6 +323,9 @@ func (f *lazyCallReq) Arg2Iterator() (arg2.KeyValIterator, error) {
 	if !bytes.Equal(f.as, _tchanThriftValueBytes) {
 		return arg2.KeyValIterator{}, fmt.Errorf("%v: got %s", errArg2ThriftOnly, f.as)
 	}
+	if len(f.compression) > 0 {
+		return arg2.KeyValIterator{}, errArg2Compressed
+	}
 	return arg2.NewKeyValIterator(f.Payload[f.arg2StartOffset:f.arg2EndOffset])
 }
 
@@ -147,12 +147,15 @@ func (w *reqResWriter) flushFragment(fragment *writableFragment) error {
 	if err := w.mex.checkError(); err != nil {
 		return w.failed(err)
 	}
+	if err := w.mex.acquireSendCredit(); err != nil {
+		return w.failed(err)
+	}
 	select {
 	case <-w.mex.ctx.Done():
 		return w.failed(GetContextError(w.mex.ctx.Err()))
 	case <-w.mex.errCh.c:
 		return w.failed(w.mex.errCh.err)
-	case w.conn.sendCh <- frame:
+	case w.conn.sendChFor(w.mex.priority) <- frame:
 		return nil
 	}
 }
@@ -228,6 +231,7 @@ func (r *reqResReader) recvNextFragment(initial bool) (*readableFragment, error)
 		fragment := r.initialFragment
 		r.initialFragment = nil
 		r.previousFragment = fragment
+		r.mex.fragmentConsumed(fragment.flags&hasMoreFragmentsFlag != 0)
 		return fragment, nil
 	}
 
@@ -252,6 +256,7 @@ func (r *reqResReader) recvNextFragment(initial bool) (*readableFragment, error)
 	}
 
 	r.previousFragment = fragment
+	r.mex.fragmentConsumed(fragment.flags&hasMoreFragmentsFlag != 0)
 	return fragment, nil
 }
 
@@ -21,13 +21,20 @@
 package tchannel
 
 import (
+	"math"
 	"net"
 	"sync"
 	"time"
 
+	"github.com/temporalio/tchannel-go/trand"
+
 	"golang.org/x/net/context"
 )
 
+const _defaultBackoffMultiplier = 2
+
+var retryRng = trand.NewSeeded()
+
 // RetryOn represents the types of errors to retry on.
 type RetryOn int
 
@@ -65,6 +72,9 @@ type RequestState struct {
 	// Attempt is 1 for the first attempt, and so on.
 	Attempt   int
 	retryOpts *RetryOptions
+
+	// hedge is shared by concurrent attempts if the request is hedged.
+	hedge *hedgeState
 }
 
 // RetriableFunc is the type of function that can be passed to RunWithRetry.
@@ -128,6 +138,73 @@ type RetryOptions struct {
 	// TimeoutPerAttempt is the per-retry timeout to use.
 	// If this is zero, then the original timeout is used.
 	TimeoutPerAttempt time.Duration
+
+	// Hedge, if set, enables hedged requests, where attempts are started
+	// concurrently when an attempt is slow.
+	Hedge *HedgeOptions
+
+	// Backoff, if set, is used to wait between retries.
+	// If this is nil, retries are made immediately.
+	Backoff *BackoffOptions
+}
+
+// BackoffOptions configures exponential backoff between retries.
+type BackoffOptions struct {
+	// Initial is the delay before the first retry.
+	Initial time.Duration
+
+	// Max caps the delay between retries. If this is zero, the delay is not capped.
+	Max time.Duration
+
+	// Multiplier is the factor the delay grows by for each retry. Defaults to 2.
+	Multiplier float64
+
+	// Jitter is the fraction of each delay that is randomized, between 0 and 1.
+	// A Jitter of 1 ("full jitter") picks a delay between 0 and the backoff.
+	Jitter float64
+}
+
+// delay returns the delay before the given retry, where the first retry is 1.
+func (b *BackoffOptions) delay(retry int) time.Duration {
+	multiplier := b.Multiplier
+	if multiplier <= 0 {
+		multiplier = _defaultBackoffMultiplier
+	}
+
+	d := float64(b.Initial) * math.Pow(multiplier, float64(retry-1))
+	if b.Max > 0 && d > float64(b.Max) {
+		d = float64(b.Max)
+	}
+	if d > math.MaxInt64 {
+		d = math.MaxInt64
+	}
+
+	if jitter := math.Min(b.Jitter, 1); jitter > 0 {
+		d -= d * jitter * retryRng.Float64()
+	}
+	return time.Duration(d)
+}
+
+// waitBackoff waits before the given retry, and returns false if the
+// context is done before the retry should be made.
+func waitBackoff(ctx context.Context, opts *RetryOptions, retry int) bool {
+	if opts.Backoff == nil {
+		return true
+	}
+
+	d := opts.Backoff.delay(retry)
+	if d <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
 }
 
 var defaultRetryOptions = &RetryOptions{
@@ -164,6 +241,19 @@ func (rs *RequestState) HasRetries(err error) bool {
 	return rs.Attempt < rOpts.MaxAttempts && rOpts.RetryOn.CanRetry(err)
 }
 
+// Hedged returns whether attempts for this request may run concurrently.
+// Attempts of hedged requests must not write to shared state without
+// synchronization.
+func (rs *RequestState) Hedged() bool {
+	return rs != nil && rs.hedge != nil
+}
+
+// superseded returns whether another attempt of a hedged request succeeded,
+// and this attempt is being cancelled.
+func (rs *RequestState) superseded() bool {
+	return rs.Hedged() && rs.hedge.done.Load()
+}
+
 // SinceStart returns the time since the start of the request. If there is no request state,
 // then the fallback is returned.
 func (rs *RequestState) SinceStart(now time.Time, fallback time.Duration) time.Duration {
@@ -178,6 +268,9 @@ func (rs *RequestState) PrevSelectedPeers() map[string]struct{} {
 	if rs == nil {
 		return nil
 	}
+	if rs.hedge != nil {
+		return rs.hedge.prevSelectedPeers()
+	}
 	return rs.SelectedPeers
 }
 
@@ -188,6 +281,9 @@ func (rs *RequestState) AddSelectedPeer(hostPort string) {
 	}
 
 	host := getHost(hostPort)
+	if rs.hedge != nil {
+		rs.hedge.addSelectedPeer(hostPort, host)
+	}
 	if rs.SelectedPeers == nil {
 		rs.SelectedPeers = map[string]struct{}{
 			hostPort: {},
@@ -213,23 +309,29 @@ func (ch *Channel) RunWithRetry(runCtx context.Context, f RetriableFunc) error {
 	var err error
 
 	opts := getRetryOptions(runCtx)
+	if opts.Hedge != nil {
+		return ch.runWithHedging(runCtx, opts, f)
+	}
+
 	rs := ch.getRequestState(opts)
 	defer requestStatePool.Put(rs)
 
 	for i := 0; i < opts.MaxAttempts; i++ {
-		rs.Attempt++
-
-		if opts.TimeoutPerAttempt == 0 {
-			err = f(runCtx, rs)
-		} else {
-			attemptCtx, cancel := context.WithTimeout(runCtx, opts.TimeoutPerAttempt)
-			err = f(attemptCtx, rs)
-			cancel()
+		if i > 0 && !waitBackoff(runCtx, opts, i) {
+			return err
 		}
+		rs.Attempt++
 
-		if err == nil {
+		attemptErr := runAttempt(runCtx, opts, rs, f)
+		if attemptErr == nil {
 			return nil
 		}
+		if attemptErr == ErrRetryBudgetExhausted {
+			ch.log.WithFields(ErrField(err)).Info("Not retrying request as retry budget is exhausted.")
+			return err
+		}
+
+		err = attemptErr
 		if !opts.RetryOn.CanRetry(err) {
 			if ch.log.Enabled(LogLevelInfo) {
 				ch.log.WithFields(ErrField(err)).Info("Failed after non-retriable error.")
@@ -248,6 +350,17 @@ func (ch *Channel) RunWithRetry(runCtx context.Context, f RetriableFunc) error {
 	return err
 }
 
+// runAttempt runs a single attempt, applying the per-attempt timeout.
+func runAttempt(ctx context.Context, opts *RetryOptions, rs *RequestState, f RetriableFunc) error {
+	if opts.TimeoutPerAttempt == 0 {
+		return f(ctx, rs)
+	}
+
+	attemptCtx, cancel := context.WithTimeout(ctx, opts.TimeoutPerAttempt)
+	defer cancel()
+	return f(attemptCtx, rs)
+}
+
 func (ch *Channel) getRequestState(retryOpts *RetryOptions) *RequestState {
 	rs := requestStatePool.Get().(*RequestState)
 	*rs = RequestState{
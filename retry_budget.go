@@ -0,0 +1,169 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"errors"
+	"sync"
+)
+
+const (
+	_defaultRetryBudgetRatio = 0.1
+	_defaultRetryBudgetBurst = 10
+)
+
+var (
+	// ErrRetryBudgetExhausted is returned when a retry is not made since the
+	// retry budget has been used up. RunWithRetry returns the error of the
+	// previous attempt instead of this error.
+	ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+	errNegativeRetryBudget = errors.New("retry budget Ratio and Burst cannot be negative")
+)
+
+// RetryBudget limits retries to a ratio of calls, to avoid retry storms when
+// a service is degraded. It is a token bucket, where each call adds Ratio
+// tokens, and each retry or hedged attempt uses a token.
+type RetryBudget struct {
+	// Ratio is the maximum ratio of retries to calls. Defaults to 0.1, which
+	// allows retries to be at most 10% of calls.
+	Ratio float64 `json:"ratio"`
+
+	// Burst is the number of retries allowed before any calls are made, and
+	// the maximum number of tokens that can be saved up. Defaults to 10.
+	Burst int `json:"burst"`
+}
+
+// retryBudget is the token bucket for a RetryBudget.
+// A nil retryBudget allows all retries.
+type retryBudget struct {
+	sync.Mutex
+
+	ratio  float64
+	burst  float64
+	tokens float64
+}
+
+func newRetryBudget(rb RetryBudget) (*retryBudget, error) {
+	if rb.Ratio < 0 || rb.Burst < 0 {
+		return nil, errNegativeRetryBudget
+	}
+	if rb.Ratio == 0 {
+		rb.Ratio = _defaultRetryBudgetRatio
+	}
+	if rb.Burst == 0 {
+		rb.Burst = _defaultRetryBudgetBurst
+	}
+	return &retryBudget{
+		ratio:  rb.Ratio,
+		burst:  float64(rb.Burst),
+		tokens: float64(rb.Burst),
+	}, nil
+}
+
+// deposit adds tokens for a call that is not a retry.
+func (b *retryBudget) deposit() {
+	if b == nil {
+		return
+	}
+
+	b.Lock()
+	b.add(b.ratio)
+	b.Unlock()
+}
+
+// withdraw uses a token for a retry, and returns false if there are no tokens.
+func (b *retryBudget) withdraw() bool {
+	if b == nil {
+		return true
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// refund returns a token that was withdrawn for a retry that was not made.
+func (b *retryBudget) refund() {
+	if b == nil {
+		return
+	}
+
+	b.Lock()
+	b.add(1)
+	b.Unlock()
+}
+
+func (b *retryBudget) add(tokens float64) {
+	b.tokens += tokens
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// SetRetryBudget sets the budget for retries of outbound calls to this service.
+// It applies in addition to the channel's RetryBudget.
+func (c *SubChannel) SetRetryBudget(rb RetryBudget) error {
+	b, err := newRetryBudget(rb)
+	if err != nil {
+		return err
+	}
+
+	c.Lock()
+	c.retryBudget = b
+	c.Unlock()
+	return nil
+}
+
+func (c *SubChannel) getRetryBudget() *retryBudget {
+	c.RLock()
+	defer c.RUnlock()
+	return c.retryBudget
+}
+
+// allowCall updates the channel and service retry budgets for an outbound call,
+// and returns false if the call is a retry that exceeds either budget.
+func (c *Connection) allowCall(serviceName string, rs *RequestState) bool {
+	var serviceBudget *retryBudget
+	if sc, ok := c.subChannels.get(serviceName); ok {
+		serviceBudget = sc.getRetryBudget()
+	}
+
+	if rs.RetryCount() == 0 {
+		c.retryBudget.deposit()
+		serviceBudget.deposit()
+		return true
+	}
+
+	if !c.retryBudget.withdraw() {
+		return false
+	}
+	if !serviceBudget.withdraw() {
+		c.retryBudget.refund()
+		return false
+	}
+	return true
+}
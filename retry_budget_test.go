@@ -0,0 +1,86 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryBudgetTokens(t *testing.T) {
+	b, err := newRetryBudget(RetryBudget{Ratio: 0.25, Burst: 2})
+	require.NoError(t, err, "newRetryBudget failed")
+
+	assert.True(t, b.withdraw(), "Burst should allow retries")
+	assert.True(t, b.withdraw(), "Burst should allow retries")
+	assert.False(t, b.withdraw(), "Retries over the burst should fail")
+
+	for i := 0; i < 3; i++ {
+		b.deposit()
+		assert.False(t, b.withdraw(), "Retry should fail before enough calls are made")
+	}
+	b.deposit()
+	assert.True(t, b.withdraw(), "Retry should be allowed after 4 calls")
+
+	for i := 0; i < 100; i++ {
+		b.deposit()
+	}
+	assert.True(t, b.withdraw(), "Burst should allow retries")
+	assert.True(t, b.withdraw(), "Burst should allow retries")
+	assert.False(t, b.withdraw(), "Tokens should be capped by the burst")
+
+	b.refund()
+	assert.True(t, b.withdraw(), "Refunded token should allow a retry")
+
+	var nilBudget *retryBudget
+	assert.True(t, nilBudget.withdraw(), "nil budget should allow all retries")
+}
+
+func TestBackoffDelay(t *testing.T) {
+	b := &BackoffOptions{
+		Initial: 10 * time.Millisecond,
+		Max:     100 * time.Millisecond,
+	}
+	tests := []struct {
+		retry int
+		want  time.Duration
+	}{
+		{1, 10 * time.Millisecond},
+		{2, 20 * time.Millisecond},
+		{3, 40 * time.Millisecond},
+		{4, 80 * time.Millisecond},
+		{5, 100 * time.Millisecond},
+		{100, 100 * time.Millisecond},
+		{10000, 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, b.delay(tt.retry), "Unexpected delay for retry %v", tt.retry)
+	}
+
+	b.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := b.delay(5)
+		assert.True(t, d > 50*time.Millisecond && d <= 100*time.Millisecond, "Delay %v out of jitter range", d)
+	}
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/temporalio/tchannel-go/testutils"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/net/context"
 )
 
@@ -81,3 +82,127 @@ func TestRequestStateRetry(t *testing.T) {
 		assert.Equal(t, 5, counter, "RunWithRetry should retry 5 times")
 	})
 }
+
+func TestRetryBudget(t *testing.T) {
+	testutils.WithTestServer(t, nil, func(t testing.TB, ts *testutils.TestServer) {
+		var calls int
+		testutils.RegisterFunc(ts.Server(), "busy", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			calls++
+			return nil, tchannel.ErrServerBusy
+		})
+
+		clientStats := newRecordingStatsReporter()
+		client := ts.NewClient(testutils.NewOpts().
+			SetStatsReporter(clientStats).
+			SetRetryBudget(tchannel.RetryBudget{Ratio: 0.5, Burst: 1}))
+		client.Peers().Add(ts.HostPort())
+		sc := client.GetSubChannel(ts.Server().ServiceName())
+
+		callBusy := func() error {
+			ctx, cancel := tchannel.NewContext(time.Second)
+			defer cancel()
+
+			return client.RunWithRetry(ctx, func(ctx context.Context, rs *tchannel.RequestState) error {
+				_, err := raw.CallV2(ctx, sc, raw.CArgs{
+					Method:      "busy",
+					CallOptions: &tchannel.CallOptions{RequestState: rs},
+				})
+				return err
+			})
+		}
+
+		// The budget starts with a single token, so only one retry is made.
+		assert.Equal(t, tchannel.ErrServerBusy, callBusy(), "Expected error from the last attempt")
+		assert.Equal(t, 2, calls, "Expected a single retry")
+
+		// Each call adds half a token, so every second call can retry.
+		assert.Equal(t, tchannel.ErrServerBusy, callBusy(), "Expected error from the last attempt")
+		assert.Equal(t, 3, calls, "Expected no retries without tokens")
+
+		assert.Equal(t, tchannel.ErrServerBusy, callBusy(), "Expected error from the last attempt")
+		assert.Equal(t, 5, calls, "Expected a retry after tokens are added")
+
+		var suppressed int64
+		for _, v := range clientStats.Values["outbound.calls.retries.suppressed"] {
+			suppressed += v.count
+		}
+		assert.EqualValues(t, 3, suppressed, "Unexpected number of suppressed retries")
+	})
+}
+
+func TestRetryBudgetPerService(t *testing.T) {
+	testutils.WithTestServer(t, nil, func(t testing.TB, ts *testutils.TestServer) {
+		var calls int
+		testutils.RegisterFunc(ts.Server(), "busy", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			calls++
+			return nil, tchannel.ErrServerBusy
+		})
+
+		client := ts.NewClient(nil)
+		client.Peers().Add(ts.HostPort())
+		sc := client.GetSubChannel(ts.Server().ServiceName())
+		assert.Error(t, sc.SetRetryBudget(tchannel.RetryBudget{Ratio: -1}), "Negative ratio should fail")
+		require.NoError(t, sc.SetRetryBudget(tchannel.RetryBudget{Burst: 2}), "SetRetryBudget failed")
+
+		ctx, cancel := tchannel.NewContext(time.Second)
+		defer cancel()
+
+		err := client.RunWithRetry(ctx, func(ctx context.Context, rs *tchannel.RequestState) error {
+			_, err := raw.CallV2(ctx, sc, raw.CArgs{
+				Method:      "busy",
+				CallOptions: &tchannel.CallOptions{RequestState: rs},
+			})
+			return err
+		})
+		assert.Equal(t, tchannel.ErrServerBusy, err, "Expected error from the last attempt")
+		assert.Equal(t, 3, calls, "Expected retries to be limited by the service budget")
+	})
+}
+
+func TestRetryBackoff(t *testing.T) {
+	ch := testutils.NewClient(t, nil)
+	defer ch.Close()
+
+	retryOpts := &tchannel.RetryOptions{
+		MaxAttempts: 4,
+		Backoff: &tchannel.BackoffOptions{
+			Initial: 10 * time.Millisecond,
+			Max:     20 * time.Millisecond,
+		},
+	}
+	ctx, cancel := tchannel.NewContextBuilder(time.Second).SetRetryOptions(retryOpts).Build()
+	defer cancel()
+
+	var attemptTimes []time.Time
+	err := ch.RunWithRetry(ctx, func(context.Context, *tchannel.RequestState) error {
+		attemptTimes = append(attemptTimes, time.Now())
+		return tchannel.ErrServerBusy
+	})
+	assert.Equal(t, tchannel.ErrServerBusy, err, "Expected error from the last attempt")
+	require.Len(t, attemptTimes, 4, "Unexpected number of attempts")
+
+	minDelays := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 20 * time.Millisecond}
+	for i, minDelay := range minDelays {
+		delay := attemptTimes[i+1].Sub(attemptTimes[i])
+		assert.True(t, delay >= minDelay, "Retry %v delay %v should be at least %v", i+1, delay, minDelay)
+	}
+}
+
+func TestRetryBackoffContextDone(t *testing.T) {
+	ch := testutils.NewClient(t, nil)
+	defer ch.Close()
+
+	retryOpts := &tchannel.RetryOptions{
+		Backoff: &tchannel.BackoffOptions{Initial: time.Second},
+	}
+	ctx, cancel := tchannel.NewContextBuilder(50 * time.Millisecond).SetRetryOptions(retryOpts).Build()
+	defer cancel()
+
+	attempts := 0
+	err := ch.RunWithRetry(ctx, func(context.Context, *tchannel.RequestState) error {
+		attempts++
+		return tchannel.ErrServerBusy
+	})
+	assert.Equal(t, tchannel.ErrServerBusy, err, "Expected error from the last attempt")
+	assert.Equal(t, 1, attempts, "Retry should not be made after the context is done")
+}
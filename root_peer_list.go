@@ -29,13 +29,20 @@ type RootPeerList struct {
 
 	channel             Connectable
 	onPeerStatusChanged func(*Peer)
+	newBreaker          func() *circuitBreaker
+	connsPerPeer        int
 	peersByHostPort     map[string]*Peer
 }
 
-func newRootPeerList(ch Connectable, onPeerStatusChanged func(*Peer)) *RootPeerList {
+func newRootPeerList(ch Connectable, onPeerStatusChanged func(*Peer), newBreaker func() *circuitBreaker, connsPerPeer int) *RootPeerList {
+	if newBreaker == nil {
+		newBreaker = func() *circuitBreaker { return nil }
+	}
 	return &RootPeerList{
 		channel:             ch,
 		onPeerStatusChanged: onPeerStatusChanged,
+		newBreaker:          newBreaker,
+		connsPerPeer:        connsPerPeer,
 		peersByHostPort:     make(map[string]*Peer),
 	}
 }
@@ -68,6 +75,8 @@ func (l *RootPeerList) Add(hostPort string) *Peer {
 	// To avoid duplicate connections, only the root list should create new
 	// peers. All other lists should keep refs to the root list's peers.
 	p = newPeer(l.channel, hostPort, l.onPeerStatusChanged, l.onClosedConnRemoved)
+	p.breaker = l.newBreaker()
+	p.connsPerPeer = l.connsPerPeer
 	l.peersByHostPort[hostPort] = p
 	return p
 }
@@ -103,7 +112,7 @@ func (l *RootPeerList) onClosedConnRemoved(peer *Peer) {
 		l.Lock()
 		delete(l.peersByHostPort, hostPort)
 		l.Unlock()
-		l.channel.Logger().WithFields(
+		loggerFor(l.channel, LogSubsystemPeer).WithFields(
 			LogField{"remoteHostPort", hostPort},
 		).Debug("Removed peer from root peer list.")
 	}
@@ -0,0 +1,217 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// The smallest values of options that can be set using UpdateOptions, so that
+// an update can't close every connection or fail every relayed call.
+const (
+	minMaxIdleTime       = time.Second
+	minIdleCheckInterval = time.Second
+	minRelayMaxTimeout   = time.Second
+)
+
+var (
+	errInvalidIdleCheck = fmt.Errorf("MaxIdleTime and IdleCheckInterval must be zero or at least %v and %v",
+		minMaxIdleTime, minIdleCheckInterval)
+	errInvalidRelayMaxTimeout = fmt.Errorf("RelayMaxTimeout must be between %v and MaxUint32 milliseconds",
+		minRelayMaxTimeout)
+	errInvalidHealthChecks   = errors.New("HealthChecks options must not be negative")
+	errInvalidSendBufferSize = errors.New("SendBufferSize must be positive")
+)
+
+// RuntimeOptions are the options that can be changed after a channel is
+// created using UpdateOptions. See ChannelOptions and ConnectionOptions for
+// the meaning of each option.
+//
+// MaxIdleTime and IdleCheckInterval must be zero or at least 1s, and
+// RelayMaxTimeout must be at least 1s.
+type RuntimeOptions struct {
+	// MaxIdleTime and IdleCheckInterval configure the idle connection sweep,
+	// which is restarted when either is changed.
+	MaxIdleTime       time.Duration `json:"maxIdleTime"`
+	IdleCheckInterval time.Duration `json:"idleCheckInterval"`
+
+	// RelayMaxTimeout applies to calls relayed on all connections, including
+	// existing connections.
+	RelayMaxTimeout time.Duration `json:"relayMaxTimeout"`
+
+	// HealthChecks and SendBufferSize only apply to new connections.
+	HealthChecks   HealthCheckOptions `json:"healthChecks"`
+	SendBufferSize int                `json:"sendBufferSize"`
+}
+
+func (o RuntimeOptions) validate() error {
+	if !isZeroOrAtLeast(o.MaxIdleTime, minMaxIdleTime) || !isZeroOrAtLeast(o.IdleCheckInterval, minIdleCheckInterval) {
+		return errInvalidIdleCheck
+	}
+	if o.IdleCheckInterval > 0 && o.MaxIdleTime <= 0 {
+		return errMaxIdleTimeNotSet
+	}
+	if o.RelayMaxTimeout < minRelayMaxTimeout || !isValidRelayMaxTimeout(o.RelayMaxTimeout) {
+		return errInvalidRelayMaxTimeout
+	}
+	hc := o.HealthChecks
+	if hc.Interval < 0 || hc.Timeout < 0 || hc.FailuresToClose < 0 {
+		return errInvalidHealthChecks
+	}
+	if o.SendBufferSize <= 0 {
+		return errInvalidSendBufferSize
+	}
+	return nil
+}
+
+func isZeroOrAtLeast(d, min time.Duration) bool {
+	return d == 0 || d >= min
+}
+
+// RuntimeOptions returns the current values of the options that can be
+// changed using UpdateOptions.
+func (ch *Channel) RuntimeOptions() RuntimeOptions {
+	ch.mutable.RLock()
+	defer ch.mutable.RUnlock()
+
+	return ch.runtimeOptionsLocked()
+}
+
+func (ch *Channel) runtimeOptionsLocked() RuntimeOptions {
+	return RuntimeOptions{
+		MaxIdleTime:       ch.mutable.idleSweep.maxIdleTime,
+		IdleCheckInterval: ch.mutable.idleSweep.idleCheckInterval,
+		RelayMaxTimeout:   ch.relayMaxTimeout.Load(),
+		HealthChecks:      ch.connectionOptions.HealthChecks,
+		SendBufferSize:    ch.connectionOptions.SendBufferSize,
+	}
+}
+
+// UpdateOptions replaces the channel's runtime options after validating them.
+// To change a single option, modify the value returned by RuntimeOptions.
+func (ch *Channel) UpdateOptions(opts RuntimeOptions) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	opts.HealthChecks = opts.HealthChecks.withDefaults()
+
+	ch.mutable.Lock()
+	defer ch.mutable.Unlock()
+
+	if ch.mutable.state >= ChannelStartClose {
+		return ErrChannelClosed
+	}
+
+	prev := ch.runtimeOptionsLocked()
+	if opts == prev {
+		return nil
+	}
+
+	if opts.MaxIdleTime != prev.MaxIdleTime || opts.IdleCheckInterval != prev.IdleCheckInterval {
+		ch.mutable.idleSweep.Stop()
+		ch.mutable.idleSweep = startIdleSweep(ch, opts.MaxIdleTime, opts.IdleCheckInterval)
+	}
+	ch.relayMaxTimeout.Store(opts.RelayMaxTimeout)
+	ch.connectionOptions.HealthChecks = opts.HealthChecks
+	ch.connectionOptions.SendBufferSize = opts.SendBufferSize
+
+	ch.log.WithFields(
+		LogField{"previousOptions", prev},
+		LogField{"options", opts},
+	).Info("Updated channel options.")
+	return nil
+}
+
+// getConnectionOptions returns a copy of the connection options, which may
+// be changed by UpdateOptions.
+func (ch *Channel) getConnectionOptions() ConnectionOptions {
+	ch.mutable.RLock()
+	defer ch.mutable.RUnlock()
+
+	return ch.connectionOptions
+}
+
+// configDuration decodes a JSON duration into d, either as a string such as
+// "30s", or as a number of nanoseconds.
+type configDuration struct {
+	d *time.Duration
+}
+
+func (c configDuration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return json.Unmarshal(data, c.d)
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*c.d = d
+	return nil
+}
+
+// configRequest is the request for _gometa_config. Each field points to the
+// option it updates, so options that are not specified are not changed.
+type configRequest struct {
+	MaxIdleTime       configDuration `json:"maxIdleTime"`
+	IdleCheckInterval configDuration `json:"idleCheckInterval"`
+	RelayMaxTimeout   configDuration `json:"relayMaxTimeout"`
+	HealthChecks      struct {
+		Interval        configDuration `json:"interval"`
+		Timeout         configDuration `json:"timeout"`
+		FailuresToClose *int           `json:"failuresToClose"`
+	} `json:"healthChecks"`
+	SendBufferSize *int `json:"sendBufferSize"`
+}
+
+func newConfigRequest(opts *RuntimeOptions) *configRequest {
+	req := &configRequest{
+		MaxIdleTime:       configDuration{&opts.MaxIdleTime},
+		IdleCheckInterval: configDuration{&opts.IdleCheckInterval},
+		RelayMaxTimeout:   configDuration{&opts.RelayMaxTimeout},
+		SendBufferSize:    &opts.SendBufferSize,
+	}
+	req.HealthChecks.Interval = configDuration{&opts.HealthChecks.Interval}
+	req.HealthChecks.Timeout = configDuration{&opts.HealthChecks.Timeout}
+	req.HealthChecks.FailuresToClose = &opts.HealthChecks.FailuresToClose
+	return req
+}
+
+// handleConfig applies the options in arg3 to the current runtime options,
+// so that only the options that are specified are changed. Durations may be
+// specified as strings, such as "30s", or as nanoseconds.
+func (ch *Channel) handleConfig(arg3 []byte) interface{} {
+	opts := ch.RuntimeOptions()
+	if len(arg3) > 0 {
+		if err := json.Unmarshal(arg3, newConfigRequest(&opts)); err != nil {
+			return map[string]string{"error": err.Error()}
+		}
+		if err := ch.UpdateOptions(opts); err != nil {
+			return map[string]string{"error": err.Error()}
+		}
+	}
+
+	return ch.RuntimeOptions()
+}
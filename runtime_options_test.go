@@ -0,0 +1,275 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/temporalio/tchannel-go"
+	"github.com/temporalio/tchannel-go/json"
+	"github.com/temporalio/tchannel-go/raw"
+	"github.com/temporalio/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateOptions(t *testing.T) {
+	opts := testutils.NewOpts()
+	opts.RelayMaxTimeout = time.Minute
+	opts.DefaultConnectionOptions.SendBufferSize = 100
+	ch := testutils.NewServer(t, opts)
+	defer ch.Close()
+
+	assert.Equal(t, tchannel.RuntimeOptions{
+		RelayMaxTimeout: time.Minute,
+		HealthChecks: tchannel.HealthCheckOptions{
+			Timeout:         time.Second,
+			FailuresToClose: 5,
+		},
+		SendBufferSize: 100,
+	}, ch.RuntimeOptions(), "unexpected initial options")
+
+	updated := tchannel.RuntimeOptions{
+		MaxIdleTime:       time.Minute,
+		IdleCheckInterval: time.Second,
+		RelayMaxTimeout:   time.Second,
+		HealthChecks:      tchannel.HealthCheckOptions{Interval: time.Second},
+		SendBufferSize:    10,
+	}
+	require.NoError(t, ch.UpdateOptions(updated), "UpdateOptions failed")
+
+	updated.HealthChecks = tchannel.HealthCheckOptions{
+		Interval:        time.Second,
+		Timeout:         time.Second,
+		FailuresToClose: 5,
+	}
+	assert.Equal(t, updated, ch.RuntimeOptions(), "options not updated")
+	assert.Equal(t, updated.HealthChecks, ch.ConnectionOptions().HealthChecks, "connection options not updated")
+	assert.Equal(t, 10, ch.ConnectionOptions().SendBufferSize, "connection options not updated")
+
+	ch.Close()
+	assert.Equal(t, tchannel.ErrChannelClosed, ch.UpdateOptions(tchannel.RuntimeOptions{
+		RelayMaxTimeout: time.Second,
+		SendBufferSize:  10,
+	}), "UpdateOptions should fail after Close")
+}
+
+func TestUpdateOptionsValidation(t *testing.T) {
+	ch := testutils.NewServer(t, nil)
+	defer ch.Close()
+
+	tests := []struct {
+		msg    string
+		update func(*tchannel.RuntimeOptions)
+	}{
+		{
+			msg:    "negative max idle time",
+			update: func(o *tchannel.RuntimeOptions) { o.MaxIdleTime = -time.Second },
+		},
+		{
+			msg:    "max idle time below minimum",
+			update: func(o *tchannel.RuntimeOptions) { o.MaxIdleTime = 100 * time.Millisecond },
+		},
+		{
+			msg: "idle check interval below minimum",
+			update: func(o *tchannel.RuntimeOptions) {
+				o.MaxIdleTime = time.Minute
+				o.IdleCheckInterval = time.Millisecond
+			},
+		},
+		{
+			msg:    "idle check interval without max idle time",
+			update: func(o *tchannel.RuntimeOptions) { o.IdleCheckInterval = time.Second },
+		},
+		{
+			msg:    "zero relay max timeout",
+			update: func(o *tchannel.RuntimeOptions) { o.RelayMaxTimeout = 0 },
+		},
+		{
+			msg:    "relay max timeout below minimum",
+			update: func(o *tchannel.RuntimeOptions) { o.RelayMaxTimeout = 100 * time.Millisecond },
+		},
+		{
+			msg:    "negative health check interval",
+			update: func(o *tchannel.RuntimeOptions) { o.HealthChecks.Interval = -time.Second },
+		},
+		{
+			msg:    "negative health check failures",
+			update: func(o *tchannel.RuntimeOptions) { o.HealthChecks.FailuresToClose = -1 },
+		},
+		{
+			msg:    "zero send buffer size",
+			update: func(o *tchannel.RuntimeOptions) { o.SendBufferSize = 0 },
+		},
+	}
+
+	for _, tt := range tests {
+		want := ch.RuntimeOptions()
+		opts := want
+		tt.update(&opts)
+		assert.Error(t, ch.UpdateOptions(opts), "%v: expected error", tt.msg)
+		assert.Equal(t, want, ch.RuntimeOptions(), "%v: options should not change", tt.msg)
+	}
+}
+
+func TestUpdateOptionsIdleSweep(t *testing.T) {
+	listener := newPeerStatusListener()
+	ctx, cancel := tchannel.NewContext(time.Second)
+	defer cancel()
+
+	serverTicker := testutils.NewFakeTicker()
+	clock := testutils.NewStubClock(time.Now())
+
+	serverOpts := testutils.NewOpts().
+		SetTimeTicker(serverTicker.New).
+		SetOnPeerStatusChanged(listener.onStatusChange).
+		SetTimeNow(clock.Now).
+		NoRelay()
+
+	clientOpts := testutils.NewOpts().
+		SetOnPeerStatusChanged(listener.onStatusChange)
+
+	testutils.WithTestServer(t, serverOpts, func(t testing.TB, ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+
+		client := ts.NewClient(clientOpts)
+		_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, nil)
+		require.NoError(t, err, "Call failed")
+
+		// The idle sweep is not running, so the connection is kept.
+		clock.Elapse(time.Hour)
+		assert.Equal(t, 1, numConnections(ts.Server()))
+
+		opts := ts.Server().RuntimeOptions()
+		opts.MaxIdleTime = time.Minute
+		opts.IdleCheckInterval = 30 * time.Second
+		require.NoError(t, ts.Server().UpdateOptions(opts), "UpdateOptions failed")
+
+		serverTicker.Tick()
+		listener.waitForZeroConnections(t, ts.Server(), client)
+	})
+}
+
+func TestUpdateOptionsRelayMaxTimeout(t *testing.T) {
+	testutils.WithTestServer(t, testutils.NewOpts().SetRelayOnly(), func(t testing.TB, ts *testutils.TestServer) {
+		testutils.RegisterEcho(ts.Server(), nil)
+
+		ctx, cancel := tchannel.NewContext(time.Second)
+		defer cancel()
+
+		client := ts.NewClient(nil)
+		_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, nil)
+		require.NoError(t, err, "Call failed")
+
+		opts := ts.Relay().RuntimeOptions()
+		opts.RelayMaxTimeout = 3 * time.Second
+		require.NoError(t, ts.Relay().UpdateOptions(opts), "UpdateOptions failed")
+
+		// Relayers on existing connections should use the new timeout.
+		var numRelayers int
+		for _, peer := range ts.Relay().IntrospectState(nil).RootPeers {
+			for _, conn := range append(peer.InboundConnections, peer.OutboundConnections...) {
+				assert.Equal(t, 3*time.Second, conn.Relayer.MaxTimeout, "unexpected relayer max timeout")
+				numRelayers++
+			}
+		}
+		assert.NotZero(t, numRelayers, "expected relayers on existing connections")
+	})
+}
+
+func TestUpdateOptionsEndpoint(t *testing.T) {
+	opts := testutils.NewOpts().NoRelay()
+	opts.EnableConfigEndpoint = true
+	testutils.WithTestServer(t, opts, func(t testing.TB, ts *testutils.TestServer) {
+		client := testutils.NewClient(t, nil)
+		defer client.Close()
+
+		ctx, cancel := json.NewContext(time.Second)
+		defer cancel()
+
+		want := ts.Server().RuntimeOptions()
+
+		var resp tchannel.RuntimeOptions
+		peer := client.Peers().GetOrAdd(ts.HostPort())
+		require.NoError(t, json.CallPeer(ctx, peer, "tchannel", "_gometa_config", nil, &resp),
+			"Call _gometa_config failed")
+		assert.Equal(t, want, resp, "unexpected options")
+
+		// Only the specified options are changed, and durations can be
+		// specified as strings or nanoseconds.
+		require.NoError(t, json.CallPeer(ctx, peer, "tchannel", "_gometa_config", map[string]interface{}{
+			"sendBufferSize":  50,
+			"relayMaxTimeout": "90s",
+			"healthChecks":    map[string]interface{}{"interval": time.Second},
+		}, &resp), "Call _gometa_config failed")
+		want.SendBufferSize = 50
+		want.RelayMaxTimeout = 90 * time.Second
+		want.HealthChecks.Interval = time.Second
+		assert.Equal(t, want, resp, "unexpected options in response")
+		assert.Equal(t, want, ts.Server().RuntimeOptions(), "options not applied to channel")
+
+		errTests := []struct {
+			msg     string
+			req     map[string]interface{}
+			wantErr string
+		}{
+			{
+				msg:     "below minimum",
+				req:     map[string]interface{}{"maxIdleTime": "10ms"},
+				wantErr: "MaxIdleTime and IdleCheckInterval must be zero or at least 1s and 1s",
+			},
+			{
+				msg:     "invalid duration",
+				req:     map[string]interface{}{"relayMaxTimeout": "soon"},
+				wantErr: `time: invalid duration "soon"`,
+			},
+		}
+		for _, tt := range errTests {
+			var errResp map[string]interface{}
+			require.NoError(t, json.CallPeer(ctx, peer, "tchannel", "_gometa_config", tt.req, &errResp),
+				"%v: Call _gometa_config failed", tt.msg)
+			assert.Equal(t, tt.wantErr, errResp["error"], "%v: unexpected error", tt.msg)
+			assert.Equal(t, want, ts.Server().RuntimeOptions(), "%v: options should not change on error", tt.msg)
+		}
+	})
+}
+
+func TestUpdateOptionsEndpointDisabled(t *testing.T) {
+	opts := testutils.NewOpts().NoRelay().AddLogFilter("Couldn't find handler.", 1)
+	testutils.WithTestServer(t, opts, func(t testing.TB, ts *testutils.TestServer) {
+		client := testutils.NewClient(t, nil)
+		defer client.Close()
+
+		ctx, cancel := json.NewContext(time.Second)
+		defer cancel()
+
+		var resp tchannel.RuntimeOptions
+		peer := client.Peers().GetOrAdd(ts.HostPort())
+		err := json.CallPeer(ctx, peer, "tchannel", "_gometa_config", map[string]interface{}{
+			"sendBufferSize": 50,
+		}, &resp)
+		require.Error(t, err, "_gometa_config should not be registered")
+		assert.Contains(t, err.Error(), "no handler for service", "Unexpected error")
+		assert.NotEqual(t, 50, ts.Server().RuntimeOptions().SendBufferSize, "options should not change")
+	})
+}
@@ -0,0 +1,427 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package stats
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/temporalio/tchannel-go"
+	thttp "github.com/temporalio/tchannel-go/http"
+
+	"go.uber.org/atomic"
+	"golang.org/x/net/context"
+)
+
+// DefaultPrometheusBuckets are the histogram buckets, in seconds, used for
+// timers if PrometheusOptions.Buckets is not set.
+var DefaultPrometheusBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+const (
+	// defaultMaxLabelValues is the default limit on distinct values per label.
+	defaultMaxLabelValues = 1000
+
+	// overflowLabelValue replaces label values once the limit is reached.
+	overflowLabelValue = "_other"
+
+	prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// PrometheusOptions are options for NewPrometheusReporter.
+type PrometheusOptions struct {
+	// Namespace is prepended to all metric names. Defaults to "tchannel".
+	Namespace string
+
+	// Buckets are the upper bounds, in seconds, of the histogram buckets
+	// used for timers. Defaults to DefaultPrometheusBuckets.
+	Buckets []float64
+
+	// MaxLabelValues limits the number of distinct values tracked for each
+	// label, to guard against high-cardinality tags such as method names.
+	// Once the limit is reached, new values are reported as "_other".
+	// Defaults to 1000; a negative value disables the limit.
+	MaxLabelValues int
+}
+
+// PrometheusReporter is a StatsReporter that exposes metrics in the
+// Prometheus text format. Counters are exported with a "_total" suffix,
+// and timers as histograms, in seconds, with a "_seconds" suffix.
+//
+// TChannel's tags are mapped to the labels: service, method, caller and
+// retry_count. All other tags are ignored.
+type PrometheusReporter struct {
+	sync.RWMutex
+
+	namespace      string
+	buckets        []float64
+	maxLabelValues int
+
+	metrics     map[string]*promMetric
+	labelValues map[string]map[string]struct{}
+}
+
+type promMetricType string
+
+const (
+	promCounter   promMetricType = "counter"
+	promGauge     promMetricType = "gauge"
+	promHistogram promMetricType = "histogram"
+)
+
+type promMetric struct {
+	sync.RWMutex
+
+	name       string
+	metricType promMetricType
+	series     map[promLabels]*promSeries
+}
+
+// promLabels are the labels for a single series.
+type promLabels struct {
+	service    string
+	method     string
+	caller     string
+	retryCount string
+}
+
+type promSeries struct {
+	value atomic.Int64
+
+	// Histograms are updated under a lock so that a scrape sees a
+	// consistent count, sum and buckets.
+	sync.Mutex
+	bucketCounts []uint64
+	sum          float64
+	count        uint64
+}
+
+var _ tchannel.StatsReporter = (*PrometheusReporter)(nil)
+
+// NewPrometheusReporter returns a StatsReporter that exposes metrics for
+// Prometheus. Use ServeHTTP or Register to expose the metrics for scraping.
+func NewPrometheusReporter(opts PrometheusOptions) *PrometheusReporter {
+	if opts.Namespace == "" {
+		opts.Namespace = "tchannel"
+	}
+	if opts.Buckets == nil {
+		opts.Buckets = DefaultPrometheusBuckets
+	}
+	buckets := append([]float64(nil), opts.Buckets...)
+	sort.Float64s(buckets)
+
+	if opts.MaxLabelValues == 0 {
+		opts.MaxLabelValues = defaultMaxLabelValues
+	}
+
+	return &PrometheusReporter{
+		namespace:      opts.Namespace,
+		buckets:        buckets,
+		maxLabelValues: opts.MaxLabelValues,
+		metrics:        make(map[string]*promMetric),
+		labelValues:    make(map[string]map[string]struct{}),
+	}
+}
+
+// IncCounter increments the counter for the given name and tags.
+func (r *PrometheusReporter) IncCounter(name string, tags map[string]string, value int64) {
+	r.getSeries(name+"_total", promCounter, tags).value.Add(value)
+}
+
+// UpdateGauge sets the gauge for the given name and tags.
+func (r *PrometheusReporter) UpdateGauge(name string, tags map[string]string, value int64) {
+	r.getSeries(name, promGauge, tags).value.Store(value)
+}
+
+// RecordTimer records d in the histogram for the given name and tags.
+func (r *PrometheusReporter) RecordTimer(name string, tags map[string]string, d time.Duration) {
+	s := r.getSeries(name+"_seconds", promHistogram, tags)
+	seconds := d.Seconds()
+	idx := sort.SearchFloat64s(r.buckets, seconds)
+
+	s.Lock()
+	if idx < len(s.bucketCounts) {
+		s.bucketCounts[idx]++
+	}
+	s.sum += seconds
+	s.count++
+	s.Unlock()
+}
+
+// ServeHTTP writes all metrics in the Prometheus text format.
+func (r *PrometheusReporter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", prometheusContentType)
+	w.Write(r.export())
+}
+
+// Register registers a "_metrics" endpoint on the given registrar that
+// serves the metrics over TChannel. Like the pprof endpoint, it uses
+// as-http and responds to any HTTP request with the metrics.
+func (r *PrometheusReporter) Register(registrar tchannel.Registrar) {
+	handler := func(ctx context.Context, call *tchannel.InboundCall) {
+		req, err := thttp.ReadRequest(call)
+		if err != nil {
+			registrar.Logger().WithFields(
+				tchannel.LogField{Key: "err", Value: err.Error()},
+			).Warn("Failed to read HTTP request.")
+			return
+		}
+
+		rw, finish := thttp.ResponseWriter(call.Response())
+		r.ServeHTTP(rw, req)
+		finish()
+	}
+	registrar.Register(tchannel.HandlerFunc(handler), "_metrics")
+}
+
+func (r *PrometheusReporter) getSeries(name string, metricType promMetricType, tags map[string]string) *promSeries {
+	m := r.getMetric(name, metricType)
+	labels := r.convertLabels(tags)
+
+	m.RLock()
+	s, ok := m.series[labels]
+	m.RUnlock()
+	if ok {
+		return s
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	// Always double-check under the write-lock.
+	if s, ok := m.series[labels]; ok {
+		return s
+	}
+
+	s = &promSeries{}
+	if metricType == promHistogram {
+		s.bucketCounts = make([]uint64, len(r.buckets))
+	}
+	m.series[labels] = s
+	return s
+}
+
+func (r *PrometheusReporter) getMetric(name string, metricType promMetricType) *promMetric {
+	key := string(metricType) + ":" + name
+
+	r.RLock()
+	m, ok := r.metrics[key]
+	r.RUnlock()
+	if ok {
+		return m
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	// Always double-check under the write-lock.
+	if m, ok := r.metrics[key]; ok {
+		return m
+	}
+
+	m = &promMetric{
+		name:       promName(r.namespace + "_" + name),
+		metricType: metricType,
+		series:     make(map[promLabels]*promSeries),
+	}
+	r.metrics[key] = m
+	return m
+}
+
+// convertLabels maps TChannel's tags to labels, applying the cardinality limit.
+func (r *PrometheusReporter) convertLabels(tags map[string]string) promLabels {
+	kt := convertTags(tags)
+	return promLabels{
+		service:    r.limitLabel("service", kt.dest),
+		method:     r.limitLabel("method", kt.procedure),
+		caller:     r.limitLabel("caller", kt.source),
+		retryCount: r.limitLabel("retry_count", kt.retryCount),
+	}
+}
+
+// limitLabel returns the value to use for the given label, replacing new
+// values with overflowLabelValue once the label has too many values.
+func (r *PrometheusReporter) limitLabel(label, value string) string {
+	if value == "" || r.maxLabelValues < 0 {
+		return value
+	}
+
+	r.RLock()
+	_, ok := r.labelValues[label][value]
+	r.RUnlock()
+	if ok {
+		return value
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	values, ok := r.labelValues[label]
+	if !ok {
+		values = make(map[string]struct{})
+		r.labelValues[label] = values
+	}
+	if _, ok := values[value]; ok {
+		return value
+	}
+	if len(values) >= r.maxLabelValues {
+		return overflowLabelValue
+	}
+	values[value] = struct{}{}
+	return value
+}
+
+// export returns all metrics in the Prometheus text format, sorted by name.
+func (r *PrometheusReporter) export() []byte {
+	r.RLock()
+	metrics := make([]*promMetric, 0, len(r.metrics))
+	for _, m := range r.metrics {
+		metrics = append(metrics, m)
+	}
+	r.RUnlock()
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].name < metrics[j].name
+	})
+
+	buf := &bytes.Buffer{}
+	for _, m := range metrics {
+		m.writeTo(buf, r.buckets)
+	}
+	return buf.Bytes()
+}
+
+func (m *promMetric) writeTo(buf *bytes.Buffer, buckets []float64) {
+	m.RLock()
+	type labeledSeries struct {
+		labels string
+		series *promSeries
+	}
+	series := make([]labeledSeries, 0, len(m.series))
+	for labels, s := range m.series {
+		series = append(series, labeledSeries{labels.String(), s})
+	}
+	m.RUnlock()
+	sort.Slice(series, func(i, j int) bool {
+		return series[i].labels < series[j].labels
+	})
+
+	buf.WriteString("# TYPE ")
+	buf.WriteString(m.name)
+	buf.WriteByte(' ')
+	buf.WriteString(string(m.metricType))
+	buf.WriteByte('\n')
+
+	for _, ls := range series {
+		if m.metricType != promHistogram {
+			writeSample(buf, m.name, ls.labels, "", strconv.FormatInt(ls.series.value.Load(), 10))
+			continue
+		}
+
+		s := ls.series
+		s.Lock()
+		var cumulative uint64
+		for i, upper := range buckets {
+			cumulative += s.bucketCounts[i]
+			writeSample(buf, m.name+"_bucket", ls.labels, formatFloat(upper), strconv.FormatUint(cumulative, 10))
+		}
+		writeSample(buf, m.name+"_bucket", ls.labels, "+Inf", strconv.FormatUint(s.count, 10))
+		writeSample(buf, m.name+"_sum", ls.labels, "", formatFloat(s.sum))
+		writeSample(buf, m.name+"_count", ls.labels, "", strconv.FormatUint(s.count, 10))
+		s.Unlock()
+	}
+}
+
+// String returns the labels in the Prometheus text format, without braces.
+// Empty labels are omitted.
+func (l promLabels) String() string {
+	buf := &bytes.Buffer{}
+	writeLabel(buf, "service", l.service)
+	writeLabel(buf, "method", l.method)
+	writeLabel(buf, "caller", l.caller)
+	writeLabel(buf, "retry_count", l.retryCount)
+	return buf.String()
+}
+
+func writeLabel(buf *bytes.Buffer, name, value string) {
+	if value == "" {
+		return
+	}
+	if buf.Len() > 0 {
+		buf.WriteByte(',')
+	}
+	buf.WriteString(name)
+	buf.WriteString(`="`)
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '\\':
+			buf.WriteString(`\\`)
+		case '"':
+			buf.WriteString(`\"`)
+		case '\n':
+			buf.WriteString(`\n`)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteByte('"')
+}
+
+func writeSample(buf *bytes.Buffer, name, labels, le, value string) {
+	buf.WriteString(name)
+	if labels != "" || le != "" {
+		buf.WriteByte('{')
+		buf.WriteString(labels)
+		if le != "" {
+			if labels != "" {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(`le="`)
+			buf.WriteString(le)
+			buf.WriteByte('"')
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// promName replaces characters that are not valid in Prometheus metric
+// names with '_'.
+func promName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_', c == ':':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
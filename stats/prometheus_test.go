@@ -0,0 +1,167 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package stats
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/temporalio/tchannel-go"
+	thttp "github.com/temporalio/tchannel-go/http"
+	"github.com/temporalio/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusReporter(t *testing.T) {
+	r := NewPrometheusReporter(PrometheusOptions{
+		Buckets: []float64{1, 0.01, 0.1},
+	})
+
+	outboundTags := map[string]string{
+		"service":         "caller",
+		"target-service":  "svc",
+		"target-endpoint": "method",
+		"host":            "ignored",
+	}
+	inboundTags := map[string]string{
+		"service":         "svc",
+		"calling-service": "caller",
+		"endpoint":        "method",
+	}
+	retryTags := map[string]string{
+		"service":         "caller",
+		"target-service":  "svc",
+		"target-endpoint": "method",
+		"retry-count":     "2",
+	}
+
+	r.IncCounter("outbound.calls.send", outboundTags, 1)
+	r.IncCounter("outbound.calls.send", outboundTags, 2)
+	r.IncCounter("outbound.calls.retries", retryTags, 1)
+	r.IncCounter("inbound.calls.recvd", inboundTags, 1)
+	r.UpdateGauge("inbound.calls.concurrency-limit", nil, 10)
+	r.UpdateGauge("inbound.calls.concurrency-limit", nil, 5)
+	r.RecordTimer("inbound.calls.latency", inboundTags, 5*time.Millisecond)
+	r.RecordTimer("inbound.calls.latency", inboundTags, 50*time.Millisecond)
+	r.RecordTimer("inbound.calls.latency", inboundTags, 2*time.Second)
+
+	want := `# TYPE tchannel_inbound_calls_concurrency_limit gauge
+tchannel_inbound_calls_concurrency_limit 5
+# TYPE tchannel_inbound_calls_latency_seconds histogram
+tchannel_inbound_calls_latency_seconds_bucket{service="svc",method="method",caller="caller",le="0.01"} 1
+tchannel_inbound_calls_latency_seconds_bucket{service="svc",method="method",caller="caller",le="0.1"} 2
+tchannel_inbound_calls_latency_seconds_bucket{service="svc",method="method",caller="caller",le="1"} 2
+tchannel_inbound_calls_latency_seconds_bucket{service="svc",method="method",caller="caller",le="+Inf"} 3
+tchannel_inbound_calls_latency_seconds_sum{service="svc",method="method",caller="caller"} 2.055
+tchannel_inbound_calls_latency_seconds_count{service="svc",method="method",caller="caller"} 3
+# TYPE tchannel_inbound_calls_recvd_total counter
+tchannel_inbound_calls_recvd_total{service="svc",method="method",caller="caller"} 1
+# TYPE tchannel_outbound_calls_retries_total counter
+tchannel_outbound_calls_retries_total{service="svc",method="method",caller="caller",retry_count="2"} 1
+# TYPE tchannel_outbound_calls_send_total counter
+tchannel_outbound_calls_send_total{service="svc",method="method",caller="caller"} 3
+`
+	assert.Equal(t, want, string(r.export()))
+}
+
+func TestPrometheusReporterLabelLimit(t *testing.T) {
+	r := NewPrometheusReporter(PrometheusOptions{
+		Namespace:      "test",
+		MaxLabelValues: 2,
+	})
+
+	for i := 0; i < 5; i++ {
+		r.IncCounter("inbound.calls.recvd", map[string]string{
+			"service":         "svc",
+			"calling-service": "caller",
+			"endpoint":        fmt.Sprintf("method-%v", i),
+		}, 1)
+	}
+
+	want := `# TYPE test_inbound_calls_recvd_total counter
+test_inbound_calls_recvd_total{service="svc",method="_other",caller="caller"} 3
+test_inbound_calls_recvd_total{service="svc",method="method-0",caller="caller"} 1
+test_inbound_calls_recvd_total{service="svc",method="method-1",caller="caller"} 1
+`
+	assert.Equal(t, want, string(r.export()))
+}
+
+func TestPrometheusLabelEscaping(t *testing.T) {
+	l := promLabels{service: `a"b\c` + "\n"}
+	assert.Equal(t, `service="a\"b\\c\n"`, l.String())
+}
+
+func TestPrometheusName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"tchannel_outbound.calls.per-attempt.latency", "tchannel_outbound_calls_per_attempt_latency"},
+		{"1abc:def", "_abc:def"},
+		{"a1 b", "a1_b"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, promName(tt.name), "promName(%q)", tt.name)
+	}
+}
+
+func TestPrometheusServeHTTP(t *testing.T) {
+	r := NewPrometheusReporter(PrometheusOptions{})
+	r.IncCounter("outbound.calls.send", nil, 1)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, prometheusContentType, rec.Header().Get("Content-Type"))
+	assert.Equal(t, "# TYPE tchannel_outbound_calls_send_total counter\ntchannel_outbound_calls_send_total 1\n", rec.Body.String())
+}
+
+func TestPrometheusRegister(t *testing.T) {
+	r := NewPrometheusReporter(PrometheusOptions{})
+	ch := testutils.NewServer(t, testutils.NewOpts().SetStatsReporter(r))
+	defer ch.Close()
+	r.Register(ch)
+
+	ctx, cancel := tchannel.NewContext(time.Second)
+	defer cancel()
+
+	req, err := http.NewRequest("GET", "/metrics", nil)
+	require.NoError(t, err, "NewRequest failed")
+
+	call, err := ch.BeginCall(ctx, ch.PeerInfo().HostPort, ch.ServiceName(), "_metrics", nil)
+	require.NoError(t, err, "BeginCall failed")
+	require.NoError(t, thttp.WriteRequest(call, req), "thttp.WriteRequest failed")
+
+	response, err := thttp.ReadResponse(call.Response())
+	require.NoError(t, err, "ReadResponse failed")
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	body, err := ioutil.ReadAll(response.Body)
+	require.NoError(t, err, "Read body failed")
+	assert.Contains(t, string(body), `tchannel_inbound_calls_recvd_total{service="testService",method="_metrics",caller="testService"} 1`)
+}
@@ -0,0 +1,326 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+
+	"go.uber.org/atomic"
+	"golang.org/x/net/context"
+)
+
+// maxStreamMessageSize is the maximum size of a single message sent over a
+// streaming call.
+const maxStreamMessageSize = 64 * 1024 * 1024
+
+var (
+	// ErrStreamClosed is returned when sending on a stream after CloseSend.
+	ErrStreamClosed = errors.New("stream is closed for sending")
+
+	errNotStreamingCall       = NewSystemError(ErrCodeBadRequest, "call is not a streaming call")
+	errStreamMessageTooLarge  = errors.New("stream message exceeds maximum size")
+	errStreamMessageTruncated = errors.New("stream message was truncated")
+)
+
+// A StreamingCall is a call where both peers exchange a sequence of messages
+// over a single call. Each message is sent in arg3 as a length-prefixed
+// chunk, and is flushed as a continue fragment as soon as it is sent.
+//
+// Either side half-closes the stream using CloseSend, after which Recv on the
+// peer returns io.EOF. The call completes once the server closes its side, so
+// callers should close their side first if the server expects it.
+//
+// Send and Recv may be called concurrently with each other, but multiple
+// goroutines must not call Send, or Recv, concurrently.
+//
+// Streams should be used on connections that negotiate flow control (see
+// ConnectionOptions.FlowControlWindow), so that Send blocks until the peer has
+// read earlier messages. Without flow control, messages that have not been
+// read using Recv are buffered by the call, and once that buffer is full the
+// receiving connection stops reading frames for all of its calls until Recv
+// catches up.
+type StreamingCall struct {
+	sendMu     sync.Mutex
+	w          ArgWriter
+	sendErr    error
+	sendLenBuf [4]byte
+
+	recvMu     sync.Mutex
+	r          ArgReader
+	peerArg2   []byte
+	recvErr    error
+	recvLenBuf [4]byte
+	openReader func() ([]byte, ArgReader, error)
+	openErr    error
+
+	// done is set once the call has completed, after which Send and Recv
+	// return io.EOF.
+	done atomic.Bool
+	// isServer is set for the inbound side of the call, which completes the
+	// call when it closes its side of the stream.
+	isServer bool
+}
+
+// BeginStreamingCall starts a new streaming call to the given service and
+// method. The given arg2 is sent with the call request. Calls are not retried.
+func (ch *Channel) BeginStreamingCall(ctx context.Context, hostPort, serviceName, methodName string, callOptions *CallOptions, arg2 []byte) (*StreamingCall, error) {
+	p := ch.RootPeers().GetOrAdd(hostPort)
+	return p.BeginStreamingCall(ctx, serviceName, methodName, callOptions, arg2)
+}
+
+// BeginStreamingCall starts a new streaming call to a peer selected from the
+// subchannel's peers. The given arg2 is sent with the call request.
+func (c *SubChannel) BeginStreamingCall(ctx context.Context, methodName string, callOptions *CallOptions, arg2 []byte) (*StreamingCall, error) {
+	if callOptions == nil {
+		callOptions = defaultCallOptions
+	}
+
+	peer, err := c.peers.Get(callOptions.RequestState.PrevSelectedPeers())
+	if err != nil {
+		return nil, err
+	}
+
+	return peer.BeginStreamingCall(ctx, c.ServiceName(), methodName, callOptions, arg2)
+}
+
+// BeginStreamingCall starts a new streaming call to this peer. The given arg2
+// is sent with the call request.
+func (p *Peer) BeginStreamingCall(ctx context.Context, serviceName, methodName string, callOptions *CallOptions, arg2 []byte) (*StreamingCall, error) {
+	opts := CallOptions{}
+	if callOptions != nil {
+		opts = *callOptions
+	}
+	opts.streaming = true
+
+	call, err := p.BeginCall(ctx, serviceName, methodName, &opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := NewArgWriter(call.Arg2Writer()).Write(arg2); err != nil {
+		return nil, err
+	}
+	w, err := call.Arg3Writer()
+	if err != nil {
+		return nil, err
+	}
+	// Flush the call request so the peer can start handling the call before
+	// any messages are sent.
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+
+	s := &StreamingCall{w: w}
+	s.openReader = func() ([]byte, ArgReader, error) {
+		response := call.Response()
+
+		var arg2 []byte
+		if err := NewArgReader(response.Arg2Reader()).Read(&arg2); err != nil {
+			return nil, nil, err
+		}
+		r, err := response.Arg3Reader()
+		return arg2, r, err
+	}
+	return s, nil
+}
+
+// Streaming returns whether the call was started using BeginStreamingCall.
+func (call *InboundCall) Streaming() bool {
+	return call.headers[Streaming] != ""
+}
+
+// AcceptStream reads the call's arg2 and returns a StreamingCall to exchange
+// messages with the caller. The given arg2 is sent to the caller before any
+// messages. It returns an error if the call is not a streaming call.
+func (call *InboundCall) AcceptStream(arg2 []byte) (*StreamingCall, error) {
+	if !call.Streaming() {
+		return nil, errNotStreamingCall
+	}
+
+	var reqArg2 []byte
+	if err := NewArgReader(call.Arg2Reader()).Read(&reqArg2); err != nil {
+		return nil, err
+	}
+	r, err := call.Arg3Reader()
+	if err != nil {
+		return nil, err
+	}
+
+	response := call.Response()
+	if err := NewArgWriter(response.Arg2Writer()).Write(arg2); err != nil {
+		return nil, err
+	}
+	w, err := response.Arg3Writer()
+	if err != nil {
+		return nil, err
+	}
+	// Flush the call response so the caller can read arg2 before any
+	// messages are sent.
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+
+	return &StreamingCall{
+		w:        w,
+		r:        r,
+		peerArg2: reqArg2,
+		isServer: true,
+	}, nil
+}
+
+// PeerArg2 returns the arg2 sent by the peer. For callers, it blocks until
+// the server has accepted the stream.
+func (s *StreamingCall) PeerArg2() ([]byte, error) {
+	s.recvMu.Lock()
+	defer s.recvMu.Unlock()
+
+	if err := s.openRecv(); err != nil {
+		return nil, err
+	}
+	return s.peerArg2, nil
+}
+
+// Send sends a single message to the peer. It returns ErrStreamClosed after
+// CloseSend, and io.EOF if the call has completed.
+func (s *StreamingCall) Send(msg []byte) error {
+	if len(msg) > maxStreamMessageSize {
+		return errStreamMessageTooLarge
+	}
+
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	if s.sendErr != nil {
+		return s.sendErr
+	}
+	if s.done.Load() {
+		return io.EOF
+	}
+
+	binary.BigEndian.PutUint32(s.sendLenBuf[:], uint32(len(msg)))
+	if _, err := s.w.Write(s.sendLenBuf[:]); err != nil {
+		return s.sendFailed(err)
+	}
+	if _, err := s.w.Write(msg); err != nil {
+		return s.sendFailed(err)
+	}
+	if err := s.w.Flush(); err != nil {
+		return s.sendFailed(err)
+	}
+	return nil
+}
+
+// CloseSend half-closes the stream, after which the peer's Recv returns io.EOF.
+// When called by the server, it completes the call.
+func (s *StreamingCall) CloseSend() error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	if s.sendErr == ErrStreamClosed {
+		return nil
+	}
+	if s.sendErr != nil {
+		return s.sendErr
+	}
+	if s.done.Load() {
+		return io.EOF
+	}
+
+	s.sendErr = ErrStreamClosed
+	if s.isServer {
+		s.done.Store(true)
+	}
+	return s.w.Close()
+}
+
+func (s *StreamingCall) sendFailed(err error) error {
+	if s.done.Load() {
+		err = io.EOF
+	}
+	s.sendErr = err
+	return err
+}
+
+// Recv receives the next message from the peer. It returns io.EOF once the
+// peer has closed its side of the stream, or the call has completed.
+func (s *StreamingCall) Recv() ([]byte, error) {
+	s.recvMu.Lock()
+	defer s.recvMu.Unlock()
+
+	if err := s.openRecv(); err != nil {
+		return nil, err
+	}
+	if s.recvErr != nil {
+		return nil, s.recvErr
+	}
+
+	n, err := io.ReadFull(s.r, s.recvLenBuf[:])
+	if err == io.EOF && n == 0 {
+		// The peer closed its side of the stream.
+		if err := s.r.Close(); err != nil {
+			return nil, s.recvFailed(err)
+		}
+		if !s.isServer {
+			s.done.Store(true)
+		}
+		s.recvErr = io.EOF
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, s.recvFailed(err)
+	}
+
+	size := binary.BigEndian.Uint32(s.recvLenBuf[:])
+	if size > maxStreamMessageSize {
+		return nil, s.recvFailed(errStreamMessageTooLarge)
+	}
+
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(s.r, msg); err != nil {
+		return nil, s.recvFailed(err)
+	}
+	return msg, nil
+}
+
+// openRecv reads the peer's arg2 if it has not been read yet.
+// The caller must hold recvMu.
+func (s *StreamingCall) openRecv() error {
+	if s.openReader != nil {
+		openReader := s.openReader
+		s.openReader = nil
+		s.peerArg2, s.r, s.openErr = openReader()
+	}
+	return s.openErr
+}
+
+func (s *StreamingCall) recvFailed(err error) error {
+	switch {
+	case s.done.Load():
+		err = io.EOF
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		err = errStreamMessageTruncated
+	}
+	s.recvErr = err
+	return err
+}
@@ -0,0 +1,221 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/temporalio/tchannel-go"
+	"github.com/temporalio/tchannel-go/raw"
+	"github.com/temporalio/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// registerStreamEcho registers a streaming handler that echoes every message
+// back to the caller, and closes its side once the caller has closed theirs.
+func registerStreamEcho(t testing.TB, ts *testutils.TestServer) {
+	ts.Register(tchannel.HandlerFunc(func(ctx context.Context, call *tchannel.InboundCall) {
+		stream, err := call.AcceptStream([]byte("res-arg2"))
+		if !assert.NoError(t, err, "AcceptStream failed") {
+			return
+		}
+
+		arg2, err := stream.PeerArg2()
+		assert.NoError(t, err, "PeerArg2 failed")
+		assert.Equal(t, "req-arg2", string(arg2), "Unexpected request arg2")
+
+		for {
+			msg, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if !assert.NoError(t, err, "Recv failed") {
+				return
+			}
+			if !assert.NoError(t, stream.Send(msg), "Send failed") {
+				return
+			}
+		}
+		assert.NoError(t, stream.CloseSend(), "CloseSend failed")
+	}), "echo")
+}
+
+func TestStreamingCall(t *testing.T) {
+	testutils.WithTestServer(t, nil, func(t testing.TB, ts *testutils.TestServer) {
+		registerStreamEcho(t, ts)
+
+		client := ts.NewClient(nil)
+		ctx, cancel := tchannel.NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+
+		stream, err := client.BeginStreamingCall(ctx, ts.HostPort(), ts.ServiceName(), "echo", nil, []byte("req-arg2"))
+		require.NoError(t, err, "BeginStreamingCall failed")
+
+		arg2, err := stream.PeerArg2()
+		require.NoError(t, err, "PeerArg2 failed")
+		assert.Equal(t, "res-arg2", string(arg2), "Unexpected response arg2")
+
+		// Each message is received before the next is sent, so the server
+		// must respond to messages before the request is complete.
+		msgs := [][]byte{
+			[]byte("hello"),
+			{},
+			testutils.RandBytes(100000),
+			[]byte("world"),
+		}
+		for i, msg := range msgs {
+			require.NoError(t, stream.Send(msg), "Send %v failed", i)
+			got, err := stream.Recv()
+			require.NoError(t, err, "Recv %v failed", i)
+			assert.True(t, bytes.Equal(msg, got), "Unexpected message %v", i)
+		}
+
+		require.NoError(t, stream.CloseSend(), "CloseSend failed")
+		assert.Equal(t, tchannel.ErrStreamClosed, stream.Send([]byte("late")), "Send after CloseSend should fail")
+
+		_, err = stream.Recv()
+		assert.Equal(t, io.EOF, err, "Expected EOF after the server closed")
+		_, err = stream.Recv()
+		assert.Equal(t, io.EOF, err, "Recv after EOF should return EOF")
+	})
+}
+
+func TestStreamingCallConcurrentSendRecv(t *testing.T) {
+	// Relays drop calls when a connection's send buffer is full, so keep the
+	// number of in-flight messages below the buffer size.
+	const numMessages = 200
+
+	testutils.WithTestServer(t, nil, func(t testing.TB, ts *testutils.TestServer) {
+		registerStreamEcho(t, ts)
+
+		client := ts.NewClient(nil)
+		ctx, cancel := tchannel.NewContext(testutils.Timeout(5 * time.Second))
+		defer cancel()
+
+		stream, err := client.BeginStreamingCall(ctx, ts.HostPort(), ts.ServiceName(), "echo", nil, []byte("req-arg2"))
+		require.NoError(t, err, "BeginStreamingCall failed")
+
+		sendErr := make(chan error, 1)
+		go func() {
+			for i := 0; i < numMessages; i++ {
+				if err := stream.Send([]byte(fmt.Sprint(i))); err != nil {
+					sendErr <- err
+					return
+				}
+			}
+			sendErr <- stream.CloseSend()
+		}()
+
+		for i := 0; i < numMessages; i++ {
+			msg, err := stream.Recv()
+			require.NoError(t, err, "Recv %v failed", i)
+			assert.Equal(t, fmt.Sprint(i), string(msg), "Messages received out of order")
+		}
+		_, err = stream.Recv()
+		assert.Equal(t, io.EOF, err, "Expected EOF after all messages")
+		assert.NoError(t, <-sendErr, "Send failed")
+	})
+}
+
+func TestStreamingCallServerCloses(t *testing.T) {
+	testutils.WithTestServer(t, nil, func(t testing.TB, ts *testutils.TestServer) {
+		ts.Register(tchannel.HandlerFunc(func(ctx context.Context, call *tchannel.InboundCall) {
+			stream, err := call.AcceptStream(nil)
+			if !assert.NoError(t, err, "AcceptStream failed") {
+				return
+			}
+
+			msg, err := stream.Recv()
+			assert.NoError(t, err, "Recv failed")
+			for i := 0; i < 3; i++ {
+				assert.NoError(t, stream.Send(msg), "Send failed")
+			}
+			assert.NoError(t, stream.CloseSend(), "CloseSend failed")
+		}), "repeat")
+
+		client := ts.NewClient(nil)
+		ctx, cancel := tchannel.NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+
+		stream, err := client.BeginStreamingCall(ctx, ts.HostPort(), ts.ServiceName(), "repeat", nil, nil)
+		require.NoError(t, err, "BeginStreamingCall failed")
+		require.NoError(t, stream.Send([]byte("msg")), "Send failed")
+
+		for i := 0; i < 3; i++ {
+			msg, err := stream.Recv()
+			require.NoError(t, err, "Recv %v failed", i)
+			assert.Equal(t, "msg", string(msg), "Unexpected message")
+		}
+		_, err = stream.Recv()
+		assert.Equal(t, io.EOF, err, "Expected EOF once the server closed")
+		assert.Equal(t, io.EOF, stream.Send([]byte("msg")), "Send after the call completed should fail")
+	})
+}
+
+func TestStreamingCallSystemError(t *testing.T) {
+	testutils.WithTestServer(t, nil, func(t testing.TB, ts *testutils.TestServer) {
+		ts.Register(tchannel.HandlerFunc(func(ctx context.Context, call *tchannel.InboundCall) {
+			stream, err := call.AcceptStream(nil)
+			if !assert.NoError(t, err, "AcceptStream failed") {
+				return
+			}
+			_, err = stream.Recv()
+			assert.NoError(t, err, "Recv failed")
+			call.Response().SendSystemError(tchannel.NewSystemError(tchannel.ErrCodeUnexpected, "stream failed"))
+		}), "fail")
+
+		client := ts.NewClient(nil)
+		ctx, cancel := tchannel.NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+
+		stream, err := client.BeginStreamingCall(ctx, ts.HostPort(), ts.ServiceName(), "fail", nil, nil)
+		require.NoError(t, err, "BeginStreamingCall failed")
+		require.NoError(t, stream.Send([]byte("msg")), "Send failed")
+
+		_, err = stream.Recv()
+		assert.Equal(t, tchannel.ErrCodeUnexpected, tchannel.GetSystemErrorCode(err), "Unexpected error: %v", err)
+		assert.Contains(t, err.Error(), "stream failed", "Unexpected error message")
+	})
+}
+
+func TestStreamingCallNotStreaming(t *testing.T) {
+	testutils.WithTestServer(t, nil, func(t testing.TB, ts *testutils.TestServer) {
+		ts.Register(tchannel.HandlerFunc(func(ctx context.Context, call *tchannel.InboundCall) {
+			assert.False(t, call.Streaming(), "Call should not be streaming")
+			_, err := call.AcceptStream(nil)
+			require.Error(t, err, "AcceptStream should fail for non-streaming calls")
+			call.Response().SendSystemError(err)
+		}), "echo")
+
+		ctx, cancel := tchannel.NewContext(testutils.Timeout(time.Second))
+		defer cancel()
+
+		_, _, _, err := raw.Call(ctx, ts.NewClient(nil), ts.HostPort(), ts.ServiceName(), "echo", nil, nil)
+		assert.Equal(t, tchannel.ErrCodeBadRequest, tchannel.GetSystemErrorCode(err), "Unexpected error: %v", err)
+	})
+}
@@ -51,6 +51,10 @@ type SubChannel struct {
 	handler            Handler
 	logger             Logger
 	statsReporter      StatsReporter
+	limiter            *concurrencyLimiter
+	methodLimiters     map[string]*concurrencyLimiter
+	retryBudget        *retryBudget
+	authz              authorizer
 }
 
 // Map of subchannel and the corresponding service
@@ -103,6 +107,11 @@ func (c *SubChannel) Isolated() bool {
 	return c.topChannel.Peers() != c.peers
 }
 
+// Draining returns whether the underlying channel is draining.
+func (c *SubChannel) Draining() bool {
+	return c.topChannel.Draining()
+}
+
 // Register registers a handler on the subchannel for the given method.
 //
 // This function panics if the Handler for the SubChannel was overwritten with
@@ -195,7 +195,7 @@ func TestGetHandlers(t *testing.T) {
 	}{
 		{
 			serviceName: ch.ServiceName(),
-			wantMethods: []string{"_gometa_introspect", "_gometa_runtime", "method1", "method2"},
+			wantMethods: []string{"_gometa_introspect", "_gometa_log_levels", "_gometa_runtime", "method1", "method2"},
 		},
 		{
 			serviceName: "foo",
@@ -21,6 +21,7 @@
 package testutils
 
 import (
+	"crypto/tls"
 	"flag"
 	"net"
 	"testing"
@@ -29,6 +30,7 @@ import (
 	"github.com/temporalio/tchannel-go"
 	"github.com/temporalio/tchannel-go/tos"
 
+	"github.com/opentracing/opentracing-go"
 	"go.uber.org/atomic"
 	"golang.org/x/net/context"
 )
@@ -119,6 +121,12 @@ func (o *ChannelOpts) SetStatsReporter(statsReporter tchannel.StatsReporter) *Ch
 	return o
 }
 
+// SetTracer sets Tracer in ChannelOptions.
+func (o *ChannelOpts) SetTracer(tracer opentracing.Tracer) *ChannelOpts {
+	o.Tracer = tracer
+	return o
+}
+
 // SetFramePool sets FramePool in DefaultConnectionOptions.
 func (o *ChannelOpts) SetFramePool(framePool tchannel.FramePool) *ChannelOpts {
 	o.DefaultConnectionOptions.FramePool = framePool
@@ -137,6 +145,24 @@ func (o *ChannelOpts) SetSendBufferSize(bufSize int) *ChannelOpts {
 	return o
 }
 
+// SetCompression sets the Compression in DefaultConnectionOptions.
+func (o *ChannelOpts) SetCompression(compression ...tchannel.CompressionType) *ChannelOpts {
+	o.DefaultConnectionOptions.Compression = compression
+	return o
+}
+
+// SetConnectionsPerPeer sets the ConnectionsPerPeer in DefaultConnectionOptions.
+func (o *ChannelOpts) SetConnectionsPerPeer(n int) *ChannelOpts {
+	o.DefaultConnectionOptions.ConnectionsPerPeer = n
+	return o
+}
+
+// SetFlowControlWindow sets the FlowControlWindow in DefaultConnectionOptions.
+func (o *ChannelOpts) SetFlowControlWindow(window int) *ChannelOpts {
+	o.DefaultConnectionOptions.FlowControlWindow = window
+	return o
+}
+
 // SetSendBufferSizeOverrides sets the SendBufferOverrides in DefaultConnectionOptions.
 func (o *ChannelOpts) SetSendBufferSizeOverrides(overrides []tchannel.SendBufferSizeOverride) *ChannelOpts {
 	o.DefaultConnectionOptions.SendBufferSizeOverrides = overrides
@@ -282,6 +308,24 @@ func (o *ChannelOpts) SetConnContext(f func(context.Context, net.Conn) context.C
 	return o
 }
 
+// SetTLSConfig sets the TLS configuration used for connections.
+func (o *ChannelOpts) SetTLSConfig(cfg *tls.Config) *ChannelOpts {
+	o.ChannelOptions.TLSConfig = cfg
+	return o
+}
+
+// SetCircuitBreaker enables per-peer circuit breakers with the given options.
+func (o *ChannelOpts) SetCircuitBreaker(opts tchannel.CircuitBreakerOptions) *ChannelOpts {
+	o.ChannelOptions.CircuitBreaker = &opts
+	return o
+}
+
+// SetRetryBudget sets the channel-wide budget for retries of outbound calls.
+func (o *ChannelOpts) SetRetryBudget(budget tchannel.RetryBudget) *ChannelOpts {
+	o.ChannelOptions.RetryBudget = &budget
+	return o
+}
+
 func defaultString(v string, defaultValue string) string {
 	if v == "" {
 		return defaultValue
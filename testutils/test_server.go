@@ -431,6 +431,9 @@ func comparableState(ch *tchannel.Channel, opts *tchannel.IntrospectionOptions)
 
 	// Tests start with ChannelClient or ChannelListening, but end with ChannelClosed.
 	s.ChannelState = ""
+
+	// Limits may be changed by tests, but calls should not be left in-flight.
+	s.ConcurrencyLimit = tchannel.ConcurrencyLimitState{InFlight: s.ConcurrencyLimit.InFlight}
 	return s
 }
 
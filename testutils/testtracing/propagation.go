@@ -120,6 +120,8 @@ const (
 	Mock TracerType = "MOCK"
 	// Jaeger is Uber's tracer, baggage-capable, Zipkin-style trace IDs
 	Jaeger TracerType = "JAEGER"
+	// W3C tracer, baggage-capable, W3C Trace Context propagation like OpenTelemetry
+	W3C TracerType = "W3C"
 )
 
 // TracingCall is used in a few other structs here
@@ -153,9 +155,10 @@ type tracerChoice struct {
 	resetSpans       func()
 	isFake           bool
 	zipkinCompatible bool
+	w3cCompatible    bool
 }
 
-// Run executes the test cases in the test suite against 3 different tracer implementations
+// Run executes the test cases in the test suite against 4 different tracer implementations
 func (s *PropagationTestSuite) Run(t *testing.T) {
 	tests := []struct {
 		name string
@@ -164,6 +167,7 @@ func (s *PropagationTestSuite) Run(t *testing.T) {
 		{"Noop_Tracer", s.runWithNoopTracer},
 		{"Mock_Tracer", s.runWithMockTracer},
 		{"Jaeger_Tracer", s.runWithJaegerTracer},
+		{"W3C_Tracer", s.runWithW3CTracer},
 	}
 	for _, test := range tests {
 		t.Logf("Running with %s", test.name)
@@ -195,6 +199,21 @@ func (s *PropagationTestSuite) runWithMockTracer(t *testing.T) {
 	})
 }
 
+func (s *PropagationTestSuite) runWithW3CTracer(t *testing.T) {
+	w3cTracer := NewW3CTracer()
+	s.runWithTracer(t, tracerChoice{
+		tracerType: W3C,
+		tracer:     w3cTracer,
+		spansRecorded: func() int {
+			return len(MockTracerSampledSpans(w3cTracer))
+		},
+		resetSpans: func() {
+			w3cTracer.Reset()
+		},
+		w3cCompatible: true,
+	})
+}
+
 func (s *PropagationTestSuite) runWithJaegerTracer(t *testing.T) {
 	jaegerReporter := jaeger.NewInMemoryReporter()
 	jaegerTracer, jaegerCloser := jaeger.NewTracer(testutils.DefaultServerName,
@@ -292,7 +311,7 @@ func (s *PropagationTestSuite) runTestCase(
 	for r, cnt := response, 0; r != nil || cnt <= test.ForwardCount; r, cnt = r.Child, cnt+1 {
 		require.NotNil(t, r, "Expecting response for forward=%d; %s", cnt, descr)
 		if !tracer.isFake {
-			if tracer.zipkinCompatible || s.Encoding.HeadersSupported {
+			if tracer.zipkinCompatible || tracer.w3cCompatible || s.Encoding.HeadersSupported {
 				assert.Equal(t, root.TraceID, r.TraceID, "traceID should be the same; %s", descr)
 			}
 			assert.Equal(t, test.ExpectedBaggage, r.Luggage, "baggage should propagate; %s", descr)
@@ -124,6 +124,12 @@ func TestRawTracingPropagation(t *testing.T) {
 				// even from the Raw encoding.
 				{ForwardCount: 2, TracingDisabled: false, ExpectedBaggage: "", ExpectedSpanCount: 6},
 			},
+			W3C: {
+				// W3C Trace Context is propagated in transport headers for Raw encoding,
+				// but baggage is not.
+				{ForwardCount: 2, TracingDisabled: true, ExpectedBaggage: "", ExpectedSpanCount: 0},
+				{ForwardCount: 2, TracingDisabled: false, ExpectedBaggage: "", ExpectedSpanCount: 6},
+			},
 		},
 	}
 	suite.Run(t)
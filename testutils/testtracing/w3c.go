@@ -0,0 +1,122 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package testtracing
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+const (
+	traceParentKey = "traceparent"
+	baggageKey     = "baggage"
+)
+
+// NewW3CTracer returns a MockTracer that propagates spans using W3C Trace Context
+// headers and does not support Zipkin-style trace IDs, similar to the Tracer
+// in the otelbridge package.
+func NewW3CTracer() *mocktracer.MockTracer {
+	tracer := mocktracer.New()
+	propagator := w3cPropagator{}
+	tracer.RegisterInjector(opentracing.TextMap, propagator)
+	tracer.RegisterInjector(opentracing.HTTPHeaders, propagator)
+	tracer.RegisterExtractor(opentracing.TextMap, propagator)
+	tracer.RegisterExtractor(opentracing.HTTPHeaders, propagator)
+	return tracer
+}
+
+type w3cPropagator struct{}
+
+func (w3cPropagator) Inject(sc mocktracer.MockSpanContext, carrier interface{}) error {
+	writer, ok := carrier.(opentracing.TextMapWriter)
+	if !ok {
+		return opentracing.ErrInvalidCarrier
+	}
+
+	var flags byte
+	if sc.Sampled {
+		flags = 1
+	}
+	writer.Set(traceParentKey, fmt.Sprintf("00-%032x-%016x-%02x", sc.TraceID, sc.SpanID, flags))
+
+	var baggage []string
+	for k, v := range sc.Baggage {
+		baggage = append(baggage, url.QueryEscape(k)+"="+url.QueryEscape(v))
+	}
+	if len(baggage) > 0 {
+		writer.Set(baggageKey, strings.Join(baggage, ","))
+	}
+	return nil
+}
+
+func (w3cPropagator) Extract(carrier interface{}) (mocktracer.MockSpanContext, error) {
+	reader, ok := carrier.(opentracing.TextMapReader)
+	if !ok {
+		return mocktracer.MockSpanContext{}, opentracing.ErrInvalidCarrier
+	}
+
+	var traceParent, baggage string
+	reader.ForeachKey(func(key, val string) error {
+		switch strings.ToLower(key) {
+		case traceParentKey:
+			traceParent = val
+		case baggageKey:
+			baggage = val
+		}
+		return nil
+	})
+
+	parts := strings.Split(traceParent, "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return mocktracer.MockSpanContext{}, opentracing.ErrSpanContextNotFound
+	}
+	traceID, err1 := hex.DecodeString(parts[1])
+	spanID, err2 := hex.DecodeString(parts[2])
+	flags, err3 := hex.DecodeString(parts[3])
+	if err1 != nil || err2 != nil || err3 != nil {
+		return mocktracer.MockSpanContext{}, opentracing.ErrSpanContextCorrupted
+	}
+
+	// MockTracer IDs are ints, so only the lower 64 bits of the trace ID are kept.
+	sc := mocktracer.MockSpanContext{
+		TraceID: int(binary.BigEndian.Uint64(traceID[8:])),
+		SpanID:  int(binary.BigEndian.Uint64(spanID)),
+		Sampled: flags[0]&1 == 1,
+	}
+	for _, kv := range strings.Split(baggage, ",") {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		k, err1 := url.QueryUnescape(parts[0])
+		v, err2 := url.QueryUnescape(parts[1])
+		if err1 == nil && err2 == nil {
+			sc = sc.WithBaggageItem(k, v)
+		}
+	}
+	return sc, nil
+}
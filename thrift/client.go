@@ -21,7 +21,10 @@
 package thrift
 
 import (
+	"bytes"
 	"context"
+	"reflect"
+	"sync"
 
 	"github.com/temporalio/tchannel-go"
 	"github.com/temporalio/tchannel-go/internal/argreader"
@@ -41,9 +44,13 @@ type client struct {
 type ClientOptions struct {
 	// HostPort specifies a specific server to hit.
 	HostPort string
+
+	// Interceptors are run, in order, for every call made by the client.
+	Interceptors []ClientInterceptor
 }
 
 // NewClient returns a Client that makes calls over the given tchannel to the given Hyperbahn service.
+// The returned client also implements TChanStreamClient.
 func NewClient(ch *tchannel.Channel, serviceName string, opts *ClientOptions) TChanClient {
 	client := &client{
 		ch:          ch,
@@ -63,6 +70,29 @@ func (c *client) startCall(ctx context.Context, method string, callOptions *tcha
 	return c.sc.BeginCall(ctx, method, callOptions)
 }
 
+func (c *client) BeginStream(ctx Context, thriftService, methodName string) (*Stream, error) {
+	var arg2 bytes.Buffer
+	if err := WriteHeaders(&arg2, ctx.Headers()); err != nil {
+		return nil, err
+	}
+
+	var (
+		call        *tchannel.StreamingCall
+		err         error
+		method      = thriftService + "::" + methodName
+		callOptions = &tchannel.CallOptions{Format: tchannel.Thrift}
+	)
+	if c.opts.HostPort != "" {
+		call, err = c.ch.BeginStreamingCall(ctx, c.opts.HostPort, c.serviceName, method, callOptions, arg2.Bytes())
+	} else {
+		call, err = c.sc.BeginStreamingCall(ctx, method, callOptions, arg2.Bytes())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newStream(ctx, call), nil
+}
+
 func writeArgs(ctx context.Context, call *tchannel.OutboundCall, headers map[string]string, req thrift.TStruct) error {
 	writer, err := call.Arg2Writer()
 	if err != nil {
@@ -126,16 +156,52 @@ func readResponse(ctx context.Context, response *tchannel.OutboundCallResponse,
 	return headers, success, reader.Close()
 }
 
+// newResponse returns a new response of the same type as resp, so that
+// concurrent attempts do not read into the same response.
+func newResponse(resp thrift.TStruct) thrift.TStruct {
+	t := reflect.TypeOf(resp)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return resp
+	}
+	return reflect.New(t.Elem()).Interface().(thrift.TStruct)
+}
+
+// copyResponse copies a response created by newResponse into dst.
+func copyResponse(dst, src thrift.TStruct) {
+	if v := reflect.ValueOf(dst); v.Kind() == reflect.Ptr && !v.IsNil() {
+		v.Elem().Set(reflect.ValueOf(src).Elem())
+	}
+}
+
 func (c *client) Call(ctx Context, thriftService, methodName string, req, resp thrift.TStruct) (bool, error) {
+	if len(c.opts.Interceptors) == 0 {
+		return c.call(ctx, thriftService, methodName, req, resp)
+	}
+
+	call := func(ctx Context, req, resp thrift.TStruct) (bool, error) {
+		return c.call(ctx, thriftService, methodName, req, resp)
+	}
+	return chainClientInterceptors(c.opts.Interceptors, thriftService+"::"+methodName, call)(ctx, req, resp)
+}
+
+func (c *client) call(ctx Context, thriftService, methodName string, req, resp thrift.TStruct) (bool, error) {
 	var (
 		headers = ctx.Headers()
 
 		respHeaders map[string]string
 		isOK        bool
+
+		// Hedged attempts run concurrently, so only the first successful
+		// attempt sets the results.
+		resultsMu  sync.Mutex
+		resultsSet bool
 	)
 
 	err := c.ch.RunWithRetry(ctx, func(ctx context.Context, rs *tchannel.RequestState) error {
-		respHeaders, isOK = nil, false
+		attemptResp := resp
+		if rs.Hedged() {
+			attemptResp = newResponse(resp)
+		}
 
 		call, err := c.startCall(ctx, thriftService+"::"+methodName, &tchannel.CallOptions{
 			Format:       tchannel.Thrift,
@@ -149,8 +215,21 @@ func (c *client) Call(ctx Context, thriftService, methodName string, req, resp t
 			return err
 		}
 
-		respHeaders, isOK, err = readResponse(ctx, call.Response(), resp)
-		return err
+		attemptHeaders, attemptOK, err := readResponse(ctx, call.Response(), attemptResp)
+		if err != nil {
+			return err
+		}
+
+		resultsMu.Lock()
+		defer resultsMu.Unlock()
+		if !resultsSet {
+			resultsSet = true
+			respHeaders, isOK = attemptHeaders, attemptOK
+			if rs.Hedged() {
+				copyResponse(resp, attemptResp)
+			}
+		}
+		return nil
 	})
 	if err != nil {
 		return false, err
@@ -39,6 +39,13 @@ To use a Thrift client use the generated TChan client:
 This client can be used similar to a standard Thrift client, except a Context
 is passed with options (such as timeout).
 
+Methods annotated with (tchannel.stream = "true") are stream methods, which
+exchange a sequence of argument and result structs over a single call. They
+are generated into separate stream interfaces:
+  server.Register(gen.NewTChan[SERVICE]StreamServer(streamHandler))
+
+  streamClient := gen.NewTChan[SERVICE]StreamClient(thriftClient.(thrift.TChanStreamClient))
+
 TODO(prashant): Add and document header support.
 */
 package thrift
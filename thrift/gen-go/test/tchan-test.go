@@ -94,14 +94,41 @@ func (s *tchanMetaServer) Handle(ctx thrift.Context, methodName string, protocol
 	}
 }
 
+func (s *tchanMetaServer) NewRequest(methodName string) athrift.TStruct {
+	switch methodName {
+	case "health":
+		return &MetaHealthArgs{}
+
+	default:
+		return nil
+	}
+}
+
+func (s *tchanMetaServer) HandleRequest(ctx thrift.Context, methodName string, req athrift.TStruct) (bool, athrift.TStruct, error) {
+	switch methodName {
+	case "health":
+		if args, ok := req.(*MetaHealthArgs); ok {
+			return s.handleHealthRequest(ctx, args)
+		}
+		return false, nil, fmt.Errorf("unexpected request type %T for method %v", req, methodName)
+
+	default:
+		return false, nil, fmt.Errorf("method %v not found in service %v", methodName, s.Service())
+	}
+}
+
 func (s *tchanMetaServer) handleHealth(ctx thrift.Context, protocol athrift.TProtocol) (bool, athrift.TStruct, error) {
 	var req MetaHealthArgs
-	var res MetaHealthResult
-
 	if err := req.Read(ctx, protocol); err != nil {
 		return false, nil, err
 	}
 
+	return s.handleHealthRequest(ctx, &req)
+}
+
+func (s *tchanMetaServer) handleHealthRequest(ctx thrift.Context, req *MetaHealthArgs) (bool, athrift.TStruct, error) {
+	var res MetaHealthResult
+
 	r, err :=
 		s.handler.Health(ctx)
 
@@ -179,14 +206,41 @@ func (s *tchanSecondServiceServer) Handle(ctx thrift.Context, methodName string,
 	}
 }
 
+func (s *tchanSecondServiceServer) NewRequest(methodName string) athrift.TStruct {
+	switch methodName {
+	case "Echo":
+		return &SecondServiceEchoArgs{}
+
+	default:
+		return nil
+	}
+}
+
+func (s *tchanSecondServiceServer) HandleRequest(ctx thrift.Context, methodName string, req athrift.TStruct) (bool, athrift.TStruct, error) {
+	switch methodName {
+	case "Echo":
+		if args, ok := req.(*SecondServiceEchoArgs); ok {
+			return s.handleEchoRequest(ctx, args)
+		}
+		return false, nil, fmt.Errorf("unexpected request type %T for method %v", req, methodName)
+
+	default:
+		return false, nil, fmt.Errorf("method %v not found in service %v", methodName, s.Service())
+	}
+}
+
 func (s *tchanSecondServiceServer) handleEcho(ctx thrift.Context, protocol athrift.TProtocol) (bool, athrift.TStruct, error) {
 	var req SecondServiceEchoArgs
-	var res SecondServiceEchoResult
-
 	if err := req.Read(ctx, protocol); err != nil {
 		return false, nil, err
 	}
 
+	return s.handleEchoRequest(ctx, &req)
+}
+
+func (s *tchanSecondServiceServer) handleEchoRequest(ctx thrift.Context, req *SecondServiceEchoArgs) (bool, athrift.TStruct, error) {
+	var res SecondServiceEchoResult
+
 	r, err :=
 		s.handler.Echo(ctx, req.Arg)
 
@@ -304,14 +358,55 @@ func (s *tchanSimpleServiceServer) Handle(ctx thrift.Context, methodName string,
 	}
 }
 
+func (s *tchanSimpleServiceServer) NewRequest(methodName string) athrift.TStruct {
+	switch methodName {
+	case "Call":
+		return &SimpleServiceCallArgs{}
+	case "Simple":
+		return &SimpleServiceSimpleArgs{}
+	case "SimpleFuture":
+		return &SimpleServiceSimpleFutureArgs{}
+
+	default:
+		return nil
+	}
+}
+
+func (s *tchanSimpleServiceServer) HandleRequest(ctx thrift.Context, methodName string, req athrift.TStruct) (bool, athrift.TStruct, error) {
+	switch methodName {
+	case "Call":
+		if args, ok := req.(*SimpleServiceCallArgs); ok {
+			return s.handleCallRequest(ctx, args)
+		}
+		return false, nil, fmt.Errorf("unexpected request type %T for method %v", req, methodName)
+	case "Simple":
+		if args, ok := req.(*SimpleServiceSimpleArgs); ok {
+			return s.handleSimpleRequest(ctx, args)
+		}
+		return false, nil, fmt.Errorf("unexpected request type %T for method %v", req, methodName)
+	case "SimpleFuture":
+		if args, ok := req.(*SimpleServiceSimpleFutureArgs); ok {
+			return s.handleSimpleFutureRequest(ctx, args)
+		}
+		return false, nil, fmt.Errorf("unexpected request type %T for method %v", req, methodName)
+
+	default:
+		return false, nil, fmt.Errorf("method %v not found in service %v", methodName, s.Service())
+	}
+}
+
 func (s *tchanSimpleServiceServer) handleCall(ctx thrift.Context, protocol athrift.TProtocol) (bool, athrift.TStruct, error) {
 	var req SimpleServiceCallArgs
-	var res SimpleServiceCallResult
-
 	if err := req.Read(ctx, protocol); err != nil {
 		return false, nil, err
 	}
 
+	return s.handleCallRequest(ctx, &req)
+}
+
+func (s *tchanSimpleServiceServer) handleCallRequest(ctx thrift.Context, req *SimpleServiceCallArgs) (bool, athrift.TStruct, error) {
+	var res SimpleServiceCallResult
+
 	r, err :=
 		s.handler.Call(ctx, req.Arg)
 
@@ -326,12 +421,16 @@ func (s *tchanSimpleServiceServer) handleCall(ctx thrift.Context, protocol athri
 
 func (s *tchanSimpleServiceServer) handleSimple(ctx thrift.Context, protocol athrift.TProtocol) (bool, athrift.TStruct, error) {
 	var req SimpleServiceSimpleArgs
-	var res SimpleServiceSimpleResult
-
 	if err := req.Read(ctx, protocol); err != nil {
 		return false, nil, err
 	}
 
+	return s.handleSimpleRequest(ctx, &req)
+}
+
+func (s *tchanSimpleServiceServer) handleSimpleRequest(ctx thrift.Context, req *SimpleServiceSimpleArgs) (bool, athrift.TStruct, error) {
+	var res SimpleServiceSimpleResult
+
 	err :=
 		s.handler.Simple(ctx)
 
@@ -353,12 +452,16 @@ func (s *tchanSimpleServiceServer) handleSimple(ctx thrift.Context, protocol ath
 
 func (s *tchanSimpleServiceServer) handleSimpleFuture(ctx thrift.Context, protocol athrift.TProtocol) (bool, athrift.TStruct, error) {
 	var req SimpleServiceSimpleFutureArgs
-	var res SimpleServiceSimpleFutureResult
-
 	if err := req.Read(ctx, protocol); err != nil {
 		return false, nil, err
 	}
 
+	return s.handleSimpleFutureRequest(ctx, &req)
+}
+
+func (s *tchanSimpleServiceServer) handleSimpleFutureRequest(ctx thrift.Context, req *SimpleServiceSimpleFutureArgs) (bool, athrift.TStruct, error) {
+	var res SimpleServiceSimpleFutureResult
+
 	err :=
 		s.handler.SimpleFuture(ctx)
 
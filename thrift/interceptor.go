@@ -0,0 +1,99 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package thrift
+
+import (
+	"github.com/temporalio/tchannel-go"
+
+	"github.com/apache/thrift/lib/go/thrift"
+)
+
+// ServerHandler handles a decoded Thrift request, and returns the same values
+// as TChanServer.Handle. The response struct holds either the result or an
+// application exception.
+type ServerHandler func(ctx Context, req thrift.TStruct) (success bool, resp thrift.TStruct, err error)
+
+// ServerInterceptor intercepts calls handled by a Server. It is called with the
+// full method name ("Service::method") and the decoded request args struct, which
+// it may modify. It can reject the call by returning an error without calling
+// next, or inspect and modify the response returned by next.
+type ServerInterceptor func(ctx Context, method string, req thrift.TStruct, next ServerHandler) (success bool, resp thrift.TStruct, err error)
+
+// ClientCaller makes a Thrift call, and returns the same values as TChanClient.Call.
+type ClientCaller func(ctx Context, req, resp thrift.TStruct) (success bool, err error)
+
+// ClientInterceptor intercepts calls made by a client created using NewClient.
+// It is called with the full method name ("Service::method"), the request args
+// struct and the result struct that the response is read into. It can return
+// without calling next, for example, to fill resp from a cache.
+type ClientInterceptor func(ctx Context, method string, req, resp thrift.TStruct, next ClientCaller) (success bool, err error)
+
+// chainServerInterceptors returns a handler that runs the interceptors in order
+// before calling handler.
+func chainServerInterceptors(interceptors []ServerInterceptor, method string, handler ServerHandler) ServerHandler {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], handler
+		handler = func(ctx Context, req thrift.TStruct) (bool, thrift.TStruct, error) {
+			return interceptor(ctx, method, req, next)
+		}
+	}
+	return handler
+}
+
+// chainClientInterceptors returns a caller that runs the interceptors in order
+// before calling caller.
+func chainClientInterceptors(interceptors []ClientInterceptor, method string, caller ClientCaller) ClientCaller {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], caller
+		caller = func(ctx Context, req, resp thrift.TStruct) (bool, error) {
+			return interceptor(ctx, method, req, resp, next)
+		}
+	}
+	return caller
+}
+
+// handleRequest reads the request for the method from protocol and passes it
+// to the handler's server, running any interceptors.
+func handleRequest(ctx Context, h handler, interceptors []ServerInterceptor, method string, protocol thrift.TProtocol) (bool, thrift.TStruct, error) {
+	if len(interceptors) == 0 {
+		return h.server.Handle(ctx, method, protocol)
+	}
+
+	server, ok := h.server.(TChanStructServer)
+	if !ok {
+		return false, nil, tchannel.NewSystemError(tchannel.ErrCodeUnexpected,
+			"server for %v does not support interceptors, regenerate it using thrift-gen", h.server.Service())
+	}
+
+	req := server.NewRequest(method)
+	if req == nil {
+		return false, nil, tchannel.NewSystemError(tchannel.ErrCodeBadRequest,
+			"method %v not found in service %v", method, server.Service())
+	}
+	if err := req.Read(ctx, protocol); err != nil {
+		return false, nil, err
+	}
+
+	handle := func(ctx Context, req thrift.TStruct) (bool, thrift.TStruct, error) {
+		return server.HandleRequest(ctx, method, req)
+	}
+	return chainServerInterceptors(interceptors, server.Service()+"::"+method, handle)(ctx, req)
+}
@@ -32,6 +32,17 @@ type TChanClient interface {
 	Call(ctx Context, serviceName, methodName string, req, resp athrift.TStruct) (success bool, err error)
 }
 
+// TChanStreamClient is a TChanClient that can also start streaming calls. It is
+// implemented by the client returned from NewClient, and is used by the
+// generated client code for stream methods.
+type TChanStreamClient interface {
+	TChanClient
+
+	// BeginStream starts a streaming call to the given method. Streaming calls
+	// are not retried, and do not run client interceptors.
+	BeginStream(ctx Context, serviceName, methodName string) (*Stream, error)
+}
+
 // TChanServer abstracts handling of an RPC that is implemented by the generated server code.
 type TChanServer interface {
 	// Handle should read the request from the given reqReader, and return the response struct.
@@ -44,3 +55,28 @@ type TChanServer interface {
 	// Methods returns the method names handled by this server.
 	Methods() []string
 }
+
+// TChanStructServer is a TChanServer that can also handle requests that have
+// already been read into the method's args struct. It is implemented by the
+// generated server code, and is required to use ServerInterceptors.
+type TChanStructServer interface {
+	TChanServer
+
+	// NewRequest returns an empty args struct for the given method, or nil
+	// if the method is not handled by this server.
+	NewRequest(methodName string) athrift.TStruct
+
+	// HandleRequest handles a request that was read into the args struct
+	// returned by NewRequest. It returns the same values as Handle.
+	HandleRequest(ctx Context, methodName string, req athrift.TStruct) (success bool, resp athrift.TStruct, err error)
+}
+
+// TChanStreamServer is a TChanServer that handles stream methods. It is
+// implemented by the generated server code for services with stream methods.
+type TChanStreamServer interface {
+	TChanServer
+
+	// HandleStream handles a streaming call to the given method. The call
+	// completes when this returns, if the stream has not been closed already.
+	HandleStream(ctx Context, methodName string, stream *Stream) error
+}
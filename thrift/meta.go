@@ -60,15 +60,32 @@ type HealthRequestFunc func(Context, HealthRequest) (ok bool, message string)
 // healthHandler implements the default health check enpoint.
 type metaHandler struct {
 	healthFn HealthRequestFunc
+	drainer  drainer
+}
+
+// drainer is implemented by channels and subchannels that can be drained.
+type drainer interface {
+	Draining() bool
 }
 
 // newMetaHandler return a new HealthHandler instance.
-func newMetaHandler() *metaHandler {
-	return &metaHandler{healthFn: defaultHealth}
+func newMetaHandler(registrar tchannel.Registrar) *metaHandler {
+	h := &metaHandler{healthFn: defaultHealth}
+	if d, ok := registrar.(drainer); ok {
+		h.drainer = d
+	}
+	return h
 }
 
 // Health returns true as default Health endpoint.
+// If the channel is draining, the health check fails with the STOPPING state.
 func (h *metaHandler) Health(ctx Context, req *meta.HealthRequest) (*meta.HealthStatus, error) {
+	if h.drainer != nil && h.drainer.Draining() {
+		state := meta.HealthState_STOPPING
+		message := "channel is draining"
+		return &meta.HealthStatus{Ok: false, Message: &message, State: &state}, nil
+	}
+
 	ok, message := h.healthFn(ctx, metaReqToReq(req))
 	if message == "" {
 		return &meta.HealthStatus{Ok: ok}, nil
@@ -126,6 +126,25 @@ func TestHealth(t *testing.T) {
 	}
 }
 
+type fakeDrainer bool
+
+func (d fakeDrainer) Draining() bool { return bool(d) }
+
+func TestHealthDraining(t *testing.T) {
+	h := &metaHandler{healthFn: defaultHealth, drainer: fakeDrainer(true)}
+	ret, err := h.Health(nil, &meta.HealthRequest{})
+	require.NoError(t, err, "Health failed")
+	assert.False(t, ret.Ok, "Draining channel should not be healthy")
+	require.NotNil(t, ret.State, "Missing health state")
+	assert.Equal(t, meta.HealthState_STOPPING, *ret.State, "Unexpected health state")
+
+	h.drainer = fakeDrainer(false)
+	ret, err = h.Health(nil, &meta.HealthRequest{})
+	require.NoError(t, err, "Health failed")
+	assert.True(t, ret.Ok, "Channel that is not draining should be healthy")
+	assert.Nil(t, ret.State, "Unexpected health state")
+}
+
 func TestMetaReqToReq(t *testing.T) {
 	tests := []struct {
 		msg  string
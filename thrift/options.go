@@ -33,6 +33,8 @@ type RegisterOption interface {
 // PostResponseCB registers a callback that is run after a response has been
 // compeltely processed (e.g. written to the channel).
 // This gives the server a chance to clean up resources from the response object
+// For stream methods, the callback is run once the stream has completed, and
+// response is nil.
 type PostResponseCB func(ctx context.Context, method string, response thrift.TStruct)
 
 type optPostResponse PostResponseCB
@@ -21,6 +21,7 @@
 package thrift
 
 import (
+	"bytes"
 	"log"
 	"strings"
 	"sync"
@@ -37,23 +38,32 @@ type handler struct {
 	postResponseCB PostResponseCB
 }
 
+type streamHandler struct {
+	server         TChanStreamServer
+	postResponseCB PostResponseCB
+}
+
 // Server handles incoming TChannel calls and forwards them to the matching TChanServer.
 type Server struct {
 	sync.RWMutex
-	ch          tchannel.Registrar
-	log         tchannel.Logger
-	handlers    map[string]handler
-	metaHandler *metaHandler
-	ctxFn       func(ctx context.Context, method string, headers map[string]string) Context
+	ch           tchannel.Registrar
+	log          tchannel.Logger
+	handlers     map[string]handler
+	streams      map[string]streamHandler
+	metaHandler  *metaHandler
+	ctxFn        func(ctx context.Context, method string, headers map[string]string) Context
+	interceptors []ServerInterceptor
+	authzPolicy  *tchannel.AuthorizationPolicy
 }
 
 // NewServer returns a server that can serve thrift services over TChannel.
 func NewServer(registrar tchannel.Registrar) *Server {
-	metaHandler := newMetaHandler()
+	metaHandler := newMetaHandler(registrar)
 	server := &Server{
 		ch:          registrar,
 		log:         registrar.Logger(),
 		handlers:    make(map[string]handler),
+		streams:     make(map[string]streamHandler),
 		metaHandler: metaHandler,
 		ctxFn:       defaultContextFn,
 	}
@@ -66,6 +76,7 @@ func NewServer(registrar tchannel.Registrar) *Server {
 }
 
 // Register registers the given TChanServer to be called on any incoming call for its' services.
+// If svr is a TChanStreamServer, its methods are handled as stream methods.
 // TODO(prashant): Replace Register call with this call.
 func (s *Server) Register(svr TChanServer, opts ...RegisterOption) {
 	service := svr.Service()
@@ -74,6 +85,11 @@ func (s *Server) Register(svr TChanServer, opts ...RegisterOption) {
 		opt.Apply(handler)
 	}
 
+	if streamServer, ok := svr.(TChanStreamServer); ok {
+		s.registerStreams(streamHandler{streamServer, handler.postResponseCB})
+		return
+	}
+
 	s.Lock()
 	s.handlers[service] = *handler
 	s.Unlock()
@@ -83,6 +99,21 @@ func (s *Server) Register(svr TChanServer, opts ...RegisterOption) {
 	}
 }
 
+func (s *Server) registerStreams(handler streamHandler) {
+	service := handler.server.Service()
+	methods := handler.server.Methods()
+
+	s.Lock()
+	for _, m := range methods {
+		s.streams[service+"::"+m] = handler
+	}
+	s.Unlock()
+
+	for _, m := range methods {
+		s.ch.Register(s, service+"::"+m)
+	}
+}
+
 // RegisterHealthHandler uses the user-specified function f for the Health endpoint.
 func (s *Server) RegisterHealthHandler(f HealthFunc) {
 	wrapped := func(ctx Context, r HealthRequest) (bool, string) {
@@ -104,6 +135,31 @@ func (s *Server) SetContextFn(f func(ctx context.Context, method string, headers
 	s.ctxFn = f
 }
 
+// SetInterceptors sets the interceptors that are run, in order, for every call
+// handled by this server after the request is decoded. The registered servers
+// must implement TChanStructServer, which is implemented by code generated
+// using thrift-gen.
+func (s *Server) SetInterceptors(interceptors ...ServerInterceptor) {
+	s.Lock()
+	s.interceptors = interceptors
+	s.Unlock()
+}
+
+// SetAuthorizationPolicy sets a policy used to authorize calls handled by this
+// server, in addition to the policies of the channel and SubChannel. Calls that
+// are not allowed are failed with tchannel.ErrUnauthorized before they are
+// decoded. A nil policy allows all calls.
+func (s *Server) SetAuthorizationPolicy(policy *tchannel.AuthorizationPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	s.Lock()
+	s.authzPolicy = policy
+	s.Unlock()
+	return nil
+}
+
 func (s *Server) onError(call *tchannel.InboundCall, err error) {
 	// TODO(prashant): Expose incoming call errors through options for NewServer.
 	remotePeer := call.RemotePeer()
@@ -121,9 +177,12 @@ func (s *Server) onError(call *tchannel.InboundCall, err error) {
 		tchannel.LogField{Key: "remoteProcess", Value: remotePeer.ProcessName},
 	)
 
-	if tchannel.GetSystemErrorCode(err) == tchannel.ErrCodeTimeout {
+	switch tchannel.GetSystemErrorCode(err) {
+	case tchannel.ErrCodeTimeout:
 		logger.Debug("Thrift server timeout.")
-	} else {
+	case tchannel.ErrCodeCancelled:
+		logger.Debug("Thrift server call cancelled.")
+	default:
 		logger.Error("Thrift server error.")
 	}
 }
@@ -132,7 +191,7 @@ func defaultContextFn(ctx context.Context, method string, headers map[string]str
 	return WithHeaders(ctx, headers)
 }
 
-func (s *Server) handle(origCtx context.Context, handler handler, method string, call *tchannel.InboundCall) error {
+func (s *Server) handle(origCtx context.Context, handler handler, interceptors []ServerInterceptor, method string, call *tchannel.InboundCall) error {
 	reader, err := call.Arg2Reader()
 	if err != nil {
 		return err
@@ -160,7 +219,7 @@ func (s *Server) handle(origCtx context.Context, handler handler, method string,
 	ctx := s.ctxFn(origCtx, method, headers)
 
 	wp := getProtocolReader(reader)
-	success, resp, err := handler.server.Handle(ctx, method, wp.protocol)
+	success, resp, err := handleRequest(ctx, handler, interceptors, method, wp.protocol)
 	thriftProtocolPool.Put(wp)
 
 	if handler.postResponseCB != nil {
@@ -214,6 +273,45 @@ func (s *Server) handle(origCtx context.Context, handler handler, method string,
 	return writer.Close()
 }
 
+func (s *Server) handleStream(origCtx context.Context, handler streamHandler, method string, call *tchannel.InboundCall) error {
+	// Response headers are not supported for streams, so the stream is
+	// accepted with empty headers.
+	var resArg2 bytes.Buffer
+	if err := WriteHeaders(&resArg2, nil); err != nil {
+		return err
+	}
+
+	stream, err := call.AcceptStream(resArg2.Bytes())
+	if err != nil {
+		return err
+	}
+
+	reqArg2, err := stream.PeerArg2()
+	if err != nil {
+		return err
+	}
+	headers, err := ReadHeaders(bytes.NewReader(reqArg2))
+	if err != nil {
+		return err
+	}
+
+	tracer := tchannel.TracerFromRegistrar(s.ch)
+	origCtx = tchannel.ExtractInboundSpan(origCtx, call, headers, tracer)
+	ctx := s.ctxFn(origCtx, method, headers)
+
+	if handler.postResponseCB != nil {
+		defer handler.postResponseCB(ctx, method, nil)
+	}
+
+	if err := handler.server.HandleStream(ctx, method, newStream(ctx, stream)); err != nil {
+		call.Response().SendSystemError(err)
+		return nil
+	}
+
+	// Complete the call if the handler did not close its side of the stream.
+	return stream.CloseSend()
+}
+
 func getServiceMethod(method string) (string, string, bool) {
 	s := string(method)
 	sep := strings.Index(s, "::")
@@ -233,12 +331,35 @@ func (s *Server) Handle(ctx context.Context, call *tchannel.InboundCall) {
 
 	s.RLock()
 	handler, ok := s.handlers[service]
+	streamHandler, isStream := s.streams[op]
+	interceptors := s.interceptors
+	authzPolicy := s.authzPolicy
 	s.RUnlock()
+
+	if !call.Authorize(authzPolicy) {
+		return
+	}
+
+	if isStream != call.Streaming() {
+		msg := "%v is not a stream method"
+		if isStream {
+			msg = "%v is a stream method and must be called using a streaming call"
+		}
+		call.Response().SendSystemError(tchannel.NewSystemError(tchannel.ErrCodeBadRequest, msg, op))
+		return
+	}
+	if isStream {
+		if err := s.handleStream(ctx, streamHandler, method, call); err != nil {
+			s.onError(call, err)
+		}
+		return
+	}
+
 	if !ok {
 		log.Fatalf("Handle got call for service %v which is not registered", service)
 	}
 
-	if err := s.handle(ctx, handler, method, call); err != nil {
+	if err := s.handle(ctx, handler, interceptors, method, call); err != nil {
 		s.onError(call, err)
 	}
 }
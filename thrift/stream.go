@@ -0,0 +1,69 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package thrift
+
+import (
+	"bytes"
+
+	"github.com/temporalio/tchannel-go"
+
+	"github.com/apache/thrift/lib/go/thrift"
+)
+
+// Stream sends and receives Thrift structs over a streaming call. It is used
+// by the code generated by thrift-gen for stream methods.
+//
+// Send and Recv may be called concurrently with each other, but multiple
+// goroutines must not call Send, or Recv, concurrently.
+type Stream struct {
+	ctx     Context
+	call    *tchannel.StreamingCall
+	sendBuf bytes.Buffer
+}
+
+func newStream(ctx Context, call *tchannel.StreamingCall) *Stream {
+	return &Stream{ctx: ctx, call: call}
+}
+
+// Send sends the given struct as a single message.
+func (s *Stream) Send(msg thrift.TStruct) error {
+	s.sendBuf.Reset()
+	if err := WriteStruct(s.ctx, &s.sendBuf, msg); err != nil {
+		return err
+	}
+	return s.call.Send(s.sendBuf.Bytes())
+}
+
+// Recv reads the next message into the given struct. It returns io.EOF once
+// the peer has closed its side of the stream.
+func (s *Stream) Recv(msg thrift.TStruct) error {
+	b, err := s.call.Recv()
+	if err != nil {
+		return err
+	}
+	return ReadStruct(s.ctx, bytes.NewReader(b), msg)
+}
+
+// CloseSend closes this side of the stream, after which the peer's Recv
+// returns io.EOF.
+func (s *Stream) CloseSend() error {
+	return s.call.CloseSend()
+}
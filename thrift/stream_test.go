@@ -0,0 +1,183 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package thrift_test
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/temporalio/tchannel-go"
+	"github.com/temporalio/tchannel-go/testutils"
+	tcthrift "github.com/temporalio/tchannel-go/thrift"
+	gen "github.com/temporalio/tchannel-go/thrift/gen-go/test"
+	"github.com/temporalio/tchannel-go/thrift/mocks"
+
+	athrift "github.com/apache/thrift/lib/go/thrift"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// echoStreamServer is a TChanStreamServer with a single "echo" stream method
+// that responds to every message with the same message and an incremented I3.
+type echoStreamServer struct {
+	headers map[string]string
+	err     error
+}
+
+func (s *echoStreamServer) Service() string   { return "StreamService" }
+func (s *echoStreamServer) Methods() []string { return []string{"echo"} }
+
+func (s *echoStreamServer) Handle(ctx tcthrift.Context, methodName string, protocol athrift.TProtocol) (bool, athrift.TStruct, error) {
+	return false, nil, errors.New("unexpected Handle call")
+}
+
+func (s *echoStreamServer) HandleStream(ctx tcthrift.Context, methodName string, stream *tcthrift.Stream) error {
+	s.headers = ctx.Headers()
+	if s.err != nil {
+		return s.err
+	}
+
+	for {
+		msg := new(gen.Data)
+		err := stream.Recv(msg)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		msg.I3++
+		if err := stream.Send(msg); err != nil {
+			return err
+		}
+	}
+}
+
+func setupStreamServer(t *testing.T, svrs ...tcthrift.TChanServer) (*tchannel.Channel, tcthrift.TChanStreamClient) {
+	serverCh := testutils.NewServer(t, nil)
+	server := tcthrift.NewServer(serverCh)
+	for _, svr := range svrs {
+		server.Register(svr)
+	}
+
+	clientCh := testutils.NewClient(t, nil)
+	clientCh.Peers().Add(serverCh.PeerInfo().HostPort)
+	client := tcthrift.NewClient(clientCh, serverCh.ServiceName(), nil)
+	return serverCh, client.(tcthrift.TChanStreamClient)
+}
+
+func TestStream(t *testing.T) {
+	svr := &echoStreamServer{}
+	serverCh, client := setupStreamServer(t, svr)
+	defer serverCh.Close()
+
+	ctx, cancel := tcthrift.NewContext(time.Second)
+	defer cancel()
+	ctx = tcthrift.WithHeaders(ctx, map[string]string{"k": "v"})
+
+	stream, err := client.BeginStream(ctx, "StreamService", "echo")
+	require.NoError(t, err, "BeginStream failed")
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, stream.Send(&gen.Data{B1: true, S2: "msg", I3: int32(i)}), "Send failed")
+
+		res := new(gen.Data)
+		require.NoError(t, stream.Recv(res), "Recv failed")
+		assert.Equal(t, &gen.Data{B1: true, S2: "msg", I3: int32(i + 1)}, res, "Unexpected response")
+	}
+
+	require.NoError(t, stream.CloseSend(), "CloseSend failed")
+	assert.Equal(t, io.EOF, stream.Recv(new(gen.Data)), "Expected EOF after the handler returned")
+	assert.Equal(t, map[string]string{"k": "v"}, svr.headers, "Unexpected request headers")
+}
+
+func TestStreamHandlerError(t *testing.T) {
+	svr := &echoStreamServer{err: tchannel.NewSystemError(tchannel.ErrCodeBusy, "too busy")}
+	serverCh, client := setupStreamServer(t, svr)
+	defer serverCh.Close()
+
+	ctx, cancel := tcthrift.NewContext(time.Second)
+	defer cancel()
+
+	stream, err := client.BeginStream(ctx, "StreamService", "echo")
+	require.NoError(t, err, "BeginStream failed")
+
+	err = stream.Recv(new(gen.Data))
+	assert.Equal(t, tchannel.ErrCodeBusy, tchannel.GetSystemErrorCode(err), "Unexpected error: %v", err)
+}
+
+func TestStreamMethodMismatch(t *testing.T) {
+	serverCh, client := setupStreamServer(t,
+		&echoStreamServer{},
+		gen.NewTChanSecondServiceServer(new(mocks.TChanSecondService)),
+	)
+	defer serverCh.Close()
+
+	ctx, cancel := tcthrift.NewContext(time.Second)
+	defer cancel()
+
+	_, err := client.Call(ctx, "StreamService", "echo", &gen.Data{}, &gen.Data{})
+	assert.Equal(t, tchannel.ErrCodeBadRequest, tchannel.GetSystemErrorCode(err), "Unexpected error: %v", err)
+	assert.Contains(t, err.Error(), "must be called using a streaming call", "Unexpected error message")
+
+	stream, err := client.BeginStream(ctx, "SecondService", "Echo")
+	require.NoError(t, err, "BeginStream failed")
+	err = stream.Recv(new(gen.Data))
+	assert.Equal(t, tchannel.ErrCodeBadRequest, tchannel.GetSystemErrorCode(err), "Unexpected error: %v", err)
+	assert.Contains(t, err.Error(), "is not a stream method", "Unexpected error message")
+}
+
+func TestStreamPostResponseCB(t *testing.T) {
+	serverCh := testutils.NewServer(t, nil)
+	defer serverCh.Close()
+
+	called := make(chan string, 1)
+	cb := func(ctx context.Context, method string, response athrift.TStruct) {
+		assert.Nil(t, response, "Stream methods should not have a response")
+		called <- method
+	}
+	server := tcthrift.NewServer(serverCh)
+	server.Register(&echoStreamServer{}, tcthrift.OptPostResponse(cb))
+
+	clientCh := testutils.NewClient(t, nil)
+	defer clientCh.Close()
+	clientCh.Peers().Add(serverCh.PeerInfo().HostPort)
+	client := tcthrift.NewClient(clientCh, serverCh.ServiceName(), nil).(tcthrift.TChanStreamClient)
+
+	ctx, cancel := tcthrift.NewContext(time.Second)
+	defer cancel()
+
+	stream, err := client.BeginStream(ctx, "StreamService", "echo")
+	require.NoError(t, err, "BeginStream failed")
+	require.NoError(t, stream.CloseSend(), "CloseSend failed")
+	assert.Equal(t, io.EOF, stream.Recv(new(gen.Data)), "Expected EOF after the handler returned")
+
+	select {
+	case method := <-called:
+		assert.Equal(t, "echo", method, "Unexpected method")
+	case <-time.After(testutils.Timeout(time.Second)):
+		t.Errorf("post-response callback not called")
+	}
+}
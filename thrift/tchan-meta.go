@@ -118,14 +118,55 @@ func (s *tchanMetaServer) Handle(ctx Context, methodName string, protocol athrif
 	}
 }
 
+func (s *tchanMetaServer) NewRequest(methodName string) athrift.TStruct {
+	switch methodName {
+	case "health":
+		return &gen.MetaHealthArgs{}
+	case "thriftIDL":
+		return &gen.MetaThriftIDLArgs{}
+	case "versionInfo":
+		return &gen.MetaVersionInfoArgs{}
+
+	default:
+		return nil
+	}
+}
+
+func (s *tchanMetaServer) HandleRequest(ctx Context, methodName string, req athrift.TStruct) (bool, athrift.TStruct, error) {
+	switch methodName {
+	case "health":
+		if args, ok := req.(*gen.MetaHealthArgs); ok {
+			return s.handleHealthRequest(ctx, args)
+		}
+		return false, nil, fmt.Errorf("unexpected request type %T for method %v", req, methodName)
+	case "thriftIDL":
+		if args, ok := req.(*gen.MetaThriftIDLArgs); ok {
+			return s.handleThriftIDLRequest(ctx, args)
+		}
+		return false, nil, fmt.Errorf("unexpected request type %T for method %v", req, methodName)
+	case "versionInfo":
+		if args, ok := req.(*gen.MetaVersionInfoArgs); ok {
+			return s.handleVersionInfoRequest(ctx, args)
+		}
+		return false, nil, fmt.Errorf("unexpected request type %T for method %v", req, methodName)
+
+	default:
+		return false, nil, fmt.Errorf("method %v not found in service %v", methodName, s.Service())
+	}
+}
+
 func (s *tchanMetaServer) handleHealth(ctx Context, protocol athrift.TProtocol) (bool, athrift.TStruct, error) {
 	var req gen.MetaHealthArgs
-	var res gen.MetaHealthResult
-
 	if err := req.Read(ctx, protocol); err != nil {
 		return false, nil, err
 	}
 
+	return s.handleHealthRequest(ctx, &req)
+}
+
+func (s *tchanMetaServer) handleHealthRequest(ctx Context, req *gen.MetaHealthArgs) (bool, athrift.TStruct, error) {
+	var res gen.MetaHealthResult
+
 	r, err :=
 		s.handler.Health(ctx, req.Hr)
 
@@ -139,12 +180,16 @@ func (s *tchanMetaServer) handleHealth(ctx Context, protocol athrift.TProtocol)
 
 func (s *tchanMetaServer) handleThriftIDL(ctx Context, protocol athrift.TProtocol) (bool, athrift.TStruct, error) {
 	var req gen.MetaThriftIDLArgs
-	var res gen.MetaThriftIDLResult
-
 	if err := req.Read(ctx, protocol); err != nil {
 		return false, nil, err
 	}
 
+	return s.handleThriftIDLRequest(ctx, &req)
+}
+
+func (s *tchanMetaServer) handleThriftIDLRequest(ctx Context, req *gen.MetaThriftIDLArgs) (bool, athrift.TStruct, error) {
+	var res gen.MetaThriftIDLResult
+
 	r, err :=
 		s.handler.ThriftIDL(ctx)
 
@@ -158,12 +203,16 @@ func (s *tchanMetaServer) handleThriftIDL(ctx Context, protocol athrift.TProtoco
 
 func (s *tchanMetaServer) handleVersionInfo(ctx Context, protocol athrift.TProtocol) (bool, athrift.TStruct, error) {
 	var req gen.MetaVersionInfoArgs
-	var res gen.MetaVersionInfoResult
-
 	if err := req.Read(ctx, protocol); err != nil {
 		return false, nil, err
 	}
 
+	return s.handleVersionInfoRequest(ctx, &req)
+}
+
+func (s *tchanMetaServer) handleVersionInfoRequest(ctx Context, req *gen.MetaVersionInfoArgs) (bool, athrift.TStruct, error) {
+	var res gen.MetaVersionInfoResult
+
 	r, err :=
 		s.handler.VersionInfo(ctx)
 
@@ -36,6 +36,22 @@ type {{ .Interface }} interface {
 		{{ .Name }}({{ .ArgList }}) {{ .RetType }}
 	{{ end }}
 }
+
+{{ if .HasStreams }}
+// {{ .StreamInterface }} is the interface that defines the server handler for the stream methods.
+type {{ .StreamInterface }} interface {
+	{{ range .StreamMethods }}
+		{{ .Name }}(ctx {{ contextType }}, stream *{{ .ServerStreamType }}) error
+	{{ end }}
+}
+
+// {{ .StreamClientInterface }} is the interface used to make calls to the stream methods.
+type {{ .StreamClientInterface }} interface {
+	{{ range .StreamMethods }}
+		{{ .Name }}(ctx {{ contextType }}) (*{{ .ClientStreamType }}, error)
+	{{ end }}
+}
+{{ end }}
 {{ end }}
 
 // Implementation of a client and service handler.
@@ -144,15 +160,52 @@ func (s *{{ .ServerStruct }}) Handle(ctx {{ contextType }}, methodName string, p
 	}
 }
 
+func (s *{{ .ServerStruct }}) NewRequest(methodName string) athrift.TStruct {
+	switch methodName {
+		{{ range .Methods }}
+			case "{{ .ThriftName }}":
+				return &{{ .ArgsType }}{}
+		{{ end }}
+		{{ range .InheritedMethods }}
+			case "{{ . }}":
+				return s.TChanServer.(thrift.TChanStructServer).NewRequest(methodName)
+		{{ end }}
+		default:
+			return nil
+	}
+}
+
+func (s *{{ .ServerStruct }}) HandleRequest(ctx {{ contextType }}, methodName string, req athrift.TStruct) (bool, athrift.TStruct, error) {
+	switch methodName {
+		{{ range .Methods }}
+			case "{{ .ThriftName }}":
+				if args, ok := req.(*{{ .ArgsType }}); ok {
+					return s.{{ .HandleFunc }}Request(ctx, args)
+				}
+				return false, nil, fmt.Errorf("unexpected request type %T for method %v", req, methodName)
+		{{ end }}
+		{{ range .InheritedMethods }}
+			case "{{ . }}":
+				return s.TChanServer.(thrift.TChanStructServer).HandleRequest(ctx, methodName, req)
+		{{ end }}
+		default:
+			return false, nil, fmt.Errorf("method %v not found in service %v", methodName, s.Service())
+	}
+}
+
 {{ range .Methods }}
 	func (s *{{ $svc.ServerStruct }}) {{ .HandleFunc }}(ctx {{ contextType }}, protocol athrift.TProtocol) (bool, athrift.TStruct, error) {
 		var req {{ .ArgsType }}
-		var res {{ .ResultType }}
-
 		if err := req.Read(ctx, protocol); err != nil {
 			return false, nil, err
 		}
 
+		return s.{{ .HandleFunc }}Request(ctx, &req)
+	}
+
+	func (s *{{ $svc.ServerStruct }}) {{ .HandleFunc }}Request(ctx {{ contextType }}, req *{{ .ArgsType }}) (bool, athrift.TStruct, error) {
+		var res {{ .ResultType }}
+
 		{{ if .HasReturn }}
 			r, err :=
 		{{ else }}
@@ -187,5 +240,116 @@ func (s *{{ .ServerStruct }}) Handle(ctx {{ contextType }}, methodName string, p
 
 {{ end }}
 
+{{ if .HasStreams }}
+type {{ .StreamClientStruct }} struct {
+	thriftService string
+	client        thrift.TChanStreamClient
+}
+
+// {{ .StreamClientConstructor }} creates a client that can be used to make streaming calls.
+func {{ .StreamClientConstructor }}(client thrift.TChanStreamClient) {{ .StreamClientInterface }} {
+	return &{{ .StreamClientStruct }}{
+		"{{ .ThriftName }}",
+		client,
+	}
+}
+
+{{ range .StreamMethods }}
+	func (c *{{ $svc.StreamClientStruct }}) {{ .Name }}(ctx {{ contextType }}) (*{{ .ClientStreamType }}, error) {
+		stream, err := c.client.BeginStream(ctx, c.thriftService, "{{ .ThriftName }}")
+		if err != nil {
+			return nil, err
+		}
+		return &{{ .ClientStreamType }}{stream}, nil
+	}
+{{ end }}
+
+type {{ .StreamServerStruct }} struct {
+	handler {{ .StreamInterface }}
+}
+
+// {{ .StreamServerConstructor }} wraps a handler for {{ .StreamInterface }} so it can be
+// registered with a thrift.Server.
+func {{ .StreamServerConstructor }}(handler {{ .StreamInterface }}) thrift.TChanStreamServer {
+	return &{{ .StreamServerStruct }}{
+		handler,
+	}
+}
+
+func (s *{{ .StreamServerStruct }}) Service() string {
+	return "{{ .ThriftName }}"
+}
+
+func (s *{{ .StreamServerStruct }}) Methods() []string {
+	return []string{
+		{{ range .StreamMethods }}
+			"{{ .ThriftName }}",
+		{{ end }}
+	}
+}
+
+func (s *{{ .StreamServerStruct }}) Handle(ctx {{ contextType }}, methodName string, protocol athrift.TProtocol) (bool, athrift.TStruct, error) {
+	return false, nil, fmt.Errorf("method %v in service %v is a stream method", methodName, s.Service())
+}
+
+func (s *{{ .StreamServerStruct }}) HandleStream(ctx {{ contextType }}, methodName string, stream *thrift.Stream) error {
+	switch methodName {
+		{{ range .StreamMethods }}
+			case "{{ .ThriftName }}":
+				return s.handler.{{ .Name }}(ctx, &{{ .ServerStreamType }}{stream})
+		{{ end }}
+		default:
+			return fmt.Errorf("method %v not found in service %v", methodName, s.Service())
+	}
+}
+
+{{ range .StreamMethods }}
+	// {{ .ClientStreamType }} is the caller's side of the {{ $svc.ThriftName }}::{{ .ThriftName }} stream method.
+	type {{ .ClientStreamType }} struct {
+		stream *thrift.Stream
+	}
+
+	// Send sends a message to the handler.
+	func (s *{{ .ClientStreamType }}) Send(msg {{ .StreamArgType }}) error {
+		return s.stream.Send(msg)
+	}
+
+	// Recv receives the next message from the handler. It returns io.EOF once
+	// the handler has closed the stream.
+	func (s *{{ .ClientStreamType }}) Recv() ({{ .StreamResultType }}, error) {
+		msg := new({{ .StreamResultStruct }})
+		if err := s.stream.Recv(msg); err != nil {
+			return nil, err
+		}
+		return msg, nil
+	}
+
+	// CloseSend closes the caller's side of the stream.
+	func (s *{{ .ClientStreamType }}) CloseSend() error {
+		return s.stream.CloseSend()
+	}
+
+	// {{ .ServerStreamType }} is the handler's side of the {{ $svc.ThriftName }}::{{ .ThriftName }} stream method.
+	type {{ .ServerStreamType }} struct {
+		stream *thrift.Stream
+	}
+
+	// Send sends a message to the caller.
+	func (s *{{ .ServerStreamType }}) Send(msg {{ .StreamResultType }}) error {
+		return s.stream.Send(msg)
+	}
+
+	// Recv receives the next message from the caller. It returns io.EOF once
+	// the caller has closed their side of the stream.
+	func (s *{{ .ServerStreamType }}) Recv() ({{ .StreamArgType }}, error) {
+		msg := new({{ .StreamArgStruct }})
+		if err := s.stream.Recv(msg); err != nil {
+			return nil, err
+		}
+		return msg, nil
+	}
+{{ end }}
+{{ end }}
+
 {{ end }}
 `
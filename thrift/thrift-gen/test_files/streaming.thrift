@@ -0,0 +1,19 @@
+struct Ping {
+  1: required string message
+}
+
+struct Pong {
+  1: required string message
+  2: required i32 count
+}
+
+service Chat {
+  // Pong is returned for each Ping, and the stream completes when the
+  // caller closes their side.
+  Pong chat(1: Ping ping) (tchannel.stream = "true")
+  Pong ping(1: Ping ping)
+}
+
+service Feed {
+  Pong subscribe(1: Ping ping) (tchannel.stream = "true")
+}
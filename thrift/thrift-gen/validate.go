@@ -36,10 +36,28 @@ func Validate(svc *parser.Service) error {
 	return nil
 }
 
+// streamAnnotation is the annotation that marks a method as a stream method,
+// which exchanges a sequence of argument and result messages in a single call.
+const streamAnnotation = "tchannel.stream"
+
+func isStreamMethod(m *parser.Method) bool {
+	for _, a := range m.Annotations {
+		if a.Name == streamAnnotation {
+			return a.Value == "true"
+		}
+	}
+	return false
+}
+
 func validateMethod(svc *parser.Service, m *parser.Method) error {
 	if m.Oneway {
 		return fmt.Errorf("oneway methods are not supported: %s.%v", svc.Name, m.Name)
 	}
+	if isStreamMethod(m) {
+		if err := validateStreamMethod(m); err != nil {
+			return fmt.Errorf("invalid stream method %s.%v: %v", svc.Name, m.Name, err)
+		}
+	}
 	for _, arg := range m.Arguments {
 		if arg.Optional {
 			// Go treats argument structs as "Required" in the generated code interface.
@@ -48,3 +66,22 @@ func validateMethod(svc *parser.Service, m *parser.Method) error {
 	}
 	return nil
 }
+
+// validateStreamMethod validates that the messages of a stream method are structs.
+func validateStreamMethod(m *parser.Method) error {
+	if len(m.Arguments) != 1 {
+		return fmt.Errorf("must have a single argument")
+	}
+	if m.ReturnType == nil {
+		return fmt.Errorf("must have a return type")
+	}
+	if len(m.Exceptions) > 0 {
+		return fmt.Errorf("cannot declare exceptions")
+	}
+	for _, t := range []*parser.Type{m.Arguments[0].Type, m.ReturnType} {
+		if _, ok := thriftToGo[t.Name]; ok || t.Name == "binary" || t.KeyType != nil || t.ValueType != nil {
+			return fmt.Errorf("type %v is not a struct", t)
+		}
+	}
+	return nil
+}
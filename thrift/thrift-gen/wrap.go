@@ -41,8 +41,10 @@ type Service struct {
 	ExtendsService *Service
 	ExtendsPrefix  string
 
-	// methods is a cache of all methods.
+	// methods is a cache of all non-stream methods.
 	methods []*Method
+	// streamMethods is a cache of all stream methods.
+	streamMethods []*Method
 	// inheritedMethods is a list of inherited method names.
 	inheritedMethods []string
 }
@@ -103,17 +105,71 @@ func (l byMethodName) Len() int           { return len(l) }
 func (l byMethodName) Less(i, j int) bool { return l[i].Method.Name < l[j].Method.Name }
 func (l byMethodName) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
 
-// Methods returns the methods on this service, not including methods from inherited services.
+// Methods returns the methods on this service, not including stream methods or
+// methods from inherited services.
 func (s *Service) Methods() []*Method {
-	if s.methods != nil {
-		return s.methods
+	if s.methods == nil {
+		s.splitMethods()
 	}
+	return s.methods
+}
+
+// StreamMethods returns the stream methods on this service.
+func (s *Service) StreamMethods() []*Method {
+	if s.methods == nil {
+		s.splitMethods()
+	}
+	return s.streamMethods
+}
 
+func (s *Service) splitMethods() {
+	s.methods = []*Method{}
 	for _, m := range s.Service.Methods {
-		s.methods = append(s.methods, &Method{m, s, s.state})
+		if isStreamMethod(m) {
+			s.streamMethods = append(s.streamMethods, &Method{m, s, s.state})
+		} else {
+			s.methods = append(s.methods, &Method{m, s, s.state})
+		}
 	}
 	sort.Sort(byMethodName(s.methods))
-	return s.methods
+	sort.Sort(byMethodName(s.streamMethods))
+}
+
+// HasStreams returns whether this service has any stream methods.
+func (s *Service) HasStreams() bool {
+	return len(s.StreamMethods()) > 0
+}
+
+// StreamInterface returns the name of the interface implemented by handlers
+// for the service's stream methods.
+func (s *Service) StreamInterface() string {
+	return "TChan" + goPublicName(s.Name) + "Streams"
+}
+
+// StreamClientInterface returns the name of the interface used to make
+// streaming calls to the service's stream methods.
+func (s *Service) StreamClientInterface() string {
+	return "TChan" + goPublicName(s.Name) + "StreamClient"
+}
+
+// StreamClientStruct returns the name of the unexported struct that satisfies StreamClientInterface.
+func (s *Service) StreamClientStruct() string {
+	return "tchan" + goPublicName(s.Name) + "StreamClient"
+}
+
+// StreamClientConstructor returns the name of the constructor used to create a stream client.
+func (s *Service) StreamClientConstructor() string {
+	return "NewTChan" + goPublicName(s.Name) + "StreamClient"
+}
+
+// StreamServerStruct returns the name of the unexported struct that satisfies TChanStreamServer.
+func (s *Service) StreamServerStruct() string {
+	return "tchan" + goPublicName(s.Name) + "StreamServer"
+}
+
+// StreamServerConstructor returns the name of the constructor used to create the TChanStreamServer interface.
+func (s *Service) StreamServerConstructor() string {
+	return "NewTChan" + goPublicName(s.Name) + "StreamServer"
 }
 
 // InheritedMethods returns names for inherited methods on this service.
@@ -122,9 +178,12 @@ func (s *Service) InheritedMethods() []string {
 		return s.inheritedMethods
 	}
 
+	// Stream methods are not inherited, since they are handled by a separate server.
 	for svc := s.ExtendsService; svc != nil; svc = svc.ExtendsService {
-		for m := range svc.Service.Methods {
-			s.inheritedMethods = append(s.inheritedMethods, m)
+		for name, m := range svc.Service.Methods {
+			if !isStreamMethod(m) {
+				s.inheritedMethods = append(s.inheritedMethods, name)
+			}
 		}
 	}
 	sort.Strings(s.inheritedMethods)
@@ -243,6 +302,36 @@ func (m *Method) ReturnWith(respName string, errName string) string {
 	return fmt.Sprintf("%v, %v", respName, errName)
 }
 
+// ClientStreamType returns the Go name for the caller's side of a stream method.
+func (m *Method) ClientStreamType() string {
+	return m.argResPrefix() + "ClientStream"
+}
+
+// ServerStreamType returns the Go name for the handler's side of a stream method.
+func (m *Method) ServerStreamType() string {
+	return m.argResPrefix() + "ServerStream"
+}
+
+// StreamArgType returns the Go type of the messages sent by the caller of a stream method.
+func (m *Method) StreamArgType() string {
+	return m.Arguments()[0].ArgType()
+}
+
+// StreamArgStruct returns the Go struct name of the messages sent by the caller of a stream method.
+func (m *Method) StreamArgStruct() string {
+	return strings.TrimPrefix(m.StreamArgType(), "*")
+}
+
+// StreamResultType returns the Go type of the messages sent by the handler of a stream method.
+func (m *Method) StreamResultType() string {
+	return m.state.goType(m.Method.ReturnType)
+}
+
+// StreamResultStruct returns the Go struct name of the messages sent by the handler of a stream method.
+func (m *Method) StreamResultStruct() string {
+	return strings.TrimPrefix(m.StreamResultType(), "*")
+}
+
 // Field is a wrapper for parser.Field.
 type Field struct {
 	*parser.Field
@@ -40,6 +40,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
 )
 
 // Generate the service mocks using go generate.
@@ -100,6 +101,33 @@ func TestRetryRequest(t *testing.T) {
 	})
 }
 
+func TestHedgedRequest(t *testing.T) {
+	withSetup(t, func(_ tcthrift.Context, args testArgs) {
+		var count atomic.Int32
+		slowDone := make(chan struct{})
+		args.s2.On("Echo", ctxArg(), "hedge").Return("hedge-echo", nil).
+			Run(func(args mock.Arguments) {
+				if count.Inc() == 1 {
+					// The first attempt is slow, and is cancelled once the hedged attempt succeeds.
+					<-args.Get(0).(context.Context).Done()
+					close(slowDone)
+				}
+			})
+
+		ctx, cancel := tchannel.NewContextBuilder(time.Second).
+			SetRetryOptions(&tchannel.RetryOptions{
+				Hedge: &tchannel.HedgeOptions{Delay: 10 * time.Millisecond},
+			}).Build()
+		defer cancel()
+
+		res, err := args.c2.Echo(tcthrift.Wrap(ctx), "hedge")
+		require.NoError(t, err, "Echo failed")
+		assert.Equal(t, "hedge-echo", res, "Unexpected response")
+		assert.EqualValues(t, 2, count.Load(), "Expected the original and hedged attempts")
+		<-slowDone
+	})
+}
+
 func TestRequestSubChannel(t *testing.T) {
 	ctx, cancel := tcthrift.NewContext(time.Second)
 	defer cancel()
@@ -472,6 +500,116 @@ func TestThriftMetaHealthNoArgs(t *testing.T) {
 	})
 }
 
+func TestServerInterceptors(t *testing.T) {
+	withSetup(t, func(ctx tcthrift.Context, args testArgs) {
+		var calls []string
+		validate := func(ctx tcthrift.Context, method string, req thrift.TStruct, next tcthrift.ServerHandler) (bool, thrift.TStruct, error) {
+			calls = append(calls, "validate "+method)
+			if echo, ok := req.(*gen.SecondServiceEchoArgs); ok {
+				if echo.Arg == "invalid" {
+					return false, nil, tchannel.NewSystemError(tchannel.ErrCodeBadRequest, "invalid arg")
+				}
+				echo.Arg = strings.ToLower(echo.Arg)
+			}
+			return next(ctx, req)
+		}
+		record := func(ctx tcthrift.Context, method string, req thrift.TStruct, next tcthrift.ServerHandler) (bool, thrift.TStruct, error) {
+			success, resp, err := next(ctx, req)
+			switch resp := resp.(type) {
+			case *gen.SecondServiceEchoResult:
+				calls = append(calls, "response "+resp.GetSuccess())
+			case *gen.SimpleServiceSimpleResult:
+				calls = append(calls, fmt.Sprintf("success=%v exception=%v", success, resp.SimpleErr.GetMessage()))
+			}
+			return success, resp, err
+		}
+		args.server.SetInterceptors(validate, record)
+
+		args.s2.On("Echo", ctxArg(), "hello").Return("hello-echo", nil)
+		res, err := args.c2.Echo(ctx, "HELLO")
+		require.NoError(t, err, "Echo failed")
+		assert.Equal(t, "hello-echo", res, "Unexpected response")
+
+		_, err = args.c2.Echo(ctx, "invalid")
+		require.Error(t, err, "Echo should be rejected")
+		assert.Equal(t, tchannel.ErrCodeBadRequest, tchannel.GetSystemErrorCode(err), "Unexpected error code")
+
+		args.s1.On("Simple", ctxArg()).Return(&gen.SimpleErr{Message: "err"})
+		err = args.c1.Simple(ctx)
+		assert.Equal(t, &gen.SimpleErr{Message: "err"}, err, "Unexpected error")
+
+		assert.Equal(t, []string{
+			"validate SecondService::Echo",
+			"response hello-echo",
+			"validate SecondService::Echo",
+			"validate SimpleService::Simple",
+			"success=false exception=err",
+		}, calls, "Unexpected interceptor calls")
+	})
+}
+
+// legacyServer hides the TChanStructServer methods of a generated server.
+type legacyServer struct {
+	tcthrift.TChanServer
+}
+
+func TestServerInterceptorsUnsupportedServer(t *testing.T) {
+	withSetup(t, func(ctx tcthrift.Context, args testArgs) {
+		args.server.Register(legacyServer{gen.NewTChanSecondServiceServer(args.s2)})
+		args.server.SetInterceptors(func(ctx tcthrift.Context, method string, req thrift.TStruct, next tcthrift.ServerHandler) (bool, thrift.TStruct, error) {
+			return next(ctx, req)
+		})
+
+		_, err := args.c2.Echo(ctx, "echo")
+		require.Error(t, err, "Echo should fail")
+		assert.Equal(t, tchannel.ErrCodeUnexpected, tchannel.GetSystemErrorCode(err), "Unexpected error code")
+		assert.Contains(t, err.Error(), "does not support interceptors", "Unexpected error")
+	})
+}
+
+func TestClientInterceptors(t *testing.T) {
+	withSetup(t, func(ctx tcthrift.Context, args testArgs) {
+		var methods []string
+		logMethod := func(ctx tcthrift.Context, method string, req, resp thrift.TStruct, next tcthrift.ClientCaller) (bool, error) {
+			methods = append(methods, method)
+			return next(ctx, req, resp)
+		}
+
+		cache := make(map[string]string)
+		cacheEcho := func(ctx tcthrift.Context, method string, req, resp thrift.TStruct, next tcthrift.ClientCaller) (bool, error) {
+			arg := req.(*gen.SecondServiceEchoArgs).Arg
+			result := resp.(*gen.SecondServiceEchoResult)
+			if cached, ok := cache[arg]; ok {
+				result.Success = &cached
+				return true, nil
+			}
+
+			success, err := next(ctx, req, resp)
+			if err == nil && success {
+				cache[arg] = result.GetSuccess()
+			}
+			return success, err
+		}
+
+		client := tcthrift.NewClient(args.clientCh, args.serverCh.ServiceName(), &tcthrift.ClientOptions{
+			Interceptors: []tcthrift.ClientInterceptor{logMethod, cacheEcho},
+		})
+		c2 := gen.NewTChanSecondServiceClient(client)
+
+		args.s2.On("Echo", ctxArg(), "cached").Return("cached-echo", nil).Once()
+		for i := 0; i < 3; i++ {
+			res, err := c2.Echo(ctx, "cached")
+			require.NoError(t, err, "Echo failed")
+			assert.Equal(t, "cached-echo", res, "Unexpected response")
+		}
+		assert.Equal(t, []string{
+			"SecondService::Echo",
+			"SecondService::Echo",
+			"SecondService::Echo",
+		}, methods, "Interceptors should be called for every call")
+	})
+}
+
 func withSetup(t *testing.T, f func(ctx tcthrift.Context, args testArgs)) {
 	args := testArgs{
 		s1: new(mocks.TChanSimpleService),
@@ -540,3 +678,30 @@ type rewriteMethodClient struct {
 func (c rewriteMethodClient) Call(ctx tcthrift.Context, serviceName, methodName string, req, resp thrift.TStruct) (success bool, err error) {
 	return c.client.Call(ctx, serviceName, c.rewriteTo, req, resp)
 }
+
+func TestServerAuthorizationPolicy(t *testing.T) {
+	withSetup(t, func(ctx tcthrift.Context, args testArgs) {
+		err := args.server.SetAuthorizationPolicy(&tchannel.AuthorizationPolicy{
+			Rules: []tchannel.AuthorizationRule{{Effect: "maybe"}},
+		})
+		assert.Error(t, err, "Invalid policies should be rejected")
+
+		require.NoError(t, args.server.SetAuthorizationPolicy(&tchannel.AuthorizationPolicy{
+			Rules: []tchannel.AuthorizationRule{
+				{Effect: tchannel.AuthorizationDeny, Methods: []string{"SecondService::*"}},
+			},
+		}), "SetAuthorizationPolicy failed")
+
+		args.s1.On("Simple", ctxArg()).Return(nil)
+		assert.NoError(t, args.c1.Simple(ctx), "Allowed methods should succeed")
+
+		_, err = args.c2.Echo(ctx, "denied")
+		assert.Equal(t, tchannel.ErrUnauthorized, err, "Denied methods should fail before the handler runs")
+
+		require.NoError(t, args.server.SetAuthorizationPolicy(nil), "SetAuthorizationPolicy failed")
+		args.s2.On("Echo", ctxArg(), "allowed").Return("allowed-echo", nil)
+		res, err := args.c2.Echo(ctx, "allowed")
+		require.NoError(t, err, "Calls should be allowed after removing the policy")
+		assert.Equal(t, "allowed-echo", res, "Unexpected response")
+	})
+}
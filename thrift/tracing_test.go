@@ -104,6 +104,10 @@ func TestThriftTracingPropagation(t *testing.T) {
 				{ForwardCount: 2, TracingDisabled: true, ExpectedBaggage: testtracing.BaggageValue, ExpectedSpanCount: 0},
 				{ForwardCount: 2, TracingDisabled: false, ExpectedBaggage: testtracing.BaggageValue, ExpectedSpanCount: 6},
 			},
+			testtracing.W3C: {
+				{ForwardCount: 2, TracingDisabled: true, ExpectedBaggage: testtracing.BaggageValue, ExpectedSpanCount: 0},
+				{ForwardCount: 2, TracingDisabled: false, ExpectedBaggage: testtracing.BaggageValue, ExpectedSpanCount: 6},
+			},
 		},
 	}
 	suite.Run(t)
@@ -0,0 +1,163 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+const spiffeScheme = "spiffe"
+
+// PeerTLSInfo contains the TLS state verified during the handshake with a peer.
+type PeerTLSInfo struct {
+	// ServerName is the SNI server name used for the connection.
+	ServerName string `json:"serverName,omitempty"`
+
+	// Subject is the subject of the peer's leaf certificate.
+	Subject string `json:"subject,omitempty"`
+
+	// URIs are the URI SANs of the peer's leaf certificate, e.g. SPIFFE IDs.
+	URIs []string `json:"uris,omitempty"`
+
+	// PeerCertificates is the certificate chain presented by the peer.
+	PeerCertificates []*x509.Certificate `json:"-"`
+
+	// VerifiedChains is the list of chains that were verified for the peer.
+	// It is empty if peer certificates were not verified.
+	VerifiedChains [][]*x509.Certificate `json:"-"`
+}
+
+// SPIFFEID returns the first spiffe:// URI SAN of the peer's leaf certificate,
+// or an empty string if there is none.
+func (i *PeerTLSInfo) SPIFFEID() string {
+	if i == nil {
+		return ""
+	}
+	for _, uri := range i.URIs {
+		if strings.HasPrefix(uri, spiffeScheme+"://") {
+			return uri
+		}
+	}
+	return ""
+}
+
+// Verified returns whether the peer presented a certificate that was verified.
+func (i *PeerTLSInfo) Verified() bool {
+	return i != nil && len(i.VerifiedChains) > 0
+}
+
+func newPeerTLSInfo(state tls.ConnectionState) *PeerTLSInfo {
+	info := &PeerTLSInfo{
+		ServerName:       state.ServerName,
+		PeerCertificates: state.PeerCertificates,
+		VerifiedChains:   state.VerifiedChains,
+	}
+	if len(state.PeerCertificates) > 0 {
+		leaf := state.PeerCertificates[0]
+		info.Subject = leaf.Subject.String()
+		for _, uri := range leaf.URIs {
+			info.URIs = append(info.URIs, uri.String())
+		}
+	}
+	return info
+}
+
+// getTLSConnectionState returns the TLS state for conn, or nil if conn
+// does not use TLS.
+func getTLSConnectionState(conn net.Conn) *tls.ConnectionState {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return nil
+	}
+	state := tlsConn.ConnectionState()
+	return &state
+}
+
+// SetTLSConfig replaces the TLS configuration used for new connections.
+// Existing connections are unaffected, which allows certificates to be
+// rotated without dropping connections. Passing nil disables TLS for new
+// connections.
+func (ch *Channel) SetTLSConfig(cfg *tls.Config) {
+	ch.tlsConfig.Store(tlsConfigHolder{cfg})
+}
+
+// TLSConfig returns the TLS configuration used for new connections, if any.
+func (ch *Channel) TLSConfig() *tls.Config {
+	holder, _ := ch.tlsConfig.Load().(tlsConfigHolder)
+	return holder.cfg
+}
+
+// tlsConfigHolder allows a nil *tls.Config to be stored in an atomic.Value.
+type tlsConfigHolder struct {
+	cfg *tls.Config
+}
+
+// tlsServer wraps an accepted connection with TLS if it is configured and the
+// connection was not already wrapped by the listener.
+func (ch *Channel) tlsServer(conn net.Conn) net.Conn {
+	cfg := ch.TLSConfig()
+	if cfg == nil {
+		return conn
+	}
+	if _, ok := conn.(*tls.Conn); ok {
+		return conn
+	}
+	return tls.Server(conn, cfg)
+}
+
+// tlsClient wraps a dialed connection with TLS if it is configured and the
+// dialer did not already return a TLS connection.
+func (ch *Channel) tlsClient(conn net.Conn, hostPort string) net.Conn {
+	cfg := ch.TLSConfig()
+	if cfg == nil {
+		return conn
+	}
+	if _, ok := conn.(*tls.Conn); ok {
+		return conn
+	}
+	if cfg.ServerName == "" {
+		// Peers that are not addressed by host:port, such as Unix sockets,
+		// have no host to verify, so the config must set ServerName.
+		if host, _, err := net.SplitHostPort(hostPort); err == nil && !isUnixHostPort(hostPort) {
+			cfg = cfg.Clone()
+			cfg.ServerName = host
+		}
+	}
+	return tls.Client(conn, cfg)
+}
+
+// tlsHandshake runs the TLS handshake on c if it uses TLS, and returns the
+// peer's TLS information.
+func tlsHandshake(ctx context.Context, c net.Conn) (*PeerTLSInfo, error) {
+	tlsConn, ok := c.(*tls.Conn)
+	if !ok {
+		return nil, nil
+	}
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil, err
+	}
+	return newPeerTLSInfo(tlsConn.ConnectionState()), nil
+}
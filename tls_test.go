@@ -0,0 +1,245 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	. "github.com/temporalio/tchannel-go"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/temporalio/tchannel-go/raw"
+	"github.com/temporalio/tchannel-go/testutils"
+	"golang.org/x/net/context"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t testing.TB) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err, "Failed to generate CA key")
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err, "Failed to create CA certificate")
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err, "Failed to parse CA certificate")
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+func (ca *testCA) issue(t testing.TB, serial int64, spiffeID string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err, "Failed to generate key")
+
+	uri, err := url.Parse(spiffeID)
+	require.NoError(t, err, "Failed to parse SPIFFE ID")
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: spiffeID},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:     []string{"localhost"},
+		URIs:         []*url.URL{uri},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err, "Failed to create certificate")
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func (ca *testCA) serverConfig(cert tls.Certificate) *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    ca.pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		RootCAs:      ca.pool,
+	}
+}
+
+func (ca *testCA) clientConfig(cert *tls.Certificate) *tls.Config {
+	cfg := &tls.Config{RootCAs: ca.pool}
+	if cert != nil {
+		cfg.Certificates = []tls.Certificate{*cert}
+	}
+	return cfg
+}
+
+func TestTLSPeerIdentity(t *testing.T) {
+	ca := newTestCA(t)
+	serverCert := ca.issue(t, 2, "spiffe://example.org/server")
+	clientCert := ca.issue(t, 3, "spiffe://example.org/client")
+
+	server := testutils.NewServer(t, testutils.NewOpts().NoRelay().SetTLSConfig(ca.serverConfig(serverCert)))
+	defer server.Close()
+
+	var gotPeer PeerInfo
+	testutils.RegisterFunc(server, "identity", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+		gotPeer = CurrentCall(ctx).RemotePeer()
+		return &raw.Res{Arg3: []byte(gotPeer.TLS.SPIFFEID())}, nil
+	})
+
+	client := testutils.NewClient(t, testutils.NewOpts().SetTLSConfig(ca.clientConfig(&clientCert)))
+	defer client.Close()
+
+	ctx, cancel := NewContext(testutils.Timeout(time.Second))
+	defer cancel()
+
+	_, arg3, _, err := raw.Call(ctx, client, server.PeerInfo().HostPort, server.ServiceName(), "identity", nil, nil)
+	require.NoError(t, err, "Call over mTLS failed")
+	assert.Equal(t, "spiffe://example.org/client", string(arg3), "Unexpected client identity")
+	assert.True(t, gotPeer.TLS.Verified(), "Client certificate should be verified")
+	assert.Equal(t, []string{"spiffe://example.org/client"}, gotPeer.TLS.URIs, "Unexpected URI SANs")
+
+	conn, err := client.Peers().GetOrAdd(server.PeerInfo().HostPort).GetConnection(ctx)
+	require.NoError(t, err, "GetConnection failed")
+	assert.Equal(t, "spiffe://example.org/server", conn.RemotePeerInfo().TLS.SPIFFEID(), "Unexpected server identity")
+}
+
+func TestTLSRequiresClientCert(t *testing.T) {
+	ca := newTestCA(t)
+	serverCert := ca.issue(t, 2, "spiffe://example.org/server")
+
+	opts := testutils.NewOpts().
+		NoRelay().
+		SetTLSConfig(ca.serverConfig(serverCert)).
+		AddLogFilter("Failed during connection handshake.", 1)
+	server := testutils.NewServer(t, opts)
+	defer server.Close()
+	testutils.RegisterEcho(server, nil)
+
+	clientOpts := testutils.NewOpts().
+		SetTLSConfig(ca.clientConfig(nil)).
+		AddLogFilter("Failed during connection handshake.", 1)
+	client := testutils.NewClient(t, clientOpts)
+	defer client.Close()
+
+	err := testutils.CallEcho(client, server.PeerInfo().HostPort, server.ServiceName(), nil)
+	assert.Error(t, err, "Call without a client certificate should fail")
+}
+
+func TestTLSCertificateRotation(t *testing.T) {
+	ca := newTestCA(t)
+	serverCert := ca.issue(t, 2, "spiffe://example.org/server")
+	clientCert := ca.issue(t, 3, "spiffe://example.org/client")
+	rotatedCert := ca.issue(t, 4, "spiffe://example.org/client-rotated")
+
+	server := testutils.NewServer(t, testutils.NewOpts().NoRelay().SetTLSConfig(ca.serverConfig(serverCert)))
+	defer server.Close()
+	testutils.RegisterFunc(server, "identity", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+		return &raw.Res{Arg3: []byte(CurrentCall(ctx).RemotePeer().TLS.SPIFFEID())}, nil
+	})
+
+	client := testutils.NewClient(t, testutils.NewOpts().SetTLSConfig(ca.clientConfig(&clientCert)))
+	defer client.Close()
+
+	ctx, cancel := NewContext(testutils.Timeout(time.Second))
+	defer cancel()
+
+	hostPort := server.PeerInfo().HostPort
+	oldConn, err := client.Connect(ctx, hostPort)
+	require.NoError(t, err, "Connect failed")
+
+	client.SetTLSConfig(ca.clientConfig(&rotatedCert))
+	newConn, err := client.Connect(ctx, hostPort)
+	require.NoError(t, err, "Connect after rotation failed")
+
+	assert.True(t, oldConn.IsActive(), "Existing connection should not be dropped by rotation")
+	assert.True(t, newConn.IsActive(), "New connection should be active")
+
+	_, arg3, _, err := raw.Call(ctx, client, hostPort, server.ServiceName(), "identity", nil, nil)
+	require.NoError(t, err, "Call after rotation failed")
+	assert.Contains(t, []string{"spiffe://example.org/client", "spiffe://example.org/client-rotated"}, string(arg3))
+}
+
+func TestTLSUnixSocket(t *testing.T) {
+	ca := newTestCA(t)
+	serverCert := ca.issue(t, 2, "spiffe://example.org/server")
+	clientCert := ca.issue(t, 3, "spiffe://example.org/client")
+
+	hostPort := unixSocketHostPort(t)
+	server := newUnixServer(t, hostPort, testutils.NewOpts().
+		SetTLSConfig(ca.serverConfig(serverCert)).
+		AddLogFilter("Failed during connection handshake.", 1))
+	defer server.Close()
+
+	tests := []struct {
+		msg        string
+		serverName string
+		wantErr    bool
+	}{
+		{
+			msg:     "no server name",
+			wantErr: true,
+		},
+		{
+			msg:        "server name set in config",
+			serverName: "localhost",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			cfg := ca.clientConfig(&clientCert)
+			cfg.ServerName = tt.serverName
+			client := testutils.NewClient(t, testutils.NewOpts().
+				SetTLSConfig(cfg).
+				AddLogFilter("Failed during connection handshake.", 1).
+				AddLogFilter("Couldn't close connection to peer.", 1))
+			defer client.Close()
+
+			err := testutils.CallEcho(client, hostPort, server.ServiceName(), nil)
+			if tt.wantErr {
+				assert.Error(t, err, "Call without a ServerName should fail verification")
+				return
+			}
+			require.NoError(t, err, "Call over TLS on a Unix socket failed")
+		})
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// traceParentVersion is the only version of the W3C traceparent header
+// that we generate.
+const traceParentVersion = "00"
+
+// traceParentLen is the length of a version 00 traceparent header,
+// e.g. 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+const traceParentLen = 55
+
+// traceFlagSampled is the sampled flag in both W3C and Zipkin-style flags.
+const traceFlagSampled = 0x01
+
+var errInvalidTraceParent = errors.New("invalid traceparent")
+
+// traceContext is a W3C Trace Context, as used by OpenTelemetry.
+// See https://www.w3.org/TR/trace-context/
+type traceContext struct {
+	traceID    [16]byte
+	spanID     [8]byte
+	flags      byte
+	traceState string
+}
+
+// parseTraceContext parses the traceparent and tracestate header values.
+func parseTraceContext(traceParent, traceState string) (traceContext, error) {
+	var tc traceContext
+
+	// Future versions may append fields, so only version 00 is required to
+	// have exactly traceParentLen characters.
+	if len(traceParent) < traceParentLen ||
+		traceParent[2] != '-' || traceParent[35] != '-' || traceParent[52] != '-' {
+		return tc, errInvalidTraceParent
+	}
+	switch version := traceParent[:2]; {
+	case version == "ff":
+		return tc, errInvalidTraceParent
+	case version == traceParentVersion && len(traceParent) != traceParentLen:
+		return tc, errInvalidTraceParent
+	case len(traceParent) > traceParentLen && traceParent[traceParentLen] != '-':
+		return tc, errInvalidTraceParent
+	}
+
+	var version [1]byte
+	if _, err := hex.Decode(version[:], []byte(traceParent[:2])); err != nil {
+		return tc, errInvalidTraceParent
+	}
+
+	var flags [1]byte
+	if _, err := hex.Decode(tc.traceID[:], []byte(traceParent[3:35])); err != nil {
+		return tc, errInvalidTraceParent
+	}
+	if _, err := hex.Decode(tc.spanID[:], []byte(traceParent[36:52])); err != nil {
+		return tc, errInvalidTraceParent
+	}
+	if _, err := hex.Decode(flags[:], []byte(traceParent[53:55])); err != nil {
+		return tc, errInvalidTraceParent
+	}
+	if !tc.valid() {
+		return tc, errInvalidTraceParent
+	}
+
+	tc.flags = flags[0]
+	tc.traceState = traceState
+	return tc, nil
+}
+
+// traceContextFromZipkin converts a Zipkin-style span into a W3C Trace
+// Context, padding the 64-bit trace ID as Zipkin and OpenTelemetry do.
+func traceContextFromZipkin(s Span) traceContext {
+	var tc traceContext
+	binary.BigEndian.PutUint64(tc.traceID[8:], s.traceID)
+	binary.BigEndian.PutUint64(tc.spanID[:], s.spanID)
+	tc.flags = s.flags & traceFlagSampled
+	return tc
+}
+
+// traceContextFromOpenTracing returns the W3C Trace Context for the span,
+// if the tracer propagates it in the TextMap format.
+func traceContextFromOpenTracing(span opentracing.Span) (traceContext, bool) {
+	carrier := opentracing.TextMapCarrier{}
+	if err := span.Tracer().Inject(span.Context(), opentracing.TextMap, carrier); err != nil {
+		return traceContext{}, false
+	}
+	tc, err := parseTraceContext(carrier[string(TraceParent)], carrier[string(TraceState)])
+	return tc, err == nil
+}
+
+func (tc traceContext) valid() bool {
+	return tc.traceID != [16]byte{} && tc.spanID != [8]byte{}
+}
+
+// zipkinSpan returns a Zipkin-style span for callers that do not support
+// W3C Trace Context. Only the lower 64 bits of the trace ID are kept.
+func (tc traceContext) zipkinSpan() Span {
+	return Span{
+		traceID: binary.BigEndian.Uint64(tc.traceID[8:]),
+		spanID:  binary.BigEndian.Uint64(tc.spanID[:]),
+		flags:   tc.flags & traceFlagSampled,
+	}
+}
+
+func (tc traceContext) traceParent() string {
+	return fmt.Sprintf("%s-%x-%x-%02x", traceParentVersion, tc.traceID, tc.spanID, tc.flags)
+}
+
+// setHeaders sets the traceparent and tracestate transport headers.
+func (tc traceContext) setHeaders(headers transportHeaders) {
+	headers[TraceParent] = tc.traceParent()
+	if tc.traceState != "" {
+		headers[TraceState] = tc.traceState
+	}
+}
+
+// carrier returns a TextMap carrier containing the W3C Trace Context.
+func (tc traceContext) carrier() opentracing.TextMapCarrier {
+	carrier := opentracing.TextMapCarrier{string(TraceParent): tc.traceParent()}
+	if tc.traceState != "" {
+		carrier[string(TraceState)] = tc.traceState
+	}
+	return carrier
+}
+
+// isTraceContextKey returns whether key is a W3C Trace Context header.
+func isTraceContextKey(key string) bool {
+	return key == string(TraceParent) || key == string(TraceState)
+}
+
+// formatHasAppHeaders returns whether calls using the format propagate
+// tracing context in application headers in arg2.
+func formatHasAppHeaders(format Format) bool {
+	return format == Thrift || format == JSON
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTraceContext(t *testing.T) {
+	const valid = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	tests := []struct {
+		msg         string
+		traceParent string
+		wantErr     bool
+	}{
+		{msg: "valid", traceParent: valid},
+		{msg: "future version", traceParent: "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-what-the-future-will-be-like"},
+		{msg: "empty", traceParent: "", wantErr: true},
+		{msg: "too short", traceParent: valid[:54], wantErr: true},
+		{msg: "version 00 too long", traceParent: valid + "-00", wantErr: true},
+		{msg: "invalid version", traceParent: "ff" + valid[2:], wantErr: true},
+		{msg: "non-hex version", traceParent: "zz" + valid[2:], wantErr: true},
+		{msg: "future version bad suffix", traceParent: "cc" + valid[2:] + "x", wantErr: true},
+		{msg: "bad separators", traceParent: "00_4bf92f3577b34da6a3ce929d0e0e4736_00f067aa0ba902b7_01", wantErr: true},
+		{msg: "non-hex trace ID", traceParent: "00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01", wantErr: true},
+		{msg: "zero trace ID", traceParent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01", wantErr: true},
+		{msg: "zero span ID", traceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			tc, err := parseTraceContext(tt.traceParent, "vendor=value")
+			if tt.wantErr {
+				assert.Equal(t, errInvalidTraceParent, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "vendor=value", tc.traceState)
+			assert.Equal(t, byte(1), tc.flags)
+			assert.Equal(t, valid, tc.traceParent(), "traceparent should be reformatted as version 00")
+		})
+	}
+}
+
+func TestTraceContextZipkin(t *testing.T) {
+	tc, err := parseTraceContext("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "")
+	require.NoError(t, err)
+
+	span := tc.zipkinSpan()
+	assert.Equal(t, Span{traceID: 0xa3ce929d0e0e4736, spanID: 0x00f067aa0ba902b7, flags: 1}, span)
+
+	// Zipkin-style IDs are padded to 128 bits.
+	assert.Equal(t, "00-0000000000000000a3ce929d0e0e4736-00f067aa0ba902b7-01", traceContextFromZipkin(span).traceParent())
+
+	_, err = zipkinTraceContext(Span{traceID: 1, spanID: 2})
+	assert.Error(t, err, "Unsampled Zipkin spans should be ignored")
+	_, err = zipkinTraceContext(Span{flags: 1})
+	assert.Error(t, err, "Empty Zipkin spans should be ignored")
+}
+
+func TestTraceContextHeaders(t *testing.T) {
+	tc, err := parseTraceContext("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", "")
+	require.NoError(t, err)
+
+	headers := transportHeaders{}
+	tc.setHeaders(headers)
+	assert.Equal(t, transportHeaders{
+		TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+	}, headers, "tracestate should be omitted if empty")
+
+	// W3C Trace Context keys are not prefixed in application headers.
+	appHeaders := map[string]string{"app": "value"}
+	carrier := tracingHeadersCarrier(appHeaders)
+	carrier.Set("traceparent", "tp")
+	carrier.Set("tracestate", "ts")
+	carrier.Set("other", "o")
+	assert.Equal(t, map[string]string{
+		"app":            "value",
+		"traceparent":    "tp",
+		"tracestate":     "ts",
+		"$tracing$other": "o",
+	}, appHeaders)
+
+	got := make(map[string]string)
+	require.NoError(t, carrier.ForeachKey(func(k, v string) error {
+		got[k] = v
+		return nil
+	}))
+	assert.Equal(t, map[string]string{"traceparent": "tp", "tracestate": "ts", "other": "o"}, got)
+
+	carrier.RemoveTracingKeys()
+	assert.Equal(t, map[string]string{"app": "value"}, appHeaders)
+}
@@ -22,6 +22,7 @@ package tchannel
 
 import (
 	"fmt"
+	"net"
 	"time"
 
 	"github.com/temporalio/tchannel-go/trand"
@@ -38,6 +39,17 @@ import (
 // NB: the string value is what's actually shared between implementations
 const zipkinSpanFormat = "zipkin-span-format"
 
+// Span tags from the OpenTelemetry semantic conventions for RPC spans.
+const (
+	rpcSystemTag          = "rpc.system"
+	rpcServiceTag         = "rpc.service"
+	rpcMethodTag          = "rpc.method"
+	networkPeerAddressTag = "network.peer.address"
+	networkPeerPortTag    = "network.peer.port"
+
+	rpcSystemTChannel = "tchannel"
+)
+
 // Span is an internal representation of Zipkin-compatible OpenTracing Span.
 // It is used as OpenTracing inject/extract Carrier with ZipkinSpanFormat.
 type Span struct {
@@ -135,27 +147,43 @@ func CurrentSpan(ctx context.Context) *Span {
 // a new root span is created.
 //
 // If the tracer supports Zipkin-style trace IDs, then call.callReq.Tracing is
-// initialized with those IDs. Otherwise it is assigned random values.
+// initialized with those IDs. If the tracer instead uses W3C Trace Context
+// (e.g. the otelbridge package), call.callReq.Tracing is derived from it, and
+// calls without application headers carry it in transport headers.
+// Otherwise call.callReq.Tracing is assigned random values.
 func (c *Connection) startOutboundSpan(ctx context.Context, serviceName, methodName string, call *OutboundCall, startTime time.Time) opentracing.Span {
 	var parent opentracing.SpanContext // ok to be nil
 	if s := opentracing.SpanFromContext(ctx); s != nil {
 		parent = s.Context()
 	}
-	span := c.Tracer().StartSpan(
-		methodName,
+	// The span kind and sampling priority are passed as start options, as some
+	// tracers (e.g. the otelbridge package) cannot change them later.
+	opts := []opentracing.StartSpanOption{
 		opentracing.ChildOf(parent),
 		opentracing.StartTime(startTime),
-	)
-	if isTracingDisabled(ctx) {
+		ext.SpanKindRPCClient,
+	}
+	tracingDisabled := isTracingDisabled(ctx)
+	if tracingDisabled {
+		opts = append(opts, opentracing.Tag{Key: string(ext.SamplingPriority), Value: uint16(0)})
+	}
+	span := c.Tracer().StartSpan(methodName, opts...)
+	if tracingDisabled {
+		// Other tracers (e.g. mocktracer) only honor the sampling priority once started.
 		ext.SamplingPriority.Set(span, 0)
 	}
-	ext.SpanKindRPCClient.Set(span)
 	ext.PeerService.Set(span, serviceName)
 	c.setPeerHostPort(span)
+	setRPCTags(span, serviceName, methodName)
 	span.SetTag("as", call.callReq.Headers[ArgScheme])
 	var injectable injectableSpan
 	if err := injectable.initFromOpenTracing(span); err == nil {
 		call.callReq.Tracing = Span(injectable)
+	} else if tc, ok := traceContextFromOpenTracing(span); ok {
+		call.callReq.Tracing = tc.zipkinSpan()
+		if !formatHasAppHeaders(Format(call.callReq.Headers[ArgScheme])) {
+			tc.setHeaders(call.callReq.Headers)
+		}
 	} else {
 		call.callReq.Tracing.initRandom()
 	}
@@ -196,11 +224,15 @@ func InjectOutboundSpan(response *OutboundCallResponse, headers map[string]strin
 
 // extractInboundSpan attempts to create a new OpenTracing Span for inbound request
 // using only trace IDs stored in the frame's tracing field. It only works if the
-// tracer understand Zipkin-style trace IDs. If such attempt fails, another attempt
+// tracer understand Zipkin-style trace IDs, or if the tracer uses W3C Trace Context
+// and the call has no application headers. If such attempt fails, another attempt
 // will be made from the higher level function ExtractInboundSpan() once the
 // application headers are read from the wire.
 func (c *Connection) extractInboundSpan(callReq *callReq) opentracing.Span {
 	spanCtx, err := c.Tracer().Extract(zipkinSpanFormat, &callReq.Tracing)
+	if err == opentracing.ErrUnsupportedFormat {
+		spanCtx, err = c.extractTraceContext(callReq)
+	}
 	if err != nil {
 		if err != opentracing.ErrUnsupportedFormat && err != opentracing.ErrSpanContextNotFound {
 			c.log.WithFields(ErrField(err)).Error("Failed to extract Zipkin-style span.")
@@ -215,16 +247,47 @@ func (c *Connection) extractInboundSpan(callReq *callReq) opentracing.Span {
 	span.SetTag("as", callReq.Headers[ArgScheme])
 	ext.PeerService.Set(span, callReq.Headers[CallerName])
 	c.setPeerHostPort(span)
+	setRPCTags(span, string(callReq.Service), "" /* methodName */)
 	return span
 }
 
+// extractTraceContext extracts the W3C Trace Context for a call from its
+// transport headers. Calls from tracers that only support Zipkin-style IDs,
+// such as those from services that have not migrated to OpenTelemetry, use
+// the frame's tracing field if it is sampled.
+// Calls with application headers are left to ExtractInboundSpan().
+func (c *Connection) extractTraceContext(callReq *callReq) (opentracing.SpanContext, error) {
+	tc, err := parseTraceContext(callReq.Headers[TraceParent], callReq.Headers[TraceState])
+	if err != nil {
+		if formatHasAppHeaders(Format(callReq.Headers[ArgScheme])) {
+			return nil, opentracing.ErrSpanContextNotFound
+		}
+		if tc, err = zipkinTraceContext(callReq.Tracing); err != nil {
+			return nil, err
+		}
+	}
+	return c.Tracer().Extract(opentracing.TextMap, tc.carrier())
+}
+
+// zipkinTraceContext returns the W3C Trace Context for a Zipkin-style span.
+// Unsampled spans are ignored, as callers without tracing send random IDs.
+func zipkinTraceContext(s Span) (traceContext, error) {
+	if s.flags&traceFlagSampled == 0 || s.traceID == 0 || s.spanID == 0 {
+		return traceContext{}, opentracing.ErrSpanContextNotFound
+	}
+	return traceContextFromZipkin(s), nil
+}
+
 // ExtractInboundSpan is a higher level version of extractInboundSpan().
 // If the lower-level attempt to create a span from incoming request was
 // successful (e.g. when then Tracer supports Zipkin-style trace IDs),
 // then the application headers are only used to read the Baggage and add
 // it to the existing span. Otherwise, the standard OpenTracing API supported
 // by all tracers is used to deserialize the tracing context from the
-// application headers and start a new server-side span.
+// application headers and start a new server-side span. If the headers do not
+// contain a tracing context, a sampled Zipkin-style span from the frame is used
+// as the parent, so that tracers using W3C Trace Context continue traces from
+// callers that only support Zipkin-style IDs.
 // Once the span is started, it is wrapped in a new Context, which is returned.
 func ExtractInboundSpan(ctx context.Context, call *InboundCall, headers map[string]string, tracer opentracing.Tracer) context.Context {
 	var span = call.Response().span
@@ -250,10 +313,18 @@ func ExtractInboundSpan(ctx context.Context, call *InboundCall, headers map[stri
 			}
 			carrier.RemoveTracingKeys()
 		}
+		if parent == nil {
+			if tc, err := zipkinTraceContext(call.tracing); err == nil {
+				if p, err := tracer.Extract(opentracing.TextMap, tc.carrier()); err == nil {
+					parent = p
+				}
+			}
+		}
 		span = tracer.StartSpan(call.MethodString(), ext.RPCServerOption(parent))
 		ext.PeerService.Set(span, call.CallerName())
 		span.SetTag("as", string(call.Format()))
 		call.conn.setPeerHostPort(span)
+		setRPCTags(span, call.ServiceName(), call.MethodString())
 		call.Response().span = span
 	}
 	return opentracing.ContextWithSpan(ctx, span)
@@ -272,6 +343,29 @@ func (c *Connection) setPeerHostPort(span opentracing.Span) {
 	if c.remotePeerAddress.port != 0 {
 		ext.PeerPort.Set(span, c.remotePeerAddress.port)
 	}
+
+	switch {
+	case c.remotePeerAddress.ipv4 != 0:
+		ip := c.remotePeerAddress.ipv4
+		span.SetTag(networkPeerAddressTag, net.IPv4(byte(ip>>24), byte(ip>>16), byte(ip>>8), byte(ip)).String())
+	case c.remotePeerAddress.ipv6 != "":
+		span.SetTag(networkPeerAddressTag, c.remotePeerAddress.ipv6)
+	case c.remotePeerAddress.hostname != "":
+		span.SetTag(networkPeerAddressTag, c.remotePeerAddress.hostname)
+	}
+	if c.remotePeerAddress.port != 0 {
+		span.SetTag(networkPeerPortTag, int(c.remotePeerAddress.port))
+	}
+}
+
+// setRPCTags sets the OpenTelemetry semantic convention tags for an RPC span.
+// methodName may be empty if it is not known yet.
+func setRPCTags(span opentracing.Span, serviceName, methodName string) {
+	span.SetTag(rpcSystemTag, rpcSystemTChannel)
+	span.SetTag(rpcServiceTag, serviceName)
+	if methodName != "" {
+		span.SetTag(rpcMethodTag, methodName)
+	}
 }
 
 type tracerProvider interface {
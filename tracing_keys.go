@@ -74,8 +74,14 @@ func (m *tracingKeysMapping) mapAndCache(key string) string {
 
 type tracingHeadersCarrier map[string]string
 
-// Set implements Set() of opentracing.TextMapWriter
+// Set implements Set() of opentracing.TextMapWriter.
+// W3C Trace Context headers are not prefixed, so that they interoperate with
+// other OpenTelemetry implementations.
 func (c tracingHeadersCarrier) Set(key, val string) {
+	if isTraceContextKey(key) {
+		c[key] = val
+		return
+	}
 	prefixedKey := tracingKeyEncoding.mapAndCache(key)
 	c[prefixedKey] = val
 }
@@ -83,6 +89,12 @@ func (c tracingHeadersCarrier) Set(key, val string) {
 // ForeachKey conforms to the TextMapReader interface.
 func (c tracingHeadersCarrier) ForeachKey(handler func(key, val string) error) error {
 	for k, v := range c {
+		if isTraceContextKey(k) {
+			if err := handler(k, v); err != nil {
+				return err
+			}
+			continue
+		}
 		if !strings.HasPrefix(k, tracingKeyPrefix) {
 			continue
 		}
@@ -96,7 +108,7 @@ func (c tracingHeadersCarrier) ForeachKey(handler func(key, val string) error) e
 
 func (c tracingHeadersCarrier) RemoveTracingKeys() {
 	for key := range c {
-		if strings.HasPrefix(key, tracingKeyPrefix) {
+		if strings.HasPrefix(key, tracingKeyPrefix) || isTraceContextKey(key) {
 			delete(c, key)
 		}
 	}
@@ -29,6 +29,7 @@ import (
 
 	"github.com/temporalio/tchannel-go"
 	"github.com/temporalio/tchannel-go/json"
+	"github.com/temporalio/tchannel-go/raw"
 	"github.com/temporalio/tchannel-go/testutils"
 	"github.com/temporalio/tchannel-go/testutils/testtracing"
 
@@ -37,6 +38,7 @@ import (
 	"github.com/opentracing/opentracing-go/mocktracer"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-client-go"
 	"golang.org/x/net/context"
 )
 
@@ -167,6 +169,13 @@ func TestTracingSpanAttributes(t *testing.T) {
 		assert.NotNil(t, child.Tag("peer.ipv4"))
 		assert.NotNil(t, parent.Tag("peer.port"))
 		assert.NotNil(t, child.Tag("peer.port"))
+		for _, s := range []*mocktracer.MockSpan{parent, child} {
+			assert.Equal(t, "tchannel", s.Tag("rpc.system"))
+			assert.Equal(t, "testService", s.Tag("rpc.service"))
+			assert.Equal(t, "call", s.Tag("rpc.method"))
+			assert.Equal(t, "127.0.0.1", s.Tag("network.peer.address"))
+			assert.NotNil(t, s.Tag("network.peer.port"))
+		}
 	})
 }
 
@@ -210,3 +219,164 @@ func TestReusableHeaders(t *testing.T) {
 		assert.Equal(t, map[string]string{"life": "42"}, sharedHeaders, "headers unchanged")
 	})
 }
+
+// waitForSampledSpans waits for the tracer to record n sampled spans.
+func waitForSampledSpans(t testing.TB, tracer *mocktracer.MockTracer, n int) []*mocktracer.MockSpan {
+	// Spans are finished on different goroutines, and may finish after the
+	// response has been received.
+	testutils.WaitFor(time.Second, func() bool {
+		return len(testtracing.MockTracerSampledSpans(tracer)) >= n
+	})
+	spans := testtracing.MockTracerSampledSpans(tracer)
+	require.Len(t, spans, n, "Wrong span count")
+	return spans
+}
+
+func spanByKind(spans []*mocktracer.MockSpan, kind ext.SpanKindEnum) *mocktracer.MockSpan {
+	for _, s := range spans {
+		if s.Tag("span.kind") == kind {
+			return s
+		}
+	}
+	return nil
+}
+
+func TestW3CTraceContextRaw(t *testing.T) {
+	tracer := testtracing.NewW3CTracer()
+	opts := testutils.NewOpts().SetTracer(tracer)
+
+	testutils.WithTestServer(t, opts, func(t testing.TB, ts *testutils.TestServer) {
+		tracer.Reset()
+
+		var serverSpan opentracing.Span
+		testutils.RegisterFunc(ts.Server(), "echo", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			serverSpan = opentracing.SpanFromContext(ctx)
+			return &raw.Res{Arg2: args.Arg2, Arg3: args.Arg3}, nil
+		})
+
+		client := ts.NewClient(opts)
+		span := tracer.StartSpan("client")
+		ctx, cancel := tchannel.NewContextBuilder(testutils.Timeout(time.Second)).
+			SetParentContext(opentracing.ContextWithSpan(context.Background(), span)).
+			Build()
+		defer cancel()
+
+		_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, nil)
+		require.NoError(t, err, "Call failed")
+		require.NotNil(t, serverSpan, "Handler should have a span from the transport headers")
+
+		// The relay does not create spans, so there is only a client and server span.
+		spans := waitForSampledSpans(t, tracer, 2)
+		span.Finish()
+
+		clientSpan := spanByKind(spans, ext.SpanKindRPCClientEnum)
+		inboundSpan := spanByKind(spans, ext.SpanKindRPCServerEnum)
+		require.NotNil(t, clientSpan, "Missing client span")
+		require.NotNil(t, inboundSpan, "Missing server span")
+		assert.Equal(t, serverSpan, inboundSpan, "Handler span should be the server span")
+
+		clientCtx := clientSpan.Context().(mocktracer.MockSpanContext)
+		serverCtx := inboundSpan.Context().(mocktracer.MockSpanContext)
+		assert.Equal(t, span.Context().(mocktracer.MockSpanContext).TraceID, clientCtx.TraceID, "Client span trace ID mismatch")
+		assert.Equal(t, clientCtx.TraceID, serverCtx.TraceID, "Server span trace ID mismatch")
+		assert.Equal(t, clientCtx.SpanID, inboundSpan.ParentID, "Server span should be a child of the client span")
+
+		for _, s := range spans {
+			assert.Equal(t, "tchannel", s.Tag("rpc.system"), "rpc.system tag")
+			assert.Equal(t, ts.ServiceName(), s.Tag("rpc.service"), "rpc.service tag")
+			assert.Equal(t, "echo", s.Tag("rpc.method"), "rpc.method tag")
+		}
+	})
+}
+
+func TestW3CTraceContextRawNotSampled(t *testing.T) {
+	tracer := testtracing.NewW3CTracer()
+	opts := testutils.NewOpts().SetTracer(tracer)
+
+	testutils.WithTestServer(t, opts, func(t testing.TB, ts *testutils.TestServer) {
+		var serverCtx mocktracer.MockSpanContext
+		testutils.RegisterFunc(ts.Server(), "echo", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			if span := opentracing.SpanFromContext(ctx); span != nil {
+				serverCtx = span.Context().(mocktracer.MockSpanContext)
+			}
+			return &raw.Res{}, nil
+		})
+
+		client := ts.NewClient(opts)
+		span := tracer.StartSpan("client")
+		defer span.Finish()
+		ctx, cancel := tchannel.NewContextBuilder(testutils.Timeout(time.Second)).
+			SetParentContext(opentracing.ContextWithSpan(context.Background(), span)).
+			DisableTracing().
+			Build()
+		defer cancel()
+
+		_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, nil)
+		require.NoError(t, err, "Call failed")
+
+		// The sampling decision is propagated in the traceparent flags.
+		assert.Equal(t, span.Context().(mocktracer.MockSpanContext).TraceID, serverCtx.TraceID, "Trace ID mismatch")
+		assert.False(t, serverCtx.Sampled, "Server span should not be sampled")
+	})
+}
+
+func TestZipkinCallerToW3CServer(t *testing.T) {
+	jaegerReporter := jaeger.NewInMemoryReporter()
+	jaegerTracer, jaegerCloser := jaeger.NewTracer(testutils.DefaultClientName,
+		jaeger.NewConstSampler(true),
+		jaegerReporter)
+	defer jaegerCloser.Close()
+
+	w3cTracer := testtracing.NewW3CTracer()
+	opts := testutils.NewOpts().SetTracer(w3cTracer)
+
+	testutils.WithTestServer(t, opts, func(t testing.TB, ts *testutils.TestServer) {
+		var (
+			mu        sync.Mutex
+			serverIDs []mocktracer.MockSpanContext
+		)
+		recordSpan := func(ctx context.Context) {
+			mu.Lock()
+			defer mu.Unlock()
+			if span := opentracing.SpanFromContext(ctx); span != nil {
+				serverIDs = append(serverIDs, span.Context().(mocktracer.MockSpanContext))
+			}
+		}
+		testutils.RegisterFunc(ts.Server(), "echo", func(ctx context.Context, args *raw.Args) (*raw.Res, error) {
+			recordSpan(ctx)
+			return &raw.Res{}, nil
+		})
+		jsonHandler := &JSONHandler{
+			TraceHandler: testtracing.TraceHandler{Ch: ts.Server()},
+			t:            t.(*testing.T),
+			sideEffect:   func(ctx json.Context) { recordSpan(ctx) },
+		}
+		json.Register(ts.Server(), json.Handlers{"call": jsonHandler.callJSON}, jsonHandler.onError)
+
+		client := ts.NewClient(testutils.NewOpts().SetTracer(jaegerTracer))
+		span := jaegerTracer.StartSpan("client")
+		defer span.Finish()
+		ctx, cancel := tchannel.NewContextBuilder(testutils.Timeout(time.Second)).
+			SetParentContext(opentracing.ContextWithSpan(context.Background(), span)).
+			Build()
+		defer cancel()
+
+		_, _, _, err := raw.Call(ctx, client, ts.HostPort(), ts.ServiceName(), "echo", nil, nil)
+		require.NoError(t, err, "raw call failed")
+
+		var response testtracing.TracingResponse
+		peer := client.Peers().GetOrAdd(ts.HostPort())
+		require.NoError(t, json.CallPeer(json.Wrap(ctx), peer, ts.ServiceName(), "call", &testtracing.TracingRequest{}, &response), "json call failed")
+
+		// The server tracer does not understand the Jaeger headers, so it
+		// continues the trace using the Zipkin-style IDs in the frame.
+		wantTraceID := tchannel.CurrentSpan(ctx).TraceID()
+		mu.Lock()
+		defer mu.Unlock()
+		require.Len(t, serverIDs, 2, "Expected a span for each call")
+		for _, sc := range serverIDs {
+			assert.Equal(t, wantTraceID, uint64(sc.TraceID), "Server should continue the Zipkin trace")
+			assert.True(t, sc.Sampled, "Server span should be sampled")
+		}
+	})
+}
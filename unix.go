@@ -0,0 +1,73 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"go.uber.org/atomic"
+)
+
+// unixScheme is the prefix used for peers addressed by a Unix domain socket.
+// Paths starting with "@" (e.g. "unix://@name") use the Linux abstract
+// socket namespace.
+const unixScheme = "unix://"
+
+// ephemeralUnixPeers is used to give each inbound connection from an
+// unnamed Unix socket a unique host:port.
+var ephemeralUnixPeers atomic.Uint64
+
+// ephemeralUnixHostPortFormat is the host:port of an unnamed Unix socket peer.
+// It's not a valid Unix socket or TCP address, so it can't be dialed, and the
+// ":0" port makes isEphemeralHostPort treat it as ephemeral.
+const ephemeralUnixHostPortFormat = "unix@ephemeral-%d:0"
+
+// isUnixHostPort returns whether hostPort addresses a Unix domain socket.
+func isUnixHostPort(hostPort string) bool {
+	return strings.HasPrefix(hostPort, unixScheme)
+}
+
+// splitNetworkAddress returns the network and address to use with the net
+// package for the given hostPort.
+func splitNetworkAddress(hostPort string) (network, address string) {
+	if isUnixHostPort(hostPort) {
+		return "unix", strings.TrimPrefix(hostPort, unixScheme)
+	}
+	return "tcp", hostPort
+}
+
+// addrHostPort returns the host:port used to identify a peer at addr.
+func addrHostPort(addr net.Addr) string {
+	unixAddr, ok := addr.(*net.UnixAddr)
+	if !ok {
+		return addr.String()
+	}
+
+	// Clients dialing a Unix socket are typically unnamed, so there is no
+	// address that identifies them. Generate a unique one so that each
+	// connection gets its own ephemeral peer, as with TCP.
+	if unixAddr.Name == "" || unixAddr.Name == "@" {
+		return fmt.Sprintf(ephemeralUnixHostPortFormat, ephemeralUnixPeers.Inc())
+	}
+	return unixScheme + unixAddr.Name
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	. "github.com/temporalio/tchannel-go"
+
+	"github.com/temporalio/tchannel-go/raw"
+	"github.com/temporalio/tchannel-go/relay/relaytest"
+	"github.com/temporalio/tchannel-go/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newUnixServer returns a server listening on the given Unix socket address.
+func newUnixServer(t testing.TB, hostPort string, opts *testutils.ChannelOpts) *Channel {
+	opts = opts.Copy().SetServiceName("unix-server")
+	ch := testutils.NewClient(t, opts)
+	require.NoError(t, ch.ListenAndServe(hostPort), "ListenAndServe failed")
+	testutils.RegisterEcho(ch, nil)
+	return ch
+}
+
+func unixSocketHostPort(t testing.TB) string {
+	return "unix://" + filepath.Join(t.TempDir(), "tchannel.sock")
+}
+
+func TestUnixSocket(t *testing.T) {
+	tests := []struct {
+		msg      string
+		hostPort func(t testing.TB) string
+	}{
+		{
+			msg:      "path",
+			hostPort: unixSocketHostPort,
+		},
+		{
+			msg: "abstract",
+			hostPort: func(t testing.TB) string {
+				if runtime.GOOS != "linux" {
+					t.Skip("abstract sockets are only supported on Linux")
+				}
+				return fmt.Sprintf("unix://@tchannel-test-%v", testutils.RandString(10))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			hostPort := tt.hostPort(t)
+			server := newUnixServer(t, hostPort, nil)
+			defer server.Close()
+			assert.Equal(t, hostPort, server.PeerInfo().HostPort, "Unexpected server host:port")
+
+			client1 := testutils.NewClient(t, nil)
+			defer client1.Close()
+			client2 := testutils.NewClient(t, nil)
+			defer client2.Close()
+
+			// Calls should work via the peer list as well as a specific host:port.
+			sc := client1.GetSubChannel(server.ServiceName())
+			sc.Peers().Add(hostPort)
+			ctx, cancel := NewContext(testutils.Timeout(time.Second))
+			defer cancel()
+			require.NoError(t, client1.Ping(ctx, hostPort), "Ping failed")
+			_, _, _, err := raw.CallSC(ctx, sc, "echo", []byte("arg2"), []byte("arg3"))
+			require.NoError(t, err, "Call using peer list failed")
+			testutils.AssertEcho(t, client1, hostPort, server.ServiceName())
+			testutils.AssertEcho(t, client2, hostPort, server.ServiceName())
+
+			clientState := client1.IntrospectState(nil)
+			assert.Contains(t, clientState.RootPeers, hostPort, "Client should have the Unix socket peer")
+
+			// Each inbound connection from an unnamed socket should get a unique
+			// peer, which can't be dialed.
+			client3 := testutils.NewClient(t, nil)
+			defer client3.Close()
+			serverPeers := server.RootPeers().Copy()
+			require.Len(t, serverPeers, 2, "Expected a peer per client")
+			for peerHostPort, peer := range serverPeers {
+				in, out := peer.NumConnections()
+				assert.Equal(t, 1, in, "Expected a single inbound connection for %v", peerHostPort)
+				assert.Equal(t, 0, out, "Expected no outbound connections for %v", peerHostPort)
+				assert.Error(t, client3.Ping(ctx, peerHostPort), "Ephemeral peer %v should not be dialable", peerHostPort)
+			}
+		})
+	}
+}
+
+func TestUnixSocketRelay(t *testing.T) {
+	relayHost := relaytest.NewStubRelayHost()
+	relay := testutils.NewServer(t, testutils.NewOpts().SetServiceName("relay").SetRelayHost(relayHost))
+	defer relay.Close()
+
+	hostPort := unixSocketHostPort(t)
+	server := newUnixServer(t, hostPort, nil)
+	defer server.Close()
+	relayHost.Add(server.ServiceName(), hostPort)
+
+	client := testutils.NewClient(t, nil)
+	defer client.Close()
+
+	for i := 0; i < 5; i++ {
+		testutils.AssertEcho(t, client, relay.PeerInfo().HostPort, server.ServiceName())
+	}
+
+	peer, ok := relay.RootPeers().Get(hostPort)
+	require.True(t, ok, "Relay should have a peer for the Unix socket")
+	_, out := peer.NumConnections()
+	assert.Equal(t, 1, out, "Relay should have a single outbound connection to the server")
+}
+
+func TestUnixSocketIdleSweep(t *testing.T) {
+	ticker := testutils.NewFakeTicker()
+	clock := testutils.NewStubClock(time.Now())
+	opts := testutils.NewOpts().
+		SetTimeNow(clock.Now).
+		SetTimeTicker(ticker.New).
+		SetMaxIdleTime(3 * time.Minute).
+		SetIdleCheckInterval(30 * time.Second)
+
+	hostPort := unixSocketHostPort(t)
+	server := newUnixServer(t, hostPort, nil)
+	defer server.Close()
+
+	client := testutils.NewClient(t, opts)
+	defer client.Close()
+	testutils.AssertEcho(t, client, hostPort, server.ServiceName())
+	require.Equal(t, 1, numConnections(client), "Expected a connection to the server")
+
+	clock.Elapse(5 * time.Minute)
+	ticker.Tick()
+	assert.True(t, testutils.WaitFor(time.Second, func() bool {
+		return numConnections(client) == 0 && numConnections(server) == 0
+	}), "Idle Unix socket connections were not closed")
+}